@@ -0,0 +1,276 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	dbProvider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/coti-io/coti-db-app/health"
+	"github.com/coti-io/coti-db-app/jobs"
+	"github.com/coti-io/coti-db-app/leader"
+	"github.com/coti-io/coti-db-app/logger"
+	"github.com/coti-io/coti-db-app/metrics"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/coti-io/coti-db-app/tracing"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var archiveOnce sync.Once
+
+// archivedTables are archived with the transactions, the parent rows are selected by the condition of each table.
+// The token generation data stays since the currencies are defined by it
+var archivedTables = []archivedTable{
+	{name: "input_base_transactions", column: "transactionId"},
+	{name: "receiver_base_transactions", column: "transactionId"},
+	{name: "fullnode_fee_base_transactions", column: "transactionId"},
+	{name: "network_fee_base_transactions", column: "transactionId"},
+	{name: "event_input_base_transactions", column: "transactionId"},
+	{name: "token_minting_service_data", column: "baseTransactionId", parentTable: "token_minting_fee_base_transactions"},
+	{name: "token_minting_fee_base_transactions", column: "transactionId"},
+	{name: "transaction_addresses", column: "transactionId"},
+	{name: "transaction_currencies", column: "transactionId"},
+	{name: "transactions", column: "id"},
+}
+
+type archivedTable struct {
+	name   string
+	column string
+	// parentTable is set when column references the parentTable rows of the transactions instead of the transactions
+	parentTable string
+}
+
+type ArchiveService interface {
+	// Run keeps the partitions and the archive up to date while the elector has the instance lead, it does nothing when neither is configured
+	Run(elector leader.Elector)
+	// GetArchivedTransaction finds an archived transaction by its hash, the transaction is nil when the archive is not a table
+	GetArchivedTransaction(hash string) (*entities.ArchiveBatch, *entities.Transaction, error)
+}
+type archiveService struct {
+	db             *gorm.DB
+	retention      time.Duration
+	reversalWindow time.Duration
+	target         entities.ArchiveTarget
+	dir            string
+	batchSize      int
+	interval       time.Duration
+	partitionKey   dbProvider.PartitionKey
+	partitionSize  int64
+	elector        leader.Elector
+}
+
+var archiveServiceInstance *archiveService
+
+func NewArchiveService(db *gorm.DB) ArchiveService {
+	archiveOnce.Do(func() {
+		archiveServiceInstance = newArchiveService(db)
+	})
+	return archiveServiceInstance
+}
+
+func newArchiveService(db *gorm.DB) *archiveService {
+	appConfig := config.Get()
+	return &archiveService{
+		db:             db,
+		retention:      appConfig.Archive.Retention,
+		reversalWindow: appConfig.Sync.ReversalMonitorWindow,
+		target:         entities.ArchiveTarget(appConfig.Archive.Target),
+		dir:            appConfig.Archive.Dir,
+		batchSize:      appConfig.Archive.BatchSize,
+		interval:       appConfig.Archive.Interval,
+		partitionKey:   dbProvider.PartitionKey(appConfig.Partition.By),
+		partitionSize:  appConfig.Partition.Size,
+	}
+}
+
+func (service *archiveService) Run(elector leader.Elector) {
+	if service.retention == 0 && service.partitionKey == "" {
+		return
+	}
+	service.elector = elector
+	go service.archive()
+}
+
+func (service *archiveService) archive() {
+	job := jobs.Register("archive", service.interval, service.batchSize)
+	iteration := 0
+	for {
+		interval := job.GetInterval()
+		dtStart := time.Now()
+		iterationLog := logger.WithIteration("archive", iteration)
+		health.Beat("archive", iteration)
+		if job.SleepIfPaused(iterationLog) || leader.SleepIfFollower(service.elector, iterationLog, interval) {
+			continue
+		}
+		iterationLog.Debug("iteration start")
+		iteration = iteration + 1
+		var err error
+		if service.partitionKey != "" {
+			err = dbProvider.PartitionTables(service.partitionKey, service.partitionSize)
+			if err != nil {
+				iterationLog.WithError(err).Error("partitioning failed")
+			}
+		}
+		if service.retention > 0 {
+			// archives batches until the transactions older than the retention are done
+			batchSize := job.GetBatchSize()
+			for {
+				archivedCount, archiveErr := service.archiveIteration(batchSize)
+				if archiveErr != nil {
+					iterationLog.WithError(archiveErr).Error("archiving failed")
+					err = archiveErr
+				}
+				if archiveErr != nil || archivedCount < batchSize {
+					break
+				}
+			}
+		}
+		job.Record(iteration, jobs.ScheduleTrigger, dtStart, err)
+		logger.WithDuration(iterationLog, dtStart).Debug("iteration end")
+		diff := time.Now().Sub(dtStart)
+		metrics.IterationDuration.WithLabelValues(metrics.ArchiveJob).Observe(diff.Seconds())
+		if diff < interval {
+			timeDurationToSleep := interval - diff
+			iterationLog.WithField("sleepSeconds", timeDurationToSleep.Seconds()).Debug("sleeping")
+			time.Sleep(timeDurationToSleep)
+		}
+	}
+}
+
+// archiveIteration moves one batch of processed transactions older than the retention out of the hot tables.
+// The balances, address counts and currency supplies are not touched since they already include the archived transactions
+func (service *archiveService) archiveIteration(batchSize int) (archivedCount int, err error) {
+	ctx, span := tracing.StartIteration("archive")
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		if r := recover(); r != nil {
+			logger.LogPanic(logger.WithJob("archive"), r)
+			metrics.DbTransactionRollbacks.WithLabelValues(metrics.ArchiveJob).Inc()
+			err = fmt.Errorf("archive iteration failed: %v", r)
+		}
+	}()
+	var batchDir string
+	err = service.db.WithContext(ctx).Transaction(func(dbTransaction *gorm.DB) error {
+		if err := service.elector.Fence(repository.NewGormRepositories(dbTransaction)); err != nil {
+			return err
+		}
+		var appState entities.AppState
+		err := dbTransaction.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", entities.ArchiveTransactions).First(&appState).Error
+		if err != nil {
+			return err
+		}
+		cutoff := decimal.NewFromInt(time.Now().Add(-service.retention).Unix())
+		// the processed time keeps out the transactions the monitor can still reverse
+		var txs []entities.Transaction
+		err = dbTransaction.Select("id", "hash", "index", "attachmentTime").
+			Where(map[string]interface{}{"isProcessed": true}).Not(map[string]interface{}{"index": nil}).
+			Where(clause.Lt{Column: "attachmentTime", Value: cutoff}).
+			Where(clause.Lt{Column: "processedTime", Value: dbProvider.Ago(service.reversalWindow)}).
+			Order(clause.OrderByColumn{Column: clause.Column{Name: "attachmentTime"}}).Limit(batchSize).Find(&txs).Error
+		if err != nil || len(txs) == 0 {
+			return err
+		}
+
+		batch := &entities.ArchiveBatch{
+			Target:             service.target,
+			TransactionCount:   int32(len(txs)),
+			FromAttachmentTime: txs[0].AttachmentTime,
+			ToAttachmentTime:   txs[len(txs)-1].AttachmentTime,
+		}
+		transactionIds := make([]int32, 0, len(txs))
+		for _, tx := range txs {
+			transactionIds = append(transactionIds, tx.ID)
+			if *tx.Index > batch.MaxIndex {
+				batch.MaxIndex = *tx.Index
+			}
+		}
+		if err := dbTransaction.Omit("CreateTime", "UpdateTime").Create(batch).Error; err != nil {
+			return err
+		}
+		var archiver tableArchiver
+		if service.target == entities.CsvArchiveTarget {
+			batchDir = filepath.Join(service.dir, fmt.Sprintf("batch-%d", batch.ID))
+			archiver = &csvArchiver{db: dbTransaction, dir: batchDir + ".tmp"}
+			batch.Location = batchDir
+		} else {
+			archiver = &archiveTableArchiver{db: dbTransaction}
+			batch.Location = "_archive"
+		}
+		for _, table := range archivedTables {
+			condition := table.condition(transactionIds)
+			if err := archiver.archive(table.name, condition); err != nil {
+				return err
+			}
+			if err := dbTransaction.Exec("DELETE FROM ? WHERE ?", clause.Table{Name: table.name}, condition).Error; err != nil {
+				return err
+			}
+		}
+
+		archivedTransactions := make([]*entities.ArchivedTransaction, 0, len(txs))
+		for i := range txs {
+			archivedTransactions = append(archivedTransactions, entities.NewArchivedTransaction(&txs[i], batch.ID))
+		}
+		if err := dbTransaction.Omit("CreateTime", "UpdateTime").Create(&archivedTransactions).Error; err != nil {
+			return err
+		}
+		if err := dbTransaction.Omit("CreateTime", "UpdateTime").Save(batch).Error; err != nil {
+			return err
+		}
+		archivedCount = len(txs)
+		logger.WithJob("archive").WithFields(logrus.Fields{"count": archivedCount, logger.ToIndexField: batch.MaxIndex, "batchId": batch.ID}).Info("transactions archived")
+		return nil
+	})
+	if err != nil {
+		metrics.DbTransactionRollbacks.WithLabelValues(metrics.ArchiveJob).Inc()
+	}
+	if batchDir != "" {
+		// the batch files are only kept when the rows were deleted
+		if err != nil {
+			_ = os.RemoveAll(batchDir + ".tmp")
+			return 0, err
+		}
+		if renameErr := os.Rename(batchDir+".tmp", batchDir); renameErr != nil {
+			logger.WithJob("archive").WithError(renameErr).WithField("dir", batchDir+".tmp").Error("failed to rename the batch files")
+		}
+	}
+	return archivedCount, err
+}
+
+// condition selects the rows of the table that belong to the transactions
+func (table archivedTable) condition(transactionIds []int32) clause.Expr {
+	if table.parentTable == "" {
+		return gorm.Expr("? IN ?", clause.Column{Name: table.column}, transactionIds)
+	}
+	return gorm.Expr("? IN (SELECT ? FROM ? WHERE ? IN ?)",
+		clause.Column{Name: table.column}, clause.Column{Name: "id"}, clause.Table{Name: table.parentTable},
+		clause.Column{Name: "transactionId"}, transactionIds)
+}
+
+func (service *archiveService) GetArchivedTransaction(hash string) (*entities.ArchiveBatch, *entities.Transaction, error) {
+	var archivedTransaction entities.ArchivedTransaction
+	err := service.db.Where("hash = ?", hash).First(&archivedTransaction).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	var batch entities.ArchiveBatch
+	err = service.db.First(&batch, archivedTransaction.ArchiveBatchId).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if batch.Target != entities.TableArchiveTarget {
+		return &batch, nil, nil
+	}
+	var tx entities.Transaction
+	err = service.db.Table("transactions_archive").Where("id = ?", archivedTransaction.ID).First(&tx).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	return &batch, &tx, nil
+}
@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// csvNull marks a NULL value in the archive csv files, the same marker mysql uses in its dumps
+const csvNull = `\N`
+
+// tableArchiver copies the rows of a table matching the condition to the archive before they are deleted
+type tableArchiver interface {
+	archive(table string, condition clause.Expr) error
+}
+
+// archiveTableArchiver copies the rows to <table>_archive, the columns of the archive table are copied so
+// a column added to a table after its archive table was created doesn't break the archive
+type archiveTableArchiver struct {
+	db *gorm.DB
+}
+
+func (archiver *archiveTableArchiver) archive(table string, condition clause.Expr) error {
+	archiveTable := table + "_archive"
+	columnTypes, err := archiver.db.Migrator().ColumnTypes(archiveTable)
+	if err != nil {
+		return err
+	}
+	columns := make([]clause.Column, 0, len(columnTypes))
+	placeholders := make([]string, 0, len(columnTypes))
+	for _, columnType := range columnTypes {
+		columns = append(columns, clause.Column{Name: columnType.Name()})
+		placeholders = append(placeholders, "?")
+	}
+	columnList := strings.Join(placeholders, ", ")
+	vars := []interface{}{clause.Table{Name: archiveTable}}
+	for _, column := range columns {
+		vars = append(vars, column)
+	}
+	for _, column := range columns {
+		vars = append(vars, column)
+	}
+	vars = append(vars, clause.Table{Name: table}, condition)
+	return archiver.db.Exec("INSERT INTO ? ("+columnList+") SELECT "+columnList+" FROM ? WHERE ?", vars...).Error
+}
+
+// csvArchiver writes the rows to a gzipped csv file per table with a header line of the column names
+type csvArchiver struct {
+	db  *gorm.DB
+	dir string
+}
+
+func (archiver *csvArchiver) archive(table string, condition clause.Expr) error {
+	if err := os.MkdirAll(archiver.dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(archiver.dir, table+".csv.gz"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gzipWriter := gzip.NewWriter(file)
+	csvWriter := csv.NewWriter(gzipWriter)
+
+	rows, err := archiver.db.Raw("SELECT * FROM ? WHERE ?", clause.Table{Name: table}, condition).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if err := csvWriter.Write(columns); err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	valuePointers := make([]interface{}, len(columns))
+	for i := range values {
+		valuePointers[i] = &values[i]
+	}
+	line := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(valuePointers...); err != nil {
+			return err
+		}
+		for i, value := range values {
+			line[i] = formatCsvValue(value)
+		}
+		if err := csvWriter.Write(line); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func formatCsvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return csvNull
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/entities"
+	rateLimit "github.com/coti-io/coti-db-app/rate-limit"
+	repository "github.com/coti-io/coti-db-app/repositories"
+)
+
+// apiKeyPrefix starts every key so a leaked key is easy to recognize
+const apiKeyPrefix = "cdb_"
+
+// prefixLength is how much of a key is kept to tell the keys apart
+const prefixLength = 12
+
+const maxApiKeyNameLength = 100
+
+// maxCachedApiKeys bounds the cache, only the valid keys are cached so it is only emptied when there are more of them
+const maxCachedApiKeys = 10000
+
+var ErrInvalidApiKeySettings = errors.New("invalid api key settings")
+
+// CreateApiKey creates a key of the scope, a limit of 0 is the default of the config.
+// Only the hash of the key is kept, the returned key can't be shown again
+func CreateApiKey(repositories repository.Repositories, name string, scope entities.ApiKeyScope, limit rateLimit.Limit) (string, *entities.ApiKey, error) {
+	if name == "" || len(name) > maxApiKeyNameLength {
+		return "", nil, fmt.Errorf("%w: the name must have 1 to %d characters", ErrInvalidApiKeySettings, maxApiKeyNameLength)
+	}
+	if !scope.IsValid() {
+		return "", nil, fmt.Errorf("%w: the scope must be one of public, read and admin, got %s", ErrInvalidApiKeySettings, scope)
+	}
+	if limit.Rate < 0 || limit.Burst < 0 {
+		return "", nil, fmt.Errorf("%w: the rate limit and the burst can't be negative", ErrInvalidApiKeySettings)
+	}
+	_, err := repositories.ApiKeys().GetByName(name)
+	if err == nil {
+		return "", nil, fmt.Errorf("%w: a key named %s already exists", ErrInvalidApiKeySettings, name)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return "", nil, err
+	}
+	randomBytes := make([]byte, 32)
+	if _, err = rand.Read(randomBytes); err != nil {
+		return "", nil, err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(randomBytes)
+	apiKey := &entities.ApiKey{
+		Name:           name,
+		Prefix:         key[:prefixLength],
+		KeyHash:        hashApiKey(key),
+		Scope:          scope,
+		RateLimit:      limit.Rate,
+		RateLimitBurst: limit.Burst,
+	}
+	err = repositories.ApiKeys().Create(apiKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return key, apiKey, nil
+}
+
+// RevokeApiKey revokes the key of the name, the other processes refuse it once their cache expires
+func RevokeApiKey(repositories repository.Repositories, name string) error {
+	err := repositories.ApiKeys().Revoke(name)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: no api key is named %s", err, name)
+	}
+	if err != nil {
+		return err
+	}
+	apiKeys.forget()
+	return nil
+}
+
+func hashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+type cachedApiKey struct {
+	apiKey     *entities.ApiKey
+	expireTime time.Time
+}
+
+// apiKeyCache keeps the valid keys by their hash for API_KEY_CACHE_TTL_IN_SECONDS so a request doesn't read the db.
+// The unknown and revoked keys are not cached, they would evict the valid ones and their requests are limited by ip
+type apiKeyCache struct {
+	mutex sync.Mutex
+	keys  map[string]cachedApiKey
+}
+
+var apiKeys = &apiKeyCache{keys: map[string]cachedApiKey{}}
+
+// getCached finds a valid key in the cache, it returns nil when the key is not cached or its cache expired
+func (cache *apiKeyCache) getCached(key string) *entities.ApiKey {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cached, ok := cache.keys[hashApiKey(key)]
+	if !ok || !time.Now().Before(cached.expireTime) {
+		return nil
+	}
+	return cached.apiKey
+}
+
+// load reads the key from the db and caches it when it is valid, it returns nil when it is unknown or revoked
+func (cache *apiKeyCache) load(repositories repository.Repositories, key string) (*entities.ApiKey, error) {
+	keyHash := hashApiKey(key)
+	apiKey, err := repositories.ApiKeys().GetByKeyHash(keyHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if apiKey.IsRevoked {
+		delete(cache.keys, keyHash)
+		return nil, nil
+	}
+	if len(cache.keys) >= maxCachedApiKeys {
+		cache.keys = map[string]cachedApiKey{}
+	}
+	cache.keys[keyHash] = cachedApiKey{apiKey: apiKey, expireTime: time.Now().Add(config.Get().Api.KeyCacheTtl)}
+	return apiKey, nil
+}
+
+func (cache *apiKeyCache) forget() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.keys = map[string]cachedApiKey{}
+}
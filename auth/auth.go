@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/coti-io/coti-db-app/metrics"
+	rateLimit "github.com/coti-io/coti-db-app/rate-limit"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+// callerKey is the gin context key of the caller found by Authenticate
+const callerKey = "caller"
+
+// the kinds of callers
+const (
+	anonymousCaller  = "anonymous"
+	apiKeyCaller     = "api-key"
+	adminTokenCaller = "admin-token"
+)
+
+// Caller is who sent the request
+type Caller struct {
+	Kind string
+	// Name is the name of the api key, it is empty for the other callers
+	Name  string
+	Scope entities.ApiKeyScope
+	limit rateLimit.Limit
+}
+
+var limiter = rateLimit.NewLimiter(time.Now)
+
+// Authenticate finds the caller by the api key of the bearer token or the X-Api-Key header and rate limits it,
+// the requests without a key and the keys that are not cached are limited by ip. The routes check the scope with RequireScope
+func Authenticate(repositories repository.Repositories) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiConfig := config.Get().Api
+		ipLimit := rateLimit.Limit{Rate: apiConfig.IpRateLimit, Burst: apiConfig.IpRateLimitBurst}
+		key := getRequestKey(c)
+		if key == "" {
+			if allow(c, "ip", "ip:"+c.ClientIP(), ipLimit) {
+				c.Set(callerKey, Caller{Kind: anonymousCaller, Scope: entities.ApiKeyScope(apiConfig.AnonymousScope)})
+				c.Next()
+			}
+			return
+		}
+		caller := findKnownCaller(key)
+		if caller == nil {
+			// the ip limit comes before the db lookup so the invalid keys can't load the db or be guessed faster than anonymous requests
+			if !allow(c, "ip", "ip:"+c.ClientIP(), ipLimit) {
+				return
+			}
+			var err error
+			caller, err = loadCaller(repositories.WithContext(c.Request.Context()), key)
+			if err != nil {
+				_ = c.Error(err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check the api key"})
+				return
+			}
+			if caller == nil {
+				c.Header("WWW-Authenticate", "Bearer")
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+				return
+			}
+		}
+		if allow(c, "key", caller.Kind+":"+caller.Name, caller.limit) {
+			c.Set(callerKey, *caller)
+			c.Next()
+		}
+	}
+}
+
+// RequireScope refuses the callers whose scope doesn't include the scope, it runs after Authenticate
+func RequireScope(scope entities.ApiKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := GetCaller(c)
+		if caller.Scope.Includes(scope) {
+			c.Next()
+			return
+		}
+		if caller.Kind == anonymousCaller {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("an api key with the %s scope is required", scope)})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("the api key doesn't have the %s scope", scope)})
+	}
+}
+
+// GetCaller is the caller that was let through by Authenticate
+func GetCaller(c *gin.Context) Caller {
+	caller, _ := c.Get(callerKey)
+	found, _ := caller.(Caller)
+	return found
+}
+
+// GetActor is the caller as it is written to the audit log
+func GetActor(c *gin.Context) string {
+	caller := GetCaller(c)
+	if caller.Kind == apiKeyCaller {
+		return apiKeyCaller + ":" + caller.Name + "@" + c.ClientIP()
+	}
+	return caller.Kind + "@" + c.ClientIP()
+}
+
+func getRequestKey(c *gin.Context) string {
+	if key := c.GetHeader("X-Api-Key"); key != "" {
+		return key
+	}
+	authorization := c.GetHeader("Authorization")
+	if strings.HasPrefix(authorization, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	}
+	return ""
+}
+
+// findKnownCaller finds the caller of the admin token or of a cached api key without reading the db, it returns nil otherwise
+func findKnownCaller(key string) *Caller {
+	adminToken := config.Get().Admin.Token
+	if adminToken != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminToken)) == 1 {
+		return &Caller{Kind: adminTokenCaller, Scope: entities.AdminScope, limit: getDefaultKeyLimit()}
+	}
+	if apiKey := apiKeys.getCached(key); apiKey != nil {
+		return newApiKeyCaller(apiKey)
+	}
+	return nil
+}
+
+// loadCaller reads the api key from the db, it returns nil when the key is not valid
+func loadCaller(repositories repository.Repositories, key string) (*Caller, error) {
+	apiKey, err := apiKeys.load(repositories, key)
+	if err != nil || apiKey == nil {
+		return nil, err
+	}
+	return newApiKeyCaller(apiKey), nil
+}
+
+func newApiKeyCaller(apiKey *entities.ApiKey) *Caller {
+	caller := &Caller{Kind: apiKeyCaller, Name: apiKey.Name, Scope: apiKey.Scope, limit: getDefaultKeyLimit()}
+	if apiKey.RateLimit > 0 {
+		caller.limit.Rate = apiKey.RateLimit
+	}
+	if apiKey.RateLimitBurst > 0 {
+		caller.limit.Burst = apiKey.RateLimitBurst
+	}
+	return caller
+}
+
+func getDefaultKeyLimit() rateLimit.Limit {
+	apiConfig := config.Get().Api
+	return rateLimit.Limit{Rate: apiConfig.KeyRateLimit, Burst: apiConfig.KeyRateLimitBurst}
+}
+
+// allow takes a token from the bucket of the ip or the caller, the request is refused with 429 when there is none
+func allow(c *gin.Context, bucket string, bucketKey string, limit rateLimit.Limit) bool {
+	ok, retryAfter := limiter.Allow(bucketKey, limit)
+	if ok {
+		return true
+	}
+	retryAfterSeconds := int(math.Ceil(retryAfter.Seconds()))
+	if retryAfterSeconds < 1 {
+		retryAfterSeconds = 1
+	}
+	metrics.RateLimitedRequests.WithLabelValues(bucket).Inc()
+	c.Header("Retry-After", strconv.Itoa(retryAfterSeconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("rate limit exceeded, retry after %d seconds", retryAfterSeconds)})
+	return false
+}
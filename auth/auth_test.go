@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/entities"
+	rateLimit "github.com/coti-io/coti-db-app/rate-limit"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+const testAdminToken = "test-admin-token"
+
+// countingRepositories counts the api key lookups of the db
+type countingRepositories struct {
+	repository.Repositories
+	lookups *int32
+}
+
+type countingApiKeyRepository struct {
+	repository.ApiKeyRepository
+	lookups *int32
+}
+
+func (repositories countingRepositories) ApiKeys() repository.ApiKeyRepository {
+	return countingApiKeyRepository{ApiKeyRepository: repositories.Repositories.ApiKeys(), lookups: repositories.lookups}
+}
+
+func (repositories countingRepositories) WithContext(ctx context.Context) repository.Repositories {
+	return repositories
+}
+
+func (repository countingApiKeyRepository) GetByKeyHash(keyHash string) (*entities.ApiKey, error) {
+	atomic.AddInt32(repository.lookups, 1)
+	return repository.ApiKeyRepository.GetByKeyHash(keyHash)
+}
+
+// newTestRouter serves a route per scope behind Authenticate with fresh rate limits and an empty key cache
+func newTestRouter(t *testing.T, settings map[string]string) (*gin.Engine, countingRepositories) {
+	t.Helper()
+	settings["ADMIN_TOKEN"] = testAdminToken
+	if err := config.Init("", settings); err != nil {
+		t.Fatal(err)
+	}
+	limiter = rateLimit.NewLimiter(time.Now)
+	apiKeys.forget()
+	repositories := countingRepositories{Repositories: repository.NewMemoryRepositories(), lookups: new(int32)}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Authenticate(repositories))
+	for _, scope := range []entities.ApiKeyScope{entities.PublicScope, entities.ReadScope, entities.AdminScope} {
+		router.GET("/"+string(scope), RequireScope(scope), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"data": GetCaller(c).Kind})
+		})
+	}
+	return router, repositories
+}
+
+func createTestApiKey(t *testing.T, repositories repository.Repositories, name string, scope entities.ApiKeyScope, limit rateLimit.Limit) string {
+	t.Helper()
+	key, _, err := CreateApiKey(repositories, name, scope, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func serve(router *gin.Engine, path string, key string, ip string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.RemoteAddr = ip + ":1234"
+	if key != "" {
+		request.Header.Set("Authorization", "Bearer "+key)
+	}
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+	return response
+}
+
+func TestRequireScope(t *testing.T) {
+	unlimited := map[string]string{"RATE_LIMIT_IP_PER_SECOND": "0", "RATE_LIMIT_KEY_PER_SECOND": "0"}
+	router, repositories := newTestRouter(t, unlimited)
+	readKey := createTestApiKey(t, repositories, "reader", entities.ReadScope, rateLimit.Limit{})
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+	}{
+		{"anonymous public", "/public", "", http.StatusOK},
+		{"anonymous read", "/read", "", http.StatusUnauthorized},
+		{"read key public", "/public", readKey, http.StatusOK},
+		{"read key read", "/read", readKey, http.StatusOK},
+		{"read key admin", "/admin", readKey, http.StatusForbidden},
+		{"admin token admin", "/admin", testAdminToken, http.StatusOK},
+		{"invalid key public", "/public", "cdb_invalid", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if response := serve(router, test.path, test.key, "192.0.2.1"); response.Code != test.status {
+				t.Fatalf("the status is %d and not %d: %s", response.Code, test.status, response.Body.String())
+			}
+		})
+	}
+
+	t.Run("read anonymous scope", func(t *testing.T) {
+		router, _ := newTestRouter(t, map[string]string{"RATE_LIMIT_IP_PER_SECOND": "0", "API_ANONYMOUS_SCOPE": "read"})
+		if response := serve(router, "/read", "", "192.0.2.1"); response.Code != http.StatusOK {
+			t.Fatalf("the status is %d", response.Code)
+		}
+		if response := serve(router, "/admin", "", "192.0.2.1"); response.Code != http.StatusUnauthorized {
+			t.Fatalf("the status is %d", response.Code)
+		}
+	})
+}
+
+func TestRateLimitRetryAfter(t *testing.T) {
+	router, repositories := newTestRouter(t, map[string]string{"RATE_LIMIT_IP_PER_SECOND": "0.5", "RATE_LIMIT_IP_BURST": "1"})
+	slowKey := createTestApiKey(t, repositories, "slow", entities.PublicScope, rateLimit.Limit{Rate: 0.25, Burst: 1})
+	tests := []struct {
+		name       string
+		key        string
+		ip         string
+		retryAfter string
+	}{
+		{"by ip", "", "192.0.2.1", "2"},
+		{"by key", slowKey, "192.0.2.2", "4"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if response := serve(router, "/public", test.key, test.ip); response.Code != http.StatusOK {
+				t.Fatalf("the first request has the status %d", response.Code)
+			}
+			response := serve(router, "/public", test.key, test.ip)
+			if response.Code != http.StatusTooManyRequests || response.Header().Get("Retry-After") != test.retryAfter {
+				t.Fatalf("the second request has the status %d and is retried after %q", response.Code, response.Header().Get("Retry-After"))
+			}
+		})
+	}
+	// every ip has its own bucket
+	if response := serve(router, "/public", "", "192.0.2.3"); response.Code != http.StatusOK {
+		t.Fatalf("the request of another ip has the status %d", response.Code)
+	}
+}
+
+func TestInvalidKeysAreLimitedByIpBeforeTheDbLookup(t *testing.T) {
+	router, repositories := newTestRouter(t, map[string]string{"RATE_LIMIT_IP_PER_SECOND": "0.001", "RATE_LIMIT_IP_BURST": "2", "RATE_LIMIT_KEY_PER_SECOND": "0"})
+	for i, status := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if response := serve(router, "/public", "cdb_invalid", "192.0.2.1"); response.Code != status {
+			t.Fatalf("the invalid key request %d has the status %d and not %d", i+1, response.Code, status)
+		}
+	}
+	// the misses are not cached and the refused request didn't read the db
+	if lookups := atomic.LoadInt32(repositories.lookups); lookups != 2 {
+		t.Fatalf("the invalid key was looked up %d times", lookups)
+	}
+
+	// a cached key is only limited by its own bucket
+	key := createTestApiKey(t, repositories, "cached", entities.PublicScope, rateLimit.Limit{})
+	for i := 0; i < 5; i++ {
+		if response := serve(router, "/public", key, "192.0.2.2"); response.Code != http.StatusOK {
+			t.Fatalf("the request %d of the key has the status %d", i+1, response.Code)
+		}
+	}
+	if lookups := atomic.LoadInt32(repositories.lookups); lookups != 3 {
+		t.Fatalf("the keys were looked up %d times", lookups)
+	}
+}
+
+func TestMissesDontEvictTheCachedKeys(t *testing.T) {
+	_, repositories := newTestRouter(t, map[string]string{})
+	key := createTestApiKey(t, repositories, "cached", entities.PublicScope, rateLimit.Limit{})
+	revokedKey := createTestApiKey(t, repositories, "revoked", entities.PublicScope, rateLimit.Limit{})
+	if err := RevokeApiKey(repositories, "revoked"); err != nil {
+		t.Fatal(err)
+	}
+	if apiKey, err := apiKeys.load(repositories, key); err != nil || apiKey == nil {
+		t.Fatalf("the key was loaded as %v: %v", apiKey, err)
+	}
+	for i := 0; i < maxCachedApiKeys; i++ {
+		if apiKey, err := apiKeys.load(repositories, fmt.Sprintf("cdb_unknown%d", i)); err != nil || apiKey != nil {
+			t.Fatalf("an unknown key was loaded as %v: %v", apiKey, err)
+		}
+	}
+	if apiKey, err := apiKeys.load(repositories, revokedKey); err != nil || apiKey != nil {
+		t.Fatalf("the revoked key was loaded as %v: %v", apiKey, err)
+	}
+	if len(apiKeys.keys) != 1 || apiKeys.getCached(key) == nil {
+		t.Fatalf("the cache has %d keys", len(apiKeys.keys))
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// commandFlags are the flags of a subcommand, the setting flags and --set override the config
+type commandFlags struct {
+	*flag.FlagSet
+	configFile string
+	settings   settingValues
+	// settingFlags are the env names of the settings by flag name
+	settingFlags map[string]string
+}
+
+func newCommandFlags(name string) *commandFlags {
+	flags := &commandFlags{
+		FlagSet:      flag.NewFlagSet(name, flag.ExitOnError),
+		settings:     settingValues{},
+		settingFlags: map[string]string{},
+	}
+	flags.StringVar(&flags.configFile, "config", "", "yaml config file, CONFIG_FILE when not set")
+	flags.Var(flags.settings, "set", "override a setting by its env name as NAME=value, can be repeated")
+	flags.settingFlag("log-level", "LOG_LEVEL", "log level: trace, debug, info, warn or error")
+	flags.settingFlag("log-format", "LOG_FORMAT", "log format: text or json")
+	return flags
+}
+
+// settingFlag adds a flag that overrides the setting of envName
+func (flags *commandFlags) settingFlag(name string, envName string, usage string) {
+	flags.String(name, "", usage)
+	flags.settingFlags[name] = envName
+}
+
+// settingBoolFlag adds a flag that overrides the bool setting of envName
+func (flags *commandFlags) settingBoolFlag(name string, envName string, usage string) {
+	flags.Bool(name, false, usage)
+	flags.settingFlags[name] = envName
+}
+
+func (flags *commandFlags) serverFlags() {
+	flags.settingFlag("port", "PORT", "port of the http server")
+}
+
+func (flags *commandFlags) fullnodeFlags() {
+	flags.settingFlag("fullnode-url", "FULLNODE_URL", "url of the main fullnode")
+	flags.settingFlag("fullnode-backup-url", "FULLNODE_BACKUP_URL", "url of the backup fullnode")
+}
+
+func (flags *commandFlags) migrateFlag() {
+	flags.settingBoolFlag("migrate", "MIGRATE_DB", "run the migrations on startup")
+}
+
+// parse parses the flags wherever they are in args, loads the config with the overrides of the flags that are set
+// and sets up the logger. It returns the positional args
+func (flags *commandFlags) parse(args []string) []string {
+	var positional []string
+	for {
+		_ = flags.Parse(args)
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	overrides := map[string]string{}
+	for envName, value := range flags.settings {
+		overrides[envName] = value
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if envName, ok := flags.settingFlags[f.Name]; ok {
+			overrides[envName] = f.Value.String()
+		}
+	})
+	err := config.Init(flags.configFile, overrides)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	err = logger.Init(config.Get().Log)
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid log config")
+	}
+	return positional
+}
+
+// settingValues are the NAME=value pairs of --set
+type settingValues map[string]string
+
+func (values settingValues) String() string {
+	pairs := make([]string, 0, len(values))
+	for name, value := range values {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (values settingValues) Set(value string) error {
+	nameValue := strings.SplitN(value, "=", 2)
+	if len(nameValue) != 2 || nameValue[0] == "" {
+		return errors.New("must be NAME=value")
+	}
+	values[nameValue[0]] = nameValue[1]
+	return nil
+}
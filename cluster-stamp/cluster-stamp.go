@@ -0,0 +1,345 @@
+package clusterStamp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/dto"
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/ebfe/keccak"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const batchSize = 1000
+
+// columns holds the position of every cluster stamp column, currencyHash is -1 when the stamp has only native balances
+type columns struct {
+	address      int
+	amount       int
+	currencyHash int
+}
+
+var positionalColumns = columns{address: 0, amount: 1, currencyHash: 2}
+
+// importResult describes a read cluster stamp
+type importResult struct {
+	fileName       string
+	hash           []byte
+	rowCount       int
+	currencyTotals map[string]decimal.Decimal
+	trailer        signatureTrailer
+}
+
+// rowHandler gets every balance line of the stamp, currencyHash is already set to the native currency hash when the line has none
+type rowHandler func(address string, amount decimal.Decimal, currencyHash string) error
+
+// Load loads the cluster stamp set by CLUSTER_STAMP_FILE_NAME if no cluster stamp was loaded to this db yet
+func Load(repositories repository.Repositories) error {
+	isInitialized, err := isClusterStampInitialized(repositories)
+	if err != nil {
+		return err
+	}
+	if isInitialized {
+		return nil
+	}
+	clusterStampConfig := config.Get().ClusterStamp
+	clusterStampFileName := clusterStampConfig.FileName
+	csvFile, err := os.Open(clusterStampFileName)
+	if err != nil {
+		return err
+	}
+	logrus.WithField("file", clusterStampFileName).Info("successfully opened the cluster stamp file")
+	defer csvFile.Close()
+
+	expectedSupplies, err := readExpectedSupplies(clusterStampConfig.ExpectedSupplyFileName)
+	if err != nil {
+		return err
+	}
+
+	// the stamp is read and verified before anything is written
+	nativeCurrencyHash := service.NewCurrencyService(repositories.Currencies()).GetNativeCurrencyHash()
+	result, err := readStamp(csv.NewReader(csvFile), nativeCurrencyHash, func(address string, amount decimal.Decimal, currencyHash string) error {
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	result.fileName = clusterStampFileName
+	err = verifyStamp(result, expectedSupplies)
+	if err != nil {
+		return err
+	}
+	_, err = csvFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	return repositories.Transaction(func(repositories repository.Repositories) error {
+		importedResult, err := importBalances(repositories, csv.NewReader(csvFile), nativeCurrencyHash)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(importedResult.hash, result.hash) {
+			return errors.New("cluster stamp file changed after it was verified")
+		}
+		_, err = recordClusterStamp(repositories, entities.InitialClusterStamp, result, nil)
+		return err
+	})
+}
+
+// isClusterStampInitialized checks the cluster stamp history, a db initialized before the history was kept gets a legacy record
+func isClusterStampInitialized(repositories repository.Repositories) (bool, error) {
+	clusterStampCount, err := repositories.ClusterStamps().Count()
+	if err != nil {
+		return false, err
+	}
+	if clusterStampCount > 0 {
+		return true, nil
+	}
+	appStateIsClusterStampInitialized, err := repositories.AppStates().GetByName(entities.IsClusterStampInitialized)
+	if err != nil {
+		return false, err
+	}
+	if appStateIsClusterStampInitialized.Value != "true" {
+		return false, nil
+	}
+	auditAppStates, err := repositories.AppStates().FindByNames([]entities.AppStatesNames{entities.ClusterStampHash, entities.ClusterStampRowCount, entities.ClusterStampTotal})
+	if err != nil {
+		return false, err
+	}
+	legacyClusterStamp := entities.NewClusterStamp(entities.LegacyClusterStamp, "", "", 0, "{}", nil)
+	for _, appState := range auditAppStates {
+		switch appState.Name {
+		case entities.ClusterStampHash:
+			legacyClusterStamp.Hash = appState.Value
+		case entities.ClusterStampRowCount:
+			rowCount, _ := strconv.Atoi(appState.Value)
+			legacyClusterStamp.RowCount = int32(rowCount)
+		case entities.ClusterStampTotal:
+			legacyClusterStamp.CurrencyTotals = appState.Value
+		}
+	}
+	err = repositories.ClusterStamps().Create(legacyClusterStamp)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifyStamp checks the stamp signature unless CLUSTER_STAMP_VERIFY_SIGNATURE is false, and the totals against the expected supplies
+func verifyStamp(result *importResult, expectedSupplies map[string]decimal.Decimal) error {
+	clusterStampConfig := config.Get().ClusterStamp
+	if clusterStampConfig.VerifySignature {
+		err := verifySignature(result.hash, result.trailer, clusterStampConfig.SignerHash)
+		if err != nil {
+			return err
+		}
+	} else {
+		logrus.Warn("cluster stamp signature verification is disabled")
+	}
+	return validateSupplies(result.currencyTotals, expectedSupplies)
+}
+
+// recordClusterStamp adds the stamp to the cluster stamp history and keeps the audit data of the latest stamp in the app states
+func recordClusterStamp(repositories repository.Repositories, clusterStampType entities.ClusterStampType, result *importResult, transactionIndex *int64) (*entities.ClusterStamp, error) {
+	currencyTotals, err := json.Marshal(result.currencyTotals)
+	if err != nil {
+		return nil, err
+	}
+	stampHash := hex.EncodeToString(result.hash)
+	clusterStamp := entities.NewClusterStamp(clusterStampType, result.fileName, stampHash, int32(result.rowCount), string(currencyTotals), transactionIndex)
+	err = repositories.ClusterStamps().Create(clusterStamp)
+	if err != nil {
+		return nil, err
+	}
+	auditValues := map[entities.AppStatesNames]string{
+		entities.ClusterStampHash:          stampHash,
+		entities.ClusterStampRowCount:      strconv.Itoa(result.rowCount),
+		entities.ClusterStampTotal:         string(currencyTotals),
+		entities.IsClusterStampInitialized: "true",
+	}
+	for name, value := range auditValues {
+		appState, err := repositories.AppStates().FirstOrCreate(name)
+		if err != nil {
+			return nil, err
+		}
+		appState.Value = value
+		err = repositories.AppStates().Save(appState)
+		if err != nil {
+			return nil, err
+		}
+	}
+	logrus.WithFields(logrus.Fields{"type": clusterStampType, "hash": stampHash, "rows": result.rowCount}).Info("cluster stamp recorded")
+	return clusterStamp, nil
+}
+
+// importBalances saves the balances of the cluster stamp
+func importBalances(repositories repository.Repositories, reader *csv.Reader, nativeCurrencyHash string) (*importResult, error) {
+	currencyHashToIdMap := make(map[string]int32)
+	var addressBalances []entities.AddressBalance
+	result, err := readStamp(reader, nativeCurrencyHash, func(address string, amount decimal.Decimal, currencyHash string) error {
+		currencyId, err := getCurrencyId(repositories, currencyHashToIdMap, currencyHash)
+		if err != nil {
+			return err
+		}
+		clusterStampData := dto.ClusterStampDataRow{
+			Address:    address,
+			Amount:     amount,
+			CurrencyId: currencyId,
+		}
+		addressBalances = append(addressBalances, *entities.NewAddressBalanceFromClusterStamp(&clusterStampData))
+		if len(addressBalances) == batchSize {
+			err = repositories.Balances().Create(addressBalances)
+			if err != nil {
+				return err
+			}
+			addressBalances = []entities.AddressBalance{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = repositories.Balances().Create(addressBalances)
+	if err != nil {
+		return nil, err
+	}
+
+	var currencyIds []int32
+	for _, currencyId := range currencyHashToIdMap {
+		currencyIds = append(currencyIds, currencyId)
+	}
+	err = service.InitCurrencySupplies(repositories, currencyIds)
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{"rows": result.rowCount, "currencies": len(currencyHashToIdMap)}).Info("cluster stamp imported")
+	return result, nil
+}
+
+// readStamp reads the balance lines of the cluster stamp, hashing them and reading the signature trailer on the way
+func readStamp(reader *csv.Reader, nativeCurrencyHash string, onRow rowHandler) (*importResult, error) {
+	reader.FieldsPerRecord = -1
+	result := &importResult{currencyTotals: make(map[string]decimal.Decimal)}
+	digest := keccak.New256()
+	isTrailer := false
+
+	stampColumns := positionalColumns
+	lineNumber := 0
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		lineNumber++
+		if err != nil {
+			return nil, fmt.Errorf("cluster stamp line %d: %w", lineNumber, err)
+		}
+		if lineNumber == 1 && isHeader(line) {
+			stampColumns, err = parseHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if isTrailerLine(line) {
+			isTrailer = true
+			result.trailer.set(line)
+			continue
+		}
+		if isTrailer {
+			return nil, fmt.Errorf("cluster stamp line %d: balance line after the signature", lineNumber)
+		}
+		address, amountText, amount, currencyHash, err := parseRow(line, stampColumns)
+		if err != nil {
+			return nil, fmt.Errorf("cluster stamp line %d: %w", lineNumber, err)
+		}
+		err = writeRowToDigest(digest, address, amountText, currencyHash)
+		if err != nil {
+			return nil, fmt.Errorf("cluster stamp line %d: %w", lineNumber, err)
+		}
+		result.rowCount++
+		if currencyHash == "" {
+			currencyHash = nativeCurrencyHash
+		}
+		result.currencyTotals[currencyHash] = result.currencyTotals[currencyHash].Add(amount)
+		err = onRow(address, amount, currencyHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.hash = digest.Sum(nil)
+	return result, nil
+}
+
+// isHeader checks if the line is a header line, data lines always have a numeric amount in the second column
+func isHeader(line []string) bool {
+	if len(line) < 2 {
+		return false
+	}
+	_, err := decimal.NewFromString(strings.TrimSpace(line[1]))
+	return err != nil
+}
+
+func parseHeader(line []string) (columns, error) {
+	headerColumns := columns{address: -1, amount: -1, currencyHash: -1}
+	for i, name := range line {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "address", "addresshash":
+			headerColumns.address = i
+		case "amount", "balance":
+			headerColumns.amount = i
+		case "currency", "currencyhash":
+			headerColumns.currencyHash = i
+		}
+	}
+	if headerColumns.address == -1 || headerColumns.amount == -1 {
+		return headerColumns, fmt.Errorf("cluster stamp header %s must have address and amount columns", strings.Join(line, ","))
+	}
+	return headerColumns, nil
+}
+
+// parseRow parses a balance line, amountText is the amount as it is written in the stamp
+func parseRow(line []string, stampColumns columns) (address string, amountText string, amount decimal.Decimal, currencyHash string, err error) {
+	if len(line) <= stampColumns.address || len(line) <= stampColumns.amount {
+		return "", "", amount, "", fmt.Errorf("expected at least %d columns but got %d", stampColumns.amount+1, len(line))
+	}
+	address = strings.TrimSpace(line[stampColumns.address])
+	if address == "" {
+		return "", "", amount, "", errors.New("address is empty")
+	}
+	amountText = strings.TrimSpace(line[stampColumns.amount])
+	amount, err = decimal.NewFromString(amountText)
+	if err != nil {
+		return "", "", amount, "", err
+	}
+	if stampColumns.currencyHash != -1 && len(line) > stampColumns.currencyHash {
+		currencyHash = strings.TrimSpace(line[stampColumns.currencyHash])
+	}
+	return address, amountText, amount, currencyHash, nil
+}
+
+// getCurrencyId gets the id of the currency hash, creating the currency when it is unknown
+func getCurrencyId(repositories repository.Repositories, currencyHashToIdMap map[string]int32, currencyHash string) (int32, error) {
+	if currencyId, ok := currencyHashToIdMap[currencyHash]; ok {
+		return currencyId, nil
+	}
+	currency, err := repositories.Currencies().FirstOrCreate(currencyHash)
+	if err != nil {
+		return 0, err
+	}
+	currencyHashToIdMap[currencyHash] = currency.ID
+	return currency.ID, nil
+}
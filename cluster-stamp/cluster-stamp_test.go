@@ -0,0 +1,181 @@
+package clusterStamp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coti-io/coti-db-app/config"
+	dbTest "github.com/coti-io/coti-db-app/db-provider/db-test"
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ebfe/keccak"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// forEachRepositories runs the test on the memory repositories and on the repositories of every db dialect
+func forEachRepositories(t *testing.T, overrides map[string]string, test func(t *testing.T, repositories repository.Repositories)) {
+	t.Run("memory", func(t *testing.T) {
+		if err := config.Init("", overrides); err != nil {
+			t.Fatal(err)
+		}
+		test(t, repository.NewMemoryRepositories())
+	})
+	dbTest.ForEachDialect(t, overrides, func(t *testing.T, db *gorm.DB) {
+		test(t, repository.NewGormRepositories(db))
+	})
+}
+
+// getBalanceLines reads the current balances as sorted currencyHash,address,amount lines
+func getBalanceLines(t *testing.T, repositories repository.Repositories) []string {
+	t.Helper()
+	var lines []string
+	err := repositories.Balances().ForEachAt(repository.BalancePoint{}, nil, "", func(balance repository.CurrencyHashBalance) error {
+		lines = append(lines, balance.CurrencyHash+","+balance.AddressHash+","+balance.Amount.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// writeStamp writes the balance lines and the signature of the signed lines to a cluster stamp file
+func writeStamp(t *testing.T, privateKey *secp256k1.PrivateKey, signedLines []string, lines []string) string {
+	t.Helper()
+	digest := keccak.New256()
+	for _, line := range signedLines {
+		fields := strings.Split(line, ",")
+		if err := writeRowToDigest(digest, fields[0], fields[1], ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trailer := signStamp(privateKey, digest.Sum(nil))
+	content := strings.Join(lines, "\n") + fmt.Sprintf("\nr,%s\ns,%s\nsignerHash,%s\n", trailer.r, trailer.s, trailer.signerHash)
+	fileName := filepath.Join(t.TempDir(), "cluster-stamp.csv")
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestLoadVerifiesTheStamp(t *testing.T) {
+	privateKey := newPrivateKey(987654321)
+	lines := []string{"ab01,10", "cd02,2.50"}
+	tests := []struct {
+		name        string
+		privateKey  *secp256k1.PrivateKey
+		signedLines []string
+		isLoaded    bool
+	}{
+		{"signed by the trusted signer", privateKey, lines, true},
+		// the same amount written differently is another stamp
+		{"signed for other amount texts", privateKey, []string{"ab01,10.0", "cd02,2.5"}, false},
+		{"signed by another signer", newPrivateKey(42), lines, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileName := writeStamp(t, test.privateKey, test.signedLines, lines)
+			forEachRepositories(t, map[string]string{
+				"NATIVE_SYMBOL":                  "COTI",
+				"CLUSTER_STAMP_FILE_NAME":        fileName,
+				"CLUSTER_STAMP_VERIFY_SIGNATURE": "true",
+				"CLUSTER_STAMP_SIGNER_HASH":      signerHash(privateKey),
+			}, func(t *testing.T, repositories repository.Repositories) {
+				if _, err := repositories.AppStates().FirstOrCreate(entities.IsClusterStampInitialized); err != nil {
+					t.Fatal(err)
+				}
+				err := Load(repositories)
+				if test.isLoaded && err != nil {
+					t.Fatalf("the stamp was refused: %v", err)
+				}
+				if !test.isLoaded && err == nil {
+					t.Fatal("the stamp was loaded")
+				}
+				clusterStampCount, err := repositories.ClusterStamps().Count()
+				if err != nil {
+					t.Fatal(err)
+				}
+				balanceCount, expectedBalanceCount, expectedClusterStampCount := len(getBalanceLines(t, repositories)), 0, int64(0)
+				if test.isLoaded {
+					expectedBalanceCount, expectedClusterStampCount = 2, 1
+				}
+				if balanceCount != expectedBalanceCount || clusterStampCount != expectedClusterStampCount {
+					t.Fatalf("%d balances and %d cluster stamps were created", balanceCount, clusterStampCount)
+				}
+			})
+		})
+	}
+}
+
+func TestRebase(t *testing.T) {
+	privateKey := newPrivateKey(987654321)
+	lines := []string{"ab01,8", "cd02,9"}
+	fileName := writeStamp(t, privateKey, lines, lines)
+	forEachRepositories(t, map[string]string{
+		"NATIVE_SYMBOL":                  "COTI",
+		"CLUSTER_STAMP_VERIFY_SIGNATURE": "true",
+		"CLUSTER_STAMP_SIGNER_HASH":      signerHash(privateKey),
+	}, func(t *testing.T, repositories repository.Repositories) {
+		nativeCurrencyHash := service.NewCurrencyService(repositories.Currencies()).GetNativeCurrencyHash()
+		createExportTestData(t, repositories, nativeCurrencyHash)
+		for _, name := range []entities.AppStatesNames{entities.LastMonitoredTransactionIndex, entities.UpdateBalances} {
+			if _, err := repositories.AppStates().FirstOrCreate(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// the pruned transaction at index 2 never reached the db before the stamp
+		index, transactionType := int32(2), "Transfer"
+		pruned := &entities.Transaction{Hash: "pruned", Index: &index, Type: &transactionType, AttachmentTime: decimal.NewFromInt(2)}
+		if err := repositories.Transactions().Create([]*entities.Transaction{pruned}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Rebase(repositories, RebaseOptions{FileName: fileName, TransactionIndex: 2}); err != nil {
+			t.Fatal(err)
+		}
+		// the stamp balances plus the diffs of the transactions at indexes 3 and 4, the token balances are kept
+		expected := []string{
+			nativeCurrencyHash + ",ab01,11", nativeCurrencyHash + ",cd02,5", nativeCurrencyHash + ",ef03,-2",
+			"token,ab01,7", "token,cd02,3",
+		}
+		sort.Strings(expected)
+		if balanceLines := getBalanceLines(t, repositories); strings.Join(balanceLines, ";") != strings.Join(expected, ";") {
+			t.Fatalf("the balances are %v and not %v", balanceLines, expected)
+		}
+		txs, err := repositories.Transactions().FindByHashes([]string{"pruned"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(txs) != 1 || !txs[0].IsProcessed || !txs[0].IsCoveredByStamp {
+			t.Fatalf("the pruned transaction is %+v", txs)
+		}
+		// the covered transaction is not an invalid skipped one
+		skipped, err := repositories.Transactions().FindSkipped(0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(skipped) != 1 || skipped[0].Hash != strings.Repeat("a", 6) {
+			t.Fatalf("the skipped transactions are %+v", skipped)
+		}
+		appStates, err := repositories.AppStates().FindByNames([]entities.AppStatesNames{entities.LastMonitoredTransactionIndex, entities.RebasedTransactionIndex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, appState := range appStates {
+			if appState.Value != "2" {
+				t.Fatalf("the %s app state is %q", appState.Name, appState.Value)
+			}
+		}
+		if clusterStampCount, err := repositories.ClusterStamps().Count(); err != nil || clusterStampCount != 1 {
+			t.Fatalf("%d cluster stamps were recorded: %v", clusterStampCount, err)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package clusterStamp
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// readExpectedSupplies reads the optional currencyHash,amount file the cluster stamp totals are validated against
+func readExpectedSupplies(fileName string) (map[string]decimal.Decimal, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+	csvFile, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer csvFile.Close()
+
+	reader := csv.NewReader(csvFile)
+	expectedSupplies := make(map[string]decimal.Decimal)
+	lineNumber := 0
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		lineNumber++
+		if err != nil {
+			return nil, fmt.Errorf("expected supply line %d: %w", lineNumber, err)
+		}
+		if lineNumber == 1 && isHeader(line) {
+			continue
+		}
+		if len(line) != 2 {
+			return nil, fmt.Errorf("expected supply line %d: expected 2 columns but got %d", lineNumber, len(line))
+		}
+		amount, err := decimal.NewFromString(strings.TrimSpace(line[1]))
+		if err != nil {
+			return nil, fmt.Errorf("expected supply line %d: %w", lineNumber, err)
+		}
+		expectedSupplies[strings.TrimSpace(line[0])] = amount
+	}
+	return expectedSupplies, nil
+}
+
+// validateSupplies makes sure every currency in the expected supplies has the expected cluster stamp total
+func validateSupplies(currencyTotals map[string]decimal.Decimal, expectedSupplies map[string]decimal.Decimal) error {
+	var mismatches []string
+	for currencyHash, expectedSupply := range expectedSupplies {
+		total := currencyTotals[currencyHash]
+		if !total.Equal(expectedSupply) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: expected %s but got %s", currencyHash, expectedSupply.String(), total.String()))
+		}
+	}
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return fmt.Errorf("cluster stamp totals do not match the expected supply: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
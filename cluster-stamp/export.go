@@ -0,0 +1,132 @@
+package clusterStamp
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportOptions sets the point in time of the exported balances, the current balances are exported when neither AtIndex nor AtTime is set
+type ExportOptions struct {
+	AtIndex        *int64
+	AtTime         *decimal.Decimal
+	CurrencyHashes []string
+}
+
+// Export writes the balances in cluster stamp format, a native currency export has the native address,amount format
+// and any other export has an address,amount,currencyHash header. The balances and the diffs after the export point
+// are read from one snapshot and streamed, the diffs are summed by the db
+func Export(repositories repository.Repositories, writer io.Writer, options ExportOptions) error {
+	if options.AtIndex != nil && options.AtTime != nil {
+		return errors.New("only one of at index and at time can be set")
+	}
+	nativeCurrencyHash := service.NewCurrencyService(repositories.Currencies()).GetNativeCurrencyHash()
+	withCurrencyColumn := len(options.CurrencyHashes) != 1 || options.CurrencyHashes[0] != nativeCurrencyHash
+
+	csvWriter := csv.NewWriter(writer)
+	if withCurrencyColumn {
+		if err := csvWriter.Write([]string{"address", "amount", "currencyHash"}); err != nil {
+			return err
+		}
+	}
+	rowCount := 0
+	err := repositories.ReadSnapshot(func(repositories repository.Repositories) error {
+		point := options.balancePoint()
+		if !point.IsCurrent() {
+			if err := checkTransactionsAfter(repositories, options); err != nil {
+				return err
+			}
+		}
+		return repositories.Balances().ForEachAt(point, options.CurrencyHashes, nativeCurrencyHash, func(balance repository.CurrencyHashBalance) error {
+			if balance.Amount.IsZero() {
+				return nil
+			}
+			line := []string{balance.AddressHash, balance.Amount.String()}
+			if withCurrencyColumn {
+				line = append(line, balance.CurrencyHash)
+			}
+			rowCount++
+			return csvWriter.Write(line)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+	logrus.WithField("balances", rowCount).Info("cluster stamp exported")
+	return nil
+}
+
+func (options ExportOptions) balancePoint() repository.BalancePoint {
+	return repository.BalancePoint{Index: options.AtIndex, AttachmentTime: options.AtTime}
+}
+
+// checkTransactionsAfter checks the transactions after the export point are not archived,
+// the transactions before the export point that are not processed yet are logged
+func checkTransactionsAfter(repositories repository.Repositories, options ExportOptions) error {
+	// the diffs of archived transactions are gone
+	archivedRange, err := repositories.Transactions().GetArchivedRange()
+	if err != nil {
+		return err
+	}
+	if options.AtIndex != nil && archivedRange.MaxIndex != nil && *options.AtIndex < int64(*archivedRange.MaxIndex) {
+		return fmt.Errorf("the transactions after index %d are archived up to index %d", *options.AtIndex, *archivedRange.MaxIndex)
+	}
+	if options.AtTime != nil && archivedRange.MaxAttachmentTime.Valid && options.AtTime.LessThan(archivedRange.MaxAttachmentTime.Decimal) {
+		return fmt.Errorf("the transactions after attachment time %s are archived up to %s", options.AtTime, archivedRange.MaxAttachmentTime.Decimal)
+	}
+	pendingCount, err := repositories.Transactions().CountPendingUpTo(options.balancePoint())
+	if err != nil {
+		return err
+	}
+	if pendingCount > 0 {
+		logrus.WithField("pending", pendingCount).Warn("transactions before the export point are not processed yet and are missing from the balances")
+	}
+	return nil
+}
+
+// getBalanceDiffsAfter sums the balance diffs of the processed transactions after the export point,
+// subtracting them from the current balances gives the balances at the export point
+func getBalanceDiffsAfter(repositories repository.Repositories, options ExportOptions) (map[string]map[string]decimal.Decimal, error) {
+	currencyAddressDiffMap := make(map[string]map[string]decimal.Decimal)
+	point := options.balancePoint()
+	if point.IsCurrent() {
+		return currencyAddressDiffMap, nil
+	}
+	err := checkTransactionsAfter(repositories, options)
+	if err != nil {
+		return nil, err
+	}
+	transactionIds, err := repositories.Transactions().FindAppliedIdsAfter(point)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(transactionIds); i += batchSize {
+		end := i + batchSize
+		if end > len(transactionIds) {
+			end = len(transactionIds)
+		}
+		batchDiffMap, err := service.CollectAddressBalanceDiffs(repositories, transactionIds[i:end])
+		if err != nil {
+			return nil, err
+		}
+		for currencyHash, addressDiffMap := range batchDiffMap {
+			if currencyAddressDiffMap[currencyHash] == nil {
+				currencyAddressDiffMap[currencyHash] = make(map[string]decimal.Decimal)
+			}
+			for addressHash, diff := range addressDiffMap {
+				currencyAddressDiffMap[currencyHash][addressHash] = currencyAddressDiffMap[currencyHash][addressHash].Add(diff)
+			}
+		}
+	}
+	return currencyAddressDiffMap, nil
+}
@@ -0,0 +1,98 @@
+package clusterStamp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/shopspring/decimal"
+)
+
+// createExportTestData creates the balances after the transactions at indexes 3 and 4 were applied,
+// the transaction at index 5 is not processed yet and the one at index 6 is skipped
+func createExportTestData(t *testing.T, repositories repository.Repositories, nativeCurrencyHash string) {
+	t.Helper()
+	check := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	nativeCurrency, token := entities.NewCurrency(nativeCurrencyHash), entities.NewCurrency("token")
+	check(repositories.Currencies().Create([]*entities.Currency{nativeCurrency, token}))
+	check(repositories.Balances().Create([]entities.AddressBalance{
+		*entities.NewAddressBalance("ab01", decimal.NewFromInt(10), nativeCurrency.ID),
+		*entities.NewAddressBalance("cd02", decimal.NewFromInt(5), nativeCurrency.ID),
+		*entities.NewAddressBalance("ab01", decimal.NewFromInt(7), token.ID),
+		*entities.NewAddressBalance("cd02", decimal.NewFromInt(3), token.ID),
+	}))
+
+	transactionType := "Transfer"
+	transactions := make(map[int32]*entities.Transaction)
+	for _, index := range []int32{1, 3, 4, 5, 6} {
+		index := index
+		transaction := &entities.Transaction{Hash: strings.Repeat("a", int(index)), Index: &index, Type: &transactionType,
+			AttachmentTime: decimal.NewFromInt(int64(index)), IsProcessed: index != 5, IsSkipped: index == 6}
+		check(repositories.Transactions().Create([]*entities.Transaction{transaction}))
+		transactions[index] = transaction
+	}
+	amount := func(value int64) decimal.Decimal { return decimal.NewFromInt(value) }
+	originalAmount := decimal.NewNullDecimal(decimal.Zero)
+	mintingFee := &entities.TokenMintingFeeBaseTransaction{TransactionId: transactions[4].ID, Hash: "m4", AddressHash: "ab01", Amount: amount(-1)}
+	check(repositories.Transactions().CreateBaseTransactions(&repository.BaseTransactions{
+		ReceiverBaseTransactions: []*entities.ReceiverBaseTransaction{
+			{TransactionId: transactions[3].ID, Hash: "r3", AddressHash: "ab01", Amount: amount(4), OriginalAmount: originalAmount},
+			{TransactionId: transactions[5].ID, Hash: "r5", AddressHash: "ab01", Amount: amount(100), OriginalAmount: originalAmount},
+			{TransactionId: transactions[6].ID, Hash: "r6", AddressHash: "ab01", Amount: amount(100), OriginalAmount: originalAmount},
+		},
+		InputBaseTransactions: []*entities.InputBaseTransaction{
+			{TransactionId: transactions[3].ID, Hash: "i3", AddressHash: "cd02", Amount: amount(-4)},
+			// ef03 has no balance row anymore
+			{TransactionId: transactions[3].ID, Hash: "i3b", AddressHash: "ef03", Amount: amount(-2), CurrencyHash: &nativeCurrencyHash},
+		},
+		TokenMintingFeeBaseTransactions: []*entities.TokenMintingFeeBaseTransaction{mintingFee},
+	}))
+	check(repositories.Transactions().CreateTokenMintingServiceData([]*entities.TokenMintingServiceData{
+		{BaseTransactionId: mintingFee.ID, MintingCurrencyHash: "token", MintingAmount: amount(3), ReceiverAddress: "cd02"},
+	}))
+}
+
+func TestExport(t *testing.T) {
+	forEachRepositories(t, map[string]string{"NATIVE_SYMBOL": "COTI"}, func(t *testing.T, repositories repository.Repositories) {
+		nativeCurrencyHash := service.NewCurrencyService(repositories.Currencies()).GetNativeCurrencyHash()
+		createExportTestData(t, repositories, nativeCurrencyHash)
+		atIndex := int64(2)
+		atTime := decimal.NewFromInt(2)
+		tests := []struct {
+			name    string
+			options ExportOptions
+			lines   []string
+		}{
+			{"current native balances", ExportOptions{CurrencyHashes: []string{nativeCurrencyHash}},
+				[]string{"ab01,10", "cd02,5"}},
+			{"current token balances", ExportOptions{CurrencyHashes: []string{"token"}},
+				[]string{"address,amount,currencyHash", "ab01,7,token", "cd02,3,token"}},
+			{"native balances at an index", ExportOptions{AtIndex: &atIndex, CurrencyHashes: []string{nativeCurrencyHash}},
+				[]string{"ab01,7", "cd02,9", "ef03,2"}},
+			{"token balances at an index", ExportOptions{AtIndex: &atIndex, CurrencyHashes: []string{"token"}},
+				[]string{"address,amount,currencyHash", "ab01,7,token"}},
+			{"all balances at a time", ExportOptions{AtTime: &atTime},
+				[]string{"address,amount,currencyHash", "ab01,7," + nativeCurrencyHash, "cd02,9," + nativeCurrencyHash, "ab01,7,token", "ef03,2," + nativeCurrencyHash}},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				var buffer bytes.Buffer
+				if err := Export(repositories, &buffer, test.options); err != nil {
+					t.Fatal(err)
+				}
+				expected := strings.Join(test.lines, "\n") + "\n"
+				if buffer.String() != expected {
+					t.Fatalf("the export is\n%s\nand not\n%s", buffer.String(), expected)
+				}
+			})
+		}
+	})
+}
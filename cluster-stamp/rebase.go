@@ -0,0 +1,224 @@
+package clusterStamp
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// maxLoggedDifferences limits the differences written to the log, all of them are kept in the cluster_stamp_differences table
+const maxLoggedDifferences = 100
+
+// RebaseOptions describes a newer cluster stamp and the transaction index it was taken at
+type RebaseOptions struct {
+	FileName               string
+	ExpectedSupplyFileName string
+	TransactionIndex       int64
+	// DryRun only reports the differences between the stamp and the computed balances
+	DryRun bool
+}
+
+// Rebase loads a newer cluster stamp into an existing db. The balances of the stamp currencies are compared with the computed
+// balances at the stamp index and then reset to the stamp balances plus the transactions applied after the stamp index.
+func Rebase(repositories repository.Repositories, options RebaseOptions) error {
+	csvFile, err := os.Open(options.FileName)
+	if err != nil {
+		return err
+	}
+	logrus.WithField("file", options.FileName).Info("successfully opened the cluster stamp file")
+	defer csvFile.Close()
+
+	expectedSupplies, err := readExpectedSupplies(options.ExpectedSupplyFileName)
+	if err != nil {
+		return err
+	}
+	stampBalances := make(map[string]map[string]decimal.Decimal)
+	nativeCurrencyHash := service.NewCurrencyService(repositories.Currencies()).GetNativeCurrencyHash()
+	result, err := readStamp(csv.NewReader(csvFile), nativeCurrencyHash, func(address string, amount decimal.Decimal, currencyHash string) error {
+		if stampBalances[currencyHash] == nil {
+			stampBalances[currencyHash] = make(map[string]decimal.Decimal)
+		}
+		stampBalances[currencyHash][address] = stampBalances[currencyHash][address].Add(amount)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	result.fileName = options.FileName
+	err = verifyStamp(result, expectedSupplies)
+	if err != nil {
+		return err
+	}
+
+	return repositories.Transaction(func(repositories repository.Repositories) error {
+		// stops the sync and the balance updates while the balances are reset
+		lastMonitoredIndexAppState, err := repositories.AppStates().GetByNameForUpdate(entities.LastMonitoredTransactionIndex)
+		if err != nil {
+			return err
+		}
+		_, err = repositories.AppStates().GetByNameForUpdate(entities.UpdateBalances)
+		if err != nil {
+			return err
+		}
+
+		currencyAddressDiffMap, err := getBalanceDiffsAfter(repositories, ExportOptions{AtIndex: &options.TransactionIndex})
+		if err != nil {
+			return err
+		}
+		differences, err := compareBalances(repositories, stampBalances, currencyAddressDiffMap)
+		if err != nil {
+			return err
+		}
+		logrus.WithFields(logrus.Fields{"differences": len(differences), "index": options.TransactionIndex}).Warn("balances differ from the cluster stamp")
+		for i, difference := range differences {
+			if i == maxLoggedDifferences {
+				logrus.Warnf("%d more differences are not logged", len(differences)-maxLoggedDifferences)
+				break
+			}
+			logrus.WithFields(logrus.Fields{"currencyHash": difference.CurrencyHash, "addressHash": difference.AddressHash, "stampAmount": difference.StampAmount.String(), "computedAmount": difference.ComputedAmount.String()}).Warn("balance differs from the cluster stamp")
+		}
+		if options.DryRun {
+			logrus.Info("dry run, the balances are not changed")
+			return nil
+		}
+
+		currencyIds, err := resetBalances(repositories, stampBalances, currencyAddressDiffMap)
+		if err != nil {
+			return err
+		}
+		err = service.RecalculateCurrencySupplies(repositories, currencyIds)
+		if err != nil {
+			return err
+		}
+		// the pruned transactions up to the stamp index are covered by the stamp balances, they are skipped without being invalid
+		err = repositories.Transactions().MarkCoveredByStamp(options.TransactionIndex)
+		if err != nil {
+			return err
+		}
+		// the processed transactions up to the stamp index are in the stamp balances as well and are no longer reversed
+		rebasedIndexAppState, err := repositories.AppStates().FirstOrCreate(entities.RebasedTransactionIndex)
+		if err != nil {
+			return err
+		}
+		if rebasedIndexAppState.Value == "" || isBehind(rebasedIndexAppState.Value, options.TransactionIndex) {
+			rebasedIndexAppState.Value = strconv.FormatInt(options.TransactionIndex, 10)
+			err = repositories.AppStates().Save(rebasedIndexAppState)
+			if err != nil {
+				return err
+			}
+		}
+		if lastMonitoredIndexAppState.Value == "" || isBehind(lastMonitoredIndexAppState.Value, options.TransactionIndex) {
+			lastMonitoredIndexAppState.Value = strconv.FormatInt(options.TransactionIndex, 10)
+			err = repositories.AppStates().Save(lastMonitoredIndexAppState)
+			if err != nil {
+				return err
+			}
+		}
+
+		clusterStamp, err := recordClusterStamp(repositories, entities.RebaseClusterStamp, result, &options.TransactionIndex)
+		if err != nil {
+			return err
+		}
+		clusterStamp.DifferenceCount = int32(len(differences))
+		err = repositories.ClusterStamps().Save(clusterStamp)
+		if err != nil {
+			return err
+		}
+		for i := range differences {
+			differences[i].ClusterStampId = clusterStamp.ID
+		}
+		err = repositories.ClusterStamps().CreateDifferences(differences)
+		if err != nil {
+			return err
+		}
+		logrus.WithFields(logrus.Fields{"currencies": len(currencyIds), "index": options.TransactionIndex}).Info("balances rebased on the cluster stamp")
+		return nil
+	})
+}
+
+// compareBalances compares the stamp balances with the current balances minus the diffs applied after the stamp index
+func compareBalances(repositories repository.Repositories, stampBalances map[string]map[string]decimal.Decimal, currencyAddressDiffMap map[string]map[string]decimal.Decimal) ([]*entities.ClusterStampDifference, error) {
+	var currencyHashes []string
+	for currencyHash := range stampBalances {
+		currencyHashes = append(currencyHashes, currencyHash)
+	}
+	computedBalances := make(map[string]map[string]decimal.Decimal)
+	for _, currencyHash := range currencyHashes {
+		computedBalances[currencyHash] = make(map[string]decimal.Decimal)
+		for addressHash, diff := range currencyAddressDiffMap[currencyHash] {
+			computedBalances[currencyHash][addressHash] = diff.Neg()
+		}
+	}
+	if len(currencyHashes) > 0 {
+		err := repositories.Balances().ForEachAt(repository.BalancePoint{}, currencyHashes, "", func(balance repository.CurrencyHashBalance) error {
+			computedBalances[balance.CurrencyHash][balance.AddressHash] = computedBalances[balance.CurrencyHash][balance.AddressHash].Add(balance.Amount)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var differences []*entities.ClusterStampDifference
+	for _, currencyHash := range currencyHashes {
+		for addressHash, stampAmount := range stampBalances[currencyHash] {
+			computedAmount := computedBalances[currencyHash][addressHash]
+			if !stampAmount.Equal(computedAmount) {
+				differences = append(differences, entities.NewClusterStampDifference(0, currencyHash, addressHash, stampAmount, computedAmount))
+			}
+		}
+		for addressHash, computedAmount := range computedBalances[currencyHash] {
+			if _, ok := stampBalances[currencyHash][addressHash]; !ok && !computedAmount.IsZero() {
+				differences = append(differences, entities.NewClusterStampDifference(0, currencyHash, addressHash, decimal.Zero, computedAmount))
+			}
+		}
+	}
+	return differences, nil
+}
+
+// resetBalances replaces the balances of the stamp currencies with the stamp balances plus the diffs applied after the stamp index
+func resetBalances(repositories repository.Repositories, stampBalances map[string]map[string]decimal.Decimal, currencyAddressDiffMap map[string]map[string]decimal.Decimal) ([]int32, error) {
+	currencyHashToIdMap := make(map[string]int32)
+	var currencyIds []int32
+	for currencyHash := range stampBalances {
+		currencyId, err := getCurrencyId(repositories, currencyHashToIdMap, currencyHash)
+		if err != nil {
+			return nil, err
+		}
+		currencyIds = append(currencyIds, currencyId)
+	}
+	err := repositories.Balances().DeleteByCurrencyIds(currencyIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var addressBalances []entities.AddressBalance
+	for currencyHash, addressBalanceMap := range stampBalances {
+		currencyId := currencyHashToIdMap[currencyHash]
+		for addressHash, amount := range addressBalanceMap {
+			amount = amount.Add(currencyAddressDiffMap[currencyHash][addressHash])
+			addressBalances = append(addressBalances, *entities.NewAddressBalance(addressHash, amount, currencyId))
+		}
+		for addressHash, diff := range currencyAddressDiffMap[currencyHash] {
+			if _, ok := addressBalanceMap[addressHash]; !ok {
+				addressBalances = append(addressBalances, *entities.NewAddressBalance(addressHash, diff, currencyId))
+			}
+		}
+	}
+	err = repositories.Balances().Create(addressBalances)
+	if err != nil {
+		return nil, err
+	}
+	return currencyIds, nil
+}
+
+func isBehind(lastMonitoredIndex string, transactionIndex int64) bool {
+	index, err := strconv.ParseInt(lastMonitoredIndex, 10, 64)
+	return err == nil && index < transactionIndex
+}
@@ -0,0 +1,128 @@
+package clusterStamp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+const (
+	signatureRKey  = "r"
+	signatureSKey  = "s"
+	signerHashKey  = "signerHash"
+	publicKeyBytes = 64
+)
+
+// signatureTrailer is the signature at the end of the cluster stamp file, after the balance lines
+type signatureTrailer struct {
+	r          string
+	s          string
+	signerHash string
+}
+
+func isTrailerLine(line []string) bool {
+	if len(line) != 2 {
+		return false
+	}
+	key := strings.TrimSpace(line[0])
+	return key == signatureRKey || key == signatureSKey || key == signerHashKey
+}
+
+func (trailer *signatureTrailer) set(line []string) {
+	value := strings.TrimSpace(line[1])
+	switch strings.TrimSpace(line[0]) {
+	case signatureRKey:
+		trailer.r = value
+	case signatureSKey:
+		trailer.s = value
+	case signerHashKey:
+		trailer.signerHash = value
+	}
+}
+
+// writeRowToDigest adds a balance line to the stamp hash, the address bytes followed by the amount text
+// as it is written in the stamp and the currency hash bytes when the line has one.
+// TestVerifyNodeSignedStamp checks it against a stamp signed by a node
+func writeRowToDigest(digest hash.Hash, address string, amountText string, currencyHash string) error {
+	addressBytes, err := hex.DecodeString(address)
+	if err != nil {
+		return fmt.Errorf("address %s is not a hex string", address)
+	}
+	digest.Write(addressBytes)
+	digest.Write([]byte(amountText))
+	if currencyHash != "" {
+		currencyHashBytes, err := hex.DecodeString(currencyHash)
+		if err != nil {
+			return fmt.Errorf("currency hash %s is not a hex string", currencyHash)
+		}
+		digest.Write(currencyHashBytes)
+	}
+	return nil
+}
+
+// verifySignature checks the stamp hash was signed by the trusted signer, an ECDSA signature on secp256k1
+// of the keccak-256 stamp hash where the signer hash is the hex x and y of the public key.
+// Only the low s form of a signature is accepted so every stamp has a single valid signature
+func verifySignature(stampHash []byte, trailer signatureTrailer, trustedSignerHash string) error {
+	if trustedSignerHash == "" {
+		return errors.New("CLUSTER_STAMP_SIGNER_HASH is mandatory to verify the cluster stamp signature")
+	}
+	if trailer.r == "" || trailer.s == "" {
+		return errors.New("cluster stamp has no signature")
+	}
+	if trailer.signerHash != "" && !strings.EqualFold(trailer.signerHash, trustedSignerHash) {
+		return fmt.Errorf("cluster stamp was signed by %s and not by the trusted signer", trailer.signerHash)
+	}
+	publicKey, err := parsePublicKey(trustedSignerHash)
+	if err != nil {
+		return err
+	}
+	var r, s secp256k1.ModNScalar
+	if err := setScalar(&r, trailer.r); err != nil {
+		return err
+	}
+	if err := setScalar(&s, trailer.s); err != nil {
+		return err
+	}
+	if s.IsOverHalfOrder() {
+		return fmt.Errorf("signature value %s is not the low s form", trailer.s)
+	}
+	if !ecdsa.NewSignature(&r, &s).Verify(stampHash, publicKey) {
+		return errors.New("cluster stamp signature does not match its hash")
+	}
+	return nil
+}
+
+// parsePublicKey parses a signer hash, which is the uncompressed public key without its prefix,
+// the key is refused when it is not a point of the curve
+func parsePublicKey(signerHash string) (*secp256k1.PublicKey, error) {
+	signerHashBytes, err := hex.DecodeString(signerHash)
+	if err != nil || len(signerHashBytes) != publicKeyBytes {
+		return nil, fmt.Errorf("signer hash %s is not a %d bytes hex public key", signerHash, publicKeyBytes)
+	}
+	publicKey, err := secp256k1.ParsePubKey(append([]byte{0x04}, signerHashBytes...))
+	if err != nil {
+		return nil, fmt.Errorf("signer hash %s is not a secp256k1 public key: %w", signerHash, err)
+	}
+	return publicKey, nil
+}
+
+// setScalar sets a signature value, which is refused when it is 0 or not below the curve order
+func setScalar(scalar *secp256k1.ModNScalar, value string) error {
+	if len(value)%2 == 1 {
+		value = "0" + value
+	}
+	valueBytes, err := hex.DecodeString(value)
+	if err != nil || len(valueBytes) > 32 {
+		return fmt.Errorf("signature value %s is not a 32 bytes hex number", value)
+	}
+	if overflow := scalar.SetByteSlice(valueBytes); overflow || scalar.IsZero() {
+		return fmt.Errorf("signature value %s is out of range", value)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package clusterStamp
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/ebfe/keccak"
+	"github.com/shopspring/decimal"
+)
+
+// nodeSignedStampDir holds a cluster stamp signed by a node and the hash of its signer
+const nodeSignedStampDir = "testdata/node-signed"
+
+// newPrivateKey creates the signing key of the tests from a number
+func newPrivateKey(value uint64) *secp256k1.PrivateKey {
+	return secp256k1.PrivKeyFromBytes([]byte(fmt.Sprintf("%032d", value)))
+}
+
+// signStamp signs the stamp hash with a deterministic nonce, the signature is in the low s form
+func signStamp(privateKey *secp256k1.PrivateKey, stampHash []byte) signatureTrailer {
+	// the compact signature is the recovery byte followed by r and s
+	signature := ecdsa.SignCompact(privateKey, stampHash, false)
+	return signatureTrailer{r: hex.EncodeToString(signature[1:33]), s: hex.EncodeToString(signature[33:]), signerHash: signerHash(privateKey)}
+}
+
+// negateS turns the signature into its high s form, which is valid ECDSA as well
+func negateS(t *testing.T, trailer signatureTrailer) signatureTrailer {
+	t.Helper()
+	var s secp256k1.ModNScalar
+	if err := setScalar(&s, trailer.s); err != nil {
+		t.Fatal(err)
+	}
+	sBytes := s.Negate().Bytes()
+	trailer.s = hex.EncodeToString(sBytes[:])
+	return trailer
+}
+
+func keccak256(data []byte) []byte {
+	digest := keccak.New256()
+	digest.Write(data)
+	return digest.Sum(nil)
+}
+
+func signerHash(privateKey *secp256k1.PrivateKey) string {
+	// the uncompressed key without its 04 prefix
+	return hex.EncodeToString(privateKey.PubKey().SerializeUncompressed()[1:])
+}
+
+func TestVerifySignature(t *testing.T) {
+	privateKey := newPrivateKey(987654321)
+	otherPrivateKey := newPrivateKey(12345)
+	stampHash := keccak256([]byte("stamp"))
+	otherHash := keccak256([]byte("other stamp"))
+	trusted := signerHash(privateKey)
+	signature := signStamp(privateKey, stampHash)
+	otherSignature := signStamp(otherPrivateKey, stampHash)
+	tests := []struct {
+		name      string
+		stampHash []byte
+		trailer   signatureTrailer
+		trusted   string
+		isValid   bool
+	}{
+		{"signed by the trusted signer", stampHash, signature, trusted, true},
+		{"without the signer hash in the trailer", stampHash, signatureTrailer{r: signature.r, s: signature.s}, trusted, true},
+		{"signed for another hash", otherHash, signature, trusted, false},
+		{"signed by another signer", stampHash, otherSignature, trusted, false},
+		{"signed by another signer without the signer hash", stampHash, signatureTrailer{r: otherSignature.r, s: otherSignature.s}, trusted, false},
+		{"without a signature", stampHash, signatureTrailer{signerHash: trusted}, trusted, false},
+		{"with the high s form", stampHash, negateS(t, signature), trusted, false},
+		{"with s out of range", stampHash, signatureTrailer{r: signature.r, s: "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"}, trusted, false},
+		{"with a zero r", stampHash, signatureTrailer{r: "0", s: signature.s}, trusted, false},
+		{"without a trusted signer", stampHash, signature, "", false},
+		{"with a trusted signer off the curve", stampHash, signature, fmt.Sprintf("%0128x", 5), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := verifySignature(test.stampHash, test.trailer, test.trusted)
+			if test.isValid && err != nil {
+				t.Fatalf("the signature was refused: %v", err)
+			}
+			if !test.isValid && err == nil {
+				t.Fatal("the signature was accepted")
+			}
+		})
+	}
+}
+
+func TestWriteRowToDigestHashesTheAmountText(t *testing.T) {
+	hashRow := func(amountText string) string {
+		digest := keccak.New256()
+		if err := writeRowToDigest(digest, "ab01", amountText, ""); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("%x", digest.Sum(nil))
+	}
+	if hashRow("1.50") == hashRow("1.5") {
+		t.Fatal("the amounts 1.50 and 1.5 have the same hash")
+	}
+}
+
+// TestVerifyNodeSignedStamp reads and verifies cluster-stamp.csv of testdata/node-signed with the signer hash in
+// signer-hash, a stamp as a node writes and signs it. It checks the digest of the balance lines is the one the nodes sign
+func TestVerifyNodeSignedStamp(t *testing.T) {
+	fileName := filepath.Join(nodeSignedStampDir, "cluster-stamp.csv")
+	signerHashBytes, err := os.ReadFile(filepath.Join(nodeSignedStampDir, "signer-hash"))
+	if os.IsNotExist(err) {
+		t.Skipf("no node signed cluster stamp in %s", nodeSignedStampDir)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	result, err := readStamp(csv.NewReader(file), "", func(string, decimal.Decimal, string) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifySignature(result.hash, result.trailer, strings.TrimSpace(string(signerHashBytes))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coti-io/coti-db-app/auth"
+	clusterStamp "github.com/coti-io/coti-db-app/cluster-stamp"
+	"github.com/coti-io/coti-db-app/config"
+	dbprovider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/coti-io/coti-db-app/entities"
+	rateLimit "github.com/coti-io/coti-db-app/rate-limit"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+func migrate(args []string) {
+	flags := newCommandFlags("migrate")
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	positional := flags.parse(args)
+	if len(positional) != 1 {
+		logrus.Fatal("usage: migrate up|down|status")
+	}
+	var err error
+	switch positional[0] {
+	case "up":
+		err = dbprovider.MigrateUp()
+	case "down":
+		err = dbprovider.MigrateDown(*steps)
+	case "status":
+		var statuses []dbprovider.MigrationStatus
+		statuses, err = dbprovider.GetMigrationStatus()
+		for _, status := range statuses {
+			state := "pending"
+			if status.IsApplied {
+				state = "applied at " + status.AppliedTime.Format(time.RFC3339)
+			}
+			if !status.IsKnown {
+				state += ", unknown to this version"
+			}
+			fmt.Printf("%06d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		logrus.Fatal("usage: migrate up|down|status")
+	}
+	if err != nil {
+		logrus.WithError(err).Fatal("migration failed")
+	}
+}
+
+func loadClusterStampCommand(args []string) {
+	flags := newCommandFlags("load-cluster-stamp")
+	flags.settingFlag("file", "CLUSTER_STAMP_FILE_NAME", "the cluster stamp file")
+	flags.settingFlag("expected-supply-file", "CLUSTER_STAMP_EXPECTED_SUPPLY_FILE_NAME", "optional expected supply per currency of the stamp")
+	flags.migrateFlag()
+	flags.parse(args)
+
+	initSyncDb()
+	logrus.Info("the cluster stamp is loaded")
+}
+
+// status prints the state of the schema, the cluster stamp and the sync as name and value lines
+func status(args []string) {
+	flags := newCommandFlags("status")
+	flags.fullnodeFlags()
+	flags.parse(args)
+
+	dbprovider.InitWithoutMigrations()
+	repositories := repository.NewGormRepositories(dbprovider.DB)
+	schemaState := "up to date"
+	if err := dbprovider.CheckSchemaVersion(); err != nil {
+		schemaState = err.Error()
+	}
+	printStatus("schema", schemaState)
+	clusterStampState, err := getAppStateValue(repositories, entities.IsClusterStampInitialized)
+	if err != nil {
+		clusterStampState = err.Error()
+	}
+	printStatus("clusterStampInitialized", clusterStampState)
+
+	lastMonitoredIndex := int64(-1)
+	lastMonitoredIndexValue, err := getAppStateValue(repositories, entities.LastMonitoredTransactionIndex)
+	if err == nil && lastMonitoredIndexValue != "" {
+		lastMonitoredIndex, err = strconv.ParseInt(lastMonitoredIndexValue, 10, 64)
+	}
+	if err != nil {
+		printStatus("lastMonitoredTransactionIndex", err.Error())
+	} else {
+		printStatus("lastMonitoredTransactionIndex", strconv.FormatInt(lastMonitoredIndex, 10))
+	}
+
+	lease, err := repositories.LeaderLeases().GetByName(entities.SyncLeaderLease)
+	if err != nil {
+		printStatus("syncLeader", err.Error())
+	} else {
+		printStatus("syncLeader", lease.Holder)
+		printStatus("syncLeaderFencingToken", strconv.FormatInt(lease.FencingToken, 10))
+		printStatus("syncLeaderLeaseExpireTime", lease.ExpireTime.UTC().Format(time.RFC3339))
+	}
+
+	transactionService := service.NewTransactionService(repositories)
+	ctx, cancel := context.WithTimeout(context.Background(), config.Get().Health.ReadinessTimeout)
+	defer cancel()
+	fullnodeIndex := int64(-1)
+	for _, node := range []struct{ name, url string }{{"fullnode", transactionService.GetFullnodeUrl()}, {"backupFullnode", transactionService.GetBackupFullnodeUrl()}} {
+		if node.url == "" {
+			printStatus(node.name+"LastIndex", "not set")
+			continue
+		}
+		result := <-transactionService.GetLastIndex(ctx, node.url)
+		if result.Error != nil {
+			printStatus(node.name+"LastIndex", result.Error.Error())
+			continue
+		}
+		printStatus(node.name+"LastIndex", strconv.FormatInt(result.Tran.LastIndex, 10))
+		if result.Tran.LastIndex > fullnodeIndex {
+			fullnodeIndex = result.Tran.LastIndex
+		}
+	}
+	if fullnodeIndex >= 0 && lastMonitoredIndex >= 0 {
+		printStatus("syncLag", strconv.FormatInt(fullnodeIndex-lastMonitoredIndex, 10))
+	}
+}
+
+func printStatus(name string, value string) {
+	fmt.Printf("%s\t%s\n", name, value)
+}
+
+// verify checks everything the sync needs and exits with 1 when a check fails, --api only checks what the api needs
+func verify(args []string) {
+	flags := newCommandFlags("verify")
+	flags.fullnodeFlags()
+	isApi := flags.Bool("api", false, "skip the settings that only the sync needs")
+	flags.parse(args)
+
+	isFailed := false
+	report := func(name string, err error) {
+		if err != nil {
+			isFailed = true
+			printStatus(name, "fail: "+err.Error())
+			return
+		}
+		printStatus(name, "ok")
+	}
+	if *isApi {
+		report("config", nil)
+	} else {
+		report("config", config.Get().ValidateSync())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Get().Health.ReadinessTimeout)
+	defer cancel()
+	fullnodeConfig := config.Get().Fullnode
+	for _, node := range []struct{ name, url string }{{"fullnode", fullnodeConfig.Url}, {"backupFullnode", fullnodeConfig.BackupUrl}} {
+		if node.url == "" {
+			continue
+		}
+		report(node.name, service.PingFullnode(ctx, node.url))
+	}
+
+	err := tryInitDb()
+	report("db", err)
+	if err == nil {
+		repositories := repository.NewGormRepositories(dbprovider.DB)
+		report("schema", dbprovider.CheckSchemaVersion())
+		report("nativeCurrency", checkNativeCurrency(repositories))
+		report("clusterStamp", checkClusterStampInitialized(repositories))
+	}
+	if isFailed {
+		os.Exit(1)
+	}
+}
+
+// tryInitDb opens the db without migrating, the panic of a failed connection is returned as an error
+func tryInitDb() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	dbprovider.InitWithoutMigrations()
+	return dbprovider.Ping(context.Background())
+}
+
+func checkClusterStampInitialized(repositories repository.Repositories) error {
+	value, err := getAppStateValue(repositories, entities.IsClusterStampInitialized)
+	if err != nil {
+		return err
+	}
+	if value != "true" {
+		return errors.New("the cluster stamp is not initialized, run load-cluster-stamp")
+	}
+	return nil
+}
+
+func getAppStateValue(repositories repository.Repositories, name entities.AppStatesNames) (string, error) {
+	appState, err := repositories.AppStates().GetByName(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the %s app state: %w", name, err)
+	}
+	return appState.Value, nil
+}
+
+func exportClusterStamp(args []string) {
+	flags := newCommandFlags("export-cluster-stamp")
+	atIndex := flags.Int64("at-index", -1, "export the balances at this transaction index")
+	atTime := flags.String("at-time", "", "export the balances at this attachment time in seconds")
+	currencies := flags.String("currency", "", "comma separated currency hashes to export, all currencies when empty")
+	out := flags.String("out", "", "output file, stdout when empty")
+	flags.parse(args)
+
+	options := clusterStamp.ExportOptions{}
+	if *atIndex >= 0 {
+		options.AtIndex = atIndex
+	}
+	if *atTime != "" {
+		attachmentTime, err := decimal.NewFromString(*atTime)
+		if err != nil {
+			logrus.WithError(err).Fatal("at-time must be a number")
+		}
+		options.AtTime = &attachmentTime
+	}
+	if *currencies != "" {
+		options.CurrencyHashes = strings.Split(*currencies, ",")
+	}
+	writer := os.Stdout
+	if *out != "" {
+		outFile, err := os.Create(*out)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to create the export file")
+		}
+		defer outFile.Close()
+		writer = outFile
+	}
+
+	dbprovider.Init()
+	err := clusterStamp.Export(repository.NewGormRepositories(dbprovider.DB), writer, options)
+	if err != nil {
+		logrus.WithError(err).Fatal("cluster stamp export failed")
+	}
+}
+
+func rebaseClusterStamp(args []string) {
+	flags := newCommandFlags("rebase-cluster-stamp")
+	file := flags.String("file", "", "the newer cluster stamp file")
+	expectedSupplyFile := flags.String("expected-supply-file", "", "optional expected supply per currency of the stamp")
+	index := flags.Int64("index", -1, "the transaction index the cluster stamp was taken at")
+	dryRun := flags.Bool("dry-run", false, "only report the differences from the computed balances")
+	flags.parse(args)
+	if *file == "" || *index < 0 {
+		logrus.Fatal("file and index are mandatory")
+	}
+
+	dbprovider.Init()
+	repositories := repository.NewGormRepositories(dbprovider.DB)
+	verifyAppStates(repositories)
+	verifyNativeCurrencyHash(repositories)
+	err := clusterStamp.Rebase(repositories, clusterStamp.RebaseOptions{
+		FileName:               *file,
+		ExpectedSupplyFileName: *expectedSupplyFile,
+		TransactionIndex:       *index,
+		DryRun:                 *dryRun,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("cluster stamp rebase failed")
+	}
+}
+
+// partition splits the transaction tables by PARTITION_BY and PARTITION_SIZE, the archive job keeps adding the new ranges
+func partition(args []string) {
+	flags := newCommandFlags("partition")
+	flags.settingFlag("by", "PARTITION_BY", "partition key: index or attachmentTime")
+	flags.settingFlag("size", "PARTITION_SIZE", "size of a partition in indexes or attachment time seconds")
+	flags.parse(args)
+
+	key, size, err := dbprovider.GetPartitionConfig()
+	if err != nil {
+		logrus.WithError(err).Fatal("invalid partition config")
+	}
+	dbprovider.Init()
+	err = dbprovider.PartitionTables(key, size)
+	if err != nil {
+		logrus.WithError(err).Fatal("partitioning failed")
+	}
+}
+
+// apiKey creates, lists and revokes the api keys, a created key is only printed once
+func apiKey(args []string) {
+	flags := newCommandFlags("api-key")
+	name := flags.String("name", "", "name of the key to create or revoke")
+	scope := flags.String("scope", string(entities.ReadScope), "scope of the created key: public, read or admin")
+	limit := flags.Float64("rate-limit", 0, "requests per second of the created key, 0 is RATE_LIMIT_KEY_PER_SECOND")
+	burst := flags.Int("rate-limit-burst", 0, "requests the created key may send at once, 0 is RATE_LIMIT_KEY_BURST")
+	positional := flags.parse(args)
+	if len(positional) != 1 {
+		logrus.Fatal("usage: api-key create|list|revoke")
+	}
+
+	dbprovider.Init()
+	repositories := repository.NewGormRepositories(dbprovider.DB)
+	switch positional[0] {
+	case "create":
+		key, created, err := auth.CreateApiKey(repositories, *name, entities.ApiKeyScope(*scope), rateLimit.Limit{Rate: *limit, Burst: *burst})
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to create the api key")
+		}
+		fmt.Printf("created the %s key %s, it can't be shown again:\n%s\n", created.Scope, created.Name, key)
+	case "list":
+		apiKeys, err := repositories.ApiKeys().FindAll()
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to list the api keys")
+		}
+		for _, listed := range apiKeys {
+			state := "active"
+			if listed.IsRevoked {
+				state = "revoked"
+			}
+			fmt.Printf("%s\t%s...\t%s\t%v/s burst %d\t%s\t%s\n", listed.Name, listed.Prefix, listed.Scope, listed.RateLimit, listed.RateLimitBurst, state, listed.CreateTime.Format(time.RFC3339))
+		}
+	case "revoke":
+		err := auth.RevokeApiKey(repositories, *name)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed to revoke the api key")
+		}
+		fmt.Printf("revoked the key %s\n", *name)
+	default:
+		logrus.Fatal("usage: api-key create|list|revoke")
+	}
+}
@@ -0,0 +1,443 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source is where the value of a setting was read from, each source overrides the ones before it
+type Source string
+
+const (
+	DefaultSource Source = "default"
+	YamlSource    Source = "yaml"
+	DotEnvSource  Source = ".env"
+	EnvSource     Source = "env"
+	FlagSource    Source = "flag"
+)
+
+// redactedValue replaces the values of the secret settings in the effective config
+const redactedValue = "******"
+
+type Config struct {
+	Port         string
+	NativeSymbol string
+	Log          LogConfig
+	Db           DbConfig
+	Fullnode     FullnodeConfig
+	Sync         SyncConfig
+	ClusterStamp ClusterStampConfig
+	Archive      ArchiveConfig
+	Partition    PartitionConfig
+	Health       HealthConfig
+	Leader       LeaderConfig
+	Admin        AdminConfig
+	Api          ApiConfig
+	Tracing      TracingConfig
+	// sources are the sources of the settings by env name
+	sources map[string]Source
+}
+
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+// DbConfig is the connection, pool and startup settings of the db
+type DbConfig struct {
+	Dialect  string
+	Name     string
+	User     string
+	Password string
+	Host     string
+	Port     string
+	// a MaxOpenConns of 0 is unlimited, sqlite always uses a single connection
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTimeout, ReadTimeout and WriteTimeout are dsn options, postgres only supports the connect timeout
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	// TLS is one of false, true, skip-verify and preferred, TLSCaFile verifies the server with a private CA
+	TLS       string
+	TLSCaFile string
+	// ConnectRetries is how many times the first connection is retried, the backoff doubles after each retry
+	ConnectRetries      int
+	ConnectRetryBackoff time.Duration
+	ReadReplicaDsns     []string
+	// ReplicaMaxLag is how many transaction indexes a replica may be behind the primary and still serve reads
+	ReplicaMaxLag           int64
+	ReplicaLagCheckInterval time.Duration
+	// Migrate runs the migrations on startup
+	Migrate bool
+}
+
+type FullnodeConfig struct {
+	Url       string
+	BackupUrl string
+}
+
+type SyncConfig struct {
+	MaxTransactionsInSyncIteration     int64
+	SyncNewTransactionsInterval        time.Duration
+	MonitorTransactionsInterval        time.Duration
+	UpdateBalancesInterval             time.Duration
+	CleanUnindexedTransactionsInterval time.Duration
+	// the unindexed transactions are deleted DeleteTxDelay after the start once they are pending for DeleteTxPendingMin
+	DeleteTxDelay      time.Duration
+	DeleteTxPendingMin time.Duration
+	// ReversalMonitorWindow is how long processed transactions are monitored for reversal
+	ReversalMonitorWindow   time.Duration
+	ConfirmationPolicy      string
+	MinTrustChainTrustScore string
+}
+
+type ClusterStampConfig struct {
+	FileName               string
+	ExpectedSupplyFileName string
+	VerifySignature        bool
+	SignerHash             string
+}
+
+type ArchiveConfig struct {
+	// Retention is how old the archived transactions are, the archive is disabled when it is 0
+	Retention time.Duration
+	Target    string
+	Dir       string
+	BatchSize int
+	Interval  time.Duration
+}
+
+type PartitionConfig struct {
+	// By is index or attachmentTime, the tables are not partitioned when it is empty
+	By   string
+	Size int64
+}
+
+type HealthConfig struct {
+	// MissedIntervals is how many intervals a job may miss before the liveness check fails
+	MissedIntervals     int
+	ReadinessMaxSyncLag int64
+	ReadinessTimeout    time.Duration
+}
+
+type LeaderConfig struct {
+	// Id identifies the instance in the lease, a hostname, pid and random suffix when it is empty
+	Id string
+	// LeaseDuration is how long the leader holds the lease without renewing it, a replica takes over after it
+	LeaseDuration time.Duration
+	RenewInterval time.Duration
+}
+
+type AdminConfig struct {
+	// Token is a bearer token with the admin scope next to the admin api keys, it is refused when it is empty
+	Token string
+}
+
+// ApiConfig is the authentication and the rate limits of the api, the rates are requests per second and a rate of 0 is unlimited
+type ApiConfig struct {
+	// AnonymousScope is the scope of the requests without an api key, public or read
+	AnonymousScope string
+	// KeyCacheTtl is how long a key is trusted before it is read again, the other processes refuse a revoked key after it
+	KeyCacheTtl      time.Duration
+	IpRateLimit      float64
+	IpRateLimitBurst int
+	// KeyRateLimit and KeyRateLimitBurst are the limits of the keys that don't have their own
+	KeyRateLimit      float64
+	KeyRateLimitBurst int
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter     string
+	OtlpEndpoint string
+	// OtlpHeaders are key=value pairs separated by commas
+	OtlpHeaders string
+	SampleRatio float64
+}
+
+// setting maps an env variable and a yaml key to a field of the config
+type setting struct {
+	env string
+	// key is the dotted path of the setting in the yaml file
+	key string
+	// value points to the config field, a *time.Duration is read as a number of units
+	value  interface{}
+	unit   time.Duration
+	secret bool
+	// requiredBySync settings must be set to run the sync
+	requiredBySync bool
+}
+
+// EffectiveSetting is a setting with the value the app runs with, secrets are redacted
+type EffectiveSetting struct {
+	Name   string
+	Key    string
+	Value  string
+	Source Source
+}
+
+// newDefaultConfig is the config when no setting is set
+func newDefaultConfig() *Config {
+	return &Config{
+		Port: "3000",
+		Log:  LogConfig{Level: "info", Format: "text"},
+		Db: DbConfig{
+			Dialect:                 "mysql",
+			MaxOpenConns:            20,
+			MaxIdleConns:            10,
+			ConnMaxLifetime:         5 * time.Minute,
+			ConnectTimeout:          10 * time.Second,
+			ConnectRetries:          10,
+			ConnectRetryBackoff:     time.Second,
+			ReplicaLagCheckInterval: 5 * time.Second,
+		},
+		Sync: SyncConfig{
+			ReversalMonitorWindow: 24 * time.Hour,
+			ConfirmationPolicy:    "dspConsensus",
+		},
+		ClusterStamp: ClusterStampConfig{VerifySignature: true},
+		Archive: ArchiveConfig{
+			Target:    "table",
+			Dir:       "archive",
+			BatchSize: 1000,
+			Interval:  time.Hour,
+		},
+		Health: HealthConfig{
+			MissedIntervals:     3,
+			ReadinessMaxSyncLag: 1000,
+			ReadinessTimeout:    5 * time.Second,
+		},
+		Leader: LeaderConfig{
+			LeaseDuration: 15 * time.Second,
+			RenewInterval: 5 * time.Second,
+		},
+		Api: ApiConfig{
+			AnonymousScope:    "public",
+			KeyCacheTtl:       30 * time.Second,
+			IpRateLimit:       5,
+			IpRateLimitBurst:  20,
+			KeyRateLimit:      20,
+			KeyRateLimitBurst: 50,
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OtlpEndpoint: "http://localhost:4318",
+			SampleRatio:  1,
+		},
+	}
+}
+
+func (config *Config) settings() []setting {
+	return []setting{
+		{env: "PORT", key: "port", value: &config.Port},
+		{env: "NATIVE_SYMBOL", key: "nativeSymbol", value: &config.NativeSymbol, requiredBySync: true},
+		{env: "LOG_LEVEL", key: "log.level", value: &config.Log.Level},
+		{env: "LOG_FORMAT", key: "log.format", value: &config.Log.Format},
+
+		{env: "DB_DIALECT", key: "db.dialect", value: &config.Db.Dialect},
+		{env: "DB_NAME", key: "db.name", value: &config.Db.Name},
+		{env: "DB_USER", key: "db.user", value: &config.Db.User},
+		{env: "DB_PASSWORD", key: "db.password", value: &config.Db.Password, secret: true},
+		{env: "DB_HOST", key: "db.host", value: &config.Db.Host},
+		{env: "DB_PORT", key: "db.port", value: &config.Db.Port},
+		{env: "DB_MAX_OPEN_CONNS", key: "db.maxOpenConns", value: &config.Db.MaxOpenConns},
+		{env: "DB_MAX_IDLE_CONNS", key: "db.maxIdleConns", value: &config.Db.MaxIdleConns},
+		{env: "DB_CONN_MAX_LIFETIME_IN_SECONDS", key: "db.connMaxLifetimeInSeconds", value: &config.Db.ConnMaxLifetime, unit: time.Second},
+		{env: "DB_CONN_MAX_IDLE_TIME_IN_SECONDS", key: "db.connMaxIdleTimeInSeconds", value: &config.Db.ConnMaxIdleTime, unit: time.Second},
+		{env: "DB_CONNECT_TIMEOUT_IN_SECONDS", key: "db.connectTimeoutInSeconds", value: &config.Db.ConnectTimeout, unit: time.Second},
+		{env: "DB_READ_TIMEOUT_IN_SECONDS", key: "db.readTimeoutInSeconds", value: &config.Db.ReadTimeout, unit: time.Second},
+		{env: "DB_WRITE_TIMEOUT_IN_SECONDS", key: "db.writeTimeoutInSeconds", value: &config.Db.WriteTimeout, unit: time.Second},
+		{env: "DB_TLS", key: "db.tls", value: &config.Db.TLS},
+		{env: "DB_TLS_CA_FILE", key: "db.tlsCaFile", value: &config.Db.TLSCaFile},
+		{env: "DB_CONNECT_RETRIES", key: "db.connectRetries", value: &config.Db.ConnectRetries},
+		{env: "DB_CONNECT_RETRY_BACKOFF_IN_SECONDS", key: "db.connectRetryBackoffInSeconds", value: &config.Db.ConnectRetryBackoff, unit: time.Second},
+		// the replica dsns hold the replica passwords
+		{env: "DB_READ_REPLICA_DSNS", key: "db.readReplicaDsns", value: &config.Db.ReadReplicaDsns, secret: true},
+		{env: "DB_REPLICA_MAX_LAG", key: "db.replicaMaxLag", value: &config.Db.ReplicaMaxLag},
+		{env: "DB_REPLICA_LAG_CHECK_INTERVAL_IN_SECONDS", key: "db.replicaLagCheckIntervalInSeconds", value: &config.Db.ReplicaLagCheckInterval, unit: time.Second},
+		{env: "MIGRATE_DB", key: "db.migrate", value: &config.Db.Migrate},
+
+		{env: "FULLNODE_URL", key: "fullnode.url", value: &config.Fullnode.Url, requiredBySync: true},
+		{env: "FULLNODE_BACKUP_URL", key: "fullnode.backupUrl", value: &config.Fullnode.BackupUrl, requiredBySync: true},
+
+		{env: "MAX_TRANSACTION_IN_SYNC_ITERATION", key: "sync.maxTransactionsInSyncIteration", value: &config.Sync.MaxTransactionsInSyncIteration, requiredBySync: true},
+		{env: "SYNC_NEW_TRANSACTIONS_INTERVAL_IN_SECONDS", key: "sync.syncNewTransactionsIntervalInSeconds", value: &config.Sync.SyncNewTransactionsInterval, unit: time.Second, requiredBySync: true},
+		{env: "MONITOR_TRANSACTION_INTERVAL_IN_SECONDS", key: "sync.monitorTransactionsIntervalInSeconds", value: &config.Sync.MonitorTransactionsInterval, unit: time.Second, requiredBySync: true},
+		{env: "UPDATE_BALANCES_INTERVAL_IN_SECONDS", key: "sync.updateBalancesIntervalInSeconds", value: &config.Sync.UpdateBalancesInterval, unit: time.Second, requiredBySync: true},
+		{env: "CLEAN_UNINDEXED_TRANSACTIONS_INTERVAL_IN_SECONDS", key: "sync.cleanUnindexedTransactionsIntervalInSeconds", value: &config.Sync.CleanUnindexedTransactionsInterval, unit: time.Second, requiredBySync: true},
+		{env: "DELETE_TX_DELAY_IN_HOURS", key: "sync.deleteTxDelayInHours", value: &config.Sync.DeleteTxDelay, unit: time.Hour, requiredBySync: true},
+		{env: "DELETE_TX_PENDING_MIN_HOURS", key: "sync.deleteTxPendingMinHours", value: &config.Sync.DeleteTxPendingMin, unit: time.Hour, requiredBySync: true},
+		{env: "REVERSAL_MONITOR_WINDOW_IN_HOURS", key: "sync.reversalMonitorWindowInHours", value: &config.Sync.ReversalMonitorWindow, unit: time.Hour},
+		{env: "CONFIRMATION_POLICY", key: "sync.confirmationPolicy", value: &config.Sync.ConfirmationPolicy},
+		{env: "MIN_TRUST_CHAIN_TRUST_SCORE", key: "sync.minTrustChainTrustScore", value: &config.Sync.MinTrustChainTrustScore},
+
+		{env: "CLUSTER_STAMP_FILE_NAME", key: "clusterStamp.fileName", value: &config.ClusterStamp.FileName},
+		{env: "CLUSTER_STAMP_EXPECTED_SUPPLY_FILE_NAME", key: "clusterStamp.expectedSupplyFileName", value: &config.ClusterStamp.ExpectedSupplyFileName},
+		{env: "CLUSTER_STAMP_VERIFY_SIGNATURE", key: "clusterStamp.verifySignature", value: &config.ClusterStamp.VerifySignature},
+		{env: "CLUSTER_STAMP_SIGNER_HASH", key: "clusterStamp.signerHash", value: &config.ClusterStamp.SignerHash},
+
+		{env: "ARCHIVE_RETENTION_IN_DAYS", key: "archive.retentionInDays", value: &config.Archive.Retention, unit: 24 * time.Hour},
+		{env: "ARCHIVE_TARGET", key: "archive.target", value: &config.Archive.Target},
+		{env: "ARCHIVE_DIR", key: "archive.dir", value: &config.Archive.Dir},
+		{env: "ARCHIVE_BATCH_SIZE", key: "archive.batchSize", value: &config.Archive.BatchSize},
+		{env: "ARCHIVE_INTERVAL_IN_SECONDS", key: "archive.intervalInSeconds", value: &config.Archive.Interval, unit: time.Second},
+
+		{env: "PARTITION_BY", key: "partition.by", value: &config.Partition.By},
+		{env: "PARTITION_SIZE", key: "partition.size", value: &config.Partition.Size},
+
+		{env: "HEALTH_MISSED_INTERVALS", key: "health.missedIntervals", value: &config.Health.MissedIntervals},
+		{env: "READINESS_MAX_SYNC_LAG", key: "health.readinessMaxSyncLag", value: &config.Health.ReadinessMaxSyncLag},
+		{env: "READINESS_TIMEOUT_IN_SECONDS", key: "health.readinessTimeoutInSeconds", value: &config.Health.ReadinessTimeout, unit: time.Second},
+
+		{env: "LEADER_ID", key: "leader.id", value: &config.Leader.Id},
+		{env: "LEADER_LEASE_DURATION_IN_SECONDS", key: "leader.leaseDurationInSeconds", value: &config.Leader.LeaseDuration, unit: time.Second},
+		{env: "LEADER_RENEW_INTERVAL_IN_SECONDS", key: "leader.renewIntervalInSeconds", value: &config.Leader.RenewInterval, unit: time.Second},
+
+		{env: "ADMIN_TOKEN", key: "admin.token", value: &config.Admin.Token, secret: true},
+
+		{env: "API_ANONYMOUS_SCOPE", key: "api.anonymousScope", value: &config.Api.AnonymousScope},
+		{env: "API_KEY_CACHE_TTL_IN_SECONDS", key: "api.keyCacheTtlInSeconds", value: &config.Api.KeyCacheTtl, unit: time.Second},
+		{env: "RATE_LIMIT_IP_PER_SECOND", key: "api.ipRateLimit", value: &config.Api.IpRateLimit},
+		{env: "RATE_LIMIT_IP_BURST", key: "api.ipRateLimitBurst", value: &config.Api.IpRateLimitBurst},
+		{env: "RATE_LIMIT_KEY_PER_SECOND", key: "api.keyRateLimit", value: &config.Api.KeyRateLimit},
+		{env: "RATE_LIMIT_KEY_BURST", key: "api.keyRateLimitBurst", value: &config.Api.KeyRateLimitBurst},
+
+		{env: "TRACING_EXPORTER", key: "tracing.exporter", value: &config.Tracing.Exporter},
+		{env: "TRACING_OTLP_ENDPOINT", key: "tracing.otlpEndpoint", value: &config.Tracing.OtlpEndpoint},
+		{env: "TRACING_OTLP_HEADERS", key: "tracing.otlpHeaders", value: &config.Tracing.OtlpHeaders, secret: true},
+		{env: "TRACING_SAMPLE_RATIO", key: "tracing.sampleRatio", value: &config.Tracing.SampleRatio},
+	}
+}
+
+// set parses the raw value into the field of the setting
+func (s setting) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch value := s.value.(type) {
+	case *string:
+		*value = raw
+	case *int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("must be a whole number, got %q", raw)
+		}
+		*value = parsed
+	case *int64:
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be a whole number, got %q", raw)
+		}
+		*value = parsed
+	case *float64:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", raw)
+		}
+		*value = parsed
+	case *bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", raw)
+		}
+		*value = parsed
+	case *time.Duration:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", raw)
+		}
+		*value = time.Duration(parsed * float64(s.unit))
+	case *[]string:
+		*value = nil
+		for _, item := range strings.Split(raw, ",") {
+			if strings.TrimSpace(item) != "" {
+				*value = append(*value, strings.TrimSpace(item))
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported setting type %T", s.value)
+	}
+	return nil
+}
+
+// format is the value of the setting as it is set, the durations are in the unit of the setting
+func (s setting) format() string {
+	switch value := s.value.(type) {
+	case *string:
+		return *value
+	case *int:
+		return strconv.Itoa(*value)
+	case *int64:
+		return strconv.FormatInt(*value, 10)
+	case *float64:
+		return strconv.FormatFloat(*value, 'f', -1, 64)
+	case *bool:
+		return strconv.FormatBool(*value)
+	case *time.Duration:
+		return strconv.FormatFloat(float64(*value)/float64(s.unit), 'f', -1, 64)
+	case *[]string:
+		return strings.Join(*value, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Effective lists the settings the app runs with and where they were read from, the secrets and the url passwords are redacted
+func (config *Config) Effective() []EffectiveSetting {
+	settings := config.settings()
+	effective := make([]EffectiveSetting, 0, len(settings))
+	for _, s := range settings {
+		value := s.format()
+		if s.secret && value != "" {
+			value = redactedValue
+		} else {
+			value = redactUrlPassword(value)
+		}
+		source, ok := config.sources[s.env]
+		if !ok {
+			source = DefaultSource
+		}
+		effective = append(effective, EffectiveSetting{Name: s.env, Key: s.key, Value: value, Source: source})
+	}
+	return effective
+}
+
+func redactUrlPassword(value string) string {
+	if !strings.Contains(value, "://") {
+		return value
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.User == nil {
+		return value
+	}
+	if _, hasPassword := parsed.User.Password(); !hasPassword {
+		return value
+	}
+	// the url escapes the asterisks of the redacted value so a plain placeholder is replaced after formatting
+	parsed.User = url.UserPassword(parsed.User.Username(), "redacted")
+	return strings.Replace(parsed.String(), ":redacted@", ":"+redactedValue+"@", 1)
+}
+
+var current *Config
+
+// Init loads and validates the config, it is read with Get afterwards
+func Init(yamlFileName string, overrides map[string]string) error {
+	config, err := Load(yamlFileName, overrides)
+	if err != nil {
+		return err
+	}
+	current = config
+	return nil
+}
+
+// Get returns the config loaded by Init
+func Get() *Config {
+	if current == nil {
+		panic("the config is read before it is loaded")
+	}
+	return current
+}
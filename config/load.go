@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+// dotEnvFileName is read from the working directory when it exists
+const dotEnvFileName = ".env"
+
+// ValidationError lists every invalid setting so they can all be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (err *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(err.Problems, "; ")
+}
+
+// Load reads the config from the defaults, the yaml file, the .env file, the env variables and the overrides of the command line flags
+// by env name, each source overrides the ones before it and an empty value is not set.
+// The yaml file is yamlFileName, or CONFIG_FILE when it is empty, no yaml file is read when both are empty
+func Load(yamlFileName string, overrides map[string]string) (*Config, error) {
+	dotEnv, err := readDotEnv(dotEnvFileName)
+	if err != nil {
+		return nil, err
+	}
+	if yamlFileName == "" {
+		if value, ok := os.LookupEnv("CONFIG_FILE"); ok && value != "" {
+			yamlFileName = value
+		} else {
+			yamlFileName = dotEnv["CONFIG_FILE"]
+		}
+	}
+	yamlValues := map[string]string{}
+	if yamlFileName != "" {
+		yamlValues, err = readYaml(yamlFileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the config file %s: %w", yamlFileName, err)
+		}
+	}
+
+	config := newDefaultConfig()
+	config.sources = map[string]Source{}
+	var problems []string
+	knownKeys, knownNames := map[string]bool{}, map[string]bool{}
+	for _, s := range config.settings() {
+		knownKeys[s.key], knownNames[s.env] = true, true
+		var raw string
+		var source Source
+		if value := overrides[s.env]; value != "" {
+			raw, source = value, FlagSource
+		} else if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			raw, source = value, EnvSource
+		} else if value := dotEnv[s.env]; value != "" {
+			raw, source = value, DotEnvSource
+		} else if value := yamlValues[s.key]; value != "" {
+			raw, source = value, YamlSource
+		} else {
+			continue
+		}
+		if err := s.set(raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (from %s) %v", s.env, source, err))
+			continue
+		}
+		config.sources[s.env] = source
+	}
+	var unknownOverrides []string
+	for name := range overrides {
+		if !knownNames[name] {
+			unknownOverrides = append(unknownOverrides, name)
+		}
+	}
+	sort.Strings(unknownOverrides)
+	for _, name := range unknownOverrides {
+		problems = append(problems, fmt.Sprintf("%s is not a setting, it is set by a flag", name))
+	}
+	var unknownKeys []string
+	for key := range yamlValues {
+		if !knownKeys[key] {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	sort.Strings(unknownKeys)
+	for _, key := range unknownKeys {
+		problems = append(problems, fmt.Sprintf("%s is not a setting, it is set in %s", key, yamlFileName))
+	}
+	problems = append(problems, config.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return config, nil
+}
+
+// readDotEnv reads the variables of the .env file without setting them in the environment, a missing file has no variables
+func readDotEnv(fileName string) (map[string]string, error) {
+	values, err := godotenv.Read(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+	}
+	return values, nil
+}
+
+// readYaml reads the yaml file into values by their dotted key, a list is read as its items separated by commas
+func readYaml(fileName string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var document map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	return values, flattenYaml("", document, values)
+}
+
+func flattenYaml(prefix string, node interface{}, values map[string]string) error {
+	switch node := node.(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		for key, child := range node {
+			childKey := fmt.Sprint(key)
+			if prefix != "" {
+				childKey = prefix + "." + childKey
+			}
+			if err := flattenYaml(childKey, child, values); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(node))
+		for _, item := range node {
+			switch item.(type) {
+			case map[interface{}]interface{}, []interface{}:
+				return fmt.Errorf("%s must be a list of values", prefix)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		values[prefix] = strings.Join(items, ",")
+	default:
+		values[prefix] = fmt.Sprint(node)
+	}
+	return nil
+}
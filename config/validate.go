@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// validate checks the values that are set, the settings that are only needed by the sync are checked by ValidateSync
+func (config *Config) validate() []string {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if _, err := logrus.ParseLevel(config.Log.Level); err != nil {
+		addProblem("LOG_LEVEL must be one of trace, debug, info, warn and error, got %s", config.Log.Level)
+	}
+	if !isOneOf(config.Log.Format, "text", "json") {
+		addProblem("LOG_FORMAT must be text or json, got %s", config.Log.Format)
+	}
+
+	if !isOneOf(config.Db.Dialect, "mysql", "postgres", "sqlite") {
+		addProblem("DB_DIALECT must be one of mysql, postgres and sqlite, got %s", config.Db.Dialect)
+	}
+	if !isOneOf(config.Db.TLS, "", "false", "true", "skip-verify", "preferred") {
+		addProblem("DB_TLS must be one of false, true, skip-verify and preferred, got %s", config.Db.TLS)
+	}
+	if config.Db.MaxOpenConns < 0 || config.Db.MaxIdleConns < 0 || config.Db.ConnectRetries < 0 {
+		addProblem("DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and DB_CONNECT_RETRIES can't be negative")
+	}
+	if config.Db.MaxOpenConns > 0 && config.Db.MaxIdleConns > config.Db.MaxOpenConns {
+		addProblem("DB_MAX_IDLE_CONNS %d is more than DB_MAX_OPEN_CONNS %d", config.Db.MaxIdleConns, config.Db.MaxOpenConns)
+	}
+	if config.Db.ReplicaMaxLag < 0 {
+		addProblem("DB_REPLICA_MAX_LAG can't be negative")
+	}
+	if config.Db.ReplicaLagCheckInterval <= 0 {
+		addProblem("DB_REPLICA_LAG_CHECK_INTERVAL_IN_SECONDS must be positive")
+	}
+
+	for _, fullnodeUrl := range []struct{ name, value string }{{"FULLNODE_URL", config.Fullnode.Url}, {"FULLNODE_BACKUP_URL", config.Fullnode.BackupUrl}} {
+		if fullnodeUrl.value != "" && !isHttpUrl(fullnodeUrl.value) {
+			addProblem("%s must be an http or https url, got %s", fullnodeUrl.name, redactUrlPassword(fullnodeUrl.value))
+		}
+	}
+
+	switch config.Sync.ConfirmationPolicy {
+	case "dspConsensus", "trustChainConsensus", "dspAndTrustChainConsensus":
+	case "minTrustChainTrustScore":
+		if _, err := decimal.NewFromString(config.Sync.MinTrustChainTrustScore); err != nil {
+			addProblem("MIN_TRUST_CHAIN_TRUST_SCORE must be a number for the minTrustChainTrustScore confirmation policy, got %q", config.Sync.MinTrustChainTrustScore)
+		}
+	default:
+		addProblem("CONFIRMATION_POLICY must be one of dspConsensus, trustChainConsensus, dspAndTrustChainConsensus and minTrustChainTrustScore, got %s", config.Sync.ConfirmationPolicy)
+	}
+	if config.Sync.ReversalMonitorWindow < 0 {
+		addProblem("REVERSAL_MONITOR_WINDOW_IN_HOURS can't be negative")
+	}
+
+	if !isOneOf(config.Archive.Target, "table", "csv") {
+		addProblem("ARCHIVE_TARGET must be table or csv, got %s", config.Archive.Target)
+	}
+	if config.Archive.Retention < 0 {
+		addProblem("ARCHIVE_RETENTION_IN_DAYS can't be negative")
+	}
+	if config.Archive.Retention > 0 && config.Archive.Retention <= config.Sync.ReversalMonitorWindow {
+		addProblem("ARCHIVE_RETENTION_IN_DAYS must be longer than REVERSAL_MONITOR_WINDOW_IN_HOURS, transactions that can still be reversed can't be archived")
+	}
+	if config.Archive.BatchSize <= 0 {
+		addProblem("ARCHIVE_BATCH_SIZE must be positive")
+	}
+	if config.Archive.Interval <= 0 {
+		addProblem("ARCHIVE_INTERVAL_IN_SECONDS must be positive")
+	}
+
+	if !isOneOf(config.Partition.By, "", "index", "attachmentTime") {
+		addProblem("PARTITION_BY must be index or attachmentTime, got %s", config.Partition.By)
+	}
+	if config.Partition.By != "" && config.Partition.Size <= 0 {
+		addProblem("PARTITION_SIZE must be positive when PARTITION_BY is set")
+	}
+
+	if config.Health.MissedIntervals <= 0 {
+		addProblem("HEALTH_MISSED_INTERVALS must be positive")
+	}
+	if config.Health.ReadinessMaxSyncLag < 0 {
+		addProblem("READINESS_MAX_SYNC_LAG can't be negative")
+	}
+	if config.Health.ReadinessTimeout <= 0 {
+		addProblem("READINESS_TIMEOUT_IN_SECONDS must be positive")
+	}
+
+	if config.Leader.RenewInterval <= 0 {
+		addProblem("LEADER_RENEW_INTERVAL_IN_SECONDS must be positive")
+	}
+	if config.Leader.LeaseDuration <= config.Leader.RenewInterval {
+		addProblem("LEADER_LEASE_DURATION_IN_SECONDS must be longer than LEADER_RENEW_INTERVAL_IN_SECONDS, the lease would expire between renewals")
+	}
+
+	if !isOneOf(config.Api.AnonymousScope, "public", "read") {
+		addProblem("API_ANONYMOUS_SCOPE must be public or read, got %s", config.Api.AnonymousScope)
+	}
+	if config.Api.KeyCacheTtl <= 0 {
+		addProblem("API_KEY_CACHE_TTL_IN_SECONDS must be positive")
+	}
+	if config.Api.IpRateLimit < 0 || config.Api.KeyRateLimit < 0 {
+		addProblem("RATE_LIMIT_IP_PER_SECOND and RATE_LIMIT_KEY_PER_SECOND can't be negative")
+	}
+	if config.Api.IpRateLimitBurst < 1 || config.Api.KeyRateLimitBurst < 1 {
+		addProblem("RATE_LIMIT_IP_BURST and RATE_LIMIT_KEY_BURST must be at least 1")
+	}
+
+	if !isOneOf(config.Tracing.Exporter, "none", "stdout", "otlp") {
+		addProblem("TRACING_EXPORTER must be one of none, stdout and otlp, got %s", config.Tracing.Exporter)
+	}
+	if !isHttpUrl(config.Tracing.OtlpEndpoint) {
+		addProblem("TRACING_OTLP_ENDPOINT must be an http or https url, got %s", redactUrlPassword(config.Tracing.OtlpEndpoint))
+	}
+	if _, err := config.Tracing.ParseOtlpHeaders(); err != nil {
+		addProblem("TRACING_OTLP_HEADERS %v", err)
+	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		addProblem("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", config.Tracing.SampleRatio)
+	}
+	return problems
+}
+
+// ValidateSync checks that the settings the sync needs are set
+func (config *Config) ValidateSync() error {
+	var problems []string
+	for _, s := range config.settings() {
+		if _, isSet := config.sources[s.env]; s.requiredBySync && !isSet {
+			problems = append(problems, fmt.Sprintf("%s is required to run the sync", s.env))
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	checks := []struct {
+		isValid bool
+		problem string
+	}{
+		{config.Sync.MaxTransactionsInSyncIteration > 0, "MAX_TRANSACTION_IN_SYNC_ITERATION must be positive"},
+		{config.Sync.SyncNewTransactionsInterval > 0, "SYNC_NEW_TRANSACTIONS_INTERVAL_IN_SECONDS must be positive"},
+		{config.Sync.MonitorTransactionsInterval > 0, "MONITOR_TRANSACTION_INTERVAL_IN_SECONDS must be positive"},
+		{config.Sync.UpdateBalancesInterval > 0, "UPDATE_BALANCES_INTERVAL_IN_SECONDS must be positive"},
+		{config.Sync.CleanUnindexedTransactionsInterval > 0, "CLEAN_UNINDEXED_TRANSACTIONS_INTERVAL_IN_SECONDS must be positive"},
+		{config.Sync.DeleteTxDelay >= 0, "DELETE_TX_DELAY_IN_HOURS can't be negative"},
+		{config.Sync.DeleteTxPendingMin >= 0, "DELETE_TX_PENDING_MIN_HOURS can't be negative"},
+	}
+	for _, check := range checks {
+		if !check.isValid {
+			problems = append(problems, check.problem)
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ParseOtlpHeaders reads the key=value pairs of OtlpHeaders
+func (tracing TracingConfig) ParseOtlpHeaders() (map[string]string, error) {
+	headers := map[string]string{}
+	if tracing.OtlpHeaders == "" {
+		return headers, nil
+	}
+	for _, pair := range strings.Split(tracing.OtlpHeaders, ",") {
+		keyValue := strings.SplitN(pair, "=", 2)
+		if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+			return nil, fmt.Errorf("must be key=value pairs separated by commas")
+		}
+		headers[strings.TrimSpace(keyValue[0])] = strings.TrimSpace(keyValue[1])
+	}
+	return headers, nil
+}
+
+func isOneOf(value string, values ...string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func isHttpUrl(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
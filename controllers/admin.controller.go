@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coti-io/coti-db-app/auth"
+	"github.com/coti-io/coti-db-app/dto"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/coti-io/coti-db-app/jobs"
+	"github.com/coti-io/coti-db-app/leader"
+	"github.com/coti-io/coti-db-app/logger"
+	rateLimit "github.com/coti-io/coti-db-app/rate-limit"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/gin-gonic/gin"
+)
+
+const maxAdminListLimit = 100
+
+// maxAuditErrorLength is the size of the error column of the audit log
+const maxAuditErrorLength = 1000
+
+var errJobNotFound = errors.New("the job doesn't run in this process")
+
+// AdminController controls the jobs of the process it is served by and manages the api keys, every action is written to the audit log
+type AdminController struct {
+	transactionService service.TransactionService
+	repositories       repository.Repositories
+	// elector is nil when the process doesn't run the sync
+	elector leader.Elector
+}
+
+func NewAdminController(transactionService service.TransactionService, repositories repository.Repositories, elector leader.Elector) *AdminController {
+	return &AdminController{transactionService: transactionService, repositories: repositories, elector: elector}
+}
+
+// GetJobs Get the jobs of the process with their runtime settings
+func (controller *AdminController) GetJobs(c *gin.Context) {
+	response := dto.AdminJobsResponse{
+		IsLeader:           controller.elector != nil && controller.elector.IsLeader(),
+		CurrentFullnodeUrl: controller.transactionService.GetCurrentFullnodeUrl(),
+		Jobs:               []dto.AdminJobResponse{},
+	}
+	for _, job := range jobs.GetAll() {
+		response.Jobs = append(response.Jobs, toAdminJobResponse(job.GetStatus()))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// PauseJob Pause a job, it skips its iterations until it is resumed
+func (controller *AdminController) PauseJob(c *gin.Context) {
+	controller.setJobPaused(c, "pauseJob", true)
+}
+
+// ResumeJob Resume a paused job
+func (controller *AdminController) ResumeJob(c *gin.Context) {
+	controller.setJobPaused(c, "resumeJob", false)
+}
+
+func (controller *AdminController) setJobPaused(c *gin.Context, action string, isPaused bool) {
+	name := c.Param("name")
+	job, ok := jobs.Get(name)
+	if !ok {
+		controller.writeError(c, action, name, nil, errJobNotFound)
+		return
+	}
+	job.SetPaused(isPaused)
+	controller.audit(c, action, name, nil, nil)
+	c.JSON(http.StatusOK, gin.H{"data": toAdminJobResponse(job.GetStatus())})
+}
+
+// UpdateJobSettings Change the interval and the batch size of a job, they take effect from its next iteration
+func (controller *AdminController) UpdateJobSettings(c *gin.Context) {
+	name := c.Param("name")
+	var request dto.AdminJobSettingsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		controller.writeError(c, "updateJobSettings", name, nil, fmt.Errorf("%w: %v", service.ErrInvalidAdminRequest, err))
+		return
+	}
+	job, ok := jobs.Get(name)
+	if !ok {
+		controller.writeError(c, "updateJobSettings", name, request, errJobNotFound)
+		return
+	}
+	if request.IntervalInSeconds != nil {
+		if err := job.SetInterval(time.Duration(*request.IntervalInSeconds * float64(time.Second))); err != nil {
+			controller.writeError(c, "updateJobSettings", name, request, fmt.Errorf("%w: %v", service.ErrInvalidAdminRequest, err))
+			return
+		}
+	}
+	if request.BatchSize != nil {
+		if err := job.SetBatchSize(*request.BatchSize); err != nil {
+			controller.writeError(c, "updateJobSettings", name, request, fmt.Errorf("%w: %v", service.ErrInvalidAdminRequest, err))
+			return
+		}
+	}
+	controller.audit(c, "updateJobSettings", name, request, nil)
+	c.JSON(http.StatusOK, gin.H{"data": toAdminJobResponse(job.GetStatus())})
+}
+
+// RunJob Run one iteration of the monitorTransactions or the cleanUnindexedTransaction job now, the process must be the leader
+func (controller *AdminController) RunJob(c *gin.Context) {
+	name := c.Param("name")
+	err := controller.transactionService.RunJobIteration(name)
+	if err != nil {
+		controller.writeError(c, "runJob", name, nil, err)
+		return
+	}
+	controller.audit(c, "runJob", name, nil, nil)
+	job, _ := jobs.Get(name)
+	c.JSON(http.StatusOK, gin.H{"data": toAdminIterationResultResponse(job.GetResults(1)[0])})
+}
+
+// GetJobIterations Get the last iteration results of a job, the limit query param is how many
+func (controller *AdminController) GetJobIterations(c *gin.Context) {
+	limit, ok := getAdminListLimit(c)
+	if !ok {
+		return
+	}
+	job, ok := jobs.Get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": errJobNotFound.Error()})
+		return
+	}
+	results := job.GetResults(limit)
+	response := make([]dto.AdminIterationResultResponse, 0, len(results))
+	for _, result := range results {
+		response = append(response, toAdminIterationResultResponse(result))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// SwitchFullnode Make the jobs use the main or the backup fullnode
+func (controller *AdminController) SwitchFullnode(c *gin.Context) {
+	var request dto.AdminSwitchFullnodeRequest
+	// the body is optional
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			controller.writeError(c, "switchFullnode", "", nil, fmt.Errorf("%w: %v", service.ErrInvalidAdminRequest, err))
+			return
+		}
+	}
+	fullnodeUrl, err := controller.transactionService.SwitchFullnode(request.Node)
+	if err != nil {
+		controller.writeError(c, "switchFullnode", request.Node, request, err)
+		return
+	}
+	controller.audit(c, "switchFullnode", request.Node, request, nil)
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"currentFullnodeUrl": fullnodeUrl}})
+}
+
+// SetLastMonitoredTransactionIndex Set the index the sync continues from, syncNewTransactions must be paused
+// and moving the index forward must be forced
+func (controller *AdminController) SetLastMonitoredTransactionIndex(c *gin.Context) {
+	target := string(entities.LastMonitoredTransactionIndex)
+	var request dto.AdminSetLastMonitoredIndexRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Index == nil {
+		controller.writeError(c, "setLastMonitoredTransactionIndex", target, request, fmt.Errorf("%w: index is mandatory", service.ErrInvalidAdminRequest))
+		return
+	}
+	previousIndex, err := controller.transactionService.SetLastMonitoredIndex(c.Request.Context(), *request.Index, request.Force)
+	if err != nil {
+		controller.writeError(c, "setLastMonitoredTransactionIndex", target, request, err)
+		return
+	}
+	controller.audit(c, "setLastMonitoredTransactionIndex", target, request, nil)
+	c.JSON(http.StatusOK, gin.H{"data": dto.AdminSetLastMonitoredIndexResponse{PreviousIndex: previousIndex, Index: *request.Index}})
+}
+
+// GetAuditLogs Get the last admin actions, the limit query param is how many
+func (controller *AdminController) GetAuditLogs(c *gin.Context) {
+	limit, ok := getAdminListLimit(c)
+	if !ok {
+		return
+	}
+	auditLogs, err := controller.repositories.WithContext(c.Request.Context()).AdminAuditLogs().FindLatest(limit)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": auditLogs})
+}
+
+// GetApiKeys Get the api keys without the keys themselves
+func (controller *AdminController) GetApiKeys(c *gin.Context) {
+	apiKeys, err := controller.repositories.WithContext(c.Request.Context()).ApiKeys().FindAll()
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response := make([]dto.AdminApiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		response = append(response, toAdminApiKeyResponse(apiKey))
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// CreateApiKey Create an api key, the key is only in this response
+func (controller *AdminController) CreateApiKey(c *gin.Context) {
+	var request dto.AdminCreateApiKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		controller.writeError(c, "createApiKey", "", nil, fmt.Errorf("%w: %v", service.ErrInvalidAdminRequest, err))
+		return
+	}
+	limit := rateLimit.Limit{Rate: request.RateLimit, Burst: request.RateLimitBurst}
+	key, apiKey, err := auth.CreateApiKey(controller.repositories.WithContext(c.Request.Context()), request.Name, entities.ApiKeyScope(request.Scope), limit)
+	if err != nil {
+		controller.writeError(c, "createApiKey", request.Name, request, err)
+		return
+	}
+	controller.audit(c, "createApiKey", request.Name, request, nil)
+	c.JSON(http.StatusOK, gin.H{"data": dto.AdminCreateApiKeyResponse{AdminApiKeyResponse: toAdminApiKeyResponse(*apiKey), Key: key}})
+}
+
+// RevokeApiKey Revoke an api key, the other processes refuse it once their cache of the keys expires
+func (controller *AdminController) RevokeApiKey(c *gin.Context) {
+	name := c.Param("name")
+	err := auth.RevokeApiKey(controller.repositories.WithContext(c.Request.Context()), name)
+	if err != nil {
+		controller.writeError(c, "revokeApiKey", name, nil, err)
+		return
+	}
+	controller.audit(c, "revokeApiKey", name, nil, nil)
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"name": name, "isRevoked": true}})
+}
+
+// writeError audits the failed action and responds with the status of the error
+func (controller *AdminController) writeError(c *gin.Context, action string, target string, params interface{}, err error) {
+	controller.audit(c, action, target, params, err)
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInvalidAdminRequest), errors.Is(err, auth.ErrInvalidApiKeySettings):
+		status = http.StatusBadRequest
+	case errors.Is(err, errJobNotFound), errors.Is(err, repository.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, leader.ErrNotLeader), errors.Is(err, service.ErrSyncNotRunning):
+		status = http.StatusConflict
+	default:
+		_ = c.Error(err)
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// audit writes the action to the audit log, a failure to write it is only logged since the action already happened
+func (controller *AdminController) audit(c *gin.Context, action string, target string, params interface{}, err error) {
+	paramsJson, marshalErr := json.Marshal(params)
+	if marshalErr != nil {
+		paramsJson = []byte("null")
+	}
+	auditLog := &entities.AdminAuditLog{
+		Action:    action,
+		Target:    target,
+		Actor:     auth.GetActor(c),
+		Params:    string(paramsJson),
+		IsSuccess: err == nil,
+	}
+	if err != nil {
+		auditLog.Error = err.Error()
+		if len(auditLog.Error) > maxAuditErrorLength {
+			auditLog.Error = auditLog.Error[:maxAuditErrorLength]
+		}
+	}
+	if auditErr := controller.repositories.WithContext(c.Request.Context()).AdminAuditLogs().Create(auditLog); auditErr != nil {
+		_ = c.Error(auditErr)
+		logger.WithJob("admin").WithError(auditErr).WithField("action", action).Error("failed to write the audit log")
+	}
+}
+
+func getAdminListLimit(c *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > maxAdminListLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number between 1 and " + strconv.Itoa(maxAdminListLimit)})
+		return 0, false
+	}
+	return limit, true
+}
+
+func toAdminJobResponse(status jobs.Status) dto.AdminJobResponse {
+	return dto.AdminJobResponse{
+		Name:              status.Name,
+		IsPaused:          status.IsPaused,
+		IntervalInSeconds: status.Interval.Seconds(),
+		BatchSize:         status.BatchSize,
+	}
+}
+
+func toAdminApiKeyResponse(apiKey entities.ApiKey) dto.AdminApiKeyResponse {
+	return dto.AdminApiKeyResponse{
+		Name:           apiKey.Name,
+		Prefix:         apiKey.Prefix,
+		Scope:          string(apiKey.Scope),
+		RateLimit:      apiKey.RateLimit,
+		RateLimitBurst: apiKey.RateLimitBurst,
+		IsRevoked:      apiKey.IsRevoked,
+		CreateTime:     apiKey.CreateTime.UTC(),
+	}
+}
+
+func toAdminIterationResultResponse(result jobs.IterationResult) dto.AdminIterationResultResponse {
+	return dto.AdminIterationResultResponse{
+		Iteration:    result.Iteration,
+		Trigger:      result.Trigger,
+		StartTime:    result.StartTime.UTC(),
+		DurationInMs: result.Duration.Milliseconds(),
+		Error:        result.Error,
+	}
+}
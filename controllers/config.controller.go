@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/dto"
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigController struct {
+}
+
+func NewConfigController() *ConfigController {
+	return &ConfigController{}
+}
+
+// GetEffectiveConfig Get the settings the app runs with and where each was read from, the secrets are redacted
+func (controller *ConfigController) GetEffectiveConfig(c *gin.Context) {
+	settings := config.Get().Effective()
+	response := make([]dto.ConfigSettingResponse, 0, len(settings))
+	for _, setting := range settings {
+		response = append(response, dto.ConfigSettingResponse{
+			Name:   setting.Name,
+			Key:    setting.Key,
+			Value:  setting.Value,
+			Source: string(setting.Source),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
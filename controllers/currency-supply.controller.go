@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CurrencySupplyController struct {
+	currencySupplyService service.CurrencySupplyService
+}
+
+func NewCurrencySupplyController(currencySupplyService service.CurrencySupplyService) *CurrencySupplyController {
+	return &CurrencySupplyController{currencySupplyService: currencySupplyService}
+}
+
+// GetCurrencySupplies Get the supply of all currencies
+func (controller *CurrencySupplyController) GetCurrencySupplies(c *gin.Context) {
+	supplies, err := controller.currencySupplyService.GetCurrencySupplies()
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": supplies})
+}
+
+// GetCurrencySupply Get the supply of one currency by its hash
+func (controller *CurrencySupplyController) GetCurrencySupply(c *gin.Context) {
+	supply, err := controller.currencySupplyService.GetCurrencySupply(c.Param("currencyHash"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "currency supply not found"})
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": supply})
+}
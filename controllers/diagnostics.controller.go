@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+
+	dbprovider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/coti-io/coti-db-app/dto"
+	"github.com/gin-gonic/gin"
+)
+
+type DiagnosticsController struct {
+}
+
+func NewDiagnosticsController() *DiagnosticsController {
+	return &DiagnosticsController{}
+}
+
+// GetDbPoolStats Get the connection pool usage of the primary db and the read replicas
+func (controller *DiagnosticsController) GetDbPoolStats(c *gin.Context) {
+	poolStats, err := dbprovider.GetPoolStats()
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response := make([]dto.DbPoolStatsResponse, 0, len(poolStats))
+	for _, pool := range poolStats {
+		response = append(response, dto.DbPoolStatsResponse{
+			Name:               pool.Name,
+			IsUsable:           pool.IsUsable,
+			MaxOpenConnections: pool.Stats.MaxOpenConnections,
+			OpenConnections:    pool.Stats.OpenConnections,
+			InUse:              pool.Stats.InUse,
+			Idle:               pool.Stats.Idle,
+			WaitCount:          pool.Stats.WaitCount,
+			WaitDurationInMs:   pool.Stats.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      pool.Stats.MaxIdleClosed,
+			MaxIdleTimeClosed:  pool.Stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  pool.Stats.MaxLifetimeClosed,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	dbprovider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/coti-io/coti-db-app/dto"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/coti-io/coti-db-app/health"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	service "github.com/coti-io/coti-db-app/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	healthStatusOk   = "ok"
+	healthStatusFail = "fail"
+)
+
+type HealthController struct {
+	transactionService service.TransactionService
+	appStateRepository repository.AppStateRepository
+	// maxSyncLag is how many indexes the sync may be behind the fullnode tip and still be ready
+	maxSyncLag       int64
+	readinessTimeout time.Duration
+}
+
+// NewHealthController reads the readiness settings from READINESS_MAX_SYNC_LAG and READINESS_TIMEOUT_IN_SECONDS
+func NewHealthController(transactionService service.TransactionService, appStateRepository repository.AppStateRepository) *HealthController {
+	healthConfig := config.Get().Health
+	return &HealthController{
+		transactionService: transactionService,
+		appStateRepository: appStateRepository,
+		maxSyncLag:         healthConfig.ReadinessMaxSyncLag,
+		readinessTimeout:   healthConfig.ReadinessTimeout,
+	}
+}
+
+// GetLiveness fails when a background job stopped beating
+func (controller *HealthController) GetLiveness(c *gin.Context) {
+	writeHealthResponse(c, health.Liveness())
+}
+
+// GetReadiness fails when the db or both fullnodes can't be reached, the cluster stamp is not loaded or the sync is behind
+func (controller *HealthController) GetReadiness(c *gin.Context) {
+	results := health.RunChecks([]health.Check{
+		{Name: "db", Run: controller.checkDb},
+		{Name: "fullnode", Run: controller.checkFullnodes},
+		{Name: "clusterStamp", Run: controller.checkClusterStamp},
+		{Name: "syncLag", Run: controller.checkSyncLag},
+	}, controller.readinessTimeout)
+	writeHealthResponse(c, results)
+}
+
+func (controller *HealthController) checkDb(ctx context.Context) (map[string]interface{}, error) {
+	return nil, dbprovider.Ping(ctx)
+}
+
+func (controller *HealthController) checkFullnodes(ctx context.Context) (map[string]interface{}, error) {
+	nodeErrors := controller.transactionService.PingFullnodes(ctx)
+	details := make(map[string]interface{}, len(nodeErrors))
+	isReachable := false
+	for node, err := range nodeErrors {
+		if err != nil {
+			details[node] = err.Error()
+			continue
+		}
+		details[node] = healthStatusOk
+		isReachable = true
+	}
+	if !isReachable {
+		return details, errors.New("no fullnode is reachable")
+	}
+	return details, nil
+}
+
+func (controller *HealthController) checkClusterStamp(ctx context.Context) (map[string]interface{}, error) {
+	appState, err := controller.appStateRepository.GetByName(entities.IsClusterStampInitialized)
+	if err != nil {
+		return nil, err
+	}
+	if appState.Value != "true" {
+		return nil, errors.New("the cluster stamp is not initialized")
+	}
+	return nil, nil
+}
+
+func (controller *HealthController) checkSyncLag(ctx context.Context) (map[string]interface{}, error) {
+	appState, err := controller.appStateRepository.GetByName(entities.LastMonitoredTransactionIndex)
+	if err != nil {
+		return nil, err
+	}
+	lastMonitoredIndex := int64(-1)
+	if appState.Value != "" {
+		lastMonitoredIndex, err = strconv.ParseInt(appState.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid last monitored transaction index %s: %w", appState.Value, err)
+		}
+	}
+	// the sync sets the last iteration index, a process that doesn't sync only knows the last index of the fullnodes
+	fullnodeIndex := controller.transactionService.GetLastIteration()
+	syncHistory := controller.transactionService.GetSyncHistory()
+	for _, nodeIndex := range []int64{syncHistory.LastIndexMainNode, syncHistory.LastIndexBackupNode} {
+		if nodeIndex > fullnodeIndex {
+			fullnodeIndex = nodeIndex
+		}
+	}
+	details := map[string]interface{}{
+		"lastMonitoredTransactionIndex": lastMonitoredIndex,
+		"fullnodeLastIndex":             fullnodeIndex,
+		"maxLag":                        controller.maxSyncLag,
+	}
+	if fullnodeIndex == 0 {
+		return details, errors.New("the fullnode last index is not known yet")
+	}
+	lag := fullnodeIndex - lastMonitoredIndex
+	details["lag"] = lag
+	if lag > controller.maxSyncLag {
+		return details, fmt.Errorf("the sync is %d transactions behind", lag)
+	}
+	return details, nil
+}
+
+// writeHealthResponse responds 200 when every check passed and 503 otherwise
+func writeHealthResponse(c *gin.Context, results []health.CheckResult) {
+	response := dto.HealthResponse{Status: healthStatusOk, Checks: make(map[string]dto.HealthCheckResponse, len(results))}
+	for _, result := range results {
+		check := dto.HealthCheckResponse{Status: healthStatusOk, DurationInMs: result.Duration.Milliseconds(), Details: result.Details}
+		if result.Error != nil {
+			check.Status = healthStatusFail
+			check.Error = result.Error.Error()
+			response.Status = healthStatusFail
+		}
+		response.Checks[result.Name] = check
+	}
+	status := http.StatusOK
+	if response.Status != healthStatusOk {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
@@ -7,30 +7,82 @@ import (
 
 	"github.com/coti-io/coti-db-app/dto"
 	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
 	service "github.com/coti-io/coti-db-app/services"
 
-	dbprovider "github.com/coti-io/coti-db-app/db-provider"
-
 	"github.com/gin-gonic/gin"
 )
 
-// GetSyncState Get all books
-func GetSyncState(c *gin.Context) {
-	transactionService := service.NewTransactionService()
+type StateController struct {
+	transactionService service.TransactionService
+	appStateRepository repository.AppStateRepository
+}
+
+func NewStateController(transactionService service.TransactionService, appStateRepository repository.AppStateRepository) *StateController {
+	return &StateController{transactionService: transactionService, appStateRepository: appStateRepository}
+}
+
+// GetSyncState Get the sync state, the fields of /v1/sync-state are not added to it
+func (controller *StateController) GetSyncState(c *gin.Context) {
 	// check both nodes for last index
-	syncHistory := transactionService.GetSyncHistory()
+	syncHistory := controller.transactionService.GetSyncHistory()
 
 	nodeLastIndex := int64(math.Max(float64(syncHistory.LastIndexMainNode), float64(syncHistory.LastIndexBackupNode)))
-	syncIterationLastTransactionIndex := transactionService.GetLastIteration()
-	var appState entities.AppState
-	dbprovider.DB.Where("name = ?", entities.LastMonitoredTransactionIndex).First(&appState)
-	var lastMonitoredIndex int64
-	lastMonitoredIndexInt, err := strconv.Atoi(appState.Value)
+	syncIterationLastTransactionIndex := controller.transactionService.GetLastIteration()
+	appState, err := controller.appStateRepository.GetByName(entities.LastMonitoredTransactionIndex)
 	if err != nil {
-		panic(err)
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// the index is empty until the first sync iteration
+	var lastMonitoredIndex int64
+	if appState.Value != "" {
+		lastMonitoredIndex, err = strconv.ParseInt(appState.Value, 10, 64)
+		if err != nil {
+			_ = c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid last monitored transaction index " + appState.Value})
+			return
+		}
+	}
+	// a process that doesn't sync has no iteration index, the percentage is against the fullnodes then
+	percentageLastIndex := syncIterationLastTransactionIndex
+	if percentageLastIndex <= 0 {
+		percentageLastIndex = nodeLastIndex
 	}
-	lastMonitoredIndex = int64(lastMonitoredIndexInt)
-	syncPercentage := (float64(lastMonitoredIndex) / float64(syncIterationLastTransactionIndex)) * 100
+	syncPercentage := service.GetSyncPercentage(lastMonitoredIndex, percentageLastIndex)
 
 	c.JSON(http.StatusOK, gin.H{"data": dto.SyncResponse{NodeMaxIndex: nodeLastIndex, NodeLastIndex: syncHistory.LastIndexMainNode, BackupNodeLastIndex: syncHistory.LastIndexBackupNode, SyncIterationLastTransactionIndex: syncIterationLastTransactionIndex, LastMonitoredTransactionIndex: lastMonitoredIndex, SyncPercentage: syncPercentage, IsNodeSynced: syncHistory.IsSynced}})
 }
+
+// GetSyncStateV1 Get the sync state with the rolling rates, the eta to catch up with the fullnodes and the backlogs of the jobs
+func (controller *StateController) GetSyncStateV1(c *gin.Context) {
+	progress, ok := controller.transactionService.GetSyncProgress()
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "the sync state is not measured yet"})
+		return
+	}
+	response := dto.SyncStateV1Response{
+		LastMonitoredTransactionIndex: progress.LastMonitoredIndex,
+		NodeLastIndex:                 progress.NodeLastIndex,
+		MainNodeLastIndex:             progress.MainNodeLastIndex,
+		BackupNodeLastIndex:           progress.BackupNodeLastIndex,
+		RemainingTransactions:         progress.GetRemaining(),
+		SyncPercentage:                progress.GetSyncPercentage(),
+		IsSynced:                      progress.IsSynced,
+		TransactionsPerSecond:         progress.SyncRate,
+		NodeTransactionsPerSecond:     progress.NodeRate,
+		RateWindowInSeconds:           service.SyncRateWindow.Seconds(),
+		Backlogs: dto.SyncBacklogsResponse{
+			BalanceProcessing: progress.Backlogs.BalanceProcessing,
+			Monitor:           progress.Backlogs.Monitor,
+			PendingUnindexed:  progress.Backlogs.PendingUnindexed,
+		},
+		UpdateTime: progress.UpdateTime.UTC(),
+	}
+	if progress.Eta != nil {
+		etaInSeconds := progress.Eta.Seconds()
+		response.EtaInSeconds = &etaInSeconds
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	service "github.com/coti-io/coti-db-app/services"
+	"github.com/gin-gonic/gin"
+)
+
+const maxTransactionReversalsLimit = 1000
+
+type TransactionReversalController struct {
+	transactionReversalService service.TransactionReversalService
+}
+
+func NewTransactionReversalController(transactionReversalService service.TransactionReversalService) *TransactionReversalController {
+	return &TransactionReversalController{transactionReversalService: transactionReversalService}
+}
+
+// GetTransactionReversals Get the reversal events after the fromId query param
+func (controller *TransactionReversalController) GetTransactionReversals(c *gin.Context) {
+	fromId, err := strconv.ParseInt(c.DefaultQuery("fromId", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fromId must be a number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > maxTransactionReversalsLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number between 1 and " + strconv.Itoa(maxTransactionReversalsLimit)})
+		return
+	}
+	reversals, err := controller.transactionReversalService.GetTransactionReversals(int32(fromId), limit)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": reversals})
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/coti-io/coti-db-app/archive"
+	"github.com/coti-io/coti-db-app/dto"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+const maxSkippedTransactionsLimit = 1000
+
+type TransactionController struct {
+	transactionRepository repository.TransactionRepository
+	archiveService        archive.ArchiveService
+}
+
+func NewTransactionController(transactionRepository repository.TransactionRepository, archiveService archive.ArchiveService) *TransactionController {
+	return &TransactionController{transactionRepository: transactionRepository, archiveService: archiveService}
+}
+
+// GetTransaction Get a transaction by its hash, an archived transaction has isArchived set with the archive it was moved to
+// and its data is only returned when it was archived to a table
+func (controller *TransactionController) GetTransaction(c *gin.Context) {
+	hash := c.Param("hash")
+	txs, err := controller.transactionRepository.FindByHashes([]string{hash})
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(txs) > 0 {
+		c.JSON(http.StatusOK, gin.H{"data": txs[0], "isArchived": false})
+		return
+	}
+	batch, tx, err := controller.archiveService.GetArchivedTransaction(hash)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tx, "isArchived": true, "archive": dto.ArchiveResponse{
+		BatchId:     batch.ID,
+		Target:      string(batch.Target),
+		Location:    batch.Location,
+		ArchiveTime: batch.CreateTime,
+	}})
+}
+
+// GetSkippedTransactions Get the invalid transactions whose balances were not applied after the fromId query param
+func (controller *TransactionController) GetSkippedTransactions(c *gin.Context) {
+	fromId, err := strconv.ParseInt(c.DefaultQuery("fromId", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fromId must be a number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > maxSkippedTransactionsLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number between 1 and " + strconv.Itoa(maxSkippedTransactionsLimit)})
+		return
+	}
+	txs, err := controller.transactionRepository.FindSkipped(int32(fromId), limit)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": txs})
+}
@@ -0,0 +1,45 @@
+package dbProvider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+)
+
+// mysqlCaTlsConfigName is the name the tls config of DB_TLS_CA_FILE is registered with in the mysql driver
+const mysqlCaTlsConfigName = "coti-db-app-ca"
+
+// Config is the connection, pool and startup settings of the db
+type Config = config.DbConfig
+
+const maxConnectRetryBackoff = 30 * time.Second
+
+// applyPool sets the pool settings on the connections of a db handle
+func applyPool(sqlDB *sql.DB, dbConfig Config) {
+	sqlDB.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(dbConfig.ConnMaxIdleTime)
+}
+
+// registerMysqlCaTlsConfig lets the mysql driver verify the server certificate with the CA of DB_TLS_CA_FILE
+func registerMysqlCaTlsConfig(dbConfig Config) error {
+	caPem, err := ioutil.ReadFile(dbConfig.TLSCaFile)
+	if err != nil {
+		return err
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caPem) {
+		return fmt.Errorf("no certificates found in DB_TLS_CA_FILE %s", dbConfig.TLSCaFile)
+	}
+	return mysqlDriver.RegisterTLSConfig(mysqlCaTlsConfigName, &tls.Config{
+		RootCAs:    rootCAs,
+		ServerName: dbConfig.Host,
+	})
+}
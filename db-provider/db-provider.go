@@ -1,88 +1,171 @@
 package dbProvider
 
 import (
-	"github.com/coti-io/coti-db-app/entities"
+	"fmt"
 	"gorm.io/gorm/logger"
-	"os"
 	"time"
 
-	"gorm.io/driver/mysql"
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/tracing"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// currentConfig is the config DB was opened with, set by Init
+var currentConfig Config
+
+// Init opens DB and ReadDB, the migrations are run first when MIGRATE_DB is set
 func Init() {
-	dbName := os.Getenv("DB_NAME")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbPort := os.Getenv("DB_PORT")
-	dbHost := os.Getenv("DB_HOST")
-	if os.Getenv("MIGRATE_DB") == "true" {
-		migrateDb(dbName, dbUser, dbHost, dbPort, dbPassword)
-	}
-
-	dbConnectionString := dbUser + `:` + dbPassword + `@tcp(` + dbHost + `:` + dbPort + `)/` + dbName + `?charset=utf8&parseTime=True&loc=Local`
-	db, dbError := gorm.Open(mysql.New(mysql.Config{
-		DSN:                       dbConnectionString, // data source name
-		DefaultStringSize:         256,                // default size for string fields
-		DisableDatetimePrecision:  true,               // disable datetime precision, which not supported before MySQL 5.6
-		DontSupportRenameIndex:    true,               // drop & create when rename index, rename index not supported before MySQL 5.7, MariaDB
-		DontSupportRenameColumn:   true,               // `change` when rename column, rename column not supported before MySQL 8, MariaDB
-		SkipInitializeWithVersion: false,              // auto configure based on currently MySQL version
-	}), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Error),
-		NowFunc: func() time.Time {
-			return time.Now().UTC()
-		},
-	})
+	initDb(config.Get().Db.Migrate)
+}
 
-	if dbError != nil {
-		panic("failed to connect database")
+// InitWithoutMigrations opens DB and ReadDB for a process that doesn't own the schema, like an api replica next to the sync
+func InitWithoutMigrations() {
+	initDb(false)
+}
+
+func initDb(migrate bool) {
+	dbDialect, dbConfig, err := loadConfig()
+	if err != nil {
+		panic(err)
+	}
+	dialect = dbDialect
+	currentConfig = dbConfig
+	// an in-memory sqlite db is dropped with its last connection so it is opened before the migrations run
+	if isMemoryDb(dialect, currentConfig.Name) {
+		DB = openDb(currentConfig)
+	}
+	if migrate {
+		err := MigrateUp()
+		if err != nil {
+			panic(err)
+		}
+	}
+	if DB == nil {
+		DB = openDb(currentConfig)
 	}
+	initReplicas()
+}
 
-	DB = db
+// loadConfig reads the dialect and the db config and registers the tls config they need
+func loadConfig() (Dialect, Config, error) {
+	dbConfig := config.Get().Db
+	dbDialect := GetDialect()
+	if dbDialect == MysqlDialect && dbConfig.TLSCaFile != "" {
+		err := registerMysqlCaTlsConfig(dbConfig)
+		if err != nil {
+			return "", Config{}, err
+		}
+	}
+	return dbDialect, dbConfig, nil
 }
 
-func migrateDb(dbName string, dbUser string, dbHost string, dbPort string, dbPassword string) {
-	dbConnectionWithNoDbString := dbUser + `:` + dbPassword + `@tcp(` + dbHost + `:` + dbPort + `)/`
-	db, dbError := gorm.Open(mysql.New(mysql.Config{
-		DSN: dbConnectionWithNoDbString, // data source name
-	}), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-		NowFunc: func() time.Time {
-			return time.Now().UTC()
-		},
+func openDb(dbConfig Config) *gorm.DB {
+	db, dbError := openWithRetries(dbConfig, func() (*gorm.DB, error) {
+		return gorm.Open(newDialector(dialect, dbConfig, dbConfig.Name, false), newGormConfig())
 	})
-
 	if dbError != nil {
-		panic("failed to connect database")
+		panic(fmt.Sprintf("failed to connect %s database %s on %s:%s: %v", dialect, dbConfig.Name, dbConfig.Host, dbConfig.Port, dbError))
+	}
+	// the read db is a session of the db so its queries are traced as well
+	err := db.Use(tracing.NewGormPlugin())
+	if err != nil {
+		panic(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+	applyPool(sqlDB, dbConfig)
+	if dialect == SqliteDialect {
+		// sqlite has a single writer, one connection serializes the writers instead of failing them with a locked db.
+		// The connection is never recycled since an in-memory db is dropped with it
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
 	}
-	db = db.Set("gorm:table_options", "CHARSET=utf8 auto_increment=1")
+	return db
+}
 
-	createDbRes := db.Exec("CREATE DATABASE IF NOT EXISTS " + dbName + " DEFAULT CHARACTER SET utf8 COLLATE utf8_unicode_ci ")
-	if createDbRes.Error != nil {
-		panic(createDbRes.Error)
+// openWithRetries retries opening the db while it is starting up, the backoff doubles after each failed attempt
+func openWithRetries(dbConfig Config, open func() (*gorm.DB, error)) (*gorm.DB, error) {
+	backoff := dbConfig.ConnectRetryBackoff
+	for attempt := 0; ; attempt++ {
+		db, err := open()
+		if err == nil || attempt >= dbConfig.ConnectRetries {
+			return db, err
+		}
+		logrus.WithError(err).WithFields(logrus.Fields{"attempt": attempt + 1, "attempts": dbConfig.ConnectRetries + 1, "backoff": backoff.String()}).Warn("db connection failed, retrying")
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxConnectRetryBackoff {
+			backoff = maxConnectRetryBackoff
+		}
 	}
+}
+
+// gormLogWriter writes the gorm logs through the app logger
+type gormLogWriter struct {
+	level logrus.Level
+}
 
-	setDbRes := db.Exec("USE " + dbName)
-	if setDbRes.Error != nil {
-		panic(setDbRes.Error)
+func (writer gormLogWriter) Printf(format string, args ...interface{}) {
+	logrus.WithField("component", "gorm").Logf(writer.level, format, args...)
+}
+
+// newGormLogger logs every query at queryLogLevel when that level is enabled, only the failed and slow queries as warnings otherwise
+func newGormLogger(queryLogLevel logrus.Level) logger.Interface {
+	writer, logLevel := gormLogWriter{level: logrus.WarnLevel}, logger.Warn
+	if logrus.IsLevelEnabled(queryLogLevel) {
+		writer, logLevel = gormLogWriter{level: queryLogLevel}, logger.Info
 	}
+	return logger.New(writer, logger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  logLevel,
+		IgnoreRecordNotFoundError: true,
+	})
+}
 
-	// Migrate the schema
-	db.AutoMigrate(&entities.AppState{}, &entities.Currency{}, &entities.Transaction{}, &entities.FullnodeFeeBaseTransaction{},
-		&entities.InputBaseTransaction{}, &entities.NetworkFeeBaseTransaction{}, &entities.ReceiverBaseTransaction{}, &entities.AddressBalance{},
-		&entities.CurrencyTypeData{}, &entities.OriginatorCurrencyData{}, &entities.TokenGenerationFeeBaseTransaction{}, &entities.TokenMintingFeeBaseTransaction{},
-		&entities.TokenMintingServiceData{}, &entities.TokenGenerationServiceData{}, &entities.EventInputBaseTransaction{}, &entities.AddressTransactionCount{},
-		&entities.TransactionAddress{}, &entities.Address{}, &entities.TransactionCurrency{},
-	)
-	sqlDB, err := db.DB()
+func newGormConfig() *gorm.Config {
+	return &gorm.Config{
+		Logger: newGormLogger(logrus.TraceLevel),
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+}
+
+// openMigrationDb connects to the db with multi statements enabled for the migration files, creating the db if it doesn't exist
+func openMigrationDb() (*gorm.DB, Dialect, error) {
+	dbDialect, dbConfig, err := loadConfig()
 	if err != nil {
-		panic(err)
+		return nil, "", err
+	}
+	db, dbError := openWithRetries(dbConfig, func() (*gorm.DB, error) {
+		return gorm.Open(newDialector(dbDialect, dbConfig, "", true), &gorm.Config{
+			Logger: newGormLogger(logrus.InfoLevel),
+		})
+	})
+	if dbError != nil {
+		return nil, "", dbError
+	}
+	err = createDbIfNotExists(db, dbDialect, dbConfig.Name)
+	closeDb(db)
+	if err != nil {
+		return nil, "", err
 	}
-	dbCloseError := sqlDB.Close()
-	if dbCloseError != nil {
-		panic(dbCloseError)
+
+	db, dbError = gorm.Open(newDialector(dbDialect, dbConfig, dbConfig.Name, true), &gorm.Config{
+		Logger: newGormLogger(logrus.InfoLevel),
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+	if dbError != nil {
+		return nil, "", dbError
 	}
+	return db, dbDialect, nil
 }
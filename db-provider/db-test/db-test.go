@@ -0,0 +1,107 @@
+// Package dbTest opens a fresh migrated db for each test so the same tests run on every dialect
+package dbTest
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/coti-io/coti-db-app/config"
+	dbProvider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// testDbName is the db the tests reset on the mysql and postgres servers
+const testDbName = "coti_db_app_test"
+
+// memoryDbCount names the in-memory sqlite dbs so every test starts empty
+var memoryDbCount int64
+
+type server struct {
+	envPrefix   string
+	defaultPort string
+	defaultUser string
+}
+
+var servers = map[dbProvider.Dialect]server{
+	dbProvider.MysqlDialect:    {envPrefix: "TEST_MYSQL_", defaultPort: "3306", defaultUser: "root"},
+	dbProvider.PostgresDialect: {envPrefix: "TEST_POSTGRES_", defaultPort: "5432", defaultUser: "postgres"},
+}
+
+// Dialects are the backends the tests run on, sqlite always and mysql and postgres when TEST_MYSQL_HOST and TEST_POSTGRES_HOST are set
+func Dialects() []dbProvider.Dialect {
+	dialects := []dbProvider.Dialect{dbProvider.SqliteDialect}
+	for _, dialect := range []dbProvider.Dialect{dbProvider.MysqlDialect, dbProvider.PostgresDialect} {
+		if os.Getenv(servers[dialect].envPrefix+"HOST") != "" {
+			dialects = append(dialects, dialect)
+		}
+	}
+	return dialects
+}
+
+// ForEachDialect runs test as a subtest on an empty db of every dialect of Dialects
+func ForEachDialect(t *testing.T, overrides map[string]string, test func(t *testing.T, db *gorm.DB)) {
+	for _, dialect := range Dialects() {
+		dialect := dialect
+		t.Run(string(dialect), func(t *testing.T) {
+			test(t, Open(t, dialect, overrides))
+		})
+	}
+}
+
+// Open loads the config with overrides and points dbProvider.DB at an empty migrated db of the dialect until the test ends.
+// sqlite gets a new in-memory db, mysql and postgres reset the coti_db_app_test db of the server set by TEST_<DIALECT>_HOST,
+// TEST_<DIALECT>_PORT, TEST_<DIALECT>_USER and TEST_<DIALECT>_PASSWORD. The test is skipped when the server is not set
+func Open(t testing.TB, dialect dbProvider.Dialect, overrides map[string]string) *gorm.DB {
+	t.Helper()
+	// the migrations log every statement at info
+	logrus.SetLevel(logrus.WarnLevel)
+	settings := map[string]string{"DB_DIALECT": string(dialect), "MIGRATE_DB": "true", "DB_CONNECT_RETRIES": "0"}
+	if dialect == dbProvider.SqliteDialect {
+		settings["DB_NAME"] = fmt.Sprintf("file:coti-db-app-test-%d?mode=memory&cache=shared", atomic.AddInt64(&memoryDbCount, 1))
+	} else {
+		s := servers[dialect]
+		host := os.Getenv(s.envPrefix + "HOST")
+		if host == "" {
+			t.Skipf("%sHOST is not set", s.envPrefix)
+		}
+		settings["DB_NAME"] = testDbName
+		settings["DB_HOST"] = host
+		settings["DB_PORT"] = getEnv(s.envPrefix+"PORT", s.defaultPort)
+		settings["DB_USER"] = getEnv(s.envPrefix+"USER", s.defaultUser)
+		settings["DB_PASSWORD"] = os.Getenv(s.envPrefix + "PASSWORD")
+	}
+	for name, value := range overrides {
+		settings[name] = value
+	}
+	if err := config.Init("", settings); err != nil {
+		t.Fatal(err)
+	}
+	if dialect != dbProvider.SqliteDialect {
+		// the db of the last run is migrated up first so every migration can be reverted
+		if err := dbProvider.MigrateUp(); err != nil {
+			t.Fatal(err)
+		}
+		if err := dbProvider.MigrateDown(1 << 16); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dbProvider.Init()
+	db := dbProvider.DB
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func getEnv(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return defaultValue
+}
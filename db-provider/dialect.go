@@ -0,0 +1,233 @@
+package dbProvider
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Dialect string
+
+const (
+	MysqlDialect    Dialect = "mysql"
+	PostgresDialect Dialect = "postgres"
+	// SqliteDialect is meant for embedded and test use, DB_NAME is the db file path or :memory:
+	SqliteDialect Dialect = "sqlite"
+)
+
+// memoryDbDsn is shared by all the connections of the process, a plain :memory: db would be private to one connection
+const memoryDbDsn = "file:coti-db-app?mode=memory&cache=shared"
+
+// dialect is the backend of DB, set by Init
+var dialect = MysqlDialect
+
+// GetDialect returns the backend set by DB_DIALECT
+func GetDialect() Dialect {
+	return Dialect(config.Get().Db.Dialect)
+}
+
+// newDialector creates the gorm dialector of the backend, an empty dbName connects to the server without selecting the app db
+func newDialector(dbDialect Dialect, dbConfig Config, dbName string, isMigration bool) gorm.Dialector {
+	switch dbDialect {
+	case PostgresDialect:
+		if dbName == "" {
+			dbName = "postgres"
+		}
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s TimeZone=UTC", quotePostgresDsnValue(dbConfig.Host), quotePostgresDsnValue(dbConfig.Port), quotePostgresDsnValue(dbConfig.User), quotePostgresDsnValue(dbConfig.Password), quotePostgresDsnValue(dbName))
+		dsn += getPostgresDsnOptions(dbConfig)
+		return postgres.New(postgres.Config{
+			DSN: dsn, // data source name
+		})
+	case SqliteDialect:
+		return sqlite.Open(getSqliteDsn(dbName))
+	default:
+		dsn := dbConfig.User + `:` + dbConfig.Password + `@tcp(` + dbConfig.Host + `:` + dbConfig.Port + `)/` + dbName
+		options := getMysqlDsnOptions(dbConfig)
+		if dbName != "" {
+			options = append([]string{`charset=utf8`, `parseTime=True`, `loc=Local`}, options...)
+		}
+		if isMigration && dbName != "" {
+			options = append(options, `multiStatements=true`)
+		}
+		if len(options) > 0 {
+			dsn += `?` + strings.Join(options, `&`)
+		}
+		if isMigration {
+			return mysql.New(mysql.Config{
+				DSN: dsn, // data source name
+			})
+		}
+		return newMysqlDialector(dsn)
+	}
+}
+
+func getMysqlDsnOptions(dbConfig Config) []string {
+	var options []string
+	if dbConfig.ConnectTimeout > 0 {
+		options = append(options, `timeout=`+dbConfig.ConnectTimeout.String())
+	}
+	if dbConfig.ReadTimeout > 0 {
+		options = append(options, `readTimeout=`+dbConfig.ReadTimeout.String())
+	}
+	if dbConfig.WriteTimeout > 0 {
+		options = append(options, `writeTimeout=`+dbConfig.WriteTimeout.String())
+	}
+	if dbConfig.TLSCaFile != "" {
+		options = append(options, `tls=`+mysqlCaTlsConfigName)
+	} else if dbConfig.TLS != "" {
+		options = append(options, `tls=`+dbConfig.TLS)
+	}
+	return options
+}
+
+// getPostgresDsnOptions maps the tls setting to the closest sslmode, a CA file always verifies the server
+func getPostgresDsnOptions(dbConfig Config) string {
+	var options string
+	if dbConfig.ConnectTimeout > 0 {
+		options += fmt.Sprintf(" connect_timeout=%d", int(math.Ceil(dbConfig.ConnectTimeout.Seconds())))
+	}
+	switch {
+	case dbConfig.TLSCaFile != "":
+		options += " sslmode=verify-full sslrootcert=" + quotePostgresDsnValue(dbConfig.TLSCaFile)
+	case dbConfig.TLS == "true":
+		options += " sslmode=verify-full"
+	case dbConfig.TLS == "skip-verify":
+		options += " sslmode=require"
+	case dbConfig.TLS == "preferred":
+		options += " sslmode=prefer"
+	case dbConfig.TLS == "false":
+		options += " sslmode=disable"
+	}
+	return options
+}
+
+// newReplicaDialector creates the gorm dialector of a read replica from its full dsn
+func newReplicaDialector(dbDialect Dialect, dsn string) gorm.Dialector {
+	switch dbDialect {
+	case PostgresDialect:
+		return postgres.New(postgres.Config{
+			DSN: dsn, // data source name
+		})
+	case SqliteDialect:
+		return sqlite.Open(getSqliteDsn(dsn))
+	default:
+		return newMysqlDialector(dsn)
+	}
+}
+
+func newMysqlDialector(dsn string) gorm.Dialector {
+	return mysql.New(mysql.Config{
+		DSN:                       dsn,   // data source name
+		DefaultStringSize:         256,   // default size for string fields
+		DisableDatetimePrecision:  true,  // disable datetime precision, which not supported before MySQL 5.6
+		DontSupportRenameIndex:    true,  // drop & create when rename index, rename index not supported before MySQL 5.7, MariaDB
+		DontSupportRenameColumn:   true,  // `change` when rename column, rename column not supported before MySQL 8, MariaDB
+		SkipInitializeWithVersion: false, // auto configure based on currently MySQL version
+	})
+}
+
+// quotePostgresDsnValue quotes a keyword/value dsn value so spaces, quotes and backslashes in it are kept
+func quotePostgresDsnValue(value string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + `'`
+}
+
+// getSqliteDsn waits for the write lock instead of failing with SQLITE_BUSY, file dbs use WAL so the api can read while the sync writes
+func getSqliteDsn(dbName string) string {
+	if dbName == "" || dbName == ":memory:" {
+		return memoryDbDsn + "&_pragma=busy_timeout(5000)"
+	}
+	separator := "?"
+	if strings.Contains(dbName, "?") {
+		separator = "&"
+	}
+	if isMemoryDb(SqliteDialect, dbName) {
+		return dbName + separator + "_pragma=busy_timeout(5000)"
+	}
+	return dbName + separator + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+// isMemoryDb checks if the sqlite db lives only as long as its connections, a file: uri with mode=memory names a shared in-memory db
+func isMemoryDb(dbDialect Dialect, dbName string) bool {
+	return dbDialect == SqliteDialect && (dbName == "" || dbName == ":memory:" || strings.Contains(dbName, "mode=memory"))
+}
+
+// createDbIfNotExists creates the app db, db is connected to the server without selecting the app db
+func createDbIfNotExists(db *gorm.DB, dbDialect Dialect, dbName string) error {
+	switch dbDialect {
+	case SqliteDialect:
+		// the sqlite db file is created when it is opened
+		return nil
+	case PostgresDialect:
+		var dbCount int64
+		err := db.Raw("SELECT COUNT(*) FROM pg_database WHERE datname = ?", dbName).Scan(&dbCount).Error
+		if err != nil || dbCount > 0 {
+			return err
+		}
+		return db.Exec("CREATE DATABASE " + db.Statement.Quote(dbName) + " ENCODING 'UTF8'").Error
+	default:
+		return db.Exec("CREATE DATABASE IF NOT EXISTS " + dbName + " DEFAULT CHARACTER SET utf8 COLLATE utf8_unicode_ci ").Error
+	}
+}
+
+// Ago is the db time the given duration ago, the db clock is used so app hosts with a skewed clock agree on it
+func Ago(duration time.Duration) clause.Expr {
+	seconds := int64(duration.Seconds())
+	switch dialect {
+	case PostgresDialect:
+		return gorm.Expr("NOW() - make_interval(secs => ?)", seconds)
+	case SqliteDialect:
+		return gorm.Expr("datetime('now', ?)", fmt.Sprintf("-%d seconds", seconds))
+	default:
+		return gorm.Expr("DATE_SUB(NOW(), INTERVAL ? SECOND)", seconds)
+	}
+}
+
+// Now is the db time, see Ago
+func Now() clause.Expr {
+	return Ago(0)
+}
+
+// FromNow is the db time after the given duration
+func FromNow(duration time.Duration) clause.Expr {
+	seconds := int64(duration.Seconds())
+	switch dialect {
+	case PostgresDialect:
+		return gorm.Expr("NOW() + make_interval(secs => ?)", seconds)
+	case SqliteDialect:
+		return gorm.Expr("datetime('now', ?)", fmt.Sprintf("+%d seconds", seconds))
+	default:
+		return gorm.Expr("DATE_ADD(NOW(), INTERVAL ? SECOND)", seconds)
+	}
+}
+
+// AddOnConflict is the upsert assignment adding the value of the inserted row to the value of the existing row
+func AddOnConflict(table string, column string) clause.Expr {
+	switch dialect {
+	case PostgresDialect, SqliteDialect:
+		return gorm.Expr("? + ?", clause.Column{Table: table, Name: column}, clause.Column{Table: "excluded", Name: column})
+	default:
+		return gorm.Expr("? + VALUES(?)", clause.Column{Table: table, Name: column}, clause.Column{Name: column})
+	}
+}
+
+// PairIn matches the rows whose two columns equal one of the pairs, sqlite only accepts a row value IN with a VALUES list
+func PairIn(first clause.Column, second clause.Column, pairs [][]interface{}) clause.Expr {
+	if dialect != SqliteDialect {
+		return gorm.Expr("(?, ?) IN ?", first, second, pairs)
+	}
+	values := make([]string, len(pairs))
+	vars := []interface{}{first, second}
+	for i, pair := range pairs {
+		values[i] = "(?, ?)"
+		vars = append(vars, pair...)
+	}
+	return gorm.Expr("(?, ?) IN (VALUES "+strings.Join(values, ", ")+")", vars...)
+}
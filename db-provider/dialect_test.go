@@ -0,0 +1,41 @@
+package dbProvider
+
+import (
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"gorm.io/driver/postgres"
+)
+
+func TestPostgresDsnKeepsTheValues(t *testing.T) {
+	for _, password := range []string{"plain", "with space", "it's", `back\slash`, `'\' =`, ""} {
+		dbConfig := Config{Host: "db.local", Port: "5432", User: "coti user", Password: password, TLS: "false"}
+		dialector := newDialector(PostgresDialect, dbConfig, "coti_db", false).(*postgres.Dialector)
+		parsed, err := pgconn.ParseConfig(dialector.Config.DSN)
+		if err != nil {
+			t.Fatalf("the dsn of the password %q doesn't parse: %v", password, err)
+		}
+		if parsed.Password != password || parsed.User != "coti user" || parsed.Database != "coti_db" || parsed.Host != "db.local" {
+			t.Fatalf("the dsn of the password %q is parsed as %s@%s/%s with the password %q", password, parsed.User, parsed.Host, parsed.Database, parsed.Password)
+		}
+	}
+}
+
+func TestIsMemoryDb(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		dbName   string
+		isMemory bool
+	}{
+		{SqliteDialect, "", true},
+		{SqliteDialect, ":memory:", true},
+		{SqliteDialect, "file:test-1?mode=memory&cache=shared", true},
+		{SqliteDialect, "coti.db", false},
+		{MysqlDialect, "", false},
+	}
+	for _, test := range tests {
+		if isMemory := isMemoryDb(test.dialect, test.dbName); isMemory != test.isMemory {
+			t.Errorf("isMemoryDb(%s, %q) is %t", test.dialect, test.dbName, isMemory)
+		}
+	}
+}
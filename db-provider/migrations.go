@@ -0,0 +1,272 @@
+package dbProvider
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// the migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+var migrationFileNameRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus describes a migration of the binary or a version applied to the db that the binary doesn't know
+type MigrationStatus struct {
+	Version     int64
+	Name        string
+	IsApplied   bool
+	IsKnown     bool
+	AppliedTime *time.Time
+}
+
+// loadMigrations reads the embedded migrations of the dialect ordered by version
+func loadMigrations(dbDialect Dialect) ([]migration, error) {
+	fileNames, err := fs.Glob(migrationFiles, "migrations/"+string(dbDialect)+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	versionToMigrationMap := make(map[int64]*migration)
+	for _, fileName := range fileNames {
+		match := migrationFileNameRegex.FindStringSubmatch(path.Base(fileName))
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s doesn't match <version>_<name>.<up|down>.sql", fileName)
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		content, err := migrationFiles.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		m := versionToMigrationMap[version]
+		if m == nil {
+			m = &migration{version: version, name: match[2]}
+			versionToMigrationMap[version] = m
+		}
+		if m.name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, m.name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+	migrations := make([]migration, 0, len(versionToMigrationMap))
+	for _, m := range versionToMigrationMap {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// MigrateUp applies all the pending migrations in order, each migration runs in a transaction.
+// MySQL commits every DDL statement on its own, so a failed mysql migration may have to be cleaned manually before it is retried.
+func MigrateUp() error {
+	db, dbDialect, err := openMigrationDb()
+	if err != nil {
+		return err
+	}
+	defer closeDb(db)
+	migrations, err := loadMigrations(dbDialect)
+	if err != nil {
+		return err
+	}
+	err = initSchemaMigrations(db, migrations)
+	if err != nil {
+		return err
+	}
+	appliedVersions, err := getAppliedVersions(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if _, ok := appliedVersions[m.version]; ok {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{"version": m.version, "name": m.name}).Info("applying migration")
+		err = db.Transaction(func(dbTransaction *gorm.DB) error {
+			err := dbTransaction.Exec(m.up).Error
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.version, m.name, err)
+			}
+			return dbTransaction.Omit("AppliedTime").Create(entities.NewSchemaMigration(m.version, m.name)).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateDown reverts the last applied migrations
+func MigrateDown(steps int) error {
+	db, dbDialect, err := openMigrationDb()
+	if err != nil {
+		return err
+	}
+	defer closeDb(db)
+	migrations, err := loadMigrations(dbDialect)
+	if err != nil {
+		return err
+	}
+	versionToMigrationMap := make(map[int64]migration)
+	for _, m := range migrations {
+		versionToMigrationMap[m.version] = m
+	}
+	var appliedMigrations []entities.SchemaMigration
+	err = db.Order("version desc").Limit(steps).Find(&appliedMigrations).Error
+	if err != nil {
+		return err
+	}
+	for _, appliedMigration := range appliedMigrations {
+		m, ok := versionToMigrationMap[appliedMigration.Version]
+		if !ok {
+			return fmt.Errorf("migration %d_%s is not known to this version of the app", appliedMigration.Version, appliedMigration.Name)
+		}
+		if m.down == "" {
+			return fmt.Errorf("migration %d_%s can't be reverted", m.version, m.name)
+		}
+		logrus.WithFields(logrus.Fields{"version": m.version, "name": m.name}).Info("reverting migration")
+		err = db.Transaction(func(dbTransaction *gorm.DB) error {
+			err := dbTransaction.Exec(m.down).Error
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", m.version, m.name, err)
+			}
+			return dbTransaction.Delete(&appliedMigration).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMigrationStatus lists the migrations of the binary and the versions applied to the db
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	db, dbDialect, err := openMigrationDb()
+	if err != nil {
+		return nil, err
+	}
+	defer closeDb(db)
+	return getMigrationStatus(db, dbDialect)
+}
+
+// CheckSchemaVersion returns an error when the db has pending migrations, the sync must not run against an old schema
+func CheckSchemaVersion() error {
+	statuses, err := getMigrationStatus(DB, dialect)
+	if err != nil {
+		return err
+	}
+	pendingCount := 0
+	for _, status := range statuses {
+		if !status.IsKnown {
+			logrus.WithFields(logrus.Fields{"version": status.Version, "name": status.Name}).Warn("migration is applied but not known to this version of the app")
+		}
+		if !status.IsApplied {
+			pendingCount++
+		}
+	}
+	if pendingCount > 0 {
+		return fmt.Errorf("the db schema is behind by %d migrations, run migrate up or set MIGRATE_DB=true", pendingCount)
+	}
+	return nil
+}
+
+func getMigrationStatus(db *gorm.DB, dbDialect Dialect) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(dbDialect)
+	if err != nil {
+		return nil, err
+	}
+	appliedVersions := make(map[int64]entities.SchemaMigration)
+	if db.Migrator().HasTable(&entities.SchemaMigration{}) {
+		appliedVersions, err = getAppliedVersions(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.version, Name: m.name, IsKnown: true}
+		if appliedMigration, ok := appliedVersions[m.version]; ok {
+			status.IsApplied = true
+			status.AppliedTime = &appliedMigration.AppliedTime
+			delete(appliedVersions, m.version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, appliedMigration := range appliedVersions {
+		appliedTime := appliedMigration.AppliedTime
+		statuses = append(statuses, MigrationStatus{Version: appliedMigration.Version, Name: appliedMigration.Name, IsApplied: true, AppliedTime: &appliedTime})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+func getAppliedVersions(db *gorm.DB) (map[int64]entities.SchemaMigration, error) {
+	var appliedMigrations []entities.SchemaMigration
+	err := db.Find(&appliedMigrations).Error
+	if err != nil {
+		return nil, err
+	}
+	appliedVersions := make(map[int64]entities.SchemaMigration)
+	for _, appliedMigration := range appliedMigrations {
+		appliedVersions[appliedMigration.Version] = appliedMigration
+	}
+	return appliedVersions, nil
+}
+
+// initSchemaMigrations creates the schema_migrations table, a db created by AutoMigrate before the migrations were added
+// already has the initial schema so the first migration is recorded as applied
+func initSchemaMigrations(db *gorm.DB, migrations []migration) error {
+	if db.Migrator().HasTable(&entities.SchemaMigration{}) {
+		return nil
+	}
+	err := db.Migrator().CreateTable(&entities.SchemaMigration{})
+	if err != nil {
+		return err
+	}
+	if len(migrations) > 0 && db.Migrator().HasTable(&entities.Transaction{}) {
+		logrus.WithFields(logrus.Fields{"version": migrations[0].version, "name": migrations[0].name}).Info("existing schema found, recording the migration as applied")
+		return db.Omit("AppliedTime").Create(entities.NewSchemaMigration(migrations[0].version, migrations[0].name)).Error
+	}
+	return nil
+}
+
+func closeDb(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		logrus.WithError(err).Error("failed to get the migration db connection")
+		return
+	}
+	err = sqlDB.Close()
+	if err != nil {
+		logrus.WithError(err).Error("failed to close the migration db connection")
+	}
+}
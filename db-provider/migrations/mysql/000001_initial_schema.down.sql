@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS `transaction_currencies`;
+DROP TABLE IF EXISTS `addresses`;
+DROP TABLE IF EXISTS `transaction_addresses`;
+DROP TABLE IF EXISTS `address_transaction_counts`;
+DROP TABLE IF EXISTS `event_input_base_transactions`;
+DROP TABLE IF EXISTS `token_generation_service_data`;
+DROP TABLE IF EXISTS `token_minting_service_data`;
+DROP TABLE IF EXISTS `token_minting_fee_base_transactions`;
+DROP TABLE IF EXISTS `token_generation_fee_base_transactions`;
+DROP TABLE IF EXISTS `originator_currency_data`;
+DROP TABLE IF EXISTS `currency_type_data`;
+DROP TABLE IF EXISTS `address_balances`;
+DROP TABLE IF EXISTS `receiver_base_transactions`;
+DROP TABLE IF EXISTS `network_fee_base_transactions`;
+DROP TABLE IF EXISTS `input_base_transactions`;
+DROP TABLE IF EXISTS `fullnode_fee_base_transactions`;
+DROP TABLE IF EXISTS `transactions`;
+DROP TABLE IF EXISTS `currencies`;
+DROP TABLE IF EXISTS `app_states`;
@@ -0,0 +1,276 @@
+CREATE TABLE `app_states` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `value` varchar(1000) COLLATE utf8_unicode_ci DEFAULT '',
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX name_INDEX (`name`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `currencies` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `originatorCurrencyDataId` int(11) NOT NULL,
+  `transactionId` int(11) NOT NULL,
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `hash` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `index` int(11) DEFAULT NULL,
+  `amount` decimal(25,10) NOT NULL,
+  `attachmentTime` decimal(20,6) NOT NULL,
+  `isValid` tinyint(4) DEFAULT NULL,
+  `transactionCreateTime` decimal(20,6) NOT NULL,
+  `leftParentHash` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `rightParentHash` varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `nodeHash` varchar(128) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `senderHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `senderTrustScore` decimal(25,10) NOT NULL,
+  `transactionConsensusUpdateTime` decimal(20,6),
+  `transactionDescription` varchar(500) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `trustChainConsensus` tinyint(4) DEFAULT NULL,
+  `trustChainTrustScore` decimal(25,10) NOT NULL,
+  `type` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `isProcessed` tinyint(4) DEFAULT false,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX updateTime_INDEX (`updateTime`),
+  INDEX hash_INDEX (`hash`),
+  INDEX index_INDEX (`index`),
+  INDEX attachmentTime_INDEX (`attachmentTime`),
+  INDEX composite_INDEX (`isProcessed`,`type`,`transactionConsensusUpdateTime`),
+  INDEX transactionConsensusUpdateTime_INDEX (`transactionConsensusUpdateTime`),
+  INDEX type_INDEX (`type`),
+  INDEX isProcessed_INDEX (`isProcessed`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `fullnode_fee_base_transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `name` varchar(45) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `amount` decimal(25,10) NOT NULL,
+  `currencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `fullnodeFeeCreateTime` decimal(20,6) NOT NULL,
+  `originalAmount` decimal(25,10),
+  `originalCurrencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX transactionId_INDEX (`transactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `input_base_transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `name` varchar(45) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` decimal(25,10) NOT NULL,
+  `currencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `inputCreateTime` decimal(20,6) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX transactionId_INDEX (`transactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `network_fee_base_transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` decimal(25,10) NOT NULL,
+  `currencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `name` varchar(45) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `networkFeeCreateTime` decimal(20,6) NOT NULL,
+  `originalAmount` decimal(25,10),
+  `originalCurrencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `reducedAmount` decimal(25,10),
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX transactionId_INDEX (`transactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `receiver_base_transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `name` varchar(45) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` decimal(25,10) NOT NULL,
+  `currencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `receiverCreateTime` decimal(20,6) NOT NULL,
+  `originalAmount` decimal(25,10) NOT NULL,
+  `originalCurrencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `receiverDescription` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX transactionId_INDEX (`transactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `address_balances` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `currencyId` int(11) NOT NULL,
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` decimal(25,10) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX addressHash_INDEX (`addressHash`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `currency_type_data` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `serviceDataId` int(11) NOT NULL,
+  `currencyType` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `currencyRateSourceType` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `rateSource` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `protectionModel` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `signerHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `currencyTypeDataCreateTime` decimal(20,6) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX serviceDataId_INDEX (`serviceDataId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `originator_currency_data` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `serviceDataId` int(11) NOT NULL,
+  `name` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `symbol` varchar(200) COLLATE utf8_unicode_ci,
+  `description` varchar(500) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `originatorHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `totalSupply` decimal(25,10) NOT NULL,
+  `scale` int(11) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX serviceDataId_INDEX (`serviceDataId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `token_generation_fee_base_transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `name` varchar(45) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `amount` decimal(25,10) NOT NULL,
+  `currencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `fullnodeFeeCreateTime` decimal(20,6) NOT NULL,
+  `originalAmount` decimal(25,10),
+  `originalCurrencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX transactionId_INDEX (`transactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `token_minting_fee_base_transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `name` varchar(45) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` decimal(25,10) NOT NULL,
+  `currencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `tokenMintingFeeCreateTime` decimal(20,6) NOT NULL,
+  `originalAmount` decimal(25,10),
+  `originalCurrencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `signerHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX transactionId_INDEX (`transactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `token_minting_service_data` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `baseTransactionId` int(11) NOT NULL,
+  `mintingCurrencyHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `mintingAmount` decimal(25,10) NOT NULL,
+  `serviceDataCreateTime` decimal(20,6) NOT NULL,
+  `receiverAddress` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `feeAmount` decimal(25,10) NOT NULL,
+  `signerHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX baseTransactionId_INDEX (`baseTransactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `token_generation_service_data` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `baseTransactionId` int(11) NOT NULL,
+  `feeAmount` decimal(25,10) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX baseTransactionId_INDEX (`baseTransactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `event_input_base_transactions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `name` varchar(45) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `amount` decimal(25,10) NOT NULL,
+  `currencyHash` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `eventInputCreateTime` decimal(20,6) NOT NULL,
+  `event` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `hardFork` varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX transactionId_INDEX (`transactionId`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `address_transaction_counts` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `count` int(11) NOT NULL,
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX addressHash_INDEX (`addressHash`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `transaction_addresses` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `addressId` int(11) NOT NULL,
+  `attachmentTime` decimal(20,6) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `addresses` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL UNIQUE,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX addressHash_INDEX (`addressHash`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `transaction_currencies` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `currencyId` int(11) NOT NULL,
+  `attachmentTime` decimal(20,6) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) CHARSET=utf8 auto_increment=1;
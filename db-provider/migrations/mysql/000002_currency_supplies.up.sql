@@ -0,0 +1,12 @@
+CREATE TABLE `currency_supplies` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `currencyId` int(11) NOT NULL,
+  `mintedAmount` decimal(25,10) NOT NULL,
+  `burnedAmount` decimal(25,10) NOT NULL,
+  `circulatingSupply` decimal(25,10) NOT NULL,
+  `holderCount` int(11) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX currencyId_UNIQUE (`currencyId`)
+) CHARSET=utf8 auto_increment=1;
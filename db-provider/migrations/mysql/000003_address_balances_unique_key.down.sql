@@ -0,0 +1 @@
+ALTER TABLE `address_balances` DROP INDEX addressHash_currencyId_UNIQUE;
@@ -0,0 +1,9 @@
+-- balances that were split over several rows are merged into the row with the lowest id before the unique key is added
+UPDATE address_balances ab
+  INNER JOIN (SELECT MIN(id) AS id, SUM(amount) AS amount FROM address_balances GROUP BY addressHash, currencyId HAVING COUNT(*) > 1) duplicates ON duplicates.id = ab.id
+SET ab.amount = duplicates.amount;
+
+DELETE ab FROM address_balances ab
+  INNER JOIN address_balances kept ON kept.addressHash = ab.addressHash AND kept.currencyId = ab.currencyId AND kept.id < ab.id;
+
+ALTER TABLE `address_balances` ADD UNIQUE INDEX addressHash_currencyId_UNIQUE (`addressHash`, `currencyId`);
@@ -0,0 +1,3 @@
+ALTER TABLE `transactions`
+  ADD COLUMN `isSkipped` tinyint(4) DEFAULT false AFTER `isProcessed`,
+  ADD COLUMN `confirmationPolicy` varchar(45) COLLATE utf8_unicode_ci DEFAULT NULL AFTER `isSkipped`;
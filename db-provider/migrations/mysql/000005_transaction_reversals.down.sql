@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `transaction_reversals`;
+ALTER TABLE `transactions` DROP COLUMN `isReversed`;
@@ -0,0 +1,14 @@
+ALTER TABLE `transactions` ADD COLUMN `isReversed` tinyint(4) DEFAULT false AFTER `isSkipped`;
+
+CREATE TABLE `transaction_reversals` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `transactionId` int(11) NOT NULL,
+  `transactionHash` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `reason` varchar(45) COLLATE utf8_unicode_ci NOT NULL,
+  `confirmationPolicy` varchar(45) COLLATE utf8_unicode_ci DEFAULT NULL,
+  `balanceDiff` text COLLATE utf8_unicode_ci NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX transactionId_INDEX (`transactionId`)
+) CHARSET=utf8 auto_increment=1;
@@ -0,0 +1,26 @@
+CREATE TABLE `cluster_stamps` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `type` varchar(45) COLLATE utf8_unicode_ci NOT NULL,
+  `fileName` varchar(500) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `hash` varchar(200) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `rowCount` int(11) NOT NULL,
+  `currencyTotals` text COLLATE utf8_unicode_ci NOT NULL,
+  `transactionIndex` bigint(20) DEFAULT NULL,
+  `differenceCount` int(11) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `cluster_stamp_differences` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `clusterStampId` int(11) NOT NULL,
+  `currencyHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `addressHash` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `stampAmount` decimal(25,10) NOT NULL,
+  `computedAmount` decimal(25,10) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX clusterStampId_INDEX (`clusterStampId`)
+) CHARSET=utf8 auto_increment=1;
@@ -0,0 +1,46 @@
+-- archived data leaves the hot tables, the archive tables keep the rows of the table archive target
+CREATE TABLE `archive_batches` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `target` varchar(45) COLLATE utf8_unicode_ci NOT NULL,
+  `location` varchar(500) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `transactionCount` int(11) NOT NULL,
+  `fromAttachmentTime` decimal(20,6) NOT NULL,
+  `toAttachmentTime` decimal(20,6) NOT NULL,
+  `maxIndex` int(11) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) CHARSET=utf8 auto_increment=1;
+
+CREATE TABLE `archived_transactions` (
+  `id` int(11) NOT NULL,
+  `hash` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `index` int(11) NOT NULL,
+  `attachmentTime` decimal(20,6) NOT NULL,
+  `archiveBatchId` int(11) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX hash_INDEX (`hash`),
+  INDEX archiveBatchId_INDEX (`archiveBatchId`)
+) CHARSET=utf8;
+
+CREATE TABLE `transactions_archive` LIKE `transactions`;
+
+CREATE TABLE `input_base_transactions_archive` LIKE `input_base_transactions`;
+
+CREATE TABLE `receiver_base_transactions_archive` LIKE `receiver_base_transactions`;
+
+CREATE TABLE `fullnode_fee_base_transactions_archive` LIKE `fullnode_fee_base_transactions`;
+
+CREATE TABLE `network_fee_base_transactions_archive` LIKE `network_fee_base_transactions`;
+
+CREATE TABLE `event_input_base_transactions_archive` LIKE `event_input_base_transactions`;
+
+CREATE TABLE `token_minting_fee_base_transactions_archive` LIKE `token_minting_fee_base_transactions`;
+
+CREATE TABLE `token_minting_service_data_archive` LIKE `token_minting_service_data`;
+
+CREATE TABLE `transaction_addresses_archive` LIKE `transaction_addresses`;
+
+CREATE TABLE `transaction_currencies_archive` LIKE `transaction_currencies`;
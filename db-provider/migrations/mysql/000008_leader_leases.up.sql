@@ -0,0 +1,13 @@
+-- the lease of the sync leader, only its holder runs the writer jobs and the fencing token grows with every new holder
+CREATE TABLE `leader_leases` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `holder` varchar(200) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `fencingToken` bigint(20) NOT NULL DEFAULT 0,
+  `renewTime` datetime(3) NOT NULL,
+  `expireTime` datetime(3) NOT NULL,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX name_UNIQUE (`name`)
+) CHARSET=utf8 auto_increment=1;
@@ -0,0 +1,14 @@
+-- every action of the admin api, whether it succeeded or not
+CREATE TABLE `admin_audit_logs` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `action` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `target` varchar(100) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `actor` varchar(200) COLLATE utf8_unicode_ci NOT NULL,
+  `params` text COLLATE utf8_unicode_ci NOT NULL,
+  `isSuccess` tinyint(4) NOT NULL,
+  `error` varchar(1000) COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX createTime_INDEX (`createTime`)
+) CHARSET=utf8 auto_increment=1;
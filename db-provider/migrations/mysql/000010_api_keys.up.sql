@@ -0,0 +1,16 @@
+-- the api keys, only the sha256 hash of a key is kept and a rate limit of 0 is the default of the scope
+CREATE TABLE `api_keys` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) COLLATE utf8_unicode_ci NOT NULL,
+  `prefix` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `keyHash` char(64) COLLATE utf8_unicode_ci NOT NULL,
+  `scope` varchar(20) COLLATE utf8_unicode_ci NOT NULL,
+  `rateLimit` double NOT NULL DEFAULT 0,
+  `rateLimitBurst` int(11) NOT NULL DEFAULT 0,
+  `isRevoked` tinyint(4) NOT NULL DEFAULT 0,
+  `createTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updateTime` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX name_UNIQUE (`name`),
+  UNIQUE INDEX keyHash_UNIQUE (`keyHash`)
+) CHARSET=utf8 auto_increment=1;
@@ -0,0 +1,4 @@
+ALTER TABLE `transactions_archive` DROP COLUMN `processedTime`;
+ALTER TABLE `transactions`
+  DROP INDEX processedTime_INDEX,
+  DROP COLUMN `processedTime`;
@@ -0,0 +1,6 @@
+-- the reversal window starts when a transaction is processed, the update time moves on every change of its consensus
+ALTER TABLE `transactions`
+  ADD COLUMN `processedTime` timestamp NULL DEFAULT NULL AFTER `isReversed`,
+  ADD INDEX processedTime_INDEX (`processedTime`);
+ALTER TABLE `transactions_archive` ADD COLUMN `processedTime` timestamp NULL DEFAULT NULL AFTER `isReversed`;
+UPDATE `transactions` SET `processedTime` = `updateTime`, `updateTime` = `updateTime` WHERE `isProcessed` = true;
@@ -0,0 +1,3 @@
+-- the trust score policy keeps the threshold a transaction was processed with so a changed MIN_TRUST_CHAIN_TRUST_SCORE doesn't reverse it
+ALTER TABLE `transactions` ADD COLUMN `confirmationMinTrustScore` decimal(25,10) DEFAULT NULL AFTER `confirmationPolicy`;
+ALTER TABLE `transactions_archive` ADD COLUMN `confirmationMinTrustScore` decimal(25,10) DEFAULT NULL AFTER `confirmationPolicy`;
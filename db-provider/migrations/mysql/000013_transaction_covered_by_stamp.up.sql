@@ -0,0 +1,6 @@
+-- the transactions a cluster stamp rebase marked as processed are covered by the stamp balances and are not invalid skipped transactions
+ALTER TABLE `transactions` ADD COLUMN `isCoveredByStamp` tinyint(4) DEFAULT false AFTER `isReversed`;
+ALTER TABLE `transactions_archive` ADD COLUMN `isCoveredByStamp` tinyint(4) DEFAULT false AFTER `isReversed`;
+-- only the invalid transactions were skipped by the balance update, the skipped valid ones were covered by a rebase
+UPDATE `transactions` SET `isCoveredByStamp` = true WHERE `isSkipped` = true AND (`isValid` IS NULL OR `isValid` = true);
+UPDATE `transactions_archive` SET `isCoveredByStamp` = true WHERE `isSkipped` = true AND (`isValid` IS NULL OR `isValid` = true);
@@ -0,0 +1,303 @@
+-- keeps updateTime current like ON UPDATE CURRENT_TIMESTAMP does in mysql
+CREATE OR REPLACE FUNCTION set_update_time() RETURNS trigger AS $$
+BEGIN
+  NEW."updateTime" = CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE "app_states" (
+  "id" serial,
+  "name" varchar(100) NOT NULL,
+  "value" varchar(1000) DEFAULT '',
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "app_states_name_INDEX" ON "app_states" ("name");
+CREATE TRIGGER "app_states_updateTime" BEFORE UPDATE ON "app_states" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "currencies" (
+  "id" serial,
+  "originatorCurrencyDataId" integer NOT NULL,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "currencies_updateTime" BEFORE UPDATE ON "currencies" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "transactions" (
+  "id" serial,
+  "hash" varchar(100) NOT NULL,
+  "index" integer,
+  "amount" decimal(25,10) NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "isValid" boolean,
+  "transactionCreateTime" decimal(20,6) NOT NULL,
+  "leftParentHash" varchar(100),
+  "rightParentHash" varchar(100),
+  "nodeHash" varchar(128),
+  "senderHash" varchar(200),
+  "senderTrustScore" decimal(25,10) NOT NULL,
+  "transactionConsensusUpdateTime" decimal(20,6),
+  "transactionDescription" varchar(500),
+  "trustChainConsensus" boolean,
+  "trustChainTrustScore" decimal(25,10) NOT NULL,
+  "type" varchar(100) NOT NULL,
+  "isProcessed" boolean DEFAULT false,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "transactions_attachmentTime_INDEX" ON "transactions" ("attachmentTime");
+CREATE INDEX "transactions_composite_INDEX" ON "transactions" ("isProcessed","type","transactionConsensusUpdateTime");
+CREATE INDEX "transactions_hash_INDEX" ON "transactions" ("hash");
+CREATE INDEX "transactions_index_INDEX" ON "transactions" ("index");
+CREATE INDEX "transactions_isProcessed_INDEX" ON "transactions" ("isProcessed");
+CREATE INDEX "transactions_transactionConsensusUpdateTime_INDEX" ON "transactions" ("transactionConsensusUpdateTime");
+CREATE INDEX "transactions_type_INDEX" ON "transactions" ("type");
+CREATE INDEX "transactions_updateTime_INDEX" ON "transactions" ("updateTime");
+CREATE TRIGGER "transactions_updateTime" BEFORE UPDATE ON "transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "fullnode_fee_base_transactions" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "fullnodeFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "fullnode_fee_base_transactions_transactionId_INDEX" ON "fullnode_fee_base_transactions" ("transactionId");
+CREATE TRIGGER "fullnode_fee_base_transactions_updateTime" BEFORE UPDATE ON "fullnode_fee_base_transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "input_base_transactions" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "inputCreateTime" decimal(20,6) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "input_base_transactions_transactionId_INDEX" ON "input_base_transactions" ("transactionId");
+CREATE TRIGGER "input_base_transactions_updateTime" BEFORE UPDATE ON "input_base_transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "network_fee_base_transactions" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "networkFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "reducedAmount" decimal(25,10),
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "network_fee_base_transactions_transactionId_INDEX" ON "network_fee_base_transactions" ("transactionId");
+CREATE TRIGGER "network_fee_base_transactions_updateTime" BEFORE UPDATE ON "network_fee_base_transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "receiver_base_transactions" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "receiverCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10) NOT NULL,
+  "originalCurrencyHash" varchar(200),
+  "receiverDescription" varchar(200),
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "receiver_base_transactions_transactionId_INDEX" ON "receiver_base_transactions" ("transactionId");
+CREATE TRIGGER "receiver_base_transactions_updateTime" BEFORE UPDATE ON "receiver_base_transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "address_balances" (
+  "id" serial,
+  "currencyId" integer NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "address_balances_addressHash_INDEX" ON "address_balances" ("addressHash");
+CREATE TRIGGER "address_balances_updateTime" BEFORE UPDATE ON "address_balances" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "currency_type_data" (
+  "id" serial,
+  "serviceDataId" integer NOT NULL,
+  "currencyType" varchar(200),
+  "currencyRateSourceType" varchar(200),
+  "rateSource" varchar(200),
+  "protectionModel" varchar(200),
+  "signerHash" varchar(200),
+  "currencyTypeDataCreateTime" decimal(20,6) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "currency_type_data_serviceDataId_INDEX" ON "currency_type_data" ("serviceDataId");
+CREATE TRIGGER "currency_type_data_updateTime" BEFORE UPDATE ON "currency_type_data" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "originator_currency_data" (
+  "id" serial,
+  "serviceDataId" integer NOT NULL,
+  "name" varchar(200),
+  "symbol" varchar(200),
+  "description" varchar(500),
+  "originatorHash" varchar(200),
+  "totalSupply" decimal(25,10) NOT NULL,
+  "scale" integer NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "originator_currency_data_serviceDataId_INDEX" ON "originator_currency_data" ("serviceDataId");
+CREATE TRIGGER "originator_currency_data_updateTime" BEFORE UPDATE ON "originator_currency_data" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "token_generation_fee_base_transactions" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "fullnodeFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "token_generation_fee_base_transactions_transactionId_INDEX" ON "token_generation_fee_base_transactions" ("transactionId");
+CREATE TRIGGER "token_generation_fee_base_transactions_updateTime" BEFORE UPDATE ON "token_generation_fee_base_transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "token_minting_fee_base_transactions" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "tokenMintingFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "signerHash" varchar(200),
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "token_minting_fee_base_transactions_transactionId_INDEX" ON "token_minting_fee_base_transactions" ("transactionId");
+CREATE TRIGGER "token_minting_fee_base_transactions_updateTime" BEFORE UPDATE ON "token_minting_fee_base_transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "token_minting_service_data" (
+  "id" serial,
+  "baseTransactionId" integer NOT NULL,
+  "mintingCurrencyHash" varchar(200) NOT NULL,
+  "mintingAmount" decimal(25,10) NOT NULL,
+  "serviceDataCreateTime" decimal(20,6) NOT NULL,
+  "receiverAddress" varchar(200) NOT NULL,
+  "feeAmount" decimal(25,10) NOT NULL,
+  "signerHash" varchar(200) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "token_minting_service_data_baseTransactionId_INDEX" ON "token_minting_service_data" ("baseTransactionId");
+CREATE TRIGGER "token_minting_service_data_updateTime" BEFORE UPDATE ON "token_minting_service_data" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "token_generation_service_data" (
+  "id" serial,
+  "baseTransactionId" integer NOT NULL,
+  "feeAmount" decimal(25,10) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "token_generation_service_data_baseTransactionId_INDEX" ON "token_generation_service_data" ("baseTransactionId");
+CREATE TRIGGER "token_generation_service_data_updateTime" BEFORE UPDATE ON "token_generation_service_data" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "event_input_base_transactions" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "eventInputCreateTime" decimal(20,6) NOT NULL,
+  "event" varchar(200) NOT NULL,
+  "hardFork" boolean,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "event_input_base_transactions_transactionId_INDEX" ON "event_input_base_transactions" ("transactionId");
+CREATE TRIGGER "event_input_base_transactions_updateTime" BEFORE UPDATE ON "event_input_base_transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "address_transaction_counts" (
+  "id" serial,
+  "count" integer NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "address_transaction_counts_addressHash_INDEX" ON "address_transaction_counts" ("addressHash");
+CREATE TRIGGER "address_transaction_counts_updateTime" BEFORE UPDATE ON "address_transaction_counts" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "transaction_addresses" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "addressId" integer NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "transaction_addresses_updateTime" BEFORE UPDATE ON "transaction_addresses" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "addresses" (
+  "id" serial,
+  "addressHash" varchar(200) NOT NULL UNIQUE,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "addresses_addressHash_INDEX" ON "addresses" ("addressHash");
+CREATE TRIGGER "addresses_updateTime" BEFORE UPDATE ON "addresses" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "transaction_currencies" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "currencyId" integer NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "transaction_currencies_updateTime" BEFORE UPDATE ON "transaction_currencies" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
@@ -0,0 +1,13 @@
+CREATE TABLE "currency_supplies" (
+  "id" serial,
+  "currencyId" integer NOT NULL,
+  "mintedAmount" decimal(25,10) NOT NULL,
+  "burnedAmount" decimal(25,10) NOT NULL,
+  "circulatingSupply" decimal(25,10) NOT NULL,
+  "holderCount" integer NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "currency_supplies_currencyId_UNIQUE" ON "currency_supplies" ("currencyId");
+CREATE TRIGGER "currency_supplies_updateTime" BEFORE UPDATE ON "currency_supplies" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
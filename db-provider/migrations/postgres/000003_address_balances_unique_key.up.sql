@@ -0,0 +1,9 @@
+-- balances that were split over several rows are merged into the row with the lowest id before the unique key is added
+UPDATE address_balances ab SET amount = duplicates.amount
+FROM (SELECT MIN(id) AS id, SUM(amount) AS amount FROM address_balances GROUP BY "addressHash", "currencyId" HAVING COUNT(*) > 1) duplicates
+WHERE duplicates.id = ab.id;
+
+DELETE FROM address_balances ab USING address_balances kept
+WHERE kept."addressHash" = ab."addressHash" AND kept."currencyId" = ab."currencyId" AND kept.id < ab.id;
+
+CREATE UNIQUE INDEX "address_balances_addressHash_currencyId_UNIQUE" ON "address_balances" ("addressHash","currencyId");
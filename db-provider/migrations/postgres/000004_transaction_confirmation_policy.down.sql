@@ -0,0 +1,3 @@
+ALTER TABLE "transactions"
+  DROP COLUMN "confirmationPolicy",
+  DROP COLUMN "isSkipped";
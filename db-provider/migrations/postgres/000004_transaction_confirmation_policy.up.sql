@@ -0,0 +1,3 @@
+ALTER TABLE "transactions"
+  ADD COLUMN "isSkipped" boolean DEFAULT false,
+  ADD COLUMN "confirmationPolicy" varchar(45);
@@ -0,0 +1,15 @@
+ALTER TABLE "transactions" ADD COLUMN "isReversed" boolean DEFAULT false;
+
+CREATE TABLE "transaction_reversals" (
+  "id" serial,
+  "transactionId" integer NOT NULL,
+  "transactionHash" varchar(100) NOT NULL,
+  "reason" varchar(45) NOT NULL,
+  "confirmationPolicy" varchar(45),
+  "balanceDiff" text NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "transaction_reversals_transactionId_INDEX" ON "transaction_reversals" ("transactionId");
+CREATE TRIGGER "transaction_reversals_updateTime" BEFORE UPDATE ON "transaction_reversals" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
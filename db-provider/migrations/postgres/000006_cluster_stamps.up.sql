@@ -0,0 +1,28 @@
+CREATE TABLE "cluster_stamps" (
+  "id" serial,
+  "type" varchar(45) NOT NULL,
+  "fileName" varchar(500) NOT NULL DEFAULT '',
+  "hash" varchar(200) NOT NULL DEFAULT '',
+  "rowCount" integer NOT NULL,
+  "currencyTotals" text NOT NULL,
+  "transactionIndex" bigint,
+  "differenceCount" integer NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "cluster_stamps_updateTime" BEFORE UPDATE ON "cluster_stamps" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "cluster_stamp_differences" (
+  "id" serial,
+  "clusterStampId" integer NOT NULL,
+  "currencyHash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "stampAmount" decimal(25,10) NOT NULL,
+  "computedAmount" decimal(25,10) NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "cluster_stamp_differences_clusterStampId_INDEX" ON "cluster_stamp_differences" ("clusterStampId");
+CREATE TRIGGER "cluster_stamp_differences_updateTime" BEFORE UPDATE ON "cluster_stamp_differences" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS "transaction_currencies_archive";
+DROP TABLE IF EXISTS "transaction_addresses_archive";
+DROP TABLE IF EXISTS "token_minting_service_data_archive";
+DROP TABLE IF EXISTS "token_minting_fee_base_transactions_archive";
+DROP TABLE IF EXISTS "event_input_base_transactions_archive";
+DROP TABLE IF EXISTS "network_fee_base_transactions_archive";
+DROP TABLE IF EXISTS "fullnode_fee_base_transactions_archive";
+DROP TABLE IF EXISTS "receiver_base_transactions_archive";
+DROP TABLE IF EXISTS "input_base_transactions_archive";
+DROP TABLE IF EXISTS "transactions_archive";
+DROP TABLE IF EXISTS "archived_transactions";
+DROP TABLE IF EXISTS "archive_batches";
@@ -0,0 +1,48 @@
+-- archived data leaves the hot tables, the archive tables keep the rows of the table archive target
+CREATE TABLE "archive_batches" (
+  "id" serial,
+  "target" varchar(45) NOT NULL,
+  "location" varchar(500) NOT NULL DEFAULT '',
+  "transactionCount" integer NOT NULL,
+  "fromAttachmentTime" decimal(20,6) NOT NULL,
+  "toAttachmentTime" decimal(20,6) NOT NULL,
+  "maxIndex" integer NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "archive_batches_updateTime" BEFORE UPDATE ON "archive_batches" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "archived_transactions" (
+  "id" integer NOT NULL,
+  "hash" varchar(100) NOT NULL,
+  "index" integer NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "archiveBatchId" integer NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "archived_transactions_hash_INDEX" ON "archived_transactions" ("hash");
+CREATE INDEX "archived_transactions_archiveBatchId_INDEX" ON "archived_transactions" ("archiveBatchId");
+CREATE TRIGGER "archived_transactions_updateTime" BEFORE UPDATE ON "archived_transactions" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
+
+CREATE TABLE "transactions_archive" (LIKE "transactions" INCLUDING INDEXES);
+
+CREATE TABLE "input_base_transactions_archive" (LIKE "input_base_transactions" INCLUDING INDEXES);
+
+CREATE TABLE "receiver_base_transactions_archive" (LIKE "receiver_base_transactions" INCLUDING INDEXES);
+
+CREATE TABLE "fullnode_fee_base_transactions_archive" (LIKE "fullnode_fee_base_transactions" INCLUDING INDEXES);
+
+CREATE TABLE "network_fee_base_transactions_archive" (LIKE "network_fee_base_transactions" INCLUDING INDEXES);
+
+CREATE TABLE "event_input_base_transactions_archive" (LIKE "event_input_base_transactions" INCLUDING INDEXES);
+
+CREATE TABLE "token_minting_fee_base_transactions_archive" (LIKE "token_minting_fee_base_transactions" INCLUDING INDEXES);
+
+CREATE TABLE "token_minting_service_data_archive" (LIKE "token_minting_service_data" INCLUDING INDEXES);
+
+CREATE TABLE "transaction_addresses_archive" (LIKE "transaction_addresses" INCLUDING INDEXES);
+
+CREATE TABLE "transaction_currencies_archive" (LIKE "transaction_currencies" INCLUDING INDEXES);
@@ -0,0 +1,14 @@
+-- the lease of the sync leader, only its holder runs the writer jobs and the fencing token grows with every new holder
+CREATE TABLE "leader_leases" (
+  "id" serial,
+  "name" varchar(100) NOT NULL,
+  "holder" varchar(200) NOT NULL DEFAULT '',
+  "fencingToken" bigint NOT NULL DEFAULT 0,
+  "renewTime" timestamptz NOT NULL,
+  "expireTime" timestamptz NOT NULL,
+  "createTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "leader_leases_name_UNIQUE" ON "leader_leases" ("name");
+CREATE TRIGGER "leader_leases_updateTime" BEFORE UPDATE ON "leader_leases" FOR EACH ROW EXECUTE PROCEDURE set_update_time();
@@ -0,0 +1,3 @@
+ALTER TABLE "transactions_archive" DROP COLUMN "processedTime";
+DROP INDEX IF EXISTS "transactions_processedTime_INDEX";
+ALTER TABLE "transactions" DROP COLUMN "processedTime";
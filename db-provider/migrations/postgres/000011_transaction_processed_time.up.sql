@@ -0,0 +1,7 @@
+-- the reversal window starts when a transaction is processed, the update time moves on every change of its consensus
+ALTER TABLE "transactions" ADD COLUMN "processedTime" timestamptz;
+CREATE INDEX "transactions_processedTime_INDEX" ON "transactions" ("processedTime");
+ALTER TABLE "transactions_archive" ADD COLUMN "processedTime" timestamptz;
+ALTER TABLE "transactions" DISABLE TRIGGER "transactions_updateTime";
+UPDATE "transactions" SET "processedTime" = "updateTime" WHERE "isProcessed" = true;
+ALTER TABLE "transactions" ENABLE TRIGGER "transactions_updateTime";
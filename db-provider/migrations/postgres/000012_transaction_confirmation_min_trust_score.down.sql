@@ -0,0 +1,2 @@
+ALTER TABLE "transactions_archive" DROP COLUMN "confirmationMinTrustScore";
+ALTER TABLE "transactions" DROP COLUMN "confirmationMinTrustScore";
@@ -0,0 +1,2 @@
+ALTER TABLE "transactions_archive" DROP COLUMN "isCoveredByStamp";
+ALTER TABLE "transactions" DROP COLUMN "isCoveredByStamp";
@@ -0,0 +1,336 @@
+-- the updateTime triggers keep updateTime current like ON UPDATE CURRENT_TIMESTAMP does in mysql, recursive triggers are off so they don't fire themselves.
+-- sqlite stores the decimal columns as numeric, amounts with more than 15 significant digits lose precision so this backend is meant for embedded and test use.
+
+CREATE TABLE "app_states" (
+  "id" integer,
+  "name" varchar(100) NOT NULL,
+  "value" varchar(1000) DEFAULT '',
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "app_states_name_INDEX" ON "app_states" ("name");
+CREATE TRIGGER "app_states_updateTime" AFTER UPDATE ON "app_states" FOR EACH ROW BEGIN
+  UPDATE "app_states" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "currencies" (
+  "id" integer,
+  "originatorCurrencyDataId" integer NOT NULL,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "currencies_updateTime" AFTER UPDATE ON "currencies" FOR EACH ROW BEGIN
+  UPDATE "currencies" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "transactions" (
+  "id" integer,
+  "hash" varchar(100) NOT NULL,
+  "index" integer,
+  "amount" decimal(25,10) NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "isValid" boolean,
+  "transactionCreateTime" decimal(20,6) NOT NULL,
+  "leftParentHash" varchar(100),
+  "rightParentHash" varchar(100),
+  "nodeHash" varchar(128),
+  "senderHash" varchar(200),
+  "senderTrustScore" decimal(25,10) NOT NULL,
+  "transactionConsensusUpdateTime" decimal(20,6),
+  "transactionDescription" varchar(500),
+  "trustChainConsensus" boolean,
+  "trustChainTrustScore" decimal(25,10) NOT NULL,
+  "type" varchar(100) NOT NULL,
+  "isProcessed" boolean DEFAULT false,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "transactions_attachmentTime_INDEX" ON "transactions" ("attachmentTime");
+CREATE INDEX "transactions_composite_INDEX" ON "transactions" ("isProcessed","type","transactionConsensusUpdateTime");
+CREATE INDEX "transactions_hash_INDEX" ON "transactions" ("hash");
+CREATE INDEX "transactions_index_INDEX" ON "transactions" ("index");
+CREATE INDEX "transactions_isProcessed_INDEX" ON "transactions" ("isProcessed");
+CREATE INDEX "transactions_transactionConsensusUpdateTime_INDEX" ON "transactions" ("transactionConsensusUpdateTime");
+CREATE INDEX "transactions_type_INDEX" ON "transactions" ("type");
+CREATE INDEX "transactions_updateTime_INDEX" ON "transactions" ("updateTime");
+CREATE TRIGGER "transactions_updateTime" AFTER UPDATE ON "transactions" FOR EACH ROW BEGIN
+  UPDATE "transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "fullnode_fee_base_transactions" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "fullnodeFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "fullnode_fee_base_transactions_transactionId_INDEX" ON "fullnode_fee_base_transactions" ("transactionId");
+CREATE TRIGGER "fullnode_fee_base_transactions_updateTime" AFTER UPDATE ON "fullnode_fee_base_transactions" FOR EACH ROW BEGIN
+  UPDATE "fullnode_fee_base_transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "input_base_transactions" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "inputCreateTime" decimal(20,6) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "input_base_transactions_transactionId_INDEX" ON "input_base_transactions" ("transactionId");
+CREATE TRIGGER "input_base_transactions_updateTime" AFTER UPDATE ON "input_base_transactions" FOR EACH ROW BEGIN
+  UPDATE "input_base_transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "network_fee_base_transactions" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "networkFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "reducedAmount" decimal(25,10),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "network_fee_base_transactions_transactionId_INDEX" ON "network_fee_base_transactions" ("transactionId");
+CREATE TRIGGER "network_fee_base_transactions_updateTime" AFTER UPDATE ON "network_fee_base_transactions" FOR EACH ROW BEGIN
+  UPDATE "network_fee_base_transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "receiver_base_transactions" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "receiverCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10) NOT NULL,
+  "originalCurrencyHash" varchar(200),
+  "receiverDescription" varchar(200),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "receiver_base_transactions_transactionId_INDEX" ON "receiver_base_transactions" ("transactionId");
+CREATE TRIGGER "receiver_base_transactions_updateTime" AFTER UPDATE ON "receiver_base_transactions" FOR EACH ROW BEGIN
+  UPDATE "receiver_base_transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "address_balances" (
+  "id" integer,
+  "currencyId" integer NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "address_balances_addressHash_INDEX" ON "address_balances" ("addressHash");
+CREATE TRIGGER "address_balances_updateTime" AFTER UPDATE ON "address_balances" FOR EACH ROW BEGIN
+  UPDATE "address_balances" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "currency_type_data" (
+  "id" integer,
+  "serviceDataId" integer NOT NULL,
+  "currencyType" varchar(200),
+  "currencyRateSourceType" varchar(200),
+  "rateSource" varchar(200),
+  "protectionModel" varchar(200),
+  "signerHash" varchar(200),
+  "currencyTypeDataCreateTime" decimal(20,6) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "currency_type_data_serviceDataId_INDEX" ON "currency_type_data" ("serviceDataId");
+CREATE TRIGGER "currency_type_data_updateTime" AFTER UPDATE ON "currency_type_data" FOR EACH ROW BEGIN
+  UPDATE "currency_type_data" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "originator_currency_data" (
+  "id" integer,
+  "serviceDataId" integer NOT NULL,
+  "name" varchar(200),
+  "symbol" varchar(200),
+  "description" varchar(500),
+  "originatorHash" varchar(200),
+  "totalSupply" decimal(25,10) NOT NULL,
+  "scale" integer NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "originator_currency_data_serviceDataId_INDEX" ON "originator_currency_data" ("serviceDataId");
+CREATE TRIGGER "originator_currency_data_updateTime" AFTER UPDATE ON "originator_currency_data" FOR EACH ROW BEGIN
+  UPDATE "originator_currency_data" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "token_generation_fee_base_transactions" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "fullnodeFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "token_generation_fee_base_transactions_transactionId_INDEX" ON "token_generation_fee_base_transactions" ("transactionId");
+CREATE TRIGGER "token_generation_fee_base_transactions_updateTime" AFTER UPDATE ON "token_generation_fee_base_transactions" FOR EACH ROW BEGIN
+  UPDATE "token_generation_fee_base_transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "token_minting_fee_base_transactions" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "tokenMintingFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "signerHash" varchar(200),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "token_minting_fee_base_transactions_transactionId_INDEX" ON "token_minting_fee_base_transactions" ("transactionId");
+CREATE TRIGGER "token_minting_fee_base_transactions_updateTime" AFTER UPDATE ON "token_minting_fee_base_transactions" FOR EACH ROW BEGIN
+  UPDATE "token_minting_fee_base_transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "token_minting_service_data" (
+  "id" integer,
+  "baseTransactionId" integer NOT NULL,
+  "mintingCurrencyHash" varchar(200) NOT NULL,
+  "mintingAmount" decimal(25,10) NOT NULL,
+  "serviceDataCreateTime" decimal(20,6) NOT NULL,
+  "receiverAddress" varchar(200) NOT NULL,
+  "feeAmount" decimal(25,10) NOT NULL,
+  "signerHash" varchar(200) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "token_minting_service_data_baseTransactionId_INDEX" ON "token_minting_service_data" ("baseTransactionId");
+CREATE TRIGGER "token_minting_service_data_updateTime" AFTER UPDATE ON "token_minting_service_data" FOR EACH ROW BEGIN
+  UPDATE "token_minting_service_data" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "token_generation_service_data" (
+  "id" integer,
+  "baseTransactionId" integer NOT NULL,
+  "feeAmount" decimal(25,10) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "token_generation_service_data_baseTransactionId_INDEX" ON "token_generation_service_data" ("baseTransactionId");
+CREATE TRIGGER "token_generation_service_data_updateTime" AFTER UPDATE ON "token_generation_service_data" FOR EACH ROW BEGIN
+  UPDATE "token_generation_service_data" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "event_input_base_transactions" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "eventInputCreateTime" decimal(20,6) NOT NULL,
+  "event" varchar(200) NOT NULL,
+  "hardFork" boolean,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "event_input_base_transactions_transactionId_INDEX" ON "event_input_base_transactions" ("transactionId");
+CREATE TRIGGER "event_input_base_transactions_updateTime" AFTER UPDATE ON "event_input_base_transactions" FOR EACH ROW BEGIN
+  UPDATE "event_input_base_transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "address_transaction_counts" (
+  "id" integer,
+  "count" integer NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "address_transaction_counts_addressHash_INDEX" ON "address_transaction_counts" ("addressHash");
+CREATE TRIGGER "address_transaction_counts_updateTime" AFTER UPDATE ON "address_transaction_counts" FOR EACH ROW BEGIN
+  UPDATE "address_transaction_counts" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "transaction_addresses" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "addressId" integer NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "transaction_addresses_updateTime" AFTER UPDATE ON "transaction_addresses" FOR EACH ROW BEGIN
+  UPDATE "transaction_addresses" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "addresses" (
+  "id" integer,
+  "addressHash" varchar(200) NOT NULL UNIQUE,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "addresses_addressHash_INDEX" ON "addresses" ("addressHash");
+CREATE TRIGGER "addresses_updateTime" AFTER UPDATE ON "addresses" FOR EACH ROW BEGIN
+  UPDATE "addresses" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "transaction_currencies" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "currencyId" integer NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "transaction_currencies_updateTime" AFTER UPDATE ON "transaction_currencies" FOR EACH ROW BEGIN
+  UPDATE "transaction_currencies" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
@@ -0,0 +1,15 @@
+CREATE TABLE "currency_supplies" (
+  "id" integer,
+  "currencyId" integer NOT NULL,
+  "mintedAmount" decimal(25,10) NOT NULL,
+  "burnedAmount" decimal(25,10) NOT NULL,
+  "circulatingSupply" decimal(25,10) NOT NULL,
+  "holderCount" integer NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "currency_supplies_currencyId_UNIQUE" ON "currency_supplies" ("currencyId");
+CREATE TRIGGER "currency_supplies_updateTime" AFTER UPDATE ON "currency_supplies" FOR EACH ROW BEGIN
+  UPDATE "currency_supplies" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "address_balances_addressHash_currencyId_UNIQUE";
@@ -0,0 +1,10 @@
+-- balances that were split over several rows are merged into the row with the lowest id before the unique key is added
+UPDATE "address_balances" SET "amount" = (
+  SELECT SUM(duplicates."amount") FROM "address_balances" duplicates
+  WHERE duplicates."addressHash" = "address_balances"."addressHash" AND duplicates."currencyId" = "address_balances"."currencyId"
+)
+WHERE "id" IN (SELECT MIN("id") FROM "address_balances" GROUP BY "addressHash", "currencyId" HAVING COUNT(*) > 1);
+
+DELETE FROM "address_balances" WHERE "id" NOT IN (SELECT MIN("id") FROM "address_balances" GROUP BY "addressHash", "currencyId");
+
+CREATE UNIQUE INDEX "address_balances_addressHash_currencyId_UNIQUE" ON "address_balances" ("addressHash","currencyId");
@@ -0,0 +1,2 @@
+ALTER TABLE "transactions" DROP COLUMN "confirmationPolicy";
+ALTER TABLE "transactions" DROP COLUMN "isSkipped";
@@ -0,0 +1,2 @@
+ALTER TABLE "transactions" ADD COLUMN "isSkipped" boolean DEFAULT false;
+ALTER TABLE "transactions" ADD COLUMN "confirmationPolicy" varchar(45);
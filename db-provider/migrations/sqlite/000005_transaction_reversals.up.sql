@@ -0,0 +1,17 @@
+ALTER TABLE "transactions" ADD COLUMN "isReversed" boolean DEFAULT false;
+
+CREATE TABLE "transaction_reversals" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "transactionHash" varchar(100) NOT NULL,
+  "reason" varchar(45) NOT NULL,
+  "confirmationPolicy" varchar(45),
+  "balanceDiff" text NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "transaction_reversals_transactionId_INDEX" ON "transaction_reversals" ("transactionId");
+CREATE TRIGGER "transaction_reversals_updateTime" AFTER UPDATE ON "transaction_reversals" FOR EACH ROW BEGIN
+  UPDATE "transaction_reversals" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "cluster_stamp_differences";
+DROP TABLE IF EXISTS "cluster_stamps";
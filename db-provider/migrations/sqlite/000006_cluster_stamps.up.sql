@@ -0,0 +1,32 @@
+CREATE TABLE "cluster_stamps" (
+  "id" integer,
+  "type" varchar(45) NOT NULL,
+  "fileName" varchar(500) NOT NULL DEFAULT '',
+  "hash" varchar(200) NOT NULL DEFAULT '',
+  "rowCount" integer NOT NULL,
+  "currencyTotals" text NOT NULL,
+  "transactionIndex" bigint,
+  "differenceCount" integer NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "cluster_stamps_updateTime" AFTER UPDATE ON "cluster_stamps" FOR EACH ROW BEGIN
+  UPDATE "cluster_stamps" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "cluster_stamp_differences" (
+  "id" integer,
+  "clusterStampId" integer NOT NULL,
+  "currencyHash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "stampAmount" decimal(25,10) NOT NULL,
+  "computedAmount" decimal(25,10) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "cluster_stamp_differences_clusterStampId_INDEX" ON "cluster_stamp_differences" ("clusterStampId");
+CREATE TRIGGER "cluster_stamp_differences_updateTime" AFTER UPDATE ON "cluster_stamp_differences" FOR EACH ROW BEGIN
+  UPDATE "cluster_stamp_differences" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
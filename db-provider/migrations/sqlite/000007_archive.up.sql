@@ -0,0 +1,191 @@
+-- archived data leaves the hot tables, the archive tables keep the rows of the table archive target
+-- the archive tables repeat the columns of their tables, CREATE TABLE AS SELECT would drop the datetime types the driver scans times by.
+CREATE TABLE "archive_batches" (
+  "id" integer,
+  "target" varchar(45) NOT NULL,
+  "location" varchar(500) NOT NULL DEFAULT '',
+  "transactionCount" integer NOT NULL,
+  "fromAttachmentTime" decimal(20,6) NOT NULL,
+  "toAttachmentTime" decimal(20,6) NOT NULL,
+  "maxIndex" integer NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE TRIGGER "archive_batches_updateTime" AFTER UPDATE ON "archive_batches" FOR EACH ROW BEGIN
+  UPDATE "archive_batches" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "archived_transactions" (
+  "id" integer NOT NULL,
+  "hash" varchar(100) NOT NULL,
+  "index" integer NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "archiveBatchId" integer NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "archived_transactions_hash_INDEX" ON "archived_transactions" ("hash");
+CREATE INDEX "archived_transactions_archiveBatchId_INDEX" ON "archived_transactions" ("archiveBatchId");
+CREATE TRIGGER "archived_transactions_updateTime" AFTER UPDATE ON "archived_transactions" FOR EACH ROW BEGIN
+  UPDATE "archived_transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
+
+CREATE TABLE "input_base_transactions_archive" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "inputCreateTime" decimal(20,6) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "receiver_base_transactions_archive" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "receiverCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10) NOT NULL,
+  "originalCurrencyHash" varchar(200),
+  "receiverDescription" varchar(200),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "fullnode_fee_base_transactions_archive" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "fullnodeFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "network_fee_base_transactions_archive" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "networkFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "reducedAmount" decimal(25,10),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "event_input_base_transactions_archive" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "eventInputCreateTime" decimal(20,6) NOT NULL,
+  "event" varchar(200) NOT NULL,
+  "hardFork" boolean,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "token_minting_service_data_archive" (
+  "id" integer,
+  "baseTransactionId" integer NOT NULL,
+  "mintingCurrencyHash" varchar(200) NOT NULL,
+  "mintingAmount" decimal(25,10) NOT NULL,
+  "serviceDataCreateTime" decimal(20,6) NOT NULL,
+  "receiverAddress" varchar(200) NOT NULL,
+  "feeAmount" decimal(25,10) NOT NULL,
+  "signerHash" varchar(200) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "token_minting_fee_base_transactions_archive" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "hash" varchar(200) NOT NULL,
+  "name" varchar(45) NOT NULL DEFAULT '',
+  "addressHash" varchar(200) NOT NULL,
+  "amount" decimal(25,10) NOT NULL,
+  "currencyHash" varchar(200),
+  "tokenMintingFeeCreateTime" decimal(20,6) NOT NULL,
+  "originalAmount" decimal(25,10),
+  "originalCurrencyHash" varchar(200),
+  "signerHash" varchar(200),
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "transaction_addresses_archive" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "addressId" integer NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "transaction_currencies_archive" (
+  "id" integer,
+  "transactionId" integer NOT NULL,
+  "currencyId" integer NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "transactions_archive" (
+  "id" integer,
+  "hash" varchar(100) NOT NULL,
+  "index" integer,
+  "amount" decimal(25,10) NOT NULL,
+  "attachmentTime" decimal(20,6) NOT NULL,
+  "isValid" boolean,
+  "transactionCreateTime" decimal(20,6) NOT NULL,
+  "leftParentHash" varchar(100),
+  "rightParentHash" varchar(100),
+  "nodeHash" varchar(128),
+  "senderHash" varchar(200),
+  "senderTrustScore" decimal(25,10) NOT NULL,
+  "transactionConsensusUpdateTime" decimal(20,6),
+  "transactionDescription" varchar(500),
+  "trustChainConsensus" boolean,
+  "trustChainTrustScore" decimal(25,10) NOT NULL,
+  "type" varchar(100) NOT NULL,
+  "isProcessed" boolean DEFAULT false,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "isSkipped" boolean DEFAULT false,
+  "confirmationPolicy" varchar(45),
+  "isReversed" boolean DEFAULT false,
+  PRIMARY KEY ("id")
+);
@@ -0,0 +1,16 @@
+-- the lease of the sync leader, only its holder runs the writer jobs and the fencing token grows with every new holder
+CREATE TABLE "leader_leases" (
+  "id" integer,
+  "name" varchar(100) NOT NULL,
+  "holder" varchar(200) NOT NULL DEFAULT '',
+  "fencingToken" bigint NOT NULL DEFAULT 0,
+  "renewTime" datetime NOT NULL,
+  "expireTime" datetime NOT NULL,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "leader_leases_name_UNIQUE" ON "leader_leases" ("name");
+CREATE TRIGGER "leader_leases_updateTime" AFTER UPDATE ON "leader_leases" FOR EACH ROW BEGIN
+  UPDATE "leader_leases" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
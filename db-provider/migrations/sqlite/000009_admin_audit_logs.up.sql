@@ -0,0 +1,17 @@
+-- every action of the admin api, whether it succeeded or not
+CREATE TABLE "admin_audit_logs" (
+  "id" integer,
+  "action" varchar(100) NOT NULL,
+  "target" varchar(100) NOT NULL DEFAULT '',
+  "actor" varchar(200) NOT NULL,
+  "params" text NOT NULL,
+  "isSuccess" boolean NOT NULL,
+  "error" varchar(1000) NOT NULL DEFAULT '',
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "admin_audit_logs_createTime_INDEX" ON "admin_audit_logs" ("createTime");
+CREATE TRIGGER "admin_audit_logs_updateTime" AFTER UPDATE ON "admin_audit_logs" FOR EACH ROW BEGIN
+  UPDATE "admin_audit_logs" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
@@ -0,0 +1,19 @@
+-- the api keys, only the sha256 hash of a key is kept and a rate limit of 0 is the default of the scope
+CREATE TABLE "api_keys" (
+  "id" integer,
+  "name" varchar(100) NOT NULL,
+  "prefix" varchar(20) NOT NULL,
+  "keyHash" char(64) NOT NULL,
+  "scope" varchar(20) NOT NULL,
+  "rateLimit" real NOT NULL DEFAULT 0,
+  "rateLimitBurst" integer NOT NULL DEFAULT 0,
+  "isRevoked" boolean NOT NULL DEFAULT false,
+  "createTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updateTime" datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "api_keys_name_UNIQUE" ON "api_keys" ("name");
+CREATE UNIQUE INDEX "api_keys_keyHash_UNIQUE" ON "api_keys" ("keyHash");
+CREATE TRIGGER "api_keys_updateTime" AFTER UPDATE ON "api_keys" FOR EACH ROW BEGIN
+  UPDATE "api_keys" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
@@ -0,0 +1,9 @@
+-- the reversal window starts when a transaction is processed, the update time moves on every change of its consensus
+ALTER TABLE "transactions" ADD COLUMN "processedTime" datetime;
+CREATE INDEX "transactions_processedTime_INDEX" ON "transactions" ("processedTime");
+ALTER TABLE "transactions_archive" ADD COLUMN "processedTime" datetime;
+DROP TRIGGER "transactions_updateTime";
+UPDATE "transactions" SET "processedTime" = "updateTime" WHERE "isProcessed" = true;
+CREATE TRIGGER "transactions_updateTime" AFTER UPDATE ON "transactions" FOR EACH ROW BEGIN
+  UPDATE "transactions" SET "updateTime" = CURRENT_TIMESTAMP WHERE "id" = NEW."id";
+END;
@@ -0,0 +1,151 @@
+package dbProvider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PartitionKey string
+
+const (
+	IndexPartitionKey          PartitionKey = "index"
+	AttachmentTimePartitionKey PartitionKey = "attachmentTime"
+)
+
+// maxValuePartition is the open partition the new rows go to until it is split
+const maxValuePartition = "pmax"
+
+// partitionedTables are range partitioned by the transaction id, mysql requires the partitioning column in every unique key
+// so the transaction tables can't be partitioned by the nullable index. The ranges are aligned with the index or the attachment time
+// by starting each partition at the lowest transaction id of a key range, rows inserted out of order go to a neighbour partition
+var partitionedTables = []struct {
+	name   string
+	column string
+}{
+	{"transactions", "id"},
+	{"input_base_transactions", "transactionId"},
+	{"receiver_base_transactions", "transactionId"},
+	{"fullnode_fee_base_transactions", "transactionId"},
+	{"network_fee_base_transactions", "transactionId"},
+	{"event_input_base_transactions", "transactionId"},
+	{"token_generation_fee_base_transactions", "transactionId"},
+	{"token_minting_fee_base_transactions", "transactionId"},
+	{"transaction_addresses", "transactionId"},
+	{"transaction_currencies", "transactionId"},
+}
+
+// GetPartitionConfig reads the partition key and the size of a partition in indexes or attachment time seconds
+// from PARTITION_BY and PARTITION_SIZE
+func GetPartitionConfig() (PartitionKey, int64, error) {
+	partitionConfig := config.Get().Partition
+	if partitionConfig.By == "" {
+		return "", 0, fmt.Errorf("PARTITION_BY must be index or attachmentTime, it is not set")
+	}
+	return PartitionKey(partitionConfig.By), partitionConfig.Size, nil
+}
+
+// PartitionTables partitions the transaction tables by ranges of size indexes or attachment time seconds,
+// tables that are already partitioned get the ranges reached since they were last split. It is only supported on mysql
+func PartitionTables(key PartitionKey, size int64) error {
+	if dialect != MysqlDialect {
+		return fmt.Errorf("partitioning is only supported on mysql, the %s tables are not partitioned", dialect)
+	}
+	boundaries, err := getPartitionBoundaries(DB, key, size)
+	if err != nil {
+		return err
+	}
+	for _, table := range partitionedTables {
+		lastBoundary, isPartitioned, err := getLastPartitionBoundary(DB, table.name)
+		if err != nil {
+			return err
+		}
+		var newBoundaries []int64
+		for _, boundary := range boundaries {
+			if boundary > lastBoundary {
+				newBoundaries = append(newBoundaries, boundary)
+			}
+		}
+		if isPartitioned && len(newBoundaries) == 0 {
+			continue
+		}
+		if !isPartitioned && table.column != "id" {
+			// the partitioning column has to be part of the primary key
+			err = DB.Exec("ALTER TABLE ? DROP PRIMARY KEY, ADD PRIMARY KEY (?, ?)", clause.Table{Name: table.name}, clause.Column{Name: "id"}, clause.Column{Name: table.column}).Error
+			if err != nil {
+				return err
+			}
+		}
+		partitions := make([]string, 0, len(newBoundaries)+1)
+		for _, boundary := range newBoundaries {
+			partitions = append(partitions, fmt.Sprintf("PARTITION p%d VALUES LESS THAN (%d)", boundary, boundary))
+		}
+		partitions = append(partitions, "PARTITION "+maxValuePartition+" VALUES LESS THAN MAXVALUE")
+		if isPartitioned {
+			err = DB.Exec("ALTER TABLE ? REORGANIZE PARTITION "+maxValuePartition+" INTO ("+strings.Join(partitions, ", ")+")", clause.Table{Name: table.name}).Error
+		} else {
+			err = DB.Exec("ALTER TABLE ? PARTITION BY RANGE (?) ("+strings.Join(partitions, ", ")+")", clause.Table{Name: table.name}, clause.Column{Name: table.column}).Error
+		}
+		if err != nil {
+			return err
+		}
+		logrus.WithFields(logrus.Fields{"table": table.name, "newPartitions": len(newBoundaries)}).Info("table partitioned")
+	}
+	return nil
+}
+
+// getPartitionBoundaries returns the lowest transaction id of every key range but the first, in increasing order
+func getPartitionBoundaries(db *gorm.DB, key PartitionKey, size int64) ([]int64, error) {
+	var ranges []struct {
+		RangeStartId int64 `gorm:"column:rangeStartId"`
+	}
+	bucket := gorm.Expr("FLOOR(? / ?)", clause.Column{Name: string(key)}, size)
+	err := db.Table("transactions").
+		Select("MIN(?) AS rangeStartId, ? AS bucket", clause.Column{Name: "id"}, bucket).
+		Not(map[string]interface{}{string(key): nil}).
+		Group("bucket").Order("bucket").
+		Scan(&ranges).Error
+	if err != nil {
+		return nil, err
+	}
+	var boundaries []int64
+	var lastRangeStartId int64
+	for i, r := range ranges {
+		// a range starting below an earlier one was inserted out of order and stays in the earlier partition
+		if r.RangeStartId <= lastRangeStartId {
+			continue
+		}
+		if i > 0 {
+			boundaries = append(boundaries, r.RangeStartId)
+		}
+		lastRangeStartId = r.RangeStartId
+	}
+	return boundaries, nil
+}
+
+// getLastPartitionBoundary returns the highest bounded partition of the table and if the table is partitioned
+func getLastPartitionBoundary(db *gorm.DB, table string) (int64, bool, error) {
+	var descriptions []string
+	err := db.Raw("SELECT PARTITION_DESCRIPTION FROM information_schema.PARTITIONS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL", table).
+		Scan(&descriptions).Error
+	if err != nil {
+		return 0, false, err
+	}
+	var lastBoundary int64
+	for _, description := range descriptions {
+		boundary, err := strconv.ParseInt(description, 10, 64)
+		if err != nil {
+			// MAXVALUE
+			continue
+		}
+		if boundary > lastBoundary {
+			lastBoundary = boundary
+		}
+	}
+	return lastBoundary, len(descriptions) > 0, nil
+}
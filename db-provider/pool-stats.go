@@ -0,0 +1,42 @@
+package dbProvider
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+)
+
+// PoolStats is the connection pool usage of the primary or of a read replica
+type PoolStats struct {
+	Name string
+	// IsUsable is false for a replica that failed the lag check
+	IsUsable bool
+	Stats    sql.DBStats
+}
+
+// GetPoolStats returns the pool stats of the primary followed by the read replicas
+func GetPoolStats() ([]PoolStats, error) {
+	primary, err := DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	poolStats := []PoolStats{{Name: "primary", IsUsable: true, Stats: primary.Stats()}}
+	for i, replica := range replicas {
+		poolStats = append(poolStats, PoolStats{
+			Name:     fmt.Sprintf("replica-%d", i),
+			IsUsable: atomic.LoadInt32(&replica.isUsable) == 1,
+			Stats:    replica.pool.Stats(),
+		})
+	}
+	return poolStats, nil
+}
+
+// Ping checks the connection to the primary
+func Ping(ctx context.Context) error {
+	primary, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return primary.PingContext(ctx)
+}
@@ -0,0 +1,168 @@
+package dbProvider
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ReadDB is the handle of the api queries, it reads from a replica that is not behind the primary and from DB when there is none.
+// It can't begin transactions and must not be used for writes or locking reads
+var ReadDB *gorm.DB
+
+// replicas are the read replicas of ReadDB
+var replicas []*readReplica
+
+type readReplica struct {
+	dsn  string
+	db   *gorm.DB
+	pool *sql.DB
+	// isUsable is set by the lag check, a replica is not used before it is checked
+	isUsable int32
+}
+
+// replicaPool routes the queries of ReadDB, statements that don't return rows always go to the primary
+type replicaPool struct {
+	primary  *sql.DB
+	replicas []*readReplica
+	next     uint32
+}
+
+// initReplicas opens the replicas of DB_READ_REPLICA_DSNS, ReadDB is DB when no replica is set
+func initReplicas() {
+	ReadDB = DB
+	dsns := currentConfig.ReadReplicaDsns
+	if len(dsns) == 0 {
+		return
+	}
+	primary, err := DB.DB()
+	if err != nil {
+		panic(err)
+	}
+	pool := &replicaPool{primary: primary}
+	for _, dsn := range dsns {
+		db, err := gorm.Open(newReplicaDialector(dialect, dsn), newGormConfig())
+		if err != nil {
+			panic(fmt.Sprintf("failed to connect read replica %d", len(pool.replicas)))
+		}
+		replicaDB, err := db.DB()
+		if err != nil {
+			panic(err)
+		}
+		applyPool(replicaDB, currentConfig)
+		pool.replicas = append(pool.replicas, &readReplica{dsn: dsn, db: db, pool: replicaDB})
+	}
+	replicas = pool.replicas
+	ReadDB = DB.Session(&gorm.Session{NewDB: true})
+	ReadDB.Statement.ConnPool = pool
+	pool.checkLag()
+	go pool.monitorLag()
+}
+
+// read picks the next usable replica round robin and falls back to the primary
+func (pool *replicaPool) read() *sql.DB {
+	start := atomic.AddUint32(&pool.next, 1)
+	for i := 0; i < len(pool.replicas); i++ {
+		replica := pool.replicas[(int(start)+i)%len(pool.replicas)]
+		if atomic.LoadInt32(&replica.isUsable) == 1 {
+			return replica.pool
+		}
+	}
+	return pool.primary
+}
+
+func (pool *replicaPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return pool.primary.PrepareContext(ctx, query)
+}
+
+func (pool *replicaPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return pool.primary.ExecContext(ctx, query, args...)
+}
+
+func (pool *replicaPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return pool.read().QueryContext(ctx, query, args...)
+}
+
+func (pool *replicaPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return pool.read().QueryRowContext(ctx, query, args...)
+}
+
+func (pool *replicaPool) monitorLag() {
+	for {
+		time.Sleep(currentConfig.ReplicaLagCheckInterval)
+		pool.checkLag()
+	}
+}
+
+// checkLag marks the replicas whose last monitored transaction index is within DB_REPLICA_MAX_LAG of the primary as usable
+func (pool *replicaPool) checkLag() {
+	maxLag := currentConfig.ReplicaMaxLag
+	primaryIndex, err := getLastMonitoredTransactionIndex(DB)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to read the primary index, the replicas are not used")
+		pool.setUsable(func(*readReplica) bool { return false })
+		return
+	}
+	pool.setUsable(func(replica *readReplica) bool {
+		replicaIndex, err := getLastMonitoredTransactionIndex(replica.db)
+		if err != nil {
+			logrus.WithError(err).WithField("replica", replica.host()).Warn("failed to read the replica index")
+			return false
+		}
+		if primaryIndex-replicaIndex > maxLag {
+			logrus.WithFields(logrus.Fields{"replica": replica.host(), "lag": primaryIndex - replicaIndex}).Warn("replica is behind the primary")
+			return false
+		}
+		return true
+	})
+}
+
+func (pool *replicaPool) setUsable(isUsable func(replica *readReplica) bool) {
+	var wg sync.WaitGroup
+	for _, replica := range pool.replicas {
+		wg.Add(1)
+		go func(replica *readReplica) {
+			defer wg.Done()
+			var usable int32
+			if isUsable(replica) {
+				usable = 1
+			}
+			atomic.StoreInt32(&replica.isUsable, usable)
+		}(replica)
+	}
+	wg.Wait()
+}
+
+// host is the part of the dsn after the credentials so it can be logged
+func (replica *readReplica) host() string {
+	if i := strings.LastIndex(replica.dsn, "@"); i >= 0 {
+		return replica.dsn[i+1:]
+	}
+	for _, part := range strings.Fields(replica.dsn) {
+		if strings.HasPrefix(part, "host=") {
+			return strings.TrimPrefix(part, "host=")
+		}
+	}
+	return "replica"
+}
+
+func getLastMonitoredTransactionIndex(db *gorm.DB) (int64, error) {
+	var appState entities.AppState
+	err := db.Where("name = ?", entities.LastMonitoredTransactionIndex).First(&appState).Error
+	if err != nil {
+		return 0, err
+	}
+	if appState.Value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(appState.Value, 10, 64)
+}
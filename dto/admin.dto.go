@@ -0,0 +1,70 @@
+package dto
+
+import "time"
+
+type AdminJobResponse struct {
+	Name              string  `json:"name"`
+	IsPaused          bool    `json:"isPaused"`
+	IntervalInSeconds float64 `json:"intervalInSeconds"`
+	// BatchSize is omitted for the jobs that have none
+	BatchSize int `json:"batchSize,omitempty"`
+}
+
+type AdminJobsResponse struct {
+	IsLeader           bool               `json:"isLeader"`
+	CurrentFullnodeUrl string             `json:"currentFullnodeUrl"`
+	Jobs               []AdminJobResponse `json:"jobs"`
+}
+
+type AdminIterationResultResponse struct {
+	Iteration    int       `json:"iteration"`
+	Trigger      string    `json:"trigger"`
+	StartTime    time.Time `json:"startTime"`
+	DurationInMs int64     `json:"durationInMs"`
+	Error        string    `json:"error,omitempty"`
+}
+
+type AdminJobSettingsRequest struct {
+	IntervalInSeconds *float64 `json:"intervalInSeconds"`
+	BatchSize         *int     `json:"batchSize"`
+}
+
+type AdminSwitchFullnodeRequest struct {
+	// Node is main or backup, the sync switches to the other node when it is empty
+	Node string `json:"node"`
+}
+
+type AdminSetLastMonitoredIndexRequest struct {
+	Index *int64 `json:"index"`
+	// Force allows moving the index forward, the transactions in between are skipped
+	Force bool `json:"force"`
+}
+
+type AdminSetLastMonitoredIndexResponse struct {
+	PreviousIndex int64 `json:"previousIndex"`
+	Index         int64 `json:"index"`
+}
+
+type AdminCreateApiKeyRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	// RateLimit is in requests per second, a limit of 0 is the default of the config
+	RateLimit      float64 `json:"rateLimit"`
+	RateLimitBurst int     `json:"rateLimitBurst"`
+}
+
+type AdminApiKeyResponse struct {
+	Name           string    `json:"name"`
+	Prefix         string    `json:"prefix"`
+	Scope          string    `json:"scope"`
+	RateLimit      float64   `json:"rateLimit"`
+	RateLimitBurst int       `json:"rateLimitBurst"`
+	IsRevoked      bool      `json:"isRevoked"`
+	CreateTime     time.Time `json:"createTime"`
+}
+
+type AdminCreateApiKeyResponse struct {
+	AdminApiKeyResponse
+	// Key is only returned when the key is created, it can't be read again
+	Key string `json:"key"`
+}
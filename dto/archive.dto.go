@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+// ArchiveResponse tells where an archived record was moved to
+type ArchiveResponse struct {
+	BatchId     int32     `json:"batchId"`
+	Target      string    `json:"target"`
+	Location    string    `json:"location"`
+	ArchiveTime time.Time `json:"archiveTime"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type ConfigSettingResponse struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "github.com/shopspring/decimal"
+
+type CurrencySupplyResponse struct {
+	CurrencyHash      string          `json:"currencyHash"`
+	MintedAmount      decimal.Decimal `json:"mintedAmount"`
+	BurnedAmount      decimal.Decimal `json:"burnedAmount"`
+	CirculatingSupply decimal.Decimal `json:"circulatingSupply"`
+	HolderCount       int32           `json:"holderCount"`
+}
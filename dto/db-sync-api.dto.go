@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type SyncResponse struct {
 	NodeMaxIndex                      int64   `json:"nodeMaxIndex"`
 	NodeLastIndex                     int64   `json:"nodeLastIndex"`
@@ -9,3 +11,32 @@ type SyncResponse struct {
 	SyncPercentage                    float64 `json:"syncPercentage"`
 	IsNodeSynced                      bool    `json:"isNodeSynced"`
 }
+
+// SyncStateV1Response is the response of /v1/sync-state, its fields are not renamed or removed within the version
+type SyncStateV1Response struct {
+	LastMonitoredTransactionIndex int64 `json:"lastMonitoredTransactionIndex"`
+	// NodeLastIndex is the greater of the fullnode indexes
+	NodeLastIndex         int64   `json:"nodeLastIndex"`
+	MainNodeLastIndex     int64   `json:"mainNodeLastIndex"`
+	BackupNodeLastIndex   int64   `json:"backupNodeLastIndex"`
+	RemainingTransactions int64   `json:"remainingTransactions"`
+	SyncPercentage        float64 `json:"syncPercentage"`
+	IsSynced              bool    `json:"isSynced"`
+	// the rates are transactions per second over the last RateWindowInSeconds
+	TransactionsPerSecond     float64 `json:"transactionsPerSecond"`
+	NodeTransactionsPerSecond float64 `json:"nodeTransactionsPerSecond"`
+	RateWindowInSeconds       float64 `json:"rateWindowInSeconds"`
+	// EtaInSeconds is null when the sync doesn't catch up with the fullnodes at the current rates
+	EtaInSeconds *float64             `json:"etaInSeconds"`
+	Backlogs     SyncBacklogsResponse `json:"backlogs"`
+	UpdateTime   time.Time            `json:"updateTime"`
+}
+
+type SyncBacklogsResponse struct {
+	// BalanceProcessing are the confirmed transactions whose balances are not updated yet
+	BalanceProcessing int64 `json:"balanceProcessing"`
+	// Monitor are the indexed transactions without a consensus yet
+	Monitor int64 `json:"monitor"`
+	// PendingUnindexed are the transactions the fullnode didn't index yet
+	PendingUnindexed int64 `json:"pendingUnindexed"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type DbPoolStatsResponse struct {
+	Name               string `json:"name"`
+	IsUsable           bool   `json:"isUsable"`
+	MaxOpenConnections int    `json:"maxOpenConnections"`
+	OpenConnections    int    `json:"openConnections"`
+	InUse              int    `json:"inUse"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"waitCount"`
+	WaitDurationInMs   int64  `json:"waitDurationInMs"`
+	MaxIdleClosed      int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64  `json:"maxLifetimeClosed"`
+}
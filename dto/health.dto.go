@@ -0,0 +1,13 @@
+package dto
+
+type HealthCheckResponse struct {
+	Status       string                 `json:"status"`
+	Error        string                 `json:"error,omitempty"`
+	DurationInMs int64                  `json:"durationInMs"`
+	Details      map[string]interface{} `json:"details,omitempty"`
+}
+
+type HealthResponse struct {
+	Status string                         `json:"status"`
+	Checks map[string]HealthCheckResponse `json:"checks"`
+}
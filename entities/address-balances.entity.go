@@ -7,12 +7,12 @@ import (
 )
 
 type AddressBalance struct {
-	ID          int32           `json:"id" gorm:"column:id;type:int(11) NOT NULL AUTO_INCREMENT"`
-	CurrencyId  int32           `json:"currencyId" gorm:"column:currencyId;type:int(11) NOT NULL"`
-	AddressHash string          `json:"addressHash" gorm:"column:addressHash;type:varchar(200) COLLATE utf8_unicode_ci NOT NULL;index:addressHash_INDEX"`
-	Amount      decimal.Decimal `json:"amount" gorm:"column:amount;type:decimal(25,10) NOT NULL"`
-	CreateTime  time.Time       `json:"createTime" gorm:"column:createTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP;"`
-	UpdateTime  time.Time       `json:"updateTime" gorm:"column:updateTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;"`
+	ID          int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	CurrencyId  int32           `json:"currencyId" gorm:"column:currencyId;not null;uniqueIndex:addressHash_currencyId_UNIQUE,priority:2"`
+	AddressHash string          `json:"addressHash" gorm:"column:addressHash;size:200;not null;index:addressHash_INDEX;uniqueIndex:addressHash_currencyId_UNIQUE,priority:1"`
+	Amount      decimal.Decimal `json:"amount" gorm:"column:amount;type:decimal(25,10);not null"`
+	CreateTime  time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime  time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
 }
 
 func NewAddressBalanceFromClusterStamp(clusterStampDataRow *dto.ClusterStampDataRow) *AddressBalance {
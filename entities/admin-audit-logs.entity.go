@@ -0,0 +1,19 @@
+package entities
+
+import (
+	"time"
+)
+
+type AdminAuditLog struct {
+	ID     int32  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Action string `json:"action" gorm:"column:action;size:100;not null"`
+	// Target is the job or the setting the action is applied to
+	Target string `json:"target" gorm:"column:target;size:100;not null;default:''"`
+	Actor  string `json:"actor" gorm:"column:actor;size:200;not null"`
+	// Params are the json params of the request
+	Params     string    `json:"params" gorm:"column:params;type:text;not null"`
+	IsSuccess  bool      `json:"isSuccess" gorm:"column:isSuccess;not null"`
+	Error      string    `json:"error" gorm:"column:error;size:1000;not null;default:''"`
+	CreateTime time.Time `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP;index:createTime_INDEX"`
+	UpdateTime time.Time `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
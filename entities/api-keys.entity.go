@@ -0,0 +1,42 @@
+package entities
+
+import (
+	"time"
+)
+
+// ApiKeyScope is what an api key may call, every scope includes the scopes before it
+type ApiKeyScope string
+
+const (
+	PublicScope ApiKeyScope = "public"
+	ReadScope   ApiKeyScope = "read"
+	AdminScope  ApiKeyScope = "admin"
+)
+
+var apiKeyScopeRanks = map[ApiKeyScope]int{PublicScope: 0, ReadScope: 1, AdminScope: 2}
+
+// IsValid is false for the scopes that don't exist
+func (scope ApiKeyScope) IsValid() bool {
+	_, ok := apiKeyScopeRanks[scope]
+	return ok
+}
+
+// Includes is true when scope may call the routes of other
+func (scope ApiKeyScope) Includes(other ApiKeyScope) bool {
+	return scope.IsValid() && other.IsValid() && apiKeyScopeRanks[scope] >= apiKeyScopeRanks[other]
+}
+
+type ApiKey struct {
+	ID   int32  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name string `json:"name" gorm:"column:name;size:100;not null;uniqueIndex:name_UNIQUE"`
+	// Prefix is the start of the key, it tells the keys apart without keeping them
+	Prefix  string      `json:"prefix" gorm:"column:prefix;size:20;not null"`
+	KeyHash string      `json:"-" gorm:"column:keyHash;type:char(64);not null;uniqueIndex:keyHash_UNIQUE"`
+	Scope   ApiKeyScope `json:"scope" gorm:"column:scope;size:20;not null"`
+	// RateLimit is the requests per second of the key and RateLimitBurst the requests it may send at once, 0 is the default of the config
+	RateLimit      float64   `json:"rateLimit" gorm:"column:rateLimit;not null;default:0"`
+	RateLimitBurst int       `json:"rateLimitBurst" gorm:"column:rateLimitBurst;not null;default:0"`
+	IsRevoked      bool      `json:"isRevoked" gorm:"column:isRevoked;not null;default:false"`
+	CreateTime     time.Time `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime     time.Time `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
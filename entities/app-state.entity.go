@@ -12,12 +12,18 @@ const (
 	IsClusterStampInitialized     AppStatesNames = "isClusterStampInitialized"
 	UpdateBalances                AppStatesNames = "updateBalances"
 	DeleteUnindexedTransactions   AppStatesNames = "deleteUnindexedTransactions"
+	ClusterStampHash              AppStatesNames = "clusterStampHash"
+	ClusterStampRowCount          AppStatesNames = "clusterStampRowCount"
+	ClusterStampTotal             AppStatesNames = "clusterStampTotal"
+	ArchiveTransactions           AppStatesNames = "archiveTransactions"
+	RebasedTransactionIndex       AppStatesNames = "rebasedTransactionIndex"
+	MonitoredProcessedId          AppStatesNames = "monitoredProcessedId"
 )
 
 type AppState struct {
-	ID         int32          `json:"id" gorm:"column:id;type:int(11) NOT NULL AUTO_INCREMENT"`
-	Name       AppStatesNames `json:"name" gorm:"column:name;type:varchar(100) COLLATE utf8_unicode_ci NOT NULL;index:name_INDEX"`
-	Value      string         `json:"value" gorm:"column:value;type:varchar(1000) COLLATE utf8_unicode_ci DEFAULT ''"`
-	CreateTime time.Time      `json:"createTime" gorm:"column:createTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP;"`
-	UpdateTime time.Time      `json:"updateTime" gorm:"column:updateTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;"`
+	ID         int32          `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name       AppStatesNames `json:"name" gorm:"column:name;size:100;not null;index:name_INDEX"`
+	Value      string         `json:"value" gorm:"column:value;size:1000;default:''"`
+	CreateTime time.Time      `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime time.Time      `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
 }
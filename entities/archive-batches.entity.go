@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type ArchiveTarget string
+
+const (
+	// TableArchiveTarget moves the rows to the <table>_archive tables
+	TableArchiveTarget ArchiveTarget = "table"
+	// CsvArchiveTarget writes the rows to gzipped csv files, one directory per batch
+	CsvArchiveTarget ArchiveTarget = "csv"
+)
+
+type ArchiveBatch struct {
+	ID                 int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Target             ArchiveTarget   `json:"target" gorm:"column:target;size:45;not null"`
+	Location           string          `json:"location" gorm:"column:location;size:500;not null;default:''"`
+	TransactionCount   int32           `json:"transactionCount" gorm:"column:transactionCount;not null"`
+	FromAttachmentTime decimal.Decimal `json:"fromAttachmentTime" gorm:"column:fromAttachmentTime;type:decimal(20,6);not null"`
+	ToAttachmentTime   decimal.Decimal `json:"toAttachmentTime" gorm:"column:toAttachmentTime;type:decimal(20,6);not null"`
+	MaxIndex           int32           `json:"maxIndex" gorm:"column:maxIndex;not null"`
+	CreateTime         time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime         time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// ArchivedTransaction is kept for every archived transaction so a lookup by hash can tell it was archived
+type ArchivedTransaction struct {
+	ID             int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement:false"`
+	Hash           string          `json:"hash" gorm:"column:hash;size:100;not null;index:hash_INDEX"`
+	Index          int32           `json:"index" gorm:"column:index;not null"`
+	AttachmentTime decimal.Decimal `json:"attachmentTime" gorm:"column:attachmentTime;type:decimal(20,6);not null"`
+	ArchiveBatchId int32           `json:"archiveBatchId" gorm:"column:archiveBatchId;not null;index:archiveBatchId_INDEX"`
+	CreateTime     time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime     time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func NewArchivedTransaction(tx *Transaction, archiveBatchId int32) *ArchivedTransaction {
+	instance := new(ArchivedTransaction)
+	instance.ID = tx.ID
+	instance.Hash = tx.Hash
+	instance.Index = *tx.Index
+	instance.AttachmentTime = tx.AttachmentTime
+	instance.ArchiveBatchId = archiveBatchId
+	return instance
+}
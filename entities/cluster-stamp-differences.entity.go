@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type ClusterStampDifference struct {
+	ID             int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ClusterStampId int32           `json:"clusterStampId" gorm:"column:clusterStampId;not null;index:clusterStampId_INDEX"`
+	CurrencyHash   string          `json:"currencyHash" gorm:"column:currencyHash;size:200;not null"`
+	AddressHash    string          `json:"addressHash" gorm:"column:addressHash;size:200;not null"`
+	StampAmount    decimal.Decimal `json:"stampAmount" gorm:"column:stampAmount;type:decimal(25,10);not null"`
+	ComputedAmount decimal.Decimal `json:"computedAmount" gorm:"column:computedAmount;type:decimal(25,10);not null"`
+	CreateTime     time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime     time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func NewClusterStampDifference(clusterStampId int32, currencyHash string, addressHash string, stampAmount decimal.Decimal, computedAmount decimal.Decimal) *ClusterStampDifference {
+	instance := new(ClusterStampDifference)
+	instance.ClusterStampId = clusterStampId
+	instance.CurrencyHash = currencyHash
+	instance.AddressHash = addressHash
+	instance.StampAmount = stampAmount
+	instance.ComputedAmount = computedAmount
+	return instance
+}
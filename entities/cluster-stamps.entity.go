@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"time"
+)
+
+type ClusterStampType string
+
+const (
+	InitialClusterStamp ClusterStampType = "initial"
+	RebaseClusterStamp  ClusterStampType = "rebase"
+	// LegacyClusterStamp is a stamp loaded before the cluster stamp history was kept
+	LegacyClusterStamp ClusterStampType = "legacy"
+)
+
+type ClusterStamp struct {
+	ID               int32            `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Type             ClusterStampType `json:"type" gorm:"column:type;size:45;not null"`
+	FileName         string           `json:"fileName" gorm:"column:fileName;size:500;not null;default:''"`
+	Hash             string           `json:"hash" gorm:"column:hash;size:200;not null;default:''"`
+	RowCount         int32            `json:"rowCount" gorm:"column:rowCount;not null"`
+	CurrencyTotals   string           `json:"currencyTotals" gorm:"column:currencyTotals;type:text;not null"`
+	TransactionIndex *int64           `json:"transactionIndex" gorm:"column:transactionIndex"`
+	DifferenceCount  int32            `json:"differenceCount" gorm:"column:differenceCount;not null"`
+	CreateTime       time.Time        `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime       time.Time        `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func NewClusterStamp(clusterStampType ClusterStampType, fileName string, hash string, rowCount int32, currencyTotals string, transactionIndex *int64) *ClusterStamp {
+	instance := new(ClusterStamp)
+	instance.Type = clusterStampType
+	instance.FileName = fileName
+	instance.Hash = hash
+	instance.RowCount = rowCount
+	instance.CurrencyTotals = currencyTotals
+	instance.TransactionIndex = transactionIndex
+	return instance
+}
@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type CurrencySupply struct {
+	ID                int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	CurrencyId        int32           `json:"currencyId" gorm:"column:currencyId;not null;uniqueIndex:currencyId_UNIQUE"`
+	MintedAmount      decimal.Decimal `json:"mintedAmount" gorm:"column:mintedAmount;type:decimal(25,10);not null"`
+	BurnedAmount      decimal.Decimal `json:"burnedAmount" gorm:"column:burnedAmount;type:decimal(25,10);not null"`
+	CirculatingSupply decimal.Decimal `json:"circulatingSupply" gorm:"column:circulatingSupply;type:decimal(25,10);not null"`
+	HolderCount       int32           `json:"holderCount" gorm:"column:holderCount;not null"`
+	CreateTime        time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime        time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func NewCurrencySupply(currencyId int32, circulatingSupply decimal.Decimal, holderCount int32) *CurrencySupply {
+	instance := new(CurrencySupply)
+	instance.CurrencyId = currencyId
+	instance.MintedAmount = decimal.Zero
+	instance.BurnedAmount = decimal.Zero
+	instance.CirculatingSupply = circulatingSupply
+	instance.HolderCount = holderCount
+	return instance
+}
@@ -8,16 +8,16 @@ import (
 )
 
 type CurrencyTypeData struct {
-	ID                         int32           `json:"id" gorm:"column:id;type:int(11) NOT NULL AUTO_INCREMENT"`
-	ServiceDataId              int32           `json:"serviceDataId" gorm:"column:serviceDataId;type:int(11) NOT NULL;index:serviceDataId_INDEX"`
-	CurrencyType               *string         `json:"currencyType" gorm:"column:currencyType;type:varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	CurrencyRateSourceType     *string         `json:"currencyRateSourceType" gorm:"column:currencyRateSourceType;type:varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	RateSource                 *string         `json:"rateSource" gorm:"column:rateSource;type:varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	ProtectionModel            *string         `json:"protectionModel" gorm:"column:protectionModel;type:varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	SignerHash                 *string         `json:"signerHash" gorm:"column:signerHash;type:varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	CurrencyTypeDataCreateTime decimal.Decimal `json:"currencyTypeDataCreateTime" gorm:"column:currencyTypeDataCreateTime;type:decimal(20,6) NOT NULL"`
-	CreateTime                 time.Time       `json:"createTime" gorm:"column:createTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP;"`
-	UpdateTime                 time.Time       `json:"updateTime" gorm:"column:updateTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;"`
+	ID                         int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ServiceDataId              int32           `json:"serviceDataId" gorm:"column:serviceDataId;not null;index:serviceDataId_INDEX"`
+	CurrencyType               *string         `json:"currencyType" gorm:"column:currencyType;size:200"`
+	CurrencyRateSourceType     *string         `json:"currencyRateSourceType" gorm:"column:currencyRateSourceType;size:200"`
+	RateSource                 *string         `json:"rateSource" gorm:"column:rateSource;size:200"`
+	ProtectionModel            *string         `json:"protectionModel" gorm:"column:protectionModel;size:200"`
+	SignerHash                 *string         `json:"signerHash" gorm:"column:signerHash;size:200"`
+	CurrencyTypeDataCreateTime decimal.Decimal `json:"currencyTypeDataCreateTime" gorm:"column:currencyTypeDataCreateTime;type:decimal(20,6);not null"`
+	CreateTime                 time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime                 time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
 }
 
 func (CurrencyTypeData) TableName() string {
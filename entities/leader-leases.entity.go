@@ -0,0 +1,21 @@
+package entities
+
+import (
+	"time"
+)
+
+// SyncLeaderLease is the lease whose holder runs the writer jobs of the sync
+const SyncLeaderLease = "sync"
+
+type LeaderLease struct {
+	ID   int32  `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name string `json:"name" gorm:"column:name;size:100;not null;uniqueIndex:name_UNIQUE"`
+	// Holder is the id of the instance holding the lease, empty when it was released
+	Holder string `json:"holder" gorm:"column:holder;size:200;not null;default:''"`
+	// FencingToken grows every time the lease changes holder, a write fenced with an older token is rejected
+	FencingToken int64     `json:"fencingToken" gorm:"column:fencingToken;not null;default:0"`
+	RenewTime    time.Time `json:"renewTime" gorm:"column:renewTime;not null"`
+	ExpireTime   time.Time `json:"expireTime" gorm:"column:expireTime;not null"`
+	CreateTime   time.Time `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime   time.Time `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
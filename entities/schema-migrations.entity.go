@@ -0,0 +1,18 @@
+package entities
+
+import (
+	"time"
+)
+
+type SchemaMigration struct {
+	Version     int64     `json:"version" gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name        string    `json:"name" gorm:"column:name;size:200;not null"`
+	AppliedTime time.Time `json:"appliedTime" gorm:"column:appliedTime;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func NewSchemaMigration(version int64, name string) *SchemaMigration {
+	instance := new(SchemaMigration)
+	instance.Version = version
+	instance.Name = name
+	return instance
+}
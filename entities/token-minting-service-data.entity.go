@@ -8,16 +8,16 @@ import (
 )
 
 type TokenMintingServiceData struct {
-	ID                    int32           `json:"id" gorm:"column:id;type:int(11) NOT NULL AUTO_INCREMENT"`
-	BaseTransactionId     int32           `json:"baseTransactionId" gorm:"column:baseTransactionId;type:int(11) NOT NULL;index:baseTransactionId_INDEX"`
-	MintingCurrencyHash   string          `json:"mintingCurrencyHash" gorm:"column:mintingCurrencyHash;type:varchar(200) COLLATE utf8_unicode_ci NOT NULL"`
-	MintingAmount         decimal.Decimal `json:"mintingAmount" gorm:"column:mintingAmount;type:decimal(25,10) NOT NULL"`
-	ServiceDataCreateTime decimal.Decimal `json:"serviceDataCreateTime" gorm:"column:serviceDataCreateTime;type:decimal(20,6) NOT NULL"`
-	ReceiverAddress       string          `json:"receiverAddress" gorm:"column:receiverAddress;type:varchar(200) COLLATE utf8_unicode_ci NOT NULL"`
-	FeeAmount             decimal.Decimal `json:"feeAmount" gorm:"column:feeAmount;type:decimal(25,10) NOT NULL"`
-	SignerHash            string          `json:"signerHash" gorm:"column:signerHash;type:varchar(200) COLLATE utf8_unicode_ci NOT NULL"`
-	CreateTime            time.Time       `json:"createTime" gorm:"column:createTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP;"`
-	UpdateTime            time.Time       `json:"updateTime" gorm:"column:updateTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;"`
+	ID                    int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	BaseTransactionId     int32           `json:"baseTransactionId" gorm:"column:baseTransactionId;not null;index:baseTransactionId_INDEX"`
+	MintingCurrencyHash   string          `json:"mintingCurrencyHash" gorm:"column:mintingCurrencyHash;size:200;not null"`
+	MintingAmount         decimal.Decimal `json:"mintingAmount" gorm:"column:mintingAmount;type:decimal(25,10);not null"`
+	ServiceDataCreateTime decimal.Decimal `json:"serviceDataCreateTime" gorm:"column:serviceDataCreateTime;type:decimal(20,6);not null"`
+	ReceiverAddress       string          `json:"receiverAddress" gorm:"column:receiverAddress;size:200;not null"`
+	FeeAmount             decimal.Decimal `json:"feeAmount" gorm:"column:feeAmount;type:decimal(25,10);not null"`
+	SignerHash            string          `json:"signerHash" gorm:"column:signerHash;size:200;not null"`
+	CreateTime            time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime            time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
 }
 
 func (TokenMintingServiceData) TableName() string {
@@ -6,12 +6,12 @@ import (
 )
 
 type TransactionAddress struct {
-	ID             int32           `json:"id" gorm:"column:id;type:int(11) NOT NULL AUTO_INCREMENT"`
-	TransactionId  int32           `json:"transactionId" gorm:"column:transactionId;type:int(11) NOT NULL"`
-	AddressId      int32           `json:"addressId" gorm:"column:addressId;type:int(11) NOT NULL;"`
-	AttachmentTime decimal.Decimal `json:"attachmentTime" gorm:"column:attachmentTime;type:decimal(20,6) NOT NULL;"`
-	CreateTime     time.Time       `json:"createTime" gorm:"column:createTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP;"`
-	UpdateTime     time.Time       `json:"updateTime" gorm:"column:updateTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;"`
+	ID             int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	TransactionId  int32           `json:"transactionId" gorm:"column:transactionId;not null"`
+	AddressId      int32           `json:"addressId" gorm:"column:addressId;not null"`
+	AttachmentTime decimal.Decimal `json:"attachmentTime" gorm:"column:attachmentTime;type:decimal(20,6);not null"`
+	CreateTime     time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime     time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
 }
 
 func NewTransactionAddress(addressId int32, attachmentTime decimal.Decimal, transactionId int32) *TransactionAddress {
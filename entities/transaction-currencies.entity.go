@@ -6,12 +6,12 @@ import (
 )
 
 type TransactionCurrency struct {
-	ID             int32           `json:"id" gorm:"column:id;type:int(11) NOT NULL AUTO_INCREMENT"`
-	TransactionId  int32           `json:"transactionId" gorm:"column:transactionId;type:int(11) NOT NULL"`
-	CurrencyId     int32           `json:"currencyId" gorm:"column:currencyId;type:int(11) NOT NULL;"`
-	AttachmentTime decimal.Decimal `json:"attachmentTime" gorm:"column:attachmentTime;type:decimal(20,6) NOT NULL;"`
-	CreateTime     time.Time       `json:"createTime" gorm:"column:createTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP;"`
-	UpdateTime     time.Time       `json:"updateTime" gorm:"column:updateTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;"`
+	ID             int32           `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	TransactionId  int32           `json:"transactionId" gorm:"column:transactionId;not null"`
+	CurrencyId     int32           `json:"currencyId" gorm:"column:currencyId;not null"`
+	AttachmentTime decimal.Decimal `json:"attachmentTime" gorm:"column:attachmentTime;type:decimal(20,6);not null"`
+	CreateTime     time.Time       `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime     time.Time       `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
 }
 
 func NewTransactionCurrency(currencyId int32, attachmentTime decimal.Decimal, transactionId int32) *TransactionCurrency {
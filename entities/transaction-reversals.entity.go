@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"time"
+)
+
+type TransactionReversal struct {
+	ID                 int32     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	TransactionId      int32     `json:"transactionId" gorm:"column:transactionId;not null;index:transactionId_INDEX"`
+	TransactionHash    string    `json:"transactionHash" gorm:"column:transactionHash;size:100;not null"`
+	Reason             string    `json:"reason" gorm:"column:reason;size:45;not null"`
+	ConfirmationPolicy *string   `json:"confirmationPolicy" gorm:"column:confirmationPolicy;size:45"`
+	BalanceDiff        string    `json:"balanceDiff" gorm:"column:balanceDiff;type:text;not null"`
+	CreateTime         time.Time `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime         time.Time `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func NewTransactionReversal(tx *Transaction, reason string, balanceDiff string) *TransactionReversal {
+	instance := new(TransactionReversal)
+	instance.TransactionId = tx.ID
+	instance.TransactionHash = tx.Hash
+	instance.Reason = reason
+	instance.ConfirmationPolicy = tx.ConfirmationPolicy
+	instance.BalanceDiff = balanceDiff
+	return instance
+}
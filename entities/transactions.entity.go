@@ -8,26 +8,32 @@ import (
 )
 
 type Transaction struct {
-	ID                             int32               `json:"id" gorm:"column:id;type:int(11) NOT NULL AUTO_INCREMENT"`
-	Hash                           string              `json:"hash" gorm:"column:hash;type:varchar(100) COLLATE utf8_unicode_ci NOT NULL;index:hash_INDEX"`
-	Index                          *int32              `json:"index" gorm:"column:index;type:int(11) DEFAULT NULL;index:index_INDEX"`
-	Amount                         decimal.Decimal     `json:"amount" gorm:"column:amount;type:decimal(25,10) NOT NULL"`
-	AttachmentTime                 decimal.Decimal     `json:"attachmentTime" gorm:"column:attachmentTime;type:decimal(20,6) NOT NULL;index:attachmentTime_INDEX"`
-	IsValid                        sql.NullBool        `json:"isValid" gorm:"column:isValid;type:tinyint(4) DEFAULT NULL"`
-	TransactionCreateTime          decimal.Decimal     `json:"transactionCreateTime" gorm:"column:transactionCreateTime;type:decimal(20,6) NOT NULL"`
-	LeftParentHash                 *string             `json:"leftParentHash" gorm:"column:leftParentHash;type:varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	RightParentHash                *string             `json:"rightParentHash" gorm:"column:rightParentHash;type:varchar(100) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	NodeHash                       *string             `json:"nodeHash" gorm:"column:nodeHash;type:varchar(128) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	SenderHash                     *string             `json:"senderHash" gorm:"column:senderHash;type:varchar(200) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	SenderTrustScore               float64             `json:"senderTrustScore" gorm:"column:senderTrustScore;type:decimal(25,10) NOT NULL"`
+	ID                             int32               `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Hash                           string              `json:"hash" gorm:"column:hash;size:100;not null;index:hash_INDEX"`
+	Index                          *int32              `json:"index" gorm:"column:index;index:index_INDEX"`
+	Amount                         decimal.Decimal     `json:"amount" gorm:"column:amount;type:decimal(25,10);not null"`
+	AttachmentTime                 decimal.Decimal     `json:"attachmentTime" gorm:"column:attachmentTime;type:decimal(20,6);not null;index:attachmentTime_INDEX"`
+	IsValid                        sql.NullBool        `json:"isValid" gorm:"column:isValid"`
+	TransactionCreateTime          decimal.Decimal     `json:"transactionCreateTime" gorm:"column:transactionCreateTime;type:decimal(20,6);not null"`
+	LeftParentHash                 *string             `json:"leftParentHash" gorm:"column:leftParentHash;size:100"`
+	RightParentHash                *string             `json:"rightParentHash" gorm:"column:rightParentHash;size:100"`
+	NodeHash                       *string             `json:"nodeHash" gorm:"column:nodeHash;size:128"`
+	SenderHash                     *string             `json:"senderHash" gorm:"column:senderHash;size:200"`
+	SenderTrustScore               float64             `json:"senderTrustScore" gorm:"column:senderTrustScore;type:decimal(25,10);not null"`
 	TransactionConsensusUpdateTime decimal.NullDecimal `json:"transactionConsensusUpdateTime" gorm:"column:transactionConsensusUpdateTime;type:decimal(20,6);index:composite_INDEX,priority:3;index:transactionConsensusUpdateTime_INDEX"`
-	TransactionDescription         *string             `json:"transactionDescription" gorm:"column:transactionDescription;type:varchar(500) COLLATE utf8_unicode_ci DEFAULT NULL"`
-	TrustChainConsensus            bool                `json:"trustChainConsensus" gorm:"column:trustChainConsensus;type:tinyint(4) DEFAULT NULL"`
-	TrustChainTrustScore           decimal.Decimal     `json:"trustChainTrustScore" gorm:"column:trustChainTrustScore;type:decimal(25,10) NOT NULL"`
-	Type                           *string             `json:"type" gorm:"column:type;type:varchar(100) COLLATE utf8_unicode_ci NOT NULL;index:type_INDEX;index:composite_INDEX,priority:2"`
-	IsProcessed                    bool                `json:"isProcessed" gorm:"column:isProcessed;type:tinyint(4) DEFAULT false;index:isProcessed_INDEX;index:composite_INDEX,priority:1"`
-	CreateTime                     time.Time           `json:"createTime" gorm:"column:createTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP;"`
-	UpdateTime                     time.Time           `json:"updateTime" gorm:"column:updateTime;type:timestamp NOT NULL;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;index:updateTime_INDEX"`
+	TransactionDescription         *string             `json:"transactionDescription" gorm:"column:transactionDescription;size:500"`
+	TrustChainConsensus            bool                `json:"trustChainConsensus" gorm:"column:trustChainConsensus"`
+	TrustChainTrustScore           decimal.Decimal     `json:"trustChainTrustScore" gorm:"column:trustChainTrustScore;type:decimal(25,10);not null"`
+	Type                           *string             `json:"type" gorm:"column:type;size:100;not null;index:type_INDEX;index:composite_INDEX,priority:2"`
+	IsProcessed                    bool                `json:"isProcessed" gorm:"column:isProcessed;default:false;index:isProcessed_INDEX;index:composite_INDEX,priority:1"`
+	IsSkipped                      bool                `json:"isSkipped" gorm:"column:isSkipped;default:false"`
+	IsReversed                     bool                `json:"isReversed" gorm:"column:isReversed;default:false"`
+	IsCoveredByStamp               bool                `json:"isCoveredByStamp" gorm:"column:isCoveredByStamp;default:false"`
+	ConfirmationPolicy             *string             `json:"confirmationPolicy" gorm:"column:confirmationPolicy;size:45"`
+	ConfirmationMinTrustScore      decimal.NullDecimal `json:"confirmationMinTrustScore" gorm:"column:confirmationMinTrustScore;type:decimal(25,10)"`
+	ProcessedTime                  *time.Time          `json:"processedTime" gorm:"column:processedTime;index:processedTime_INDEX"`
+	CreateTime                     time.Time           `json:"createTime" gorm:"column:createTime;not null;default:CURRENT_TIMESTAMP"`
+	UpdateTime                     time.Time           `json:"updateTime" gorm:"column:updateTime;not null;default:CURRENT_TIMESTAMP;index:updateTime_INDEX"`
 }
 
 func NewTransaction(tx *dto.TransactionResponse) *Transaction {
@@ -36,6 +42,7 @@ func NewTransaction(tx *dto.TransactionResponse) *Transaction {
 	instance.Index = tx.Index
 	instance.Amount = tx.Amount
 	instance.AttachmentTime = tx.AttachmentTime
+	instance.IsValid = NewNullBool(tx.IsValid)
 	instance.TransactionCreateTime = tx.CreateTime
 	instance.LeftParentHash = tx.LeftParentHash
 	instance.RightParentHash = tx.RightParentHash
@@ -49,3 +56,10 @@ func NewTransaction(tx *dto.TransactionResponse) *Transaction {
 	instance.Type = tx.Type
 	return instance
 }
+
+func NewNullBool(value *bool) sql.NullBool {
+	if value == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *value, Valid: true}
+}
@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+)
+
+// minHeartbeatTimeout keeps the jobs with short intervals from failing the liveness check on a single slow iteration
+const minHeartbeatTimeout = time.Minute
+
+type job struct {
+	interval  time.Duration
+	lastBeat  time.Time
+	iteration int
+}
+
+var jobsMutex sync.Mutex
+var jobs = map[string]*job{}
+
+// RegisterJob adds a background job to the liveness check, the job must beat at least every interval
+func RegisterJob(name string, interval time.Duration) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	jobs[name] = &job{interval: interval, lastBeat: time.Now()}
+}
+
+// SetJobInterval changes the interval the job must beat within
+func SetJobInterval(name string, interval time.Duration) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	if registeredJob, ok := jobs[name]; ok {
+		registeredJob.interval = interval
+	}
+}
+
+// Beat records that an iteration of the job is running
+func Beat(name string, iteration int) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	if registeredJob, ok := jobs[name]; ok {
+		registeredJob.lastBeat = time.Now()
+		registeredJob.iteration = iteration
+	}
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Name     string
+	Error    error
+	Duration time.Duration
+	Details  map[string]interface{}
+}
+
+// Check is a single readiness check, details are reported with the result even when the check passes
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (details map[string]interface{}, err error)
+}
+
+// Liveness checks that every registered job beat within HEALTH_MISSED_INTERVALS of its interval
+func Liveness() []CheckResult {
+	missedIntervals := config.Get().Health.MissedIntervals
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	results := make([]CheckResult, 0, len(jobs))
+	now := time.Now()
+	for name, registeredJob := range jobs {
+		timeout := time.Duration(missedIntervals) * registeredJob.interval
+		if timeout < minHeartbeatTimeout {
+			timeout = minHeartbeatTimeout
+		}
+		sinceLastBeat := now.Sub(registeredJob.lastBeat)
+		result := CheckResult{
+			Name: "job:" + name,
+			Details: map[string]interface{}{
+				"lastBeat":                  registeredJob.lastBeat.UTC(),
+				"secondsSinceLastBeat":      sinceLastBeat.Seconds(),
+				"heartbeatTimeoutInSeconds": timeout.Seconds(),
+				"iteration":                 registeredJob.iteration,
+			},
+		}
+		if sinceLastBeat > timeout {
+			result.Error = fmt.Errorf("no heartbeat for %s", sinceLastBeat.Round(time.Second))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// RunChecks runs the checks concurrently, a check that doesn't return within the timeout fails
+func RunChecks(checks []Check, timeout time.Duration) []CheckResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			start := time.Now()
+			resultCh := make(chan CheckResult, 1)
+			go func() {
+				details, err := check.Run(ctx)
+				resultCh <- CheckResult{Name: check.Name, Error: err, Details: details}
+			}()
+			select {
+			case result := <-resultCh:
+				results[i] = result
+			case <-ctx.Done():
+				results[i] = CheckResult{Name: check.Name, Error: fmt.Errorf("timed out after %s", timeout)}
+			}
+			results[i].Duration = time.Since(start)
+		}(i, check)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,197 @@
+package jobs
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/coti-io/coti-db-app/health"
+	"github.com/sirupsen/logrus"
+)
+
+// maxResults is how many iteration results a job keeps
+const maxResults = 100
+
+// the triggers of the iterations
+const (
+	ScheduleTrigger = "schedule"
+	AdminTrigger    = "admin"
+)
+
+// ErrNoBatchSize is returned when the batch size of a job that doesn't have one is set
+var ErrNoBatchSize = errors.New("the job has no batch size")
+
+// IterationResult is the outcome of a single iteration of a job
+type IterationResult struct {
+	Iteration int
+	Trigger   string
+	StartTime time.Time
+	Duration  time.Duration
+	// Error is empty when the iteration succeeded
+	Error string
+}
+
+// Status is the runtime control state of a job
+type Status struct {
+	Name      string
+	IsPaused  bool
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Job is the runtime control of a background job, the admin api pauses it and changes its interval and batch size
+type Job struct {
+	name      string
+	mutex     sync.Mutex
+	isPaused  bool
+	interval  time.Duration
+	batchSize int
+	// results is a ring of the last iteration results, next is where the next result goes
+	results []IterationResult
+	next    int
+	// iterationMutex runs the scheduled and the admin iterations one at a time
+	iterationMutex sync.Mutex
+}
+
+var jobsMutex sync.Mutex
+var jobs = map[string]*Job{}
+
+// Register adds a job with its configured interval and batch size, a batch size of 0 means the job has none.
+// The job is added to the liveness check as well
+func Register(name string, interval time.Duration, batchSize int) *Job {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	health.RegisterJob(name, interval)
+	job := &Job{name: name, interval: interval, batchSize: batchSize}
+	jobs[name] = job
+	return job
+}
+
+// Get finds a registered job by its name
+func Get(name string) (*Job, bool) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	job, ok := jobs[name]
+	return job, ok
+}
+
+// GetAll returns the registered jobs by name
+func GetAll() []*Job {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	all := make([]*Job, 0, len(jobs))
+	for _, job := range jobs {
+		all = append(all, job)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	return all
+}
+
+func (job *Job) GetName() string {
+	return job.name
+}
+
+func (job *Job) GetStatus() Status {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return Status{Name: job.name, IsPaused: job.isPaused, Interval: job.interval, BatchSize: job.batchSize}
+}
+
+func (job *Job) SetPaused(isPaused bool) {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.isPaused = isPaused
+}
+
+func (job *Job) IsPaused() bool {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return job.isPaused
+}
+
+func (job *Job) GetInterval() time.Duration {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return job.interval
+}
+
+// SetInterval takes effect from the next iteration, the liveness check follows the new interval
+func (job *Job) SetInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("the interval must be positive")
+	}
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.interval = interval
+	health.SetJobInterval(job.name, interval)
+	return nil
+}
+
+func (job *Job) GetBatchSize() int {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	return job.batchSize
+}
+
+// SetBatchSize takes effect from the next iteration
+func (job *Job) SetBatchSize(batchSize int) error {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if job.batchSize == 0 {
+		return ErrNoBatchSize
+	}
+	if batchSize <= 0 {
+		return errors.New("the batch size must be positive")
+	}
+	job.batchSize = batchSize
+	return nil
+}
+
+// Record keeps the result of an iteration that started at startTime, it replaces the oldest result once there are maxResults
+func (job *Job) Record(iteration int, trigger string, startTime time.Time, err error) {
+	result := IterationResult{Iteration: iteration, Trigger: trigger, StartTime: startTime, Duration: time.Since(startTime)}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if len(job.results) < maxResults {
+		job.results = append(job.results, result)
+	} else {
+		job.results[job.next] = result
+	}
+	job.next = (job.next + 1) % maxResults
+}
+
+// GetResults returns the last limit iteration results, the latest first
+func (job *Job) GetResults(limit int) []IterationResult {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if limit > len(job.results) {
+		limit = len(job.results)
+	}
+	results := make([]IterationResult, 0, limit)
+	for i := 1; i <= limit; i++ {
+		results = append(results, job.results[(job.next-i+maxResults)%maxResults])
+	}
+	return results
+}
+
+// RunIteration runs an iteration of the job once the running one ends, an admin iteration waits for the scheduled one and the other way around
+func (job *Job) RunIteration(iteration func() error) error {
+	job.iterationMutex.Lock()
+	defer job.iterationMutex.Unlock()
+	return iteration()
+}
+
+// SleepIfPaused sleeps the interval and returns true when the job is paused, the job keeps beating for the liveness check
+func (job *Job) SleepIfPaused(iterationLog *logrus.Entry) bool {
+	if !job.IsPaused() {
+		return false
+	}
+	interval := job.GetInterval()
+	iterationLog.WithField("sleepSeconds", interval.Seconds()).Debug("paused, sleeping")
+	time.Sleep(interval)
+	return true
+}
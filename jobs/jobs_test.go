@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunIterationRunsOneIterationAtATime(t *testing.T) {
+	job := &Job{name: "test"}
+	var running, maxRunning int32
+	var wait sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			err := job.RunIteration(func() error {
+				count := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if count <= max || atomic.CompareAndSwapInt32(&maxRunning, max, count) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wait.Wait()
+	if maxRunning != 1 {
+		t.Fatalf("%d iterations ran at the same time", maxRunning)
+	}
+}
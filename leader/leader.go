@@ -0,0 +1,227 @@
+package leader
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/coti-io/coti-db-app/health"
+	"github.com/coti-io/coti-db-app/logger"
+	"github.com/coti-io/coti-db-app/metrics"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotLeader is returned by Fence when the instance doesn't hold the lease, the writes of the transaction must be rolled back
+var ErrNotLeader = errors.New("the instance is not the sync leader")
+
+var electorOnce sync.Once
+
+// Elector elects the instance that runs the writer jobs of the sync by the sync lease in the db.
+// The leader renews the lease every LEADER_RENEW_INTERVAL_IN_SECONDS, another instance takes it over once it is not renewed
+// for LEADER_LEASE_DURATION_IN_SECONDS
+type Elector interface {
+	// Run keeps acquiring or renewing the lease in the background
+	Run()
+	// IsLeader is whether the instance holds the lease, it turns false once the lease duration passed since the last renewal
+	IsLeader() bool
+	// Fence checks in the db transaction of a writer job that the instance still holds the lease with its fencing token.
+	// The lease is locked until the transaction ends so no other instance can take it over before the writes are committed
+	Fence(repositories repository.Repositories) error
+	GetStatus() Status
+}
+
+// Status is the state of the election seen by the instance
+type Status struct {
+	Id           string
+	IsLeader     bool
+	FencingToken int64
+	// ExpireTime is when the instance steps down unless the lease is renewed, zero when it is not the leader
+	ExpireTime time.Time
+}
+
+type elector struct {
+	id            string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+	repositories  repository.Repositories
+	mutex         sync.Mutex
+	isRunning     bool
+	fencingToken  int64
+	// expireTime is measured from before the lease was acquired on the local monotonic clock,
+	// so it passes before the lease expires in the db
+	expireTime time.Time
+	// wasLeader is the leadership at the last renewal, to report the transitions
+	wasLeader bool
+}
+
+var electorInstance *elector
+
+// NewElector is a singleton since the instance holds a single lease
+func NewElector(repositories repository.Repositories) Elector {
+	electorOnce.Do(func() {
+		leaderConfig := config.Get().Leader
+		id := leaderConfig.Id
+		if id == "" {
+			id = newInstanceId()
+		}
+		electorInstance = &elector{
+			id:            id,
+			leaseDuration: leaderConfig.LeaseDuration,
+			renewInterval: leaderConfig.RenewInterval,
+			repositories:  repositories,
+		}
+	})
+	return electorInstance
+}
+
+// newInstanceId is unique across the hosts and the restarts of a process
+func newInstanceId() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%06x", hostname, os.Getpid(), rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1<<24))
+}
+
+func (elector *elector) Run() {
+	elector.mutex.Lock()
+	defer elector.mutex.Unlock()
+	if elector.isRunning {
+		return
+	}
+	elector.isRunning = true
+	go elector.renewLease()
+}
+
+func (elector *elector) renewLease() {
+	health.RegisterJob("leaderElection", elector.renewInterval)
+	logger.WithJob("leaderElection").WithField("leaderId", elector.id).Info("joined the leader election")
+	iteration := 0
+	for {
+		dtStart := time.Now()
+		iterationLog := logger.WithIteration("leaderElection", iteration)
+		health.Beat("leaderElection", iteration)
+		iteration = iteration + 1
+		err := elector.renewLeaseIteration()
+		if err != nil {
+			iterationLog.WithError(err).Error("iteration failed")
+		}
+		elector.reportTransition()
+		diff := time.Now().Sub(dtStart)
+		metrics.IterationDuration.WithLabelValues(metrics.LeaderElectionJob).Observe(diff.Seconds())
+		if diff < elector.renewInterval {
+			time.Sleep(elector.renewInterval - diff)
+		}
+	}
+}
+
+// renewLeaseIteration acquires the lease when it is free or expired and renews it when the instance holds it
+func (elector *elector) renewLeaseIteration() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.LogPanic(logger.WithJob("leaderElection"), r)
+			err = fmt.Errorf("leader election iteration failed: %v", r)
+		}
+	}()
+	attemptTime := time.Now()
+	var lease *entities.LeaderLease
+	err = elector.repositories.Transaction(func(repositories repository.Repositories) error {
+		leases := repositories.LeaderLeases()
+		if err := leases.CreateIfMissing(entities.SyncLeaderLease); err != nil {
+			return err
+		}
+		var err error
+		lease, err = leases.Acquire(entities.SyncLeaderLease, elector.id, elector.leaseDuration)
+		return err
+	})
+	elector.mutex.Lock()
+	defer elector.mutex.Unlock()
+	if err != nil {
+		// the instance stays the leader until its lease expires, the next renewals may still succeed
+		return err
+	}
+	if lease.Holder != elector.id {
+		elector.expireTime = time.Time{}
+		return nil
+	}
+	elector.fencingToken = lease.FencingToken
+	elector.expireTime = attemptTime.Add(elector.leaseDuration)
+	return nil
+}
+
+func (elector *elector) reportTransition() {
+	isLeader := elector.IsLeader()
+	elector.mutex.Lock()
+	wasLeader := elector.wasLeader
+	elector.wasLeader = isLeader
+	fencingToken := elector.fencingToken
+	elector.mutex.Unlock()
+	if isLeader == wasLeader {
+		return
+	}
+	metrics.LeaderTransitions.Inc()
+	transitionLog := logger.WithJob("leaderElection").WithFields(logrus.Fields{"leaderId": elector.id, "fencingToken": fencingToken})
+	if isLeader {
+		metrics.IsLeader.Set(1)
+		transitionLog.Info("became the leader")
+	} else {
+		metrics.IsLeader.Set(0)
+		transitionLog.Warn("stepped down as the leader")
+	}
+}
+
+func (elector *elector) IsLeader() bool {
+	elector.mutex.Lock()
+	defer elector.mutex.Unlock()
+	return elector.isLeader()
+}
+
+func (elector *elector) isLeader() bool {
+	return time.Now().Before(elector.expireTime)
+}
+
+func (elector *elector) Fence(repositories repository.Repositories) error {
+	elector.mutex.Lock()
+	isLeader := elector.isLeader()
+	fencingToken := elector.fencingToken
+	elector.mutex.Unlock()
+	if !isLeader {
+		return ErrNotLeader
+	}
+	lease, err := repositories.LeaderLeases().GetByNameForShare(entities.SyncLeaderLease)
+	if err != nil {
+		return err
+	}
+	if lease.Holder != elector.id || lease.FencingToken != fencingToken {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+func (elector *elector) GetStatus() Status {
+	elector.mutex.Lock()
+	defer elector.mutex.Unlock()
+	status := Status{Id: elector.id, IsLeader: elector.isLeader()}
+	if status.IsLeader {
+		status.FencingToken = elector.fencingToken
+		status.ExpireTime = elector.expireTime
+	}
+	return status
+}
+
+// SleepIfFollower sleeps the interval of a writer job and returns true when the instance is not the leader,
+// the job keeps beating for the liveness check while it waits for the leadership
+func SleepIfFollower(elector Elector, iterationLog *logrus.Entry, interval time.Duration) bool {
+	if elector.IsLeader() {
+		return false
+	}
+	iterationLog.WithField("sleepSeconds", interval.Seconds()).Debug("not the leader, sleeping")
+	time.Sleep(interval)
+	return true
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GinLogger logs the api requests, server errors are logged at the error level and client errors at the warn level
+func GinLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		entry := logrus.WithFields(logrus.Fields{
+			"method":      c.Request.Method,
+			"path":        path,
+			"status":      c.Writer.Status(),
+			"clientIp":    c.ClientIP(),
+			"bodySize":    c.Writer.Size(),
+			DurationField: time.Since(start).Seconds(),
+		})
+		if len(c.Errors) > 0 {
+			entry = entry.WithField(logrus.ErrorKey, c.Errors.String())
+		}
+		switch {
+		case c.Writer.Status() >= 500:
+			entry.Error("request")
+		case c.Writer.Status() >= 400:
+			entry.Warn("request")
+		default:
+			entry.Info("request")
+		}
+	}
+}
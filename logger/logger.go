@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/sirupsen/logrus"
+)
+
+// the field names shared by the log lines of the jobs
+const (
+	JobField       = "job"
+	IterationField = "iteration"
+	FromIndexField = "fromIndex"
+	ToIndexField   = "toIndex"
+	FullnodeField  = "fullnode"
+	// DurationField is in seconds
+	DurationField = "duration"
+	PanicField    = "panic"
+	StackField    = "stack"
+)
+
+// Init sets the level and the format of the logs from LOG_LEVEL and LOG_FORMAT (text or json).
+// The logs of the standard log package are written through the logger as well
+func Init(logConfig config.LogConfig) error {
+	level, err := logrus.ParseLevel(logConfig.Level)
+	if err != nil {
+		return fmt.Errorf("LOG_LEVEL is not a log level: %w", err)
+	}
+	switch logConfig.Format {
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	}
+	logrus.SetLevel(level)
+	// stdout is kept for the output of the commands like the cluster stamp export
+	logrus.SetOutput(os.Stderr)
+	log.SetFlags(0)
+	log.SetOutput(logrus.StandardLogger().WriterLevel(logrus.InfoLevel))
+	return nil
+}
+
+// WithJob is the logger of a background job
+func WithJob(job string) *logrus.Entry {
+	return logrus.WithField(JobField, job)
+}
+
+// WithIteration is the logger of an iteration of a background job
+func WithIteration(job string, iteration int) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{JobField: job, IterationField: iteration})
+}
+
+// WithDuration adds the seconds since start to the log fields
+func WithDuration(entry *logrus.Entry, start time.Time) *logrus.Entry {
+	return entry.WithField(DurationField, time.Since(start).Seconds())
+}
+
+// LogPanic logs a recovered panic with its value and stack
+func LogPanic(entry *logrus.Entry, r interface{}) {
+	entry.WithFields(logrus.Fields{PanicField: r, StackField: string(debug.Stack())}).Error("recovered from a panic")
+}
@@ -1,182 +1,295 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
 	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coti-io/coti-db-app/archive"
+	"github.com/coti-io/coti-db-app/auth"
+	clusterStamp "github.com/coti-io/coti-db-app/cluster-stamp"
+	"github.com/coti-io/coti-db-app/config"
 	"github.com/coti-io/coti-db-app/controllers"
 	dbprovider "github.com/coti-io/coti-db-app/db-provider"
-	"github.com/coti-io/coti-db-app/dto"
 	"github.com/coti-io/coti-db-app/entities"
+	"github.com/coti-io/coti-db-app/leader"
+	"github.com/coti-io/coti-db-app/logger"
+	repository "github.com/coti-io/coti-db-app/repositories"
 	service "github.com/coti-io/coti-db-app/services"
+	"github.com/coti-io/coti-db-app/tracing"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	"github.com/shopspring/decimal"
-	"gorm.io/gorm"
-	"log"
-	"os"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 )
 
+// command is a subcommand of the cli
+type command struct {
+	name  string
+	usage string
+	run   func(args []string)
+}
+
+func getCommands() []command {
+	return []command{
+		{"serve", "run the sync and serve the api, the default command", serve},
+		{"sync", "run the sync, only the health, metrics and admin routes are served", runSync},
+		{"api", "serve the api without syncing, the sync state is read from the db", api},
+		{"migrate", "up|down|status of the db migrations", migrate},
+		{"load-cluster-stamp", "load the cluster stamp when it is not loaded yet", loadClusterStampCommand},
+		{"status", "print the schema, the cluster stamp and the sync state", status},
+		{"verify", "check the config, the db, the fullnodes and the cluster stamp", verify},
+		{"export-cluster-stamp", "export the balances as a cluster stamp", exportClusterStamp},
+		{"rebase-cluster-stamp", "replace the balances with a newer cluster stamp", rebaseClusterStamp},
+		{"partition", "partition the transaction tables by PARTITION_BY and PARTITION_SIZE", partition},
+		{"api-key", "create|list|revoke the api keys", apiKey},
+	}
+}
+
 func main() {
-	err := godotenv.Load()
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, cmd := range getCommands() {
+		if cmd.name == name {
+			cmd.run(args)
+			return
+		}
+	}
+	printUsage()
+	if name != "help" {
+		os.Exit(2)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags], run <command> -h for its flags\n\ncommands:\n", os.Args[0])
+	for _, cmd := range getCommands() {
+		fmt.Fprintf(os.Stderr, "  %-22s%s\n", cmd.name, cmd.usage)
+	}
+}
+
+// serve runs the sync and the api in one process
+func serve(args []string) {
+	flags := newCommandFlags("serve")
+	flags.serverFlags()
+	flags.fullnodeFlags()
+	flags.migrateFlag()
+	flags.parse(args)
+	validateSyncConfig()
+	defer startTracing()()
+
+	repositories := initSyncDb()
+	elector := startElection(repositories)
+	transactionService := startSync(repositories, elector)
+	archiveService := archive.NewArchiveService(dbprovider.DB)
+	archiveService.Run(elector)
+
+	server := newServer()
+	registerApiRoutes(server, transactionService, archiveService, repositories)
+	registerOpsRoutes(server, transactionService, repositories, elector)
+	runServer(server)
+}
+
+// runSync runs the sync writer, the api is served by the api processes
+func runSync(args []string) {
+	flags := newCommandFlags("sync")
+	flags.serverFlags()
+	flags.fullnodeFlags()
+	flags.migrateFlag()
+	flags.parse(args)
+	validateSyncConfig()
+	defer startTracing()()
+
+	repositories := initSyncDb()
+	elector := startElection(repositories)
+	transactionService := startSync(repositories, elector)
+	archive.NewArchiveService(dbprovider.DB).Run(elector)
+
+	server := newServer()
+	registerOpsRoutes(server, transactionService, repositories, elector)
+	runServer(server)
+}
+
+// api serves the api next to a sync process, it doesn't migrate or write to the db
+func api(args []string) {
+	flags := newCommandFlags("api")
+	flags.serverFlags()
+	flags.fullnodeFlags()
+	flags.parse(args)
+	defer startTracing()()
+
+	dbprovider.InitWithoutMigrations()
+	checkSchemaVersion()
+	repositories := repository.NewGormRepositories(dbprovider.DB)
+	verifyNativeCurrencyHash(repositories)
+	transactionService := service.NewTransactionService(repositories)
+	// the fullnode indexes of the sync state and the readiness check
+	transactionService.RunSyncStatusMonitor()
+
+	server := newServer()
+	registerApiRoutes(server, transactionService, archive.NewArchiveService(dbprovider.DB), repositories)
+	// no elector, the jobs of the admin routes don't run in the api processes
+	registerOpsRoutes(server, transactionService, repositories, nil)
+	runServer(server)
+}
+
+func validateSyncConfig() {
+	err := config.Get().ValidateSync()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// startTracing traces the sync jobs, the fullnode requests, the db queries and the api requests when TRACING_EXPORTER is set,
+// the returned func flushes the pending spans
+func startTracing() func() {
+	shutdownTracing, err := tracing.Init(config.Get().Tracing)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		logrus.WithError(err).Fatal("invalid tracing config")
+	}
+	return func() {
+		_ = shutdownTracing(context.Background())
 	}
-	server := gin.Default()
+}
 
-	// Init the db connection
+// initSyncDb opens the db and prepares the app states, the native currency and the cluster stamp the sync needs
+func initSyncDb() repository.Repositories {
 	dbprovider.Init()
+	checkSchemaVersion()
+	repositories := repository.NewGormRepositories(dbprovider.DB)
 
 	// making sure all the app states exists and create them if not
-	verifyAppStates()
+	verifyAppStates(repositories)
 
 	// making sure we have the native currency hash in the db
-	verifyNativeCurrencyHash()
+	verifyNativeCurrencyHash(repositories)
 
 	// load cluster stamp if not loaded
-	loadClusterStamp()
+	loadClusterStamp(repositories)
+	return repositories
+}
 
-	// run the sync tasks
-	transactionService := service.NewTransactionService()
-	transactionService.RunSync()
+// startElection joins the leader election, every sync process serves its routes but only the leader runs the writer jobs
+func startElection(repositories repository.Repositories) leader.Elector {
+	elector := leader.NewElector(repositories)
+	elector.Run()
+	return elector
+}
 
-	// register routes
-	server.GET("/get-sync-state", controllers.GetSyncState)
+func startSync(repositories repository.Repositories, elector leader.Elector) service.TransactionService {
+	transactionService := service.NewTransactionService(repositories)
+	transactionService.RunSync(elector)
+	return transactionService
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000"
-	}
-	// runs the server
-	serverRunError := server.Run(":" + port)
-	if serverRunError != nil {
-		log.Fatal("Server run error")
-		return
+// checkSchemaVersion stops the app when the db schema is older than the app
+func checkSchemaVersion() {
+	err := dbprovider.CheckSchemaVersion()
+	if err != nil {
+		logrus.WithError(err).Fatal("db schema check failed")
 	}
+}
 
+func newServer() *gin.Engine {
+	server := gin.New()
+	server.Use(tracing.GinMiddleware(), logger.GinLogger(), gin.RecoveryWithWriter(logrus.StandardLogger().WriterLevel(logrus.ErrorLevel)))
+	return server
 }
 
-func verifyAppStates() {
-	// TODO find a way to iterate over the enum in the top of the page
-	appStateLastMonitoredTransactionIndex := entities.AppState{Name: entities.LastMonitoredTransactionIndex}
-	appStateLastMonitoredTransactionIndexRes := dbprovider.DB.Where("name = ?", entities.LastMonitoredTransactionIndex).FirstOrCreate(&appStateLastMonitoredTransactionIndex)
-	if appStateLastMonitoredTransactionIndexRes.Error != nil {
-		panic(appStateLastMonitoredTransactionIndexRes.Error)
-	}
+// registerApiRoutes registers the public api, it reads from the replicas when there are ones.
+// The api keys are read from the primary so a revoked key isn't accepted by a lagging replica
+func registerApiRoutes(server *gin.Engine, transactionService service.TransactionService, archiveService archive.ArchiveService, repositories repository.Repositories) {
+	readRepositories := repository.NewGormRepositories(dbprovider.ReadDB)
+	stateController := controllers.NewStateController(transactionService, readRepositories.AppStates())
+	currencySupplyController := controllers.NewCurrencySupplyController(service.NewCurrencySupplyService(readRepositories.Currencies()))
+	transactionReversalController := controllers.NewTransactionReversalController(service.NewTransactionReversalService(readRepositories.Transactions()))
+	transactionController := controllers.NewTransactionController(readRepositories.Transactions(), archiveService)
 
-	appStateIsClusterStampInitialized := entities.AppState{Name: entities.IsClusterStampInitialized}
-	appStateIsClusterStampInitializedRes := dbprovider.DB.Where("name = ?", entities.IsClusterStampInitialized).FirstOrCreate(&appStateIsClusterStampInitialized)
-	if appStateIsClusterStampInitializedRes.Error != nil {
-		panic(appStateIsClusterStampInitializedRes.Error)
-	}
+	api := server.Group("", auth.Authenticate(repositories))
+	api.GET("/get-sync-state", stateController.GetSyncState)
+	api.GET("/v1/sync-state", stateController.GetSyncStateV1)
+	api.GET("/currency-supplies", currencySupplyController.GetCurrencySupplies)
+	api.GET("/currency-supply/:currencyHash", currencySupplyController.GetCurrencySupply)
+	// the transaction data needs the read scope unless API_ANONYMOUS_SCOPE is read
+	read := api.Group("", auth.RequireScope(entities.ReadScope))
+	read.GET("/transaction-reversals", transactionReversalController.GetTransactionReversals)
+	read.GET("/transaction/:hash", transactionController.GetTransaction)
+	read.GET("/skipped-transactions", transactionController.GetSkippedTransactions)
+}
 
-	appStateUpdateBalances := entities.AppState{Name: entities.UpdateBalances}
-	appStateUpdateBalancesRes := dbprovider.DB.Where("name = ?", entities.UpdateBalances).FirstOrCreate(&appStateUpdateBalances)
-	if appStateUpdateBalancesRes.Error != nil {
-		panic(appStateUpdateBalancesRes.Error)
-	}
+// registerOpsRoutes registers the health, metrics, diagnostics and admin routes every server command has
+func registerOpsRoutes(server *gin.Engine, transactionService service.TransactionService, repositories repository.Repositories, elector leader.Elector) {
+	// the readiness check must see the state of the primary
+	healthController := controllers.NewHealthController(transactionService, repositories.AppStates())
 
-	appStateDeleteUnindexedTransactions := entities.AppState{Name: entities.DeleteUnindexedTransactions}
-	appStateDeleteUnindexedTransactionsRes := dbprovider.DB.Where("name = ?", entities.DeleteUnindexedTransactions).FirstOrCreate(&appStateDeleteUnindexedTransactions)
-	if appStateDeleteUnindexedTransactionsRes.Error != nil {
-		panic(appStateDeleteUnindexedTransactionsRes.Error)
-	}
+	server.GET("/diagnostics/db-pool", controllers.NewDiagnosticsController().GetDbPoolStats)
+	server.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	server.GET("/healthz", healthController.GetLiveness)
+	server.GET("/readyz", healthController.GetReadiness)
 
-	appStateMonitorTransaction := entities.AppState{Name: entities.MonitorTransaction}
-	appStateMonitorTransactionRes := dbprovider.DB.Where("name = ?", entities.MonitorTransaction).FirstOrCreate(&appStateMonitorTransaction)
-	if appStateMonitorTransactionRes.Error != nil {
-		panic(appStateMonitorTransactionRes.Error)
+	adminController := controllers.NewAdminController(transactionService, repositories, elector)
+	admin := server.Group("/admin", auth.Authenticate(repositories), auth.RequireScope(entities.AdminScope))
+	admin.GET("/config", controllers.NewConfigController().GetEffectiveConfig)
+	admin.GET("/jobs", adminController.GetJobs)
+	admin.PATCH("/jobs/:name", adminController.UpdateJobSettings)
+	admin.POST("/jobs/:name/pause", adminController.PauseJob)
+	admin.POST("/jobs/:name/resume", adminController.ResumeJob)
+	admin.POST("/jobs/:name/run", adminController.RunJob)
+	admin.GET("/jobs/:name/iterations", adminController.GetJobIterations)
+	admin.POST("/fullnode/switch", adminController.SwitchFullnode)
+	admin.PUT("/last-monitored-transaction-index", adminController.SetLastMonitoredTransactionIndex)
+	admin.GET("/audit-logs", adminController.GetAuditLogs)
+	admin.GET("/api-keys", adminController.GetApiKeys)
+	admin.POST("/api-keys", adminController.CreateApiKey)
+	admin.DELETE("/api-keys/:name", adminController.RevokeApiKey)
+}
+
+func runServer(server *gin.Engine) {
+	serverRunError := server.Run(":" + config.Get().Port)
+	if serverRunError != nil {
+		logrus.WithError(serverRunError).Fatal("server run error")
 	}
 }
 
-func verifyNativeCurrencyHash() {
-	nativeSymbol := os.Getenv("NATIVE_SYMBOL")
-	if nativeSymbol == "" {
-		panic("NATIVE_SYMBOL is mandatory env variable")
+func verifyAppStates(repositories repository.Repositories) {
+	appStateNames := []entities.AppStatesNames{
+		entities.LastMonitoredTransactionIndex,
+		entities.IsClusterStampInitialized,
+		entities.UpdateBalances,
+		entities.DeleteUnindexedTransactions,
+		entities.MonitorTransaction,
+		entities.ArchiveTransactions,
 	}
-	var currencyService = service.NewCurrencyService()
-	// if not throw error
-	nativeCurrencyHash := currencyService.GetNativeCurrencyHash()
-	nativeCurrency := entities.Currency{Hash: nativeCurrencyHash}
-	nativeCurrencyError := dbprovider.DB.Where("hash = ?", nativeCurrencyHash).FirstOrCreate(&nativeCurrency).Error
-	if nativeCurrencyError != nil {
-		panic(nativeCurrencyError)
+	for _, appStateName := range appStateNames {
+		_, err := repositories.AppStates().FirstOrCreate(appStateName)
+		if err != nil {
+			panic(err)
+		}
 	}
 }
 
-func loadClusterStamp() {
-	// check the app state if cluster stamp initialized for this db
-	appStateIsClusterStampInitialized := entities.AppState{}
-	err := dbprovider.DB.Where("name = ?", entities.IsClusterStampInitialized).First(&appStateIsClusterStampInitialized).Error
+func verifyNativeCurrencyHash(repositories repository.Repositories) {
+	err := checkNativeCurrency(repositories)
 	if err != nil {
 		panic(err)
 	}
-	if appStateIsClusterStampInitialized.Value != "true" {
-		currencyService := service.NewCurrencyService()
-		nativeCurrencyHash := currencyService.GetNativeCurrencyHash()
-		nativeCurrency := entities.Currency{}
-		nativeCurrencyError := dbprovider.DB.Where("hash = ?", nativeCurrencyHash).First(&nativeCurrency).Error
-		if nativeCurrencyError != nil {
-			panic(nativeCurrencyError)
-		}
-		clusterStampFileName := os.Getenv("CLUSTER_STAMP_FILE_NAME")
-		csvFile, err := os.Open(clusterStampFileName)
-		if err != nil {
-			panic(err)
-		}
-		fmt.Println("Successfully Opened CSV file")
-		defer csvFile.Close()
-
-		reader := csv.NewReader(csvFile)
-
-		err = dbprovider.DB.Transaction(func(dbTransaction *gorm.DB) error {
-			var addressBalances []entities.AddressBalance
-			recordsToSaveCounter := 0
-			for {
-				line, err := reader.Read()
-				if err != nil {
-					break
-				}
-				amount, err := decimal.NewFromString(line[1])
-				if err != nil {
-					return err
-				}
-				clusterStampData := dto.ClusterStampDataRow{
-					Address:    line[0],
-					Amount:     amount,
-					CurrencyId: nativeCurrency.ID,
-				}
-				addressBalance := entities.NewAddressBalanceFromClusterStamp(&clusterStampData)
-
-				addressBalances = append(addressBalances, *addressBalance)
-				recordsToSaveCounter += 1
-				if recordsToSaveCounter == 1000 {
-					err = dbTransaction.Save(addressBalances).Error
-					if err != nil {
-						return err
-					}
-					addressBalances = []entities.AddressBalance{}
-					recordsToSaveCounter = 0
-				}
-
-			}
-			if len(addressBalances) > 0 {
-				err = dbTransaction.Save(addressBalances).Error
-				if err != nil {
-					return err
-				}
-			}
-
-			appStateIsClusterStampInitialized.Value = "true"
-			err = dbTransaction.Save(appStateIsClusterStampInitialized).Error
-			if err != nil {
-				return err
-			}
-
-			return nil
-		})
-		if err != nil {
-			panic(err)
-		}
+}
+
+func checkNativeCurrency(repositories repository.Repositories) error {
+	if config.Get().NativeSymbol == "" {
+		return fmt.Errorf("NATIVE_SYMBOL is mandatory env variable")
+	}
+	var currencyService = service.NewCurrencyService(repositories.Currencies())
+	// if not throw error
+	_, err := currencyService.GetNativeCurrency()
+	return err
+}
 
+func loadClusterStamp(repositories repository.Repositories) {
+	err := clusterStamp.Load(repositories)
+	if err != nil {
+		panic(err)
 	}
 }
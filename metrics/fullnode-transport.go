@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// fullnodeTransport records the duration and the errors of the fullnode requests by the request path
+type fullnodeTransport struct {
+	next http.RoundTripper
+}
+
+// NewFullnodeClient creates an http client whose requests are recorded in the fullnode request metrics
+func NewFullnodeClient() *http.Client {
+	return &http.Client{Transport: &fullnodeTransport{next: http.DefaultTransport}}
+}
+
+func (transport *fullnodeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := req.URL.Path
+	start := time.Now()
+	res, err := transport.next.RoundTrip(req)
+	FullnodeRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if err != nil || res.StatusCode < 200 || res.StatusCode >= 300 {
+		FullnodeRequestErrors.WithLabelValues(endpoint).Inc()
+	}
+	return res, err
+}
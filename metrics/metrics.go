@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "coti_db_app"
+
+// the jobs of the iteration metrics
+const (
+	MonitorSyncStatusJob         = "monitorSyncStatus"
+	SyncNewTransactionsJob       = "syncNewTransactions"
+	MonitorTransactionsJob       = "monitorTransactions"
+	CleanUnindexedTransactionJob = "cleanUnindexedTransaction"
+	UpdateBalancesJob            = "updateBalances"
+	ArchiveJob                   = "archive"
+	LeaderElectionJob            = "leaderElection"
+)
+
+// the fullnodes of the node metrics
+const (
+	MainNode   = "main"
+	BackupNode = "backup"
+)
+
+var (
+	LastMonitoredIndex = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_monitored_transaction_index",
+		Help:      "The index of the last transaction synced from the fullnode.",
+	})
+	FullnodeLastIndex = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "fullnode_last_index",
+		Help:      "The last transaction index of the fullnode.",
+	}, []string{"node"})
+	SyncLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_lag_transactions",
+		Help:      "How many indexes the last monitored transaction is behind the tip of the fullnode in use.",
+	})
+	SyncRate = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_rate_transactions_per_second",
+		Help:      "The rolling rate the last monitored transaction index moves forward at.",
+	})
+	SyncBacklog = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_backlog_transactions",
+		Help:      "The transactions waiting for a job by backlog: balanceProcessing, monitor and pendingUnindexed.",
+	}, []string{"backlog"})
+	CurrentFullnode = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "current_fullnode",
+		Help:      "1 for the fullnode the sync is using and 0 for the other one.",
+	}, []string{"node"})
+	TransactionsInserted = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sync_inserted_transactions",
+		Help:      "The new transactions inserted by a sync iteration.",
+		Buckets:   []float64{0, 1, 10, 50, 100, 250, 500, 1000, 2500, 5000},
+	})
+	IterationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "iteration_duration_seconds",
+		Help:      "The duration of the iterations of the sync jobs.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"job"})
+	FullnodeRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fullnode_request_duration_seconds",
+		Help:      "The duration of the fullnode http requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+	FullnodeRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fullnode_request_errors_total",
+		Help:      "The fullnode http requests that failed or returned an error status.",
+	}, []string{"endpoint"})
+	BalanceUpdatesApplied = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "balance_updates_applied_total",
+		Help:      "The address balance changes applied by the update balances job.",
+	})
+	TransactionsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_processed_total",
+		Help:      "The transactions marked as processed by the update balances job.",
+	})
+	TransactionsSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_skipped_total",
+		Help:      "The invalid transactions the update balances job marked as processed without applying their balances.",
+	})
+	UnindexedTransactionsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unindexed_transactions_deleted_total",
+		Help:      "The unindexed transactions deleted by the clean job.",
+	})
+	IsLeader = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "is_leader",
+		Help:      "1 when the instance holds the sync leader lease and runs the writer jobs.",
+	})
+	LeaderTransitions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "leader_transitions_total",
+		Help:      "The times the instance became or stopped being the sync leader.",
+	})
+	DbTransactionRollbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_rollbacks_total",
+		Help:      "The db transactions of the jobs that were rolled back on an error.",
+	}, []string{"job"})
+	RateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "The api requests refused with 429 by the bucket they were limited by, ip or key.",
+	}, []string{"bucket"})
+)
+
+// SetCurrentFullnode marks the node the sync is using
+func SetCurrentFullnode(node string) {
+	for _, n := range []string{MainNode, BackupNode} {
+		value := float64(0)
+		if n == node {
+			value = 1
+		}
+		CurrentFullnode.WithLabelValues(n).Set(value)
+	}
+}
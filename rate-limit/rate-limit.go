@@ -0,0 +1,84 @@
+package rateLimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets that are full again are dropped
+const sweepInterval = time.Minute
+
+// Limit is the rate of a token bucket in tokens per second and the tokens it holds when it is full, a rate of 0 is unlimited
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type bucket struct {
+	limit      Limit
+	tokens     float64
+	updateTime time.Time
+}
+
+// Limiter keeps a token bucket per key in memory, the clock is given so the buckets can be checked without waiting
+type Limiter struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	sweepTime time.Time
+}
+
+func NewLimiter(now func() time.Time) *Limiter {
+	return &Limiter{buckets: map[string]*bucket{}, now: now, sweepTime: now()}
+}
+
+// Allow takes a token from the bucket of the key, when there is none it returns false and how long until there is one
+func (limiter *Limiter) Allow(key string, limit Limit) (bool, time.Duration) {
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	now := limiter.now()
+	limiter.sweep(now)
+
+	keyBucket, ok := limiter.buckets[key]
+	if !ok || keyBucket.limit != limit {
+		// a changed limit starts from a full bucket
+		keyBucket = &bucket{limit: limit, tokens: float64(limit.Burst), updateTime: now}
+		limiter.buckets[key] = keyBucket
+	}
+	keyBucket.refill(now)
+	if keyBucket.tokens >= 1 {
+		keyBucket.tokens--
+		return true, 0
+	}
+	missingSeconds := (1 - keyBucket.tokens) / limit.Rate
+	return false, time.Duration(math.Ceil(missingSeconds * float64(time.Second)))
+}
+
+func (keyBucket *bucket) refill(now time.Time) {
+	elapsed := now.Sub(keyBucket.updateTime).Seconds()
+	if elapsed > 0 {
+		keyBucket.tokens = math.Min(float64(keyBucket.limit.Burst), keyBucket.tokens+elapsed*keyBucket.limit.Rate)
+		keyBucket.updateTime = now
+	}
+}
+
+// sweep drops the full buckets, a dropped bucket is the same as a new one
+func (limiter *Limiter) sweep(now time.Time) {
+	if now.Sub(limiter.sweepTime) < sweepInterval {
+		return
+	}
+	limiter.sweepTime = now
+	for key, keyBucket := range limiter.buckets {
+		keyBucket.refill(now)
+		if keyBucket.tokens >= float64(keyBucket.limit.Burst) {
+			delete(limiter.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package rateLimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is moved by the tests instead of waiting
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return clock.now
+}
+
+func TestAllow(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 3}
+	type step struct {
+		wait       time.Duration
+		isAllowed  bool
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		limit Limit
+		steps []step
+	}{
+		{"the burst is allowed at once", limit, []step{{0, true, 0}, {0, true, 0}, {0, true, 0}, {0, false, 500 * time.Millisecond}}},
+		{"a token comes back after 1/rate", limit, []step{{0, true, 0}, {0, true, 0}, {0, true, 0}, {400 * time.Millisecond, false, 100 * time.Millisecond}, {100 * time.Millisecond, true, 0}, {0, false, 500 * time.Millisecond}}},
+		{"the bucket doesn't fill past the burst", limit, []step{{time.Hour, true, 0}, {0, true, 0}, {0, true, 0}, {0, false, 500 * time.Millisecond}}},
+		{"a burst of 0 is 1", Limit{Rate: 1}, []step{{0, true, 0}, {0, false, time.Second}}},
+		{"a rate of 0 is unlimited", Limit{Burst: 1}, []step{{0, true, 0}, {0, true, 0}, {0, true, 0}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1600000000, 0)}
+			limiter := NewLimiter(clock.Now)
+			for i, step := range test.steps {
+				clock.now = clock.now.Add(step.wait)
+				isAllowed, retryAfter := limiter.Allow("key", test.limit)
+				if isAllowed != step.isAllowed || retryAfter != step.retryAfter {
+					t.Fatalf("request %d is allowed %t and retried after %s, expected %t and %s", i+1, isAllowed, retryAfter, step.isAllowed, step.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowKeepsABucketPerKey(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	limiter := NewLimiter(clock.Now)
+	limit := Limit{Rate: 1, Burst: 1}
+	if isAllowed, _ := limiter.Allow("first", limit); !isAllowed {
+		t.Fatal("the first request of the first key was refused")
+	}
+	if isAllowed, _ := limiter.Allow("second", limit); !isAllowed {
+		t.Fatal("the first request of the second key was refused")
+	}
+	if isAllowed, _ := limiter.Allow("first", limit); isAllowed {
+		t.Fatal("the second request of the first key was allowed")
+	}
+	// a changed limit starts from a full bucket
+	if isAllowed, _ := limiter.Allow("first", Limit{Rate: 1, Burst: 2}); !isAllowed {
+		t.Fatal("the request with a new limit was refused")
+	}
+}
+
+func TestSweepDropsTheFullBuckets(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	limiter := NewLimiter(clock.Now)
+	limiter.Allow("idle", Limit{Rate: 1, Burst: 1})
+	limiter.Allow("busy", Limit{Rate: 0.001, Burst: 1})
+	clock.now = clock.now.Add(sweepInterval)
+	limiter.Allow("other", Limit{Rate: 1, Burst: 1})
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Fatal("the full bucket was kept")
+	}
+	if _, ok := limiter.buckets["busy"]; !ok {
+		t.Fatal("the bucket that is not full was dropped")
+	}
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+	"gorm.io/gorm"
+)
+
+// AddressRepository keeps the addresses, their transaction counts and the transactions they take part in
+type AddressRepository interface {
+	FindByHashes(addressHashes []string) ([]*entities.Address, error)
+	Create(addresses []*entities.Address) error
+	FindTransactionCounts(addressHashes []string) ([]entities.AddressTransactionCount, error)
+	CreateTransactionCounts(counts []entities.AddressTransactionCount) error
+	SaveTransactionCounts(counts []entities.AddressTransactionCount) error
+	CreateTransactionAddresses(transactionAddresses []*entities.TransactionAddress) error
+}
+
+type gormAddressRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormAddressRepository) FindByHashes(addressHashes []string) ([]*entities.Address, error) {
+	var addresses []*entities.Address
+	err := repository.db.Where(map[string]interface{}{"addressHash": addressHashes}).Find(&addresses).Error
+	return addresses, err
+}
+
+func (repository *gormAddressRepository) Create(addresses []*entities.Address) error {
+	if len(addresses) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&addresses).Error
+}
+
+func (repository *gormAddressRepository) FindTransactionCounts(addressHashes []string) ([]entities.AddressTransactionCount, error) {
+	var counts []entities.AddressTransactionCount
+	err := repository.db.Where(map[string]interface{}{"addressHash": addressHashes}).Find(&counts).Error
+	return counts, err
+}
+
+func (repository *gormAddressRepository) CreateTransactionCounts(counts []entities.AddressTransactionCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&counts).Error
+}
+
+func (repository *gormAddressRepository) SaveTransactionCounts(counts []entities.AddressTransactionCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Save(&counts).Error
+}
+
+func (repository *gormAddressRepository) CreateTransactionAddresses(transactionAddresses []*entities.TransactionAddress) error {
+	if len(transactionAddresses) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&transactionAddresses).Error
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AdminAuditLogRepository keeps the actions of the admin api
+type AdminAuditLogRepository interface {
+	Create(auditLog *entities.AdminAuditLog) error
+	// FindLatest finds the last limit actions, the latest first
+	FindLatest(limit int) ([]entities.AdminAuditLog, error)
+}
+
+type gormAdminAuditLogRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormAdminAuditLogRepository) Create(auditLog *entities.AdminAuditLog) error {
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(auditLog).Error
+}
+
+func (repository *gormAdminAuditLogRepository) FindLatest(limit int) ([]entities.AdminAuditLog, error) {
+	var auditLogs []entities.AdminAuditLog
+	err := repository.db.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: true}).Limit(limit).Find(&auditLogs).Error
+	return auditLogs, err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ApiKeyRepository keeps the api keys by the hash of the key
+type ApiKeyRepository interface {
+	Create(apiKey *entities.ApiKey) error
+	GetByName(name string) (*entities.ApiKey, error)
+	GetByKeyHash(keyHash string) (*entities.ApiKey, error)
+	FindAll() ([]entities.ApiKey, error)
+	// Revoke revokes the key of the name, it returns ErrNotFound when there is none
+	Revoke(name string) error
+}
+
+type gormApiKeyRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormApiKeyRepository) Create(apiKey *entities.ApiKey) error {
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(apiKey).Error
+}
+
+func (repository *gormApiKeyRepository) GetByName(name string) (*entities.ApiKey, error) {
+	var apiKey entities.ApiKey
+	err := repository.db.Where("name = ?", name).First(&apiKey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (repository *gormApiKeyRepository) GetByKeyHash(keyHash string) (*entities.ApiKey, error) {
+	var apiKey entities.ApiKey
+	err := repository.db.Where(clause.Eq{Column: clause.Column{Name: "keyHash"}, Value: keyHash}).First(&apiKey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (repository *gormApiKeyRepository) FindAll() ([]entities.ApiKey, error) {
+	var apiKeys []entities.ApiKey
+	err := repository.db.Order("id").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+func (repository *gormApiKeyRepository) Revoke(name string) error {
+	apiKey, err := repository.GetByName(name)
+	if err != nil {
+		return err
+	}
+	// the rows affected are not checked, mysql doesn't count a key that was already revoked
+	return repository.db.Model(&entities.ApiKey{}).Where("id = ?", apiKey.ID).Update("isRevoked", true).Error
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AppStateRepository keeps the named app states, the jobs lock their app state to run one at a time
+type AppStateRepository interface {
+	GetByName(name entities.AppStatesNames) (*entities.AppState, error)
+	// GetByNameForUpdate locks the app state until the transaction ends
+	GetByNameForUpdate(name entities.AppStatesNames) (*entities.AppState, error)
+	FirstOrCreate(name entities.AppStatesNames) (*entities.AppState, error)
+	FindByNames(names []entities.AppStatesNames) ([]entities.AppState, error)
+	Save(appState *entities.AppState) error
+}
+
+type gormAppStateRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormAppStateRepository) GetByName(name entities.AppStatesNames) (*entities.AppState, error) {
+	var appState entities.AppState
+	err := repository.db.Where("name = ?", name).First(&appState).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appState, nil
+}
+
+func (repository *gormAppStateRepository) GetByNameForUpdate(name entities.AppStatesNames) (*entities.AppState, error) {
+	var appState entities.AppState
+	err := repository.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&appState).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appState, nil
+}
+
+func (repository *gormAppStateRepository) FirstOrCreate(name entities.AppStatesNames) (*entities.AppState, error) {
+	appState := entities.AppState{Name: name}
+	err := repository.db.Where("name = ?", name).FirstOrCreate(&appState).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appState, nil
+}
+
+func (repository *gormAppStateRepository) FindByNames(names []entities.AppStatesNames) ([]entities.AppState, error) {
+	var appStates []entities.AppState
+	err := repository.db.Where("name IN ?", names).Find(&appStates).Error
+	return appStates, err
+}
+
+func (repository *gormAppStateRepository) Save(appState *entities.AppState) error {
+	return repository.db.Omit("CreateTime", "UpdateTime").Save(appState).Error
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"strings"
+
+	dbProvider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddressCurrency is the unique key of an address balance
+type AddressCurrency struct {
+	AddressHash string
+	CurrencyId  int32
+}
+
+// CurrencyBalanceTotal sums the balances of a currency, holders are the addresses with a positive balance
+type CurrencyBalanceTotal struct {
+	CurrencyId int32           `gorm:"column:currencyId"`
+	Total      decimal.Decimal `gorm:"column:total"`
+	Holders    int32           `gorm:"column:holders"`
+}
+
+// CurrencyHashBalance is an address balance with the hash of its currency
+type CurrencyHashBalance struct {
+	AddressHash  string          `gorm:"column:addressHash"`
+	Amount       decimal.Decimal `gorm:"column:amount"`
+	CurrencyHash string          `gorm:"column:currencyHash"`
+}
+
+// baseTransactionTables are the tables of the base transactions whose amounts make up the balances,
+// the token minting service data adds the minted amount to its receiver as well
+var baseTransactionTables = []string{
+	"input_base_transactions",
+	"receiver_base_transactions",
+	"fullnode_fee_base_transactions",
+	"network_fee_base_transactions",
+	"event_input_base_transactions",
+	"token_generation_fee_base_transactions",
+	"token_minting_fee_base_transactions",
+}
+
+// BalanceRepository keeps the address balances
+type BalanceRepository interface {
+	// FindForUpdate locks the existing balances of the keys until the transaction ends
+	FindForUpdate(keys []AddressCurrency) ([]entities.AddressBalance, error)
+	// AddAmounts inserts the balances that don't exist and adds the amount to the ones that do
+	AddAmounts(balances []entities.AddressBalance) error
+	SumByCurrency(currencyIds []int32) ([]CurrencyBalanceTotal, error)
+	Create(balances []entities.AddressBalance) error
+	DeleteByCurrencyIds(currencyIds []int32) error
+	// ForEachAt calls fn with the balances of the currency hashes at the point, of every currency when currencyHashes is empty.
+	// The balances come ordered by id with the diffs of the transactions applied after the point subtracted, followed by the
+	// diffs of the addresses that have no balance row anymore. The base transactions without a currency hash are native
+	ForEachAt(point BalancePoint, currencyHashes []string, nativeCurrencyHash string, fn func(balance CurrencyHashBalance) error) error
+}
+
+type gormBalanceRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormBalanceRepository) FindForUpdate(keys []AddressCurrency) ([]entities.AddressBalance, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	addressCurrencyPairs := make([][]interface{}, 0, len(keys))
+	for _, key := range keys {
+		addressCurrencyPairs = append(addressCurrencyPairs, []interface{}{key.AddressHash, key.CurrencyId})
+	}
+	var balances []entities.AddressBalance
+	err := repository.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("addressHash", "currencyId", "amount").
+		Where(dbProvider.PairIn(clause.Column{Name: "addressHash"}, clause.Column{Name: "currencyId"}, addressCurrencyPairs)).
+		Find(&balances).Error
+	return balances, err
+}
+
+func (repository *gormBalanceRepository) AddAmounts(balances []entities.AddressBalance) error {
+	if len(balances) == 0 {
+		return nil
+	}
+	return repository.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "addressHash"}, {Name: "currencyId"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"amount": dbProvider.AddOnConflict("address_balances", "amount")}),
+	}).Omit("CreateTime", "UpdateTime").Create(&balances).Error
+}
+
+func (repository *gormBalanceRepository) SumByCurrency(currencyIds []int32) ([]CurrencyBalanceTotal, error) {
+	var totals []CurrencyBalanceTotal
+	err := repository.db.Model(&entities.AddressBalance{}).
+		Select("?, SUM(amount) as total, SUM(CASE WHEN amount > 0 THEN 1 ELSE 0 END) as holders", clause.Column{Name: "currencyId"}).
+		Where(map[string]interface{}{"currencyId": currencyIds}).
+		Group("currencyId").
+		Scan(&totals).Error
+	return totals, err
+}
+
+func (repository *gormBalanceRepository) Create(balances []entities.AddressBalance) error {
+	if len(balances) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").CreateInBatches(&balances, createBatchSize).Error
+}
+
+func (repository *gormBalanceRepository) DeleteByCurrencyIds(currencyIds []int32) error {
+	return repository.db.Where(map[string]interface{}{"currencyId": currencyIds}).Delete(&entities.AddressBalance{}).Error
+}
+
+func (repository *gormBalanceRepository) ForEachAt(point BalancePoint, currencyHashes []string, nativeCurrencyHash string, fn func(balance CurrencyHashBalance) error) error {
+	balances := repository.db.Model(&entities.AddressBalance{}).
+		Joins("INNER JOIN currencies on currencies.id = ?", clause.Column{Table: "address_balances", Name: "currencyId"}).
+		Order("address_balances.id")
+	if len(currencyHashes) > 0 {
+		balances = balances.Where("currencies.hash IN ?", currencyHashes)
+	}
+	if point.IsCurrent() {
+		return repository.forEach(balances.Select("?, ?, ?",
+			clause.Column{Table: "address_balances", Name: "addressHash"},
+			clause.Column{Table: "address_balances", Name: "amount"},
+			clause.Column{Table: "currencies", Name: "hash", Alias: "currencyHash"}), fn)
+	}
+	transactions := &gormTransactionRepository{db: repository.db}
+	balanceDiffs := sumBalanceDiffs(transactions.appliedAfter(point), nativeCurrencyHash)
+	diffOn := gorm.Expr("? = ? AND ? = ?",
+		clause.Column{Table: "diffs", Name: "addressHash"}, clause.Column{Table: "address_balances", Name: "addressHash"},
+		clause.Column{Table: "diffs", Name: "currencyHash"}, clause.Column{Table: "currencies", Name: "hash"})
+	err := repository.forEach(balances.
+		Select("?, ? - COALESCE(?, 0) AS ?, ?",
+			clause.Column{Table: "address_balances", Name: "addressHash"},
+			clause.Column{Table: "address_balances", Name: "amount"}, clause.Column{Table: "diffs", Name: "amount"}, clause.Column{Name: "amount"},
+			clause.Column{Table: "currencies", Name: "hash", Alias: "currencyHash"}).
+		Joins("LEFT JOIN (?) diffs ON ?", balanceDiffs, diffOn), fn)
+	if err != nil {
+		return err
+	}
+	// diffs without a balance row belong to balances that no longer exist
+	missingBalances := repository.db.Table("(?) diffs", balanceDiffs).
+		Select("?, -? AS ?, ?",
+			clause.Column{Table: "diffs", Name: "addressHash"}, clause.Column{Table: "diffs", Name: "amount"}, clause.Column{Name: "amount"},
+			clause.Column{Table: "diffs", Name: "currencyHash"}).
+		Where("NOT EXISTS (?)", repository.db.Model(&entities.AddressBalance{}).Select("1").
+			Joins("INNER JOIN currencies on currencies.id = ?", clause.Column{Table: "address_balances", Name: "currencyId"}).
+			Where(diffOn)).
+		Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Table: "diffs", Name: "currencyHash"}},
+			{Column: clause.Column{Table: "diffs", Name: "addressHash"}},
+		}})
+	if len(currencyHashes) > 0 {
+		missingBalances = missingBalances.Where("? IN ?", clause.Column{Table: "diffs", Name: "currencyHash"}, currencyHashes)
+	}
+	return repository.forEach(missingBalances, fn)
+}
+
+// forEach streams the rows of the balance query to fn
+func (repository *gormBalanceRepository) forEach(query *gorm.DB, fn func(balance CurrencyHashBalance) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var balance CurrencyHashBalance
+		if err := repository.db.ScanRows(rows, &balance); err != nil {
+			return err
+		}
+		if err := fn(balance); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// sumBalanceDiffs sums the amounts of the base transactions of the applied transactions by address and currency hash
+// the way the balance updates apply them, the base transactions without a currency hash are native
+func sumBalanceDiffs(appliedTransactions *gorm.DB, nativeCurrencyHash string) clause.Expr {
+	appliedTransactionIds := appliedTransactions.Session(&gorm.Session{}).Select("id")
+	addressHash, currencyHash, amount := clause.Column{Name: "addressHash"}, clause.Column{Name: "currencyHash"}, clause.Column{Name: "amount"}
+	selects := make([]string, 0, len(baseTransactionTables)+1)
+	var vars []interface{}
+	for _, table := range baseTransactionTables {
+		selects = append(selects, "SELECT ?, COALESCE(?, ?) AS ?, ? FROM ? WHERE ? IN (?)")
+		vars = append(vars, addressHash, currencyHash, nativeCurrencyHash, currencyHash, amount,
+			clause.Table{Name: table}, clause.Column{Name: "transactionId"}, appliedTransactionIds)
+	}
+	selects = append(selects, "SELECT ? AS ?, ? AS ?, ? AS ? FROM ? WHERE ? IN (SELECT ? FROM ? WHERE ? IN (?))")
+	vars = append(vars,
+		clause.Column{Name: "receiverAddress"}, addressHash,
+		clause.Column{Name: "mintingCurrencyHash"}, currencyHash,
+		clause.Column{Name: "mintingAmount"}, amount,
+		clause.Table{Name: "token_minting_service_data"}, clause.Column{Name: "baseTransactionId"},
+		clause.Column{Name: "id"}, clause.Table{Name: "token_minting_fee_base_transactions"}, clause.Column{Name: "transactionId"}, appliedTransactionIds)
+
+	vars = append([]interface{}{addressHash, currencyHash, amount, amount}, vars...)
+	vars = append(vars, addressHash, currencyHash)
+	return gorm.Expr("SELECT ?, ?, SUM(?) AS ? FROM ("+strings.Join(selects, " UNION ALL ")+") base_transactions GROUP BY ?, ?", vars...)
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+	"gorm.io/gorm"
+)
+
+// createBatchSize is the number of rows inserted by one statement
+const createBatchSize = 1000
+
+// ClusterStampRepository keeps the cluster stamp history and the balance differences found by the rebases
+type ClusterStampRepository interface {
+	Count() (int64, error)
+	Create(clusterStamp *entities.ClusterStamp) error
+	Save(clusterStamp *entities.ClusterStamp) error
+	CreateDifferences(differences []*entities.ClusterStampDifference) error
+}
+
+type gormClusterStampRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormClusterStampRepository) Count() (int64, error) {
+	var count int64
+	err := repository.db.Model(&entities.ClusterStamp{}).Count(&count).Error
+	return count, err
+}
+
+func (repository *gormClusterStampRepository) Create(clusterStamp *entities.ClusterStamp) error {
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(clusterStamp).Error
+}
+
+func (repository *gormClusterStampRepository) Save(clusterStamp *entities.ClusterStamp) error {
+	return repository.db.Omit("CreateTime", "UpdateTime").Save(clusterStamp).Error
+}
+
+func (repository *gormClusterStampRepository) CreateDifferences(differences []*entities.ClusterStampDifference) error {
+	if len(differences) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").CreateInBatches(differences, createBatchSize).Error
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CurrencySupplyRow is the supply of a currency with its hash
+type CurrencySupplyRow struct {
+	CurrencyHash      string          `gorm:"column:currencyHash"`
+	MintedAmount      decimal.Decimal `gorm:"column:mintedAmount"`
+	BurnedAmount      decimal.Decimal `gorm:"column:burnedAmount"`
+	CirculatingSupply decimal.Decimal `gorm:"column:circulatingSupply"`
+	HolderCount       int32           `gorm:"column:holderCount"`
+}
+
+// CurrencyRepository keeps the currencies, the token generation data they are created from and their supplies
+type CurrencyRepository interface {
+	FindByHashes(hashes []string) ([]entities.Currency, error)
+	FirstOrCreate(hash string) (*entities.Currency, error)
+	Create(currencies []*entities.Currency) error
+	CreateOriginatorCurrencyData(originatorCurrencyData []*entities.OriginatorCurrencyData) error
+	CreateCurrencyTypeData(currencyTypeData []*entities.CurrencyTypeData) error
+	// FindSuppliesForUpdate locks the existing supplies of the currencies until the transaction ends
+	FindSuppliesForUpdate(currencyIds []int32) ([]*entities.CurrencySupply, error)
+	CreateSupplies(supplies []*entities.CurrencySupply) error
+	SaveSupplies(supplies []*entities.CurrencySupply) error
+	FindSupplies() ([]CurrencySupplyRow, error)
+	FindSupplyByHash(currencyHash string) (*CurrencySupplyRow, error)
+}
+
+type gormCurrencyRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormCurrencyRepository) FindByHashes(hashes []string) ([]entities.Currency, error) {
+	var currencies []entities.Currency
+	err := repository.db.Where(map[string]interface{}{"hash": hashes}).Find(&currencies).Error
+	return currencies, err
+}
+
+func (repository *gormCurrencyRepository) FirstOrCreate(hash string) (*entities.Currency, error) {
+	currency := entities.NewCurrency(hash)
+	err := repository.db.Where("hash = ?", hash).FirstOrCreate(currency).Error
+	if err != nil {
+		return nil, err
+	}
+	return currency, nil
+}
+
+func (repository *gormCurrencyRepository) Create(currencies []*entities.Currency) error {
+	if len(currencies) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&currencies).Error
+}
+
+func (repository *gormCurrencyRepository) CreateOriginatorCurrencyData(originatorCurrencyData []*entities.OriginatorCurrencyData) error {
+	if len(originatorCurrencyData) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&originatorCurrencyData).Error
+}
+
+func (repository *gormCurrencyRepository) CreateCurrencyTypeData(currencyTypeData []*entities.CurrencyTypeData) error {
+	if len(currencyTypeData) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&currencyTypeData).Error
+}
+
+func (repository *gormCurrencyRepository) FindSuppliesForUpdate(currencyIds []int32) ([]*entities.CurrencySupply, error) {
+	var supplies []*entities.CurrencySupply
+	err := repository.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where(map[string]interface{}{"currencyId": currencyIds}).Find(&supplies).Error
+	return supplies, err
+}
+
+func (repository *gormCurrencyRepository) CreateSupplies(supplies []*entities.CurrencySupply) error {
+	if len(supplies) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&supplies).Error
+}
+
+func (repository *gormCurrencyRepository) SaveSupplies(supplies []*entities.CurrencySupply) error {
+	if len(supplies) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Save(&supplies).Error
+}
+
+func (repository *gormCurrencyRepository) FindSupplies() ([]CurrencySupplyRow, error) {
+	var supplies []CurrencySupplyRow
+	err := repository.currencySupplyQuery().Find(&supplies).Error
+	return supplies, err
+}
+
+func (repository *gormCurrencyRepository) FindSupplyByHash(currencyHash string) (*CurrencySupplyRow, error) {
+	var supply CurrencySupplyRow
+	err := repository.currencySupplyQuery().Where("currencies.hash = ?", currencyHash).First(&supply).Error
+	if err != nil {
+		return nil, err
+	}
+	return &supply, nil
+}
+
+func (repository *gormCurrencyRepository) currencySupplyQuery() *gorm.DB {
+	return repository.db.Model(&entities.CurrencySupply{}).
+		Select("?, ?, ?, ?, ?",
+			clause.Column{Table: "currencies", Name: "hash", Alias: "currencyHash"},
+			clause.Column{Table: "currency_supplies", Name: "mintedAmount"},
+			clause.Column{Table: "currency_supplies", Name: "burnedAmount"},
+			clause.Column{Table: "currency_supplies", Name: "circulatingSupply"},
+			clause.Column{Table: "currency_supplies", Name: "holderCount"}).
+		Joins("INNER JOIN currencies on currencies.id = ?", clause.Column{Table: "currency_supplies", Name: "currencyId"})
+}
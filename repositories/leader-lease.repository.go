@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"time"
+
+	dbProvider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/coti-io/coti-db-app/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LeaderLeaseRepository keeps the leases of the leader election, the lease times are db times so the clocks of the app hosts don't matter
+type LeaderLeaseRepository interface {
+	GetByName(name string) (*entities.LeaderLease, error)
+	// GetByNameForShare locks the lease against a new holder until the transaction ends, the holder can still be read by other transactions
+	GetByNameForShare(name string) (*entities.LeaderLease, error)
+	// CreateIfMissing creates the lease as an expired lease without a holder, nothing is done when it exists
+	CreateIfMissing(name string) error
+	// Acquire gives the lease to holder for duration when it is free, expired or already held by holder and returns the lease,
+	// the lease has another holder when it was not acquired. The fencing token grows when the holder changes
+	Acquire(name string, holder string, duration time.Duration) (*entities.LeaderLease, error)
+}
+
+type gormLeaderLeaseRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormLeaderLeaseRepository) GetByName(name string) (*entities.LeaderLease, error) {
+	var lease entities.LeaderLease
+	err := repository.db.Where("name = ?", name).First(&lease).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+func (repository *gormLeaderLeaseRepository) GetByNameForShare(name string) (*entities.LeaderLease, error) {
+	var lease entities.LeaderLease
+	err := repository.db.Clauses(clause.Locking{Strength: "SHARE"}).Where("name = ?", name).First(&lease).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+func (repository *gormLeaderLeaseRepository) CreateIfMissing(name string) error {
+	return repository.db.Model(&entities.LeaderLease{}).Clauses(clause.OnConflict{DoNothing: true}).Create(map[string]interface{}{
+		"name":       name,
+		"renewTime":  dbProvider.Now(),
+		"expireTime": dbProvider.Now(),
+	}).Error
+}
+
+func (repository *gormLeaderLeaseRepository) Acquire(name string, holder string, duration time.Duration) (*entities.LeaderLease, error) {
+	holderColumn := clause.Column{Name: "holder"}
+	fencingTokenColumn := clause.Column{Name: "fencingToken"}
+	// the columns are set in the order of their names, mysql sees the new value of a column set before, so the token is set before the holder.
+	// The affected rows are not checked since mysql doesn't count a renewal within the same second as changed
+	err := repository.db.Model(&entities.LeaderLease{}).
+		Where("name = ?", name).
+		Where("? = ? OR ? = '' OR ? < ?", holderColumn, holder, holderColumn, clause.Column{Name: "expireTime"}, dbProvider.Now()).
+		Updates(map[string]interface{}{
+			"fencingToken": gorm.Expr("CASE WHEN ? = ? THEN ? ELSE ? + 1 END", holderColumn, holder, fencingTokenColumn, fencingTokenColumn),
+			"holder":       holder,
+			"renewTime":    dbProvider.Now(),
+			"expireTime":   dbProvider.FromNow(duration),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return repository.GetByName(name)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+type memoryAddressRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryAddressRepository) FindByHashes(addressHashes []string) ([]*entities.Address, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	var addresses []*entities.Address
+	for _, address := range repository.store.data.addresses {
+		if containsString(addressHashes, address.AddressHash) {
+			found := address
+			addresses = append(addresses, &found)
+		}
+	}
+	return addresses, nil
+}
+
+func (repository *memoryAddressRepository) Create(addresses []*entities.Address) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, address := range addresses {
+		address.ID, address.CreateTime, address.UpdateTime = data.nextId("addresses"), createTime, createTime
+		data.addresses = append(data.addresses, *address)
+	}
+	return nil
+}
+
+func (repository *memoryAddressRepository) FindTransactionCounts(addressHashes []string) ([]entities.AddressTransactionCount, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	var counts []entities.AddressTransactionCount
+	for _, count := range repository.store.data.addressTransactionCounts {
+		if containsString(addressHashes, count.AddressHash) {
+			counts = append(counts, count)
+		}
+	}
+	return counts, nil
+}
+
+func (repository *memoryAddressRepository) CreateTransactionCounts(counts []entities.AddressTransactionCount) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, count := range counts {
+		count.ID, count.CreateTime, count.UpdateTime = data.nextId("address_transaction_counts"), createTime, createTime
+		data.addressTransactionCounts = append(data.addressTransactionCounts, count)
+	}
+	return nil
+}
+
+func (repository *memoryAddressRepository) SaveTransactionCounts(counts []entities.AddressTransactionCount) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	updateTime := now()
+	for _, count := range counts {
+		for i := range data.addressTransactionCounts {
+			if data.addressTransactionCounts[i].ID == count.ID {
+				count.CreateTime, count.UpdateTime = data.addressTransactionCounts[i].CreateTime, updateTime
+				data.addressTransactionCounts[i] = count
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (repository *memoryAddressRepository) CreateTransactionAddresses(transactionAddresses []*entities.TransactionAddress) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, transactionAddress := range transactionAddresses {
+		transactionAddress.ID, transactionAddress.CreateTime, transactionAddress.UpdateTime = data.nextId("transaction_addresses"), createTime, createTime
+		data.transactionAddresses = append(data.transactionAddresses, *transactionAddress)
+	}
+	return nil
+}
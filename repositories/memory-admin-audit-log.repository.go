@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+type memoryAdminAuditLogRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryAdminAuditLogRepository) Create(auditLog *entities.AdminAuditLog) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	auditLog.ID = data.nextId("admin_audit_logs")
+	auditLog.CreateTime = now()
+	auditLog.UpdateTime = auditLog.CreateTime
+	data.adminAuditLogs = append(data.adminAuditLogs, *auditLog)
+	return nil
+}
+
+func (repository *memoryAdminAuditLogRepository) FindLatest(limit int) ([]entities.AdminAuditLog, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	auditLogs := repository.store.data.adminAuditLogs
+	var latest []entities.AdminAuditLog
+	for i := len(auditLogs) - 1; i >= 0 && len(latest) < limit; i-- {
+		latest = append(latest, auditLogs[i])
+	}
+	return latest, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+type memoryApiKeyRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryApiKeyRepository) Create(apiKey *entities.ApiKey) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	apiKey.ID = data.nextId("api_keys")
+	apiKey.CreateTime = now()
+	apiKey.UpdateTime = apiKey.CreateTime
+	data.apiKeys = append(data.apiKeys, *apiKey)
+	return nil
+}
+
+func (repository *memoryApiKeyRepository) GetByName(name string) (*entities.ApiKey, error) {
+	return repository.find(func(apiKey entities.ApiKey) bool { return apiKey.Name == name })
+}
+
+func (repository *memoryApiKeyRepository) GetByKeyHash(keyHash string) (*entities.ApiKey, error) {
+	return repository.find(func(apiKey entities.ApiKey) bool { return apiKey.KeyHash == keyHash })
+}
+
+func (repository *memoryApiKeyRepository) FindAll() ([]entities.ApiKey, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	return append([]entities.ApiKey(nil), repository.store.data.apiKeys...), nil
+}
+
+func (repository *memoryApiKeyRepository) Revoke(name string) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	apiKeys := repository.store.data.apiKeys
+	for i := range apiKeys {
+		if apiKeys[i].Name == name {
+			apiKeys[i].IsRevoked = true
+			apiKeys[i].UpdateTime = now()
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (repository *memoryApiKeyRepository) find(matches func(apiKey entities.ApiKey) bool) (*entities.ApiKey, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	for _, apiKey := range repository.store.data.apiKeys {
+		if matches(apiKey) {
+			found := apiKey
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+type memoryAppStateRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryAppStateRepository) GetByName(name entities.AppStatesNames) (*entities.AppState, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	for _, appState := range repository.store.data.appStates {
+		if appState.Name == name {
+			found := appState
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// GetByNameForUpdate doesn't lock, the memory transactions already run one at a time
+func (repository *memoryAppStateRepository) GetByNameForUpdate(name entities.AppStatesNames) (*entities.AppState, error) {
+	return repository.GetByName(name)
+}
+
+func (repository *memoryAppStateRepository) FirstOrCreate(name entities.AppStatesNames) (*entities.AppState, error) {
+	appState, err := repository.GetByName(name)
+	if err != ErrNotFound {
+		return appState, err
+	}
+	appState = &entities.AppState{Name: name}
+	return appState, repository.Save(appState)
+}
+
+func (repository *memoryAppStateRepository) FindByNames(names []entities.AppStatesNames) ([]entities.AppState, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	var appStates []entities.AppState
+	for _, appState := range repository.store.data.appStates {
+		for _, name := range names {
+			if appState.Name == name {
+				appStates = append(appStates, appState)
+				break
+			}
+		}
+	}
+	return appStates, nil
+}
+
+func (repository *memoryAppStateRepository) Save(appState *entities.AppState) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	appState.UpdateTime = now()
+	for i := range data.appStates {
+		if data.appStates[i].ID == appState.ID {
+			appState.CreateTime = data.appStates[i].CreateTime
+			data.appStates[i] = *appState
+			return nil
+		}
+	}
+	appState.ID = data.nextId("app_states")
+	appState.CreateTime = appState.UpdateTime
+	data.appStates = append(data.appStates, *appState)
+	return nil
+}
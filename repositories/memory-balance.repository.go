@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/shopspring/decimal"
+)
+
+type memoryBalanceRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryBalanceRepository) FindForUpdate(keys []AddressCurrency) ([]entities.AddressBalance, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	keySet := make(map[AddressCurrency]bool, len(keys))
+	for _, key := range keys {
+		keySet[key] = true
+	}
+	var balances []entities.AddressBalance
+	for _, balance := range repository.store.data.addressBalances {
+		if keySet[AddressCurrency{AddressHash: balance.AddressHash, CurrencyId: balance.CurrencyId}] {
+			balances = append(balances, balance)
+		}
+	}
+	return balances, nil
+}
+
+func (repository *memoryBalanceRepository) AddAmounts(balances []entities.AddressBalance) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	updateTime := now()
+	for _, balance := range balances {
+		isAdded := false
+		for i := range data.addressBalances {
+			if data.addressBalances[i].AddressHash == balance.AddressHash && data.addressBalances[i].CurrencyId == balance.CurrencyId {
+				data.addressBalances[i].Amount = data.addressBalances[i].Amount.Add(balance.Amount)
+				data.addressBalances[i].UpdateTime = updateTime
+				isAdded = true
+				break
+			}
+		}
+		if !isAdded {
+			balance.ID = data.nextId("address_balances")
+			balance.CreateTime = updateTime
+			balance.UpdateTime = updateTime
+			data.addressBalances = append(data.addressBalances, balance)
+		}
+	}
+	return nil
+}
+
+func (repository *memoryBalanceRepository) SumByCurrency(currencyIds []int32) ([]CurrencyBalanceTotal, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	currencyIdToTotalMap := make(map[int32]*CurrencyBalanceTotal)
+	var totals []*CurrencyBalanceTotal
+	for _, balance := range repository.store.data.addressBalances {
+		if !containsInt32(currencyIds, balance.CurrencyId) {
+			continue
+		}
+		total := currencyIdToTotalMap[balance.CurrencyId]
+		if total == nil {
+			total = &CurrencyBalanceTotal{CurrencyId: balance.CurrencyId}
+			currencyIdToTotalMap[balance.CurrencyId] = total
+			totals = append(totals, total)
+		}
+		total.Total = total.Total.Add(balance.Amount)
+		if balance.Amount.IsPositive() {
+			total.Holders++
+		}
+	}
+	result := make([]CurrencyBalanceTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	return result, nil
+}
+
+func (repository *memoryBalanceRepository) Create(balances []entities.AddressBalance) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, balance := range balances {
+		balance.ID = data.nextId("address_balances")
+		balance.CreateTime = createTime
+		balance.UpdateTime = createTime
+		data.addressBalances = append(data.addressBalances, balance)
+	}
+	return nil
+}
+
+func (repository *memoryBalanceRepository) DeleteByCurrencyIds(currencyIds []int32) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	var balances []entities.AddressBalance
+	for _, balance := range data.addressBalances {
+		if !containsInt32(currencyIds, balance.CurrencyId) {
+			balances = append(balances, balance)
+		}
+	}
+	data.addressBalances = balances
+	return nil
+}
+
+func (repository *memoryBalanceRepository) ForEachAt(point BalancePoint, currencyHashes []string, nativeCurrencyHash string, fn func(balance CurrencyHashBalance) error) error {
+	currencyAddressDiffMap := make(map[string]map[string]decimal.Decimal)
+	if !point.IsCurrent() {
+		transactions := &memoryTransactionRepository{store: repository.store}
+		transactionIds, err := transactions.FindAppliedIdsAfter(point)
+		if err != nil {
+			return err
+		}
+		baseTransactions, err := transactions.FindBaseTransactions(transactionIds)
+		if err != nil {
+			return err
+		}
+		currencyAddressDiffMap = sumMemoryBalanceDiffs(baseTransactions, nativeCurrencyHash)
+	}
+	isExported := func(currencyHash string) bool {
+		return len(currencyHashes) == 0 || containsString(currencyHashes, currencyHash)
+	}
+
+	repository.store.mutex.Lock()
+	currencyIdToHashMap := make(map[int32]string)
+	for _, currency := range repository.store.data.currencies {
+		currencyIdToHashMap[currency.ID] = currency.Hash
+	}
+	var balances []CurrencyHashBalance
+	for _, balance := range repository.store.data.addressBalances {
+		currencyHash := currencyIdToHashMap[balance.CurrencyId]
+		if !isExported(currencyHash) {
+			continue
+		}
+		diff := currencyAddressDiffMap[currencyHash][balance.AddressHash]
+		delete(currencyAddressDiffMap[currencyHash], balance.AddressHash)
+		balances = append(balances, CurrencyHashBalance{AddressHash: balance.AddressHash, Amount: balance.Amount.Sub(diff), CurrencyHash: currencyHash})
+	}
+	repository.store.mutex.Unlock()
+
+	// diffs without a balance row belong to balances that no longer exist
+	var missingBalances []CurrencyHashBalance
+	for currencyHash, addressDiffMap := range currencyAddressDiffMap {
+		if !isExported(currencyHash) {
+			continue
+		}
+		for addressHash, diff := range addressDiffMap {
+			missingBalances = append(missingBalances, CurrencyHashBalance{AddressHash: addressHash, Amount: diff.Neg(), CurrencyHash: currencyHash})
+		}
+	}
+	sort.Slice(missingBalances, func(i, j int) bool {
+		if missingBalances[i].CurrencyHash != missingBalances[j].CurrencyHash {
+			return missingBalances[i].CurrencyHash < missingBalances[j].CurrencyHash
+		}
+		return missingBalances[i].AddressHash < missingBalances[j].AddressHash
+	})
+	for _, balance := range append(balances, missingBalances...) {
+		if err := fn(balance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sumMemoryBalanceDiffs sums the amounts of the base transactions by currency hash and address the way sumBalanceDiffs does
+func sumMemoryBalanceDiffs(baseTransactions *BaseTransactions, nativeCurrencyHash string) map[string]map[string]decimal.Decimal {
+	currencyAddressDiffMap := make(map[string]map[string]decimal.Decimal)
+	add := func(addressHash string, currencyHash *string, amount decimal.Decimal) {
+		hash := nativeCurrencyHash
+		if currencyHash != nil {
+			hash = *currencyHash
+		}
+		if currencyAddressDiffMap[hash] == nil {
+			currencyAddressDiffMap[hash] = make(map[string]decimal.Decimal)
+		}
+		currencyAddressDiffMap[hash][addressHash] = currencyAddressDiffMap[hash][addressHash].Add(amount)
+	}
+	for _, v := range baseTransactions.InputBaseTransactions {
+		add(v.AddressHash, v.CurrencyHash, v.Amount)
+	}
+	for _, v := range baseTransactions.ReceiverBaseTransactions {
+		add(v.AddressHash, v.CurrencyHash, v.Amount)
+	}
+	for _, v := range baseTransactions.FullnodeFeeBaseTransactions {
+		add(v.AddressHash, v.CurrencyHash, v.Amount)
+	}
+	for _, v := range baseTransactions.NetworkFeeBaseTransactions {
+		add(v.AddressHash, v.CurrencyHash, v.Amount)
+	}
+	for _, v := range baseTransactions.EventInputBaseTransactions {
+		add(v.AddressHash, v.CurrencyHash, v.Amount)
+	}
+	for _, v := range baseTransactions.TokenGenerationFeeBaseTransactions {
+		add(v.AddressHash, v.CurrencyHash, v.Amount)
+	}
+	for _, v := range baseTransactions.TokenMintingFeeBaseTransactions {
+		add(v.AddressHash, v.CurrencyHash, v.Amount)
+	}
+	for _, v := range baseTransactions.TokenMintingServiceData {
+		mintingCurrencyHash := v.MintingCurrencyHash
+		add(v.ReceiverAddress, &mintingCurrencyHash, v.MintingAmount)
+	}
+	return currencyAddressDiffMap
+}
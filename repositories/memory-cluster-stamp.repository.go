@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+type memoryClusterStampRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryClusterStampRepository) Count() (int64, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	return int64(len(repository.store.data.clusterStamps)), nil
+}
+
+func (repository *memoryClusterStampRepository) Create(clusterStamp *entities.ClusterStamp) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	clusterStamp.ID = data.nextId("cluster_stamps")
+	clusterStamp.CreateTime = createTime
+	clusterStamp.UpdateTime = createTime
+	data.clusterStamps = append(data.clusterStamps, *clusterStamp)
+	return nil
+}
+
+func (repository *memoryClusterStampRepository) Save(clusterStamp *entities.ClusterStamp) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	for i := range data.clusterStamps {
+		if data.clusterStamps[i].ID == clusterStamp.ID {
+			clusterStamp.CreateTime = data.clusterStamps[i].CreateTime
+			clusterStamp.UpdateTime = now()
+			data.clusterStamps[i] = *clusterStamp
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (repository *memoryClusterStampRepository) CreateDifferences(differences []*entities.ClusterStampDifference) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, difference := range differences {
+		difference.ID = data.nextId("cluster_stamp_differences")
+		difference.CreateTime = createTime
+		difference.UpdateTime = createTime
+		data.clusterStampDifferences = append(data.clusterStampDifferences, *difference)
+	}
+	return nil
+}
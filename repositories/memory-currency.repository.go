@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+type memoryCurrencyRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryCurrencyRepository) FindByHashes(hashes []string) ([]entities.Currency, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	var currencies []entities.Currency
+	for _, currency := range repository.store.data.currencies {
+		if containsString(hashes, currency.Hash) {
+			currencies = append(currencies, currency)
+		}
+	}
+	return currencies, nil
+}
+
+func (repository *memoryCurrencyRepository) FirstOrCreate(hash string) (*entities.Currency, error) {
+	currencies, err := repository.FindByHashes([]string{hash})
+	if err != nil {
+		return nil, err
+	}
+	if len(currencies) > 0 {
+		return &currencies[0], nil
+	}
+	currency := entities.NewCurrency(hash)
+	return currency, repository.Create([]*entities.Currency{currency})
+}
+
+func (repository *memoryCurrencyRepository) Create(currencies []*entities.Currency) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, currency := range currencies {
+		currency.ID, currency.CreateTime, currency.UpdateTime = data.nextId("currencies"), createTime, createTime
+		data.currencies = append(data.currencies, *currency)
+	}
+	return nil
+}
+
+func (repository *memoryCurrencyRepository) CreateOriginatorCurrencyData(originatorCurrencyData []*entities.OriginatorCurrencyData) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, v := range originatorCurrencyData {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("originator_currency_data"), createTime, createTime
+		data.originatorCurrencyData = append(data.originatorCurrencyData, *v)
+	}
+	return nil
+}
+
+func (repository *memoryCurrencyRepository) CreateCurrencyTypeData(currencyTypeData []*entities.CurrencyTypeData) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, v := range currencyTypeData {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("currency_type_data"), createTime, createTime
+		data.currencyTypeData = append(data.currencyTypeData, *v)
+	}
+	return nil
+}
+
+func (repository *memoryCurrencyRepository) FindSuppliesForUpdate(currencyIds []int32) ([]*entities.CurrencySupply, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	var supplies []*entities.CurrencySupply
+	for _, supply := range repository.store.data.currencySupplies {
+		if containsInt32(currencyIds, supply.CurrencyId) {
+			found := supply
+			supplies = append(supplies, &found)
+		}
+	}
+	return supplies, nil
+}
+
+func (repository *memoryCurrencyRepository) CreateSupplies(supplies []*entities.CurrencySupply) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, supply := range supplies {
+		supply.ID, supply.CreateTime, supply.UpdateTime = data.nextId("currency_supplies"), createTime, createTime
+		data.currencySupplies = append(data.currencySupplies, *supply)
+	}
+	return nil
+}
+
+func (repository *memoryCurrencyRepository) SaveSupplies(supplies []*entities.CurrencySupply) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	updateTime := now()
+	for _, supply := range supplies {
+		for i := range data.currencySupplies {
+			if data.currencySupplies[i].ID == supply.ID {
+				supply.CreateTime, supply.UpdateTime = data.currencySupplies[i].CreateTime, updateTime
+				data.currencySupplies[i] = *supply
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (repository *memoryCurrencyRepository) FindSupplies() ([]CurrencySupplyRow, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	var rows []CurrencySupplyRow
+	for _, supply := range data.currencySupplies {
+		for _, currency := range data.currencies {
+			if currency.ID == supply.CurrencyId {
+				rows = append(rows, CurrencySupplyRow{
+					CurrencyHash:      currency.Hash,
+					MintedAmount:      supply.MintedAmount,
+					BurnedAmount:      supply.BurnedAmount,
+					CirculatingSupply: supply.CirculatingSupply,
+					HolderCount:       supply.HolderCount,
+				})
+				break
+			}
+		}
+	}
+	return rows, nil
+}
+
+func (repository *memoryCurrencyRepository) FindSupplyByHash(currencyHash string) (*CurrencySupplyRow, error) {
+	rows, err := repository.FindSupplies()
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.CurrencyHash == currencyHash {
+			return &row, nil
+		}
+	}
+	return nil, ErrNotFound
+}
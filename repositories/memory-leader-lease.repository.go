@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+type memoryLeaderLeaseRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryLeaderLeaseRepository) GetByName(name string) (*entities.LeaderLease, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	for _, lease := range repository.store.data.leaderLeases {
+		if lease.Name == name {
+			found := lease
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// GetByNameForShare doesn't lock, the memory transactions already run one at a time
+func (repository *memoryLeaderLeaseRepository) GetByNameForShare(name string) (*entities.LeaderLease, error) {
+	return repository.GetByName(name)
+}
+
+func (repository *memoryLeaderLeaseRepository) CreateIfMissing(name string) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	for _, lease := range data.leaderLeases {
+		if lease.Name == name {
+			return nil
+		}
+	}
+	createTime := now()
+	data.leaderLeases = append(data.leaderLeases, entities.LeaderLease{
+		ID:         data.nextId("leader_leases"),
+		Name:       name,
+		RenewTime:  createTime,
+		ExpireTime: createTime,
+		CreateTime: createTime,
+		UpdateTime: createTime,
+	})
+	return nil
+}
+
+func (repository *memoryLeaderLeaseRepository) Acquire(name string, holder string, duration time.Duration) (*entities.LeaderLease, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	acquireTime := now()
+	for i := range repository.store.data.leaderLeases {
+		lease := &repository.store.data.leaderLeases[i]
+		if lease.Name != name {
+			continue
+		}
+		if lease.Holder != holder && lease.Holder != "" && !lease.ExpireTime.Before(acquireTime) {
+			found := *lease
+			return &found, nil
+		}
+		if lease.Holder != holder {
+			lease.FencingToken++
+			lease.Holder = holder
+		}
+		lease.RenewTime = acquireTime
+		lease.ExpireTime = acquireTime.Add(duration)
+		lease.UpdateTime = acquireTime
+		acquired := *lease
+		return &acquired, nil
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,465 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+type memoryTransactionRepository struct {
+	store *memoryStore
+}
+
+func (repository *memoryTransactionRepository) find(isMatch func(tx *entities.Transaction) bool, limit int) []entities.Transaction {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	var txs []entities.Transaction
+	for i := range repository.store.data.transactions {
+		if limit > 0 && len(txs) == limit {
+			break
+		}
+		tx := repository.store.data.transactions[i]
+		if isMatch(&tx) {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+func (repository *memoryTransactionRepository) FindByHashes(hashes []string) ([]entities.Transaction, error) {
+	return repository.find(func(tx *entities.Transaction) bool {
+		return containsString(hashes, tx.Hash)
+	}, 0), nil
+}
+
+// FindArchivedHashes finds nothing, the archive job only runs on a db
+func (repository *memoryTransactionRepository) FindArchivedHashes(hashes []string) ([]string, error) {
+	return nil, nil
+}
+
+func (repository *memoryTransactionRepository) FindToProcess(confirmation Confirmation, limit int) ([]entities.Transaction, error) {
+	return repository.find(func(tx *entities.Transaction) bool {
+		isInvalid := tx.IsValid.Valid && !tx.IsValid.Bool
+		return !tx.IsProcessed && (tx.Type == nil || *tx.Type != "ZeroSpend") && (confirmation.IsConfirmed(tx) || isInvalid)
+	}, limit), nil
+}
+
+func (repository *memoryTransactionRepository) FindUnconfirmed(confirmation Confirmation) ([]entities.Transaction, error) {
+	return repository.find(func(tx *entities.Transaction) bool {
+		return tx.Index != nil && !tx.IsProcessed && !confirmation.IsConfirmed(tx)
+	}, 0), nil
+}
+
+func (repository *memoryTransactionRepository) FindProcessedWithin(window time.Duration, fromId int32, limit int) ([]entities.Transaction, error) {
+	since := now().Add(-window)
+	return repository.find(func(tx *entities.Transaction) bool {
+		return tx.ID > fromId && tx.IsProcessed && !tx.IsSkipped && !tx.IsReversed && tx.ProcessedTime != nil && tx.ProcessedTime.After(since)
+	}, limit), nil
+}
+
+func (repository *memoryTransactionRepository) FindUnindexedCreatedBefore(age time.Duration) ([]entities.Transaction, error) {
+	before := now().Add(-age)
+	return repository.find(func(tx *entities.Transaction) bool {
+		return tx.Index == nil && tx.CreateTime.Before(before)
+	}, 0), nil
+}
+
+func (repository *memoryTransactionRepository) FindSkipped(fromId int32, limit int) ([]entities.Transaction, error) {
+	return repository.find(func(tx *entities.Transaction) bool {
+		return tx.IsSkipped && !tx.IsCoveredByStamp && tx.ID > fromId
+	}, limit), nil
+}
+
+func (repository *memoryTransactionRepository) CountBacklogs(confirmation Confirmation) (*Backlogs, error) {
+	return &Backlogs{
+		BalanceProcessing: int64(len(repository.find(func(tx *entities.Transaction) bool {
+			return !tx.IsProcessed && (tx.Type == nil || *tx.Type != "ZeroSpend") && confirmation.IsConfirmed(tx)
+		}, 0))),
+		Monitor: int64(len(repository.find(func(tx *entities.Transaction) bool {
+			return tx.Index != nil && !tx.IsProcessed && !confirmation.IsConfirmed(tx)
+		}, 0))),
+		PendingUnindexed: int64(len(repository.find(func(tx *entities.Transaction) bool {
+			return tx.Index == nil
+		}, 0))),
+	}, nil
+}
+
+// isAppliedAfter checks if the transaction is after the point and its balance effects are applied, the unindexed ones are after any index
+func isAppliedAfter(tx *entities.Transaction, point BalancePoint) bool {
+	if !tx.IsProcessed || tx.IsSkipped || tx.IsReversed {
+		return false
+	}
+	if point.Index != nil {
+		return tx.Index == nil || int64(*tx.Index) > *point.Index
+	}
+	return tx.AttachmentTime.GreaterThan(*point.AttachmentTime)
+}
+
+func (repository *memoryTransactionRepository) FindAppliedIdsAfter(point BalancePoint) ([]int32, error) {
+	var transactionIds []int32
+	for _, tx := range repository.find(func(tx *entities.Transaction) bool {
+		return isAppliedAfter(tx, point)
+	}, 0) {
+		transactionIds = append(transactionIds, tx.ID)
+	}
+	return transactionIds, nil
+}
+
+func (repository *memoryTransactionRepository) CountPendingUpTo(point BalancePoint) (int64, error) {
+	return int64(len(repository.find(func(tx *entities.Transaction) bool {
+		if tx.IsProcessed || (tx.Type != nil && *tx.Type == "ZeroSpend") {
+			return false
+		}
+		if point.Index != nil {
+			return tx.Index != nil && int64(*tx.Index) <= *point.Index
+		}
+		return tx.AttachmentTime.LessThanOrEqual(*point.AttachmentTime)
+	}, 0))), nil
+}
+
+func (repository *memoryTransactionRepository) MarkCoveredByStamp(index int64) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	updateTime := now()
+	for i := range repository.store.data.transactions {
+		tx := &repository.store.data.transactions[i]
+		if !tx.IsProcessed && tx.Index != nil && int64(*tx.Index) <= index {
+			tx.IsProcessed, tx.IsSkipped, tx.IsCoveredByStamp = true, true, true
+			tx.UpdateTime = updateTime
+		}
+	}
+	return nil
+}
+
+// GetArchivedRange returns an empty range, the memory repositories don't archive
+func (repository *memoryTransactionRepository) GetArchivedRange() (*ArchivedRange, error) {
+	return &ArchivedRange{}, nil
+}
+
+func (repository *memoryTransactionRepository) Create(txs []*entities.Transaction) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	for _, tx := range txs {
+		tx.ID = data.nextId("transactions")
+		tx.CreateTime = now()
+		tx.UpdateTime = tx.CreateTime
+		data.transactions = append(data.transactions, *tx)
+	}
+	return nil
+}
+
+func (repository *memoryTransactionRepository) Save(txs []*entities.Transaction) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	for _, tx := range txs {
+		tx.UpdateTime = now()
+		isSaved := false
+		for i := range data.transactions {
+			if data.transactions[i].ID == tx.ID {
+				tx.CreateTime = data.transactions[i].CreateTime
+				tx.ProcessedTime = data.transactions[i].ProcessedTime
+				setProcessedTime(tx)
+				data.transactions[i] = *tx
+				isSaved = true
+				break
+			}
+		}
+		if !isSaved {
+			tx.ID = data.nextId("transactions")
+			tx.CreateTime = tx.UpdateTime
+			setProcessedTime(tx)
+			data.transactions = append(data.transactions, *tx)
+		}
+	}
+	return nil
+}
+
+// setProcessedTime sets the processed time the first time the transaction is saved as processed
+func setProcessedTime(tx *entities.Transaction) {
+	if tx.IsProcessed && tx.ProcessedTime == nil {
+		processedTime := tx.UpdateTime
+		tx.ProcessedTime = &processedTime
+	}
+}
+
+func (repository *memoryTransactionRepository) DeleteWithBaseTransactions(transactionIds []int32) (*BaseTransactions, error) {
+	baseTransactions, err := repository.FindBaseTransactions(transactionIds)
+	if err != nil {
+		return nil, err
+	}
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+
+	var tokenGenerationFeeBaseTransactionIds []int32
+	for _, baseTransaction := range baseTransactions.TokenGenerationFeeBaseTransactions {
+		tokenGenerationFeeBaseTransactionIds = append(tokenGenerationFeeBaseTransactionIds, baseTransaction.ID)
+	}
+	var serviceDataIds []int32
+	var tokenGenerationServiceData []entities.TokenGenerationServiceData
+	for _, serviceData := range data.tokenGenerationServiceData {
+		if containsInt32(tokenGenerationFeeBaseTransactionIds, serviceData.BaseTransactionId) {
+			deleted := serviceData
+			baseTransactions.TokenGenerationServiceData = append(baseTransactions.TokenGenerationServiceData, &deleted)
+			serviceDataIds = append(serviceDataIds, serviceData.ID)
+			continue
+		}
+		tokenGenerationServiceData = append(tokenGenerationServiceData, serviceData)
+	}
+	data.tokenGenerationServiceData = tokenGenerationServiceData
+	var originatorCurrencyData []entities.OriginatorCurrencyData
+	for _, v := range data.originatorCurrencyData {
+		if !containsInt32(serviceDataIds, v.ServiceDataId) {
+			originatorCurrencyData = append(originatorCurrencyData, v)
+		}
+	}
+	data.originatorCurrencyData = originatorCurrencyData
+	var currencyTypeData []entities.CurrencyTypeData
+	for _, v := range data.currencyTypeData {
+		if !containsInt32(serviceDataIds, v.ServiceDataId) {
+			currencyTypeData = append(currencyTypeData, v)
+		}
+	}
+	data.currencyTypeData = currencyTypeData
+	var tokenMintingServiceDataIds []int32
+	for _, serviceData := range baseTransactions.TokenMintingServiceData {
+		tokenMintingServiceDataIds = append(tokenMintingServiceDataIds, serviceData.ID)
+	}
+	var tokenMintingServiceData []entities.TokenMintingServiceData
+	for _, v := range data.tokenMintingServiceData {
+		if !containsInt32(tokenMintingServiceDataIds, v.ID) {
+			tokenMintingServiceData = append(tokenMintingServiceData, v)
+		}
+	}
+	data.tokenMintingServiceData = tokenMintingServiceData
+
+	var ibts []entities.InputBaseTransaction
+	for _, v := range data.inputBaseTransactions {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			ibts = append(ibts, v)
+		}
+	}
+	data.inputBaseTransactions = ibts
+	var rbts []entities.ReceiverBaseTransaction
+	for _, v := range data.receiverBaseTransactions {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			rbts = append(rbts, v)
+		}
+	}
+	data.receiverBaseTransactions = rbts
+	var ffbts []entities.FullnodeFeeBaseTransaction
+	for _, v := range data.fullnodeFeeBaseTransactions {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			ffbts = append(ffbts, v)
+		}
+	}
+	data.fullnodeFeeBaseTransactions = ffbts
+	var nfbts []entities.NetworkFeeBaseTransaction
+	for _, v := range data.networkFeeBaseTransactions {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			nfbts = append(nfbts, v)
+		}
+	}
+	data.networkFeeBaseTransactions = nfbts
+	var eibts []entities.EventInputBaseTransaction
+	for _, v := range data.eventInputBaseTransactions {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			eibts = append(eibts, v)
+		}
+	}
+	data.eventInputBaseTransactions = eibts
+	var tgbts []entities.TokenGenerationFeeBaseTransaction
+	for _, v := range data.tokenGenerationFeeBaseTransactions {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			tgbts = append(tgbts, v)
+		}
+	}
+	data.tokenGenerationFeeBaseTransactions = tgbts
+	var tmbts []entities.TokenMintingFeeBaseTransaction
+	for _, v := range data.tokenMintingFeeBaseTransactions {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			tmbts = append(tmbts, v)
+		}
+	}
+	data.tokenMintingFeeBaseTransactions = tmbts
+	var transactionAddresses []entities.TransactionAddress
+	for _, v := range data.transactionAddresses {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			transactionAddresses = append(transactionAddresses, v)
+		}
+	}
+	data.transactionAddresses = transactionAddresses
+	var transactionCurrencies []entities.TransactionCurrency
+	for _, v := range data.transactionCurrencies {
+		if !containsInt32(transactionIds, v.TransactionId) {
+			transactionCurrencies = append(transactionCurrencies, v)
+		}
+	}
+	data.transactionCurrencies = transactionCurrencies
+	var txs []entities.Transaction
+	for _, v := range data.transactions {
+		if !containsInt32(transactionIds, v.ID) {
+			txs = append(txs, v)
+		}
+	}
+	data.transactions = txs
+	return baseTransactions, nil
+}
+
+func (repository *memoryTransactionRepository) FindBaseTransactions(transactionIds []int32) (*BaseTransactions, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	baseTransactions := &BaseTransactions{}
+	for _, v := range data.inputBaseTransactions {
+		if containsInt32(transactionIds, v.TransactionId) {
+			found := v
+			baseTransactions.InputBaseTransactions = append(baseTransactions.InputBaseTransactions, &found)
+		}
+	}
+	for _, v := range data.receiverBaseTransactions {
+		if containsInt32(transactionIds, v.TransactionId) {
+			found := v
+			baseTransactions.ReceiverBaseTransactions = append(baseTransactions.ReceiverBaseTransactions, &found)
+		}
+	}
+	for _, v := range data.fullnodeFeeBaseTransactions {
+		if containsInt32(transactionIds, v.TransactionId) {
+			found := v
+			baseTransactions.FullnodeFeeBaseTransactions = append(baseTransactions.FullnodeFeeBaseTransactions, &found)
+		}
+	}
+	for _, v := range data.networkFeeBaseTransactions {
+		if containsInt32(transactionIds, v.TransactionId) {
+			found := v
+			baseTransactions.NetworkFeeBaseTransactions = append(baseTransactions.NetworkFeeBaseTransactions, &found)
+		}
+	}
+	for _, v := range data.eventInputBaseTransactions {
+		if containsInt32(transactionIds, v.TransactionId) {
+			found := v
+			baseTransactions.EventInputBaseTransactions = append(baseTransactions.EventInputBaseTransactions, &found)
+		}
+	}
+	for _, v := range data.tokenGenerationFeeBaseTransactions {
+		if containsInt32(transactionIds, v.TransactionId) {
+			found := v
+			baseTransactions.TokenGenerationFeeBaseTransactions = append(baseTransactions.TokenGenerationFeeBaseTransactions, &found)
+		}
+	}
+	var tokenMintingFeeBaseTransactionIds []int32
+	for _, v := range data.tokenMintingFeeBaseTransactions {
+		if containsInt32(transactionIds, v.TransactionId) {
+			found := v
+			baseTransactions.TokenMintingFeeBaseTransactions = append(baseTransactions.TokenMintingFeeBaseTransactions, &found)
+			tokenMintingFeeBaseTransactionIds = append(tokenMintingFeeBaseTransactionIds, v.ID)
+		}
+	}
+	for _, v := range data.tokenMintingServiceData {
+		if containsInt32(tokenMintingFeeBaseTransactionIds, v.BaseTransactionId) {
+			found := v
+			baseTransactions.TokenMintingServiceData = append(baseTransactions.TokenMintingServiceData, &found)
+		}
+	}
+	return baseTransactions, nil
+}
+
+func (repository *memoryTransactionRepository) CreateBaseTransactions(baseTransactions *BaseTransactions) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, v := range baseTransactions.InputBaseTransactions {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("input_base_transactions"), createTime, createTime
+		data.inputBaseTransactions = append(data.inputBaseTransactions, *v)
+	}
+	for _, v := range baseTransactions.ReceiverBaseTransactions {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("receiver_base_transactions"), createTime, createTime
+		data.receiverBaseTransactions = append(data.receiverBaseTransactions, *v)
+	}
+	for _, v := range baseTransactions.FullnodeFeeBaseTransactions {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("fullnode_fee_base_transactions"), createTime, createTime
+		data.fullnodeFeeBaseTransactions = append(data.fullnodeFeeBaseTransactions, *v)
+	}
+	for _, v := range baseTransactions.NetworkFeeBaseTransactions {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("network_fee_base_transactions"), createTime, createTime
+		data.networkFeeBaseTransactions = append(data.networkFeeBaseTransactions, *v)
+	}
+	for _, v := range baseTransactions.EventInputBaseTransactions {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("event_input_base_transactions"), createTime, createTime
+		data.eventInputBaseTransactions = append(data.eventInputBaseTransactions, *v)
+	}
+	for _, v := range baseTransactions.TokenGenerationFeeBaseTransactions {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("token_generation_fee_base_transactions"), createTime, createTime
+		data.tokenGenerationFeeBaseTransactions = append(data.tokenGenerationFeeBaseTransactions, *v)
+	}
+	for _, v := range baseTransactions.TokenMintingFeeBaseTransactions {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("token_minting_fee_base_transactions"), createTime, createTime
+		data.tokenMintingFeeBaseTransactions = append(data.tokenMintingFeeBaseTransactions, *v)
+	}
+	return nil
+}
+
+func (repository *memoryTransactionRepository) CreateTokenGenerationServiceData(serviceData []*entities.TokenGenerationServiceData) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, v := range serviceData {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("token_generation_service_data"), createTime, createTime
+		data.tokenGenerationServiceData = append(data.tokenGenerationServiceData, *v)
+	}
+	return nil
+}
+
+func (repository *memoryTransactionRepository) CreateTokenMintingServiceData(serviceData []*entities.TokenMintingServiceData) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, v := range serviceData {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("token_minting_service_data"), createTime, createTime
+		data.tokenMintingServiceData = append(data.tokenMintingServiceData, *v)
+	}
+	return nil
+}
+
+func (repository *memoryTransactionRepository) CreateTransactionCurrencies(transactionCurrencies []*entities.TransactionCurrency) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	createTime := now()
+	for _, v := range transactionCurrencies {
+		v.ID, v.CreateTime, v.UpdateTime = data.nextId("transaction_currencies"), createTime, createTime
+		data.transactionCurrencies = append(data.transactionCurrencies, *v)
+	}
+	return nil
+}
+
+func (repository *memoryTransactionRepository) CreateReversal(reversal *entities.TransactionReversal) error {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	data := repository.store.data
+	reversal.ID = data.nextId("transaction_reversals")
+	reversal.CreateTime = now()
+	reversal.UpdateTime = reversal.CreateTime
+	data.transactionReversals = append(data.transactionReversals, *reversal)
+	return nil
+}
+
+func (repository *memoryTransactionRepository) FindReversals(fromId int32, limit int) ([]entities.TransactionReversal, error) {
+	repository.store.mutex.Lock()
+	defer repository.store.mutex.Unlock()
+	var reversals []entities.TransactionReversal
+	for _, reversal := range repository.store.data.transactionReversals {
+		if len(reversals) == limit {
+			break
+		}
+		if reversal.ID > fromId {
+			reversals = append(reversals, reversal)
+		}
+	}
+	return reversals, nil
+}
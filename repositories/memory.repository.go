@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coti-io/coti-db-app/entities"
+)
+
+// memoryData holds the rows by value so a copy of the slices is a snapshot of the data
+type memoryData struct {
+	lastIds                            map[string]int32
+	appStates                          []entities.AppState
+	transactions                       []entities.Transaction
+	inputBaseTransactions              []entities.InputBaseTransaction
+	receiverBaseTransactions           []entities.ReceiverBaseTransaction
+	fullnodeFeeBaseTransactions        []entities.FullnodeFeeBaseTransaction
+	networkFeeBaseTransactions         []entities.NetworkFeeBaseTransaction
+	eventInputBaseTransactions         []entities.EventInputBaseTransaction
+	tokenGenerationFeeBaseTransactions []entities.TokenGenerationFeeBaseTransaction
+	tokenMintingFeeBaseTransactions    []entities.TokenMintingFeeBaseTransaction
+	tokenGenerationServiceData         []entities.TokenGenerationServiceData
+	tokenMintingServiceData            []entities.TokenMintingServiceData
+	transactionCurrencies              []entities.TransactionCurrency
+	transactionReversals               []entities.TransactionReversal
+	addressBalances                    []entities.AddressBalance
+	addresses                          []entities.Address
+	addressTransactionCounts           []entities.AddressTransactionCount
+	transactionAddresses               []entities.TransactionAddress
+	currencies                         []entities.Currency
+	originatorCurrencyData             []entities.OriginatorCurrencyData
+	currencyTypeData                   []entities.CurrencyTypeData
+	currencySupplies                   []entities.CurrencySupply
+	leaderLeases                       []entities.LeaderLease
+	adminAuditLogs                     []entities.AdminAuditLog
+	apiKeys                            []entities.ApiKey
+	clusterStamps                      []entities.ClusterStamp
+	clusterStampDifferences            []entities.ClusterStampDifference
+}
+
+func (data *memoryData) clone() *memoryData {
+	lastIds := make(map[string]int32, len(data.lastIds))
+	for table, lastId := range data.lastIds {
+		lastIds[table] = lastId
+	}
+	return &memoryData{
+		lastIds:                            lastIds,
+		appStates:                          append([]entities.AppState(nil), data.appStates...),
+		transactions:                       append([]entities.Transaction(nil), data.transactions...),
+		inputBaseTransactions:              append([]entities.InputBaseTransaction(nil), data.inputBaseTransactions...),
+		receiverBaseTransactions:           append([]entities.ReceiverBaseTransaction(nil), data.receiverBaseTransactions...),
+		fullnodeFeeBaseTransactions:        append([]entities.FullnodeFeeBaseTransaction(nil), data.fullnodeFeeBaseTransactions...),
+		networkFeeBaseTransactions:         append([]entities.NetworkFeeBaseTransaction(nil), data.networkFeeBaseTransactions...),
+		eventInputBaseTransactions:         append([]entities.EventInputBaseTransaction(nil), data.eventInputBaseTransactions...),
+		tokenGenerationFeeBaseTransactions: append([]entities.TokenGenerationFeeBaseTransaction(nil), data.tokenGenerationFeeBaseTransactions...),
+		tokenMintingFeeBaseTransactions:    append([]entities.TokenMintingFeeBaseTransaction(nil), data.tokenMintingFeeBaseTransactions...),
+		tokenGenerationServiceData:         append([]entities.TokenGenerationServiceData(nil), data.tokenGenerationServiceData...),
+		tokenMintingServiceData:            append([]entities.TokenMintingServiceData(nil), data.tokenMintingServiceData...),
+		transactionCurrencies:              append([]entities.TransactionCurrency(nil), data.transactionCurrencies...),
+		transactionReversals:               append([]entities.TransactionReversal(nil), data.transactionReversals...),
+		addressBalances:                    append([]entities.AddressBalance(nil), data.addressBalances...),
+		addresses:                          append([]entities.Address(nil), data.addresses...),
+		addressTransactionCounts:           append([]entities.AddressTransactionCount(nil), data.addressTransactionCounts...),
+		transactionAddresses:               append([]entities.TransactionAddress(nil), data.transactionAddresses...),
+		currencies:                         append([]entities.Currency(nil), data.currencies...),
+		originatorCurrencyData:             append([]entities.OriginatorCurrencyData(nil), data.originatorCurrencyData...),
+		currencyTypeData:                   append([]entities.CurrencyTypeData(nil), data.currencyTypeData...),
+		currencySupplies:                   append([]entities.CurrencySupply(nil), data.currencySupplies...),
+		leaderLeases:                       append([]entities.LeaderLease(nil), data.leaderLeases...),
+		adminAuditLogs:                     append([]entities.AdminAuditLog(nil), data.adminAuditLogs...),
+		apiKeys:                            append([]entities.ApiKey(nil), data.apiKeys...),
+		clusterStamps:                      append([]entities.ClusterStamp(nil), data.clusterStamps...),
+		clusterStampDifferences:            append([]entities.ClusterStampDifference(nil), data.clusterStampDifferences...),
+	}
+}
+
+// nextId is the auto increment id of the table
+func (data *memoryData) nextId(table string) int32 {
+	data.lastIds[table]++
+	return data.lastIds[table]
+}
+
+// memoryStore serializes the transactions, the data mutex guards every single read and write
+type memoryStore struct {
+	transactionMutex sync.Mutex
+	mutex            sync.Mutex
+	data             *memoryData
+}
+
+type memoryRepositories struct {
+	store         *memoryStore
+	inTransaction bool
+}
+
+// NewMemoryRepositories creates repositories that keep the data in memory, for unit tests of the services.
+// Transactions run one at a time and are rolled back by restoring a snapshot, reads outside a transaction may see uncommitted data.
+func NewMemoryRepositories() Repositories {
+	return &memoryRepositories{store: &memoryStore{data: &memoryData{lastIds: make(map[string]int32)}}}
+}
+
+func (repositories *memoryRepositories) Transactions() TransactionRepository {
+	return &memoryTransactionRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) Balances() BalanceRepository {
+	return &memoryBalanceRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) AppStates() AppStateRepository {
+	return &memoryAppStateRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) Addresses() AddressRepository {
+	return &memoryAddressRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) Currencies() CurrencyRepository {
+	return &memoryCurrencyRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) LeaderLeases() LeaderLeaseRepository {
+	return &memoryLeaderLeaseRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) AdminAuditLogs() AdminAuditLogRepository {
+	return &memoryAdminAuditLogRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) ApiKeys() ApiKeyRepository {
+	return &memoryApiKeyRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) ClusterStamps() ClusterStampRepository {
+	return &memoryClusterStampRepository{store: repositories.store}
+}
+
+func (repositories *memoryRepositories) Transaction(fn func(repositories Repositories) error) (err error) {
+	// a nested transaction is part of the outer one
+	if repositories.inTransaction {
+		return fn(repositories)
+	}
+	store := repositories.store
+	store.transactionMutex.Lock()
+	defer store.transactionMutex.Unlock()
+	store.mutex.Lock()
+	snapshot := store.data.clone()
+	store.mutex.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			store.rollback(snapshot)
+			panic(r)
+		}
+		if err != nil {
+			store.rollback(snapshot)
+		}
+	}()
+	return fn(&memoryRepositories{store: store, inTransaction: true})
+}
+
+// ReadSnapshot runs fn in a transaction, the memory transactions already run one at a time
+func (repositories *memoryRepositories) ReadSnapshot(fn func(repositories Repositories) error) error {
+	return repositories.Transaction(fn)
+}
+
+// WithContext returns the same repositories, the memory queries are not traced
+func (repositories *memoryRepositories) WithContext(ctx context.Context) Repositories {
+	return repositories
+}
+
+func (store *memoryStore) rollback(snapshot *memoryData) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.data = snapshot
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func containsInt32(values []int32, value int32) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
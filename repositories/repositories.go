@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/coti-io/coti-db-app/tracing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound is returned when a single record is requested and it doesn't exist
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// Repositories groups the repositories of one db handle, the repositories given to the Transaction callback share the db transaction
+type Repositories interface {
+	Transactions() TransactionRepository
+	Balances() BalanceRepository
+	AppStates() AppStateRepository
+	Addresses() AddressRepository
+	Currencies() CurrencyRepository
+	LeaderLeases() LeaderLeaseRepository
+	AdminAuditLogs() AdminAuditLogRepository
+	ApiKeys() ApiKeyRepository
+	ClusterStamps() ClusterStampRepository
+	// Transaction runs fn in a transaction that is rolled back when fn returns an error
+	Transaction(fn func(repositories Repositories) error) error
+	// ReadSnapshot runs fn in a read only transaction whose queries all see the same snapshot of the db
+	ReadSnapshot(fn func(repositories Repositories) error) error
+	// WithContext returns the repositories whose queries run with ctx, the queries are traced as children of the span in ctx
+	WithContext(ctx context.Context) Repositories
+}
+
+// Confirmation selects the transactions whose balance effects can be applied, it is implemented by the confirmation policies
+type Confirmation interface {
+	// Condition is the sql condition matching the confirmed transactions
+	Condition() clause.Expression
+	IsConfirmed(tx *entities.Transaction) bool
+}
+
+type gormRepositories struct {
+	db *gorm.DB
+}
+
+// NewGormRepositories creates the repositories of a gorm db handle, the handle may be a db transaction
+func NewGormRepositories(db *gorm.DB) Repositories {
+	return &gormRepositories{db: db}
+}
+
+func (repositories *gormRepositories) Transactions() TransactionRepository {
+	return &gormTransactionRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) Balances() BalanceRepository {
+	return &gormBalanceRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) AppStates() AppStateRepository {
+	return &gormAppStateRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) Addresses() AddressRepository {
+	return &gormAddressRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) Currencies() CurrencyRepository {
+	return &gormCurrencyRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) LeaderLeases() LeaderLeaseRepository {
+	return &gormLeaderLeaseRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) AdminAuditLogs() AdminAuditLogRepository {
+	return &gormAdminAuditLogRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) ApiKeys() ApiKeyRepository {
+	return &gormApiKeyRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) ClusterStamps() ClusterStampRepository {
+	return &gormClusterStampRepository{db: repositories.db}
+}
+
+func (repositories *gormRepositories) Transaction(fn func(repositories Repositories) error) error {
+	ctx, span := tracing.Start(repositories.db.Statement.Context, "db.transaction")
+	err := repositories.db.WithContext(ctx).Transaction(func(dbTransaction *gorm.DB) error {
+		return fn(NewGormRepositories(dbTransaction))
+	})
+	tracing.End(span, err)
+	return err
+}
+
+// ReadSnapshot runs fn in a repeatable read transaction, sqlite ignores the options since its transactions are serializable
+func (repositories *gormRepositories) ReadSnapshot(fn func(repositories Repositories) error) error {
+	ctx, span := tracing.Start(repositories.db.Statement.Context, "db.snapshot")
+	err := repositories.db.WithContext(ctx).Transaction(func(dbTransaction *gorm.DB) error {
+		return fn(NewGormRepositories(dbTransaction))
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	tracing.End(span, err)
+	return err
+}
+
+func (repositories *gormRepositories) WithContext(ctx context.Context) Repositories {
+	return NewGormRepositories(repositories.db.WithContext(ctx))
+}
@@ -0,0 +1,564 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	dbTest "github.com/coti-io/coti-db-app/db-provider/db-test"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// forEachRepositories runs the same cases on the memory repositories and the gorm repositories of every dialect,
+// so the fakes the service tests use behave like the db
+func forEachRepositories(t *testing.T, test func(t *testing.T, repositories Repositories)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryRepositories())
+	})
+	dbTest.ForEachDialect(t, nil, func(t *testing.T, db *gorm.DB) {
+		test(t, NewGormRepositories(db))
+	})
+}
+
+// trustChainConfirmation confirms the transactions with a trust chain consensus
+type trustChainConfirmation struct{}
+
+func (confirmation trustChainConfirmation) Condition() clause.Expression {
+	return clause.Eq{Column: clause.Column{Name: "trustChainConsensus"}, Value: true}
+}
+
+func (confirmation trustChainConfirmation) IsConfirmed(tx *entities.Transaction) bool {
+	return tx.TrustChainConsensus
+}
+
+func newTestTransaction(hash string, index int32, isConfirmed bool) *entities.Transaction {
+	txType := "Transfer"
+	tx := &entities.Transaction{
+		Hash:                  hash,
+		Amount:                decimal.NewFromInt(10),
+		AttachmentTime:        decimal.NewFromInt(1600000000),
+		TransactionCreateTime: decimal.NewFromInt(1600000000),
+		TrustChainConsensus:   isConfirmed,
+		TrustChainTrustScore:  decimal.NewFromInt(100),
+		Type:                  &txType,
+	}
+	if index >= 0 {
+		tx.Index = &index
+	}
+	return tx
+}
+
+func getHashes(txs []entities.Transaction) []string {
+	hashes := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash)
+	}
+	sort.Strings(hashes)
+	return hashes
+}
+
+func assertHashes(t *testing.T, name string, txs []entities.Transaction, expected ...string) {
+	t.Helper()
+	hashes := getHashes(txs)
+	if len(hashes) != len(expected) {
+		t.Fatalf("%s found %v, expected %v", name, hashes, expected)
+	}
+	for i := range hashes {
+		if hashes[i] != expected[i] {
+			t.Fatalf("%s found %v, expected %v", name, hashes, expected)
+		}
+	}
+}
+
+func TestTransactionRepository(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		confirmed := newTestTransaction("confirmed", 1, true)
+		unconfirmed := newTestTransaction("unconfirmed", 2, false)
+		unindexed := newTestTransaction("unindexed", -1, false)
+		zeroSpend := newTestTransaction("zeroSpend", 3, true)
+		zeroSpendType := "ZeroSpend"
+		zeroSpend.Type = &zeroSpendType
+		invalid := newTestTransaction("invalid", 4, false)
+		invalid.IsValid = entities.NewNullBool(new(bool))
+		repo := repositories.Transactions()
+		if err := repo.Create([]*entities.Transaction{confirmed, unconfirmed, unindexed, zeroSpend, invalid}); err != nil {
+			t.Fatal(err)
+		}
+		if confirmed.ID == 0 || confirmed.ID == unconfirmed.ID {
+			t.Fatalf("the created transactions have the ids %d and %d", confirmed.ID, unconfirmed.ID)
+		}
+
+		txs, err := repo.FindByHashes([]string{"confirmed", "unindexed", "missing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindByHashes", txs, "confirmed", "unindexed")
+
+		txs, err = repo.FindToProcess(trustChainConfirmation{}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindToProcess", txs, "confirmed", "invalid")
+		txs, err = repo.FindToProcess(trustChainConfirmation{}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(txs) != 1 {
+			t.Fatalf("FindToProcess with a limit of 1 found %d transactions", len(txs))
+		}
+
+		txs, err = repo.FindUnconfirmed(trustChainConfirmation{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindUnconfirmed", txs, "invalid", "unconfirmed")
+
+		backlogs, err := repo.CountBacklogs(trustChainConfirmation{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *backlogs != (Backlogs{BalanceProcessing: 1, Monitor: 2, PendingUnindexed: 1}) {
+			t.Fatalf("CountBacklogs counted %+v", *backlogs)
+		}
+
+		confirmed.IsProcessed = true
+		if err := repo.Save([]*entities.Transaction{confirmed}); err != nil {
+			t.Fatal(err)
+		}
+		txs, err = repo.FindToProcess(trustChainConfirmation{}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindToProcess after the processing", txs, "invalid")
+		txs, err = repo.FindProcessedWithin(time.Hour, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindProcessedWithin", txs, "confirmed")
+		if txs[0].ProcessedTime == nil {
+			t.Fatal("the processed time was not set when the transaction was saved as processed")
+		}
+		zeroSpend.IsProcessed = true
+		if err := repo.Save([]*entities.Transaction{zeroSpend}); err != nil {
+			t.Fatal(err)
+		}
+		txs, err = repo.FindProcessedWithin(time.Hour, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindProcessedWithin with a limit of 1", txs, "confirmed")
+		txs, err = repo.FindProcessedWithin(time.Hour, confirmed.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindProcessedWithin after the first id", txs, "zeroSpend")
+
+		invalid.IsProcessed, invalid.IsSkipped = true, true
+		// a rebase skips the transactions covered by the stamp, they are not invalid
+		unconfirmed.IsProcessed, unconfirmed.IsSkipped, unconfirmed.IsCoveredByStamp = true, true, true
+		if err := repo.Save([]*entities.Transaction{invalid, unconfirmed}); err != nil {
+			t.Fatal(err)
+		}
+		txs, err = repo.FindSkipped(0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindSkipped", txs, "invalid")
+		txs, err = repo.FindSkipped(invalid.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindSkipped after its id", txs)
+
+		txs, err = repo.FindUnindexedCreatedBefore(time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindUnindexedCreatedBefore an hour", txs)
+		// the db clock has a resolution of a second
+		time.Sleep(1100 * time.Millisecond)
+		txs, err = repo.FindUnindexedCreatedBefore(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindUnindexedCreatedBefore now", txs, "unindexed")
+	})
+}
+
+func TestBaseTransactions(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		repo := repositories.Transactions()
+		tx := newTestTransaction("minting", 1, true)
+		other := newTestTransaction("other", 2, true)
+		if err := repo.Create([]*entities.Transaction{tx, other}); err != nil {
+			t.Fatal(err)
+		}
+		baseTransactions := &BaseTransactions{
+			InputBaseTransactions: []*entities.InputBaseTransaction{
+				{TransactionId: tx.ID, Hash: "ibt", Name: "IBT", AddressHash: "sender", Amount: decimal.NewFromInt(-11)},
+				{TransactionId: other.ID, Hash: "otherIbt", Name: "IBT", AddressHash: "sender", Amount: decimal.NewFromInt(-1)},
+			},
+			ReceiverBaseTransactions: []*entities.ReceiverBaseTransaction{
+				{TransactionId: tx.ID, Hash: "rbt", Name: "RBT", AddressHash: "receiver", Amount: decimal.NewFromInt(10), OriginalAmount: decimal.NewNullDecimal(decimal.NewFromInt(10))},
+			},
+			NetworkFeeBaseTransactions: []*entities.NetworkFeeBaseTransaction{
+				{TransactionId: tx.ID, Hash: "nfbt", Name: "NFBT", AddressHash: "node", Amount: decimal.NewFromInt(1)},
+			},
+			TokenMintingFeeBaseTransactions: []*entities.TokenMintingFeeBaseTransaction{
+				{TransactionId: tx.ID, Hash: "tmbt", Name: "TMBT", AddressHash: "minter", Amount: decimal.Zero},
+			},
+		}
+		if err := repo.CreateBaseTransactions(baseTransactions); err != nil {
+			t.Fatal(err)
+		}
+		tmbtId := baseTransactions.TokenMintingFeeBaseTransactions[0].ID
+		if tmbtId == 0 {
+			t.Fatal("the base transactions were created without ids")
+		}
+		serviceData := []*entities.TokenMintingServiceData{{BaseTransactionId: tmbtId, MintingCurrencyHash: "token", MintingAmount: decimal.NewFromInt(100), ReceiverAddress: "receiver"}}
+		if err := repo.CreateTokenMintingServiceData(serviceData); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.FindBaseTransactions([]int32{tx.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found.InputBaseTransactions) != 1 || len(found.ReceiverBaseTransactions) != 1 || len(found.NetworkFeeBaseTransactions) != 1 ||
+			len(found.TokenMintingFeeBaseTransactions) != 1 || len(found.TokenMintingServiceData) != 1 {
+			t.Fatalf("FindBaseTransactions found %+v", found)
+		}
+		if !found.InputBaseTransactions[0].Amount.Equal(decimal.NewFromInt(-11)) || !found.TokenMintingServiceData[0].MintingAmount.Equal(decimal.NewFromInt(100)) {
+			t.Fatalf("FindBaseTransactions found the amounts %s and %s", found.InputBaseTransactions[0].Amount, found.TokenMintingServiceData[0].MintingAmount)
+		}
+
+		deleted, err := repo.DeleteWithBaseTransactions([]int32{tx.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deleted.InputBaseTransactions) != 1 || len(deleted.TokenMintingServiceData) != 1 {
+			t.Fatalf("DeleteWithBaseTransactions returned %+v", deleted)
+		}
+		txs, err := repo.FindByHashes([]string{"minting", "other"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindByHashes after the delete", txs, "other")
+		found, err = repo.FindBaseTransactions([]int32{tx.ID, other.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found.InputBaseTransactions) != 1 || found.InputBaseTransactions[0].TransactionId != other.ID || len(found.ReceiverBaseTransactions) != 0 ||
+			len(found.TokenMintingServiceData) != 0 {
+			t.Fatalf("FindBaseTransactions found %+v after the delete", found)
+		}
+	})
+}
+
+func TestBalanceRepository(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		err := repositories.Transaction(func(repositories Repositories) error {
+			return repositories.Balances().AddAmounts([]entities.AddressBalance{
+				*entities.NewAddressBalance("first", decimal.NewFromInt(5), 1),
+				*entities.NewAddressBalance("second", decimal.NewFromInt(3), 1),
+				*entities.NewAddressBalance("first", decimal.NewFromInt(7), 2),
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = repositories.Transaction(func(repositories Repositories) error {
+			balances, err := repositories.Balances().FindForUpdate([]AddressCurrency{{AddressHash: "first", CurrencyId: 1}, {AddressHash: "missing", CurrencyId: 1}})
+			if err != nil {
+				return err
+			}
+			if len(balances) != 1 || balances[0].AddressHash != "first" || !balances[0].Amount.Equal(decimal.NewFromInt(5)) {
+				t.Fatalf("FindForUpdate found %+v", balances)
+			}
+			return repositories.Balances().AddAmounts([]entities.AddressBalance{
+				*entities.NewAddressBalance("first", decimal.NewFromFloat(-5), 1),
+				*entities.NewAddressBalance("third", decimal.NewFromFloat(0.25), 1),
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		totals, err := repositories.Balances().SumByCurrency([]int32{1, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Slice(totals, func(i, j int) bool { return totals[i].CurrencyId < totals[j].CurrencyId })
+		if len(totals) != 2 || !totals[0].Total.Equal(decimal.NewFromFloat(3.25)) || totals[0].Holders != 2 ||
+			!totals[1].Total.Equal(decimal.NewFromInt(7)) || totals[1].Holders != 1 {
+			t.Fatalf("SumByCurrency summed %+v", totals)
+		}
+	})
+}
+
+func TestTransactionRollback(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		errRollback := errors.New("rollback")
+		err := repositories.Transaction(func(repositories Repositories) error {
+			if err := repositories.Transactions().Create([]*entities.Transaction{newTestTransaction("rolledBack", 1, true)}); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("the transaction returned %v", err)
+		}
+		txs, err := repositories.Transactions().FindByHashes([]string{"rolledBack"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertHashes(t, "FindByHashes after the rollback", txs)
+	})
+}
+
+func TestAppStateRepository(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		repo := repositories.AppStates()
+		if _, err := repo.GetByName(entities.UpdateBalances); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetByName of a missing app state returned %v", err)
+		}
+		created, err := repo.FirstOrCreate(entities.LastMonitoredTransactionIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := repo.FirstOrCreate(entities.LastMonitoredTransactionIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.ID == 0 || again.ID != created.ID {
+			t.Fatalf("FirstOrCreate created the ids %d and %d", created.ID, again.ID)
+		}
+		err = repositories.Transaction(func(repositories Repositories) error {
+			appState, err := repositories.AppStates().GetByNameForUpdate(entities.LastMonitoredTransactionIndex)
+			if err != nil {
+				return err
+			}
+			appState.Value = "42"
+			return repositories.AppStates().Save(appState)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		appStates, err := repo.FindByNames([]entities.AppStatesNames{entities.LastMonitoredTransactionIndex, entities.UpdateBalances})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(appStates) != 1 || appStates[0].Value != "42" {
+			t.Fatalf("FindByNames found %+v", appStates)
+		}
+	})
+}
+
+func TestAddressRepository(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		repo := repositories.Addresses()
+		if err := repo.Create([]*entities.Address{entities.NewAddress("first"), entities.NewAddress("second")}); err != nil {
+			t.Fatal(err)
+		}
+		addresses, err := repo.FindByHashes([]string{"second", "missing"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addresses) != 1 || addresses[0].AddressHash != "second" || addresses[0].ID == 0 {
+			t.Fatalf("FindByHashes found %+v", addresses)
+		}
+		if err := repo.CreateTransactionCounts([]entities.AddressTransactionCount{*entities.NewAddressTransactionCount("first", 2)}); err != nil {
+			t.Fatal(err)
+		}
+		counts, err := repo.FindTransactionCounts([]string{"first"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(counts) != 1 || counts[0].Count != 2 {
+			t.Fatalf("FindTransactionCounts found %+v", counts)
+		}
+		counts[0].Count = 5
+		if err := repo.SaveTransactionCounts(counts); err != nil {
+			t.Fatal(err)
+		}
+		counts, err = repo.FindTransactionCounts([]string{"first", "second"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(counts) != 1 || counts[0].Count != 5 {
+			t.Fatalf("FindTransactionCounts found %+v after the save", counts)
+		}
+	})
+}
+
+func TestCurrencyRepository(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		repo := repositories.Currencies()
+		native, err := repo.FirstOrCreate("native")
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := repo.FirstOrCreate("native")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if native.ID == 0 || again.ID != native.ID {
+			t.Fatalf("FirstOrCreate created the ids %d and %d", native.ID, again.ID)
+		}
+		if err := repo.Create([]*entities.Currency{entities.NewCurrency("token")}); err != nil {
+			t.Fatal(err)
+		}
+		currencies, err := repo.FindByHashes([]string{"token", "native"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(currencies) != 2 {
+			t.Fatalf("FindByHashes found %+v", currencies)
+		}
+		err = repositories.Transaction(func(repositories Repositories) error {
+			supplies, err := repositories.Currencies().FindSuppliesForUpdate([]int32{native.ID})
+			if err != nil {
+				return err
+			}
+			if len(supplies) != 0 {
+				t.Fatalf("FindSuppliesForUpdate found %+v before they were created", supplies)
+			}
+			return repositories.Currencies().CreateSupplies([]*entities.CurrencySupply{entities.NewCurrencySupply(native.ID, decimal.NewFromInt(100), 3)})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = repositories.Transaction(func(repositories Repositories) error {
+			supplies, err := repositories.Currencies().FindSuppliesForUpdate([]int32{native.ID})
+			if err != nil {
+				return err
+			}
+			if len(supplies) != 1 {
+				t.Fatalf("FindSuppliesForUpdate found %+v", supplies)
+			}
+			supplies[0].MintedAmount = decimal.NewFromInt(20)
+			supplies[0].CirculatingSupply = decimal.NewFromInt(120)
+			return repositories.Currencies().SaveSupplies(supplies)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		supply, err := repo.FindSupplyByHash("native")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !supply.MintedAmount.Equal(decimal.NewFromInt(20)) || !supply.CirculatingSupply.Equal(decimal.NewFromInt(120)) || supply.HolderCount != 3 {
+			t.Fatalf("FindSupplyByHash found %+v", supply)
+		}
+		supplyRows, err := repo.FindSupplies()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(supplyRows) != 1 || supplyRows[0].CurrencyHash != "native" {
+			t.Fatalf("FindSupplies found %+v", supplyRows)
+		}
+		if _, err := repo.FindSupplyByHash("token"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("FindSupplyByHash of a currency without a supply returned %v", err)
+		}
+	})
+}
+
+func TestLeaderLeaseRepository(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		repo := repositories.LeaderLeases()
+		for i := 0; i < 2; i++ {
+			if err := repo.CreateIfMissing(entities.SyncLeaderLease); err != nil {
+				t.Fatal(err)
+			}
+		}
+		acquire := func(holder string) *entities.LeaderLease {
+			var lease *entities.LeaderLease
+			err := repositories.Transaction(func(repositories Repositories) (err error) {
+				lease, err = repositories.LeaderLeases().Acquire(entities.SyncLeaderLease, holder, time.Minute)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return lease
+		}
+		first := acquire("first")
+		if first.Holder != "first" || first.FencingToken == 0 {
+			t.Fatalf("the free lease was acquired as %+v", first)
+		}
+		if renewed := acquire("first"); renewed.Holder != "first" || renewed.FencingToken != first.FencingToken {
+			t.Fatalf("the lease was renewed as %+v", renewed)
+		}
+		if taken := acquire("second"); taken.Holder != "first" {
+			t.Fatalf("a held lease was taken over as %+v", taken)
+		}
+		lease, err := repo.GetByName(entities.SyncLeaderLease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lease.Holder != "first" {
+			t.Fatalf("GetByName found %+v", lease)
+		}
+	})
+}
+
+func TestApiKeyRepository(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		repo := repositories.ApiKeys()
+		apiKey := &entities.ApiKey{Name: "reader", Prefix: "cdb_abcdefgh", KeyHash: "hash", Scope: entities.ReadScope}
+		if err := repo.Create(apiKey); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.GetByKeyHash("hash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Name != "reader" || found.Scope != entities.ReadScope || found.IsRevoked {
+			t.Fatalf("GetByKeyHash found %+v", found)
+		}
+		if _, err := repo.GetByKeyHash("missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetByKeyHash of a missing key returned %v", err)
+		}
+		if err := repo.Revoke("reader"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Revoke("missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Revoke of a missing key returned %v", err)
+		}
+		found, err = repo.GetByName("reader")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found.IsRevoked {
+			t.Fatal("the revoked key is not revoked")
+		}
+		apiKeys, err := repo.FindAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(apiKeys) != 1 {
+			t.Fatalf("FindAll found %+v", apiKeys)
+		}
+	})
+}
+
+func TestAdminAuditLogRepository(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories Repositories) {
+		repo := repositories.AdminAuditLogs()
+		for _, action := range []string{"pause", "resume", "run"} {
+			if err := repo.Create(&entities.AdminAuditLog{Action: action, Actor: "admin@127.0.0.1", Params: "{}", IsSuccess: true}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		auditLogs, err := repo.FindLatest(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(auditLogs) != 2 || auditLogs[0].Action != "run" || auditLogs[1].Action != "resume" {
+			t.Fatalf("FindLatest found %+v", auditLogs)
+		}
+	})
+}
@@ -0,0 +1,378 @@
+package repository
+
+import (
+	"time"
+
+	dbProvider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BaseTransactions are the base transactions of a set of transactions with their service data
+type BaseTransactions struct {
+	InputBaseTransactions              []*entities.InputBaseTransaction
+	ReceiverBaseTransactions           []*entities.ReceiverBaseTransaction
+	FullnodeFeeBaseTransactions        []*entities.FullnodeFeeBaseTransaction
+	NetworkFeeBaseTransactions         []*entities.NetworkFeeBaseTransaction
+	EventInputBaseTransactions         []*entities.EventInputBaseTransaction
+	TokenGenerationFeeBaseTransactions []*entities.TokenGenerationFeeBaseTransaction
+	TokenMintingFeeBaseTransactions    []*entities.TokenMintingFeeBaseTransaction
+	TokenGenerationServiceData         []*entities.TokenGenerationServiceData
+	TokenMintingServiceData            []*entities.TokenMintingServiceData
+}
+
+// Backlogs are the transactions waiting for a job
+type Backlogs struct {
+	// BalanceProcessing are the confirmed transactions whose balance effects updateBalances didn't apply yet
+	BalanceProcessing int64
+	// Monitor are the indexed transactions monitorTransactions waits the confirmation of
+	Monitor int64
+	// PendingUnindexed are the transactions the fullnode didn't index yet
+	PendingUnindexed int64
+}
+
+// BalancePoint is a point in the history of the balances, the current balances when neither Index nor AttachmentTime is set
+type BalancePoint struct {
+	Index          *int64
+	AttachmentTime *decimal.Decimal
+}
+
+// IsCurrent checks if the point is the current balances
+func (point BalancePoint) IsCurrent() bool {
+	return point.Index == nil && point.AttachmentTime == nil
+}
+
+// ArchivedRange is the highest index and attachment time of the archived transactions, the history up to them is not complete
+type ArchivedRange struct {
+	MaxIndex          *int32              `gorm:"column:maxIndex"`
+	MaxAttachmentTime decimal.NullDecimal `gorm:"column:maxAttachmentTime"`
+}
+
+// TransactionRepository keeps the transactions, their base transactions and their reversal events
+type TransactionRepository interface {
+	FindByHashes(hashes []string) ([]entities.Transaction, error)
+	// FindArchivedHashes finds which of the hashes belong to transactions moved to the archive
+	FindArchivedHashes(hashes []string) ([]string, error)
+	// FindToProcess finds the unprocessed transactions that are confirmed or invalid, zero spend transactions have no balance effects
+	FindToProcess(confirmation Confirmation, limit int) ([]entities.Transaction, error)
+	// FindUnconfirmed finds the indexed transactions that are not processed and not confirmed yet
+	FindUnconfirmed(confirmation Confirmation) ([]entities.Transaction, error)
+	// FindProcessedWithin finds the transactions processed within the window that can still be reversed
+	// with an id greater than fromId, ordered by id
+	FindProcessedWithin(window time.Duration, fromId int32, limit int) ([]entities.Transaction, error)
+	FindUnindexedCreatedBefore(age time.Duration) ([]entities.Transaction, error)
+	// FindSkipped finds the invalid transactions processed without their balance effects with an id greater than fromId,
+	// the transactions covered by a cluster stamp are not invalid and are left out
+	FindSkipped(fromId int32, limit int) ([]entities.Transaction, error)
+	CountBacklogs(confirmation Confirmation) (*Backlogs, error)
+	// FindAppliedIdsAfter finds the ids of the transactions after the point whose balance effects are applied
+	FindAppliedIdsAfter(point BalancePoint) ([]int32, error)
+	// CountPendingUpTo counts the transactions up to the point whose balance effects are not applied yet, zero spend transactions have none
+	CountPendingUpTo(point BalancePoint) (int64, error)
+	// MarkCoveredByStamp marks the unprocessed transactions up to the index as processed and skipped, their balance effects are in the cluster stamp balances
+	MarkCoveredByStamp(index int64) error
+	// GetArchivedRange returns the archived range, its fields are nil when nothing was archived
+	GetArchivedRange() (*ArchivedRange, error)
+	Create(txs []*entities.Transaction) error
+	// Save saves the transactions, the processed time is set by the db the first time a transaction is saved as processed
+	Save(txs []*entities.Transaction) error
+	// DeleteWithBaseTransactions deletes the transactions with their base transactions, addresses and currencies
+	// and returns the deleted base transactions
+	DeleteWithBaseTransactions(transactionIds []int32) (*BaseTransactions, error)
+	// FindBaseTransactions finds the base transactions of the transactions and the service data of their token minting base transactions
+	FindBaseTransactions(transactionIds []int32) (*BaseTransactions, error)
+	// CreateBaseTransactions creates the base transactions, the service data is created separately since it refers to their ids
+	CreateBaseTransactions(baseTransactions *BaseTransactions) error
+	CreateTokenGenerationServiceData(serviceData []*entities.TokenGenerationServiceData) error
+	CreateTokenMintingServiceData(serviceData []*entities.TokenMintingServiceData) error
+	CreateTransactionCurrencies(transactionCurrencies []*entities.TransactionCurrency) error
+	CreateReversal(reversal *entities.TransactionReversal) error
+	// FindReversals finds the reversal events with an id greater than fromId
+	FindReversals(fromId int32, limit int) ([]entities.TransactionReversal, error)
+}
+
+type gormTransactionRepository struct {
+	db *gorm.DB
+}
+
+func (repository *gormTransactionRepository) FindByHashes(hashes []string) ([]entities.Transaction, error) {
+	var txs []entities.Transaction
+	err := repository.db.Where(map[string]interface{}{"hash": hashes}).Find(&txs).Error
+	return txs, err
+}
+
+func (repository *gormTransactionRepository) FindArchivedHashes(hashes []string) ([]string, error) {
+	var archivedHashes []string
+	err := repository.db.Model(&entities.ArchivedTransaction{}).Where(map[string]interface{}{"hash": hashes}).Pluck("hash", &archivedHashes).Error
+	return archivedHashes, err
+}
+
+func (repository *gormTransactionRepository) FindToProcess(confirmation Confirmation, limit int) ([]entities.Transaction, error) {
+	var txs []entities.Transaction
+	err := repository.db.Where(map[string]interface{}{"isProcessed": false}).Not(map[string]interface{}{"type": "ZeroSpend"}).
+		Where(clause.Or(confirmation.Condition(), clause.Eq{Column: "isValid", Value: false})).Limit(limit).Find(&txs).Error
+	return txs, err
+}
+
+func (repository *gormTransactionRepository) FindUnconfirmed(confirmation Confirmation) ([]entities.Transaction, error) {
+	var txs []entities.Transaction
+	err := repository.db.Not(map[string]interface{}{"index": nil}).Where(map[string]interface{}{"isProcessed": false}).
+		Not(confirmation.Condition()).Find(&txs).Error
+	return txs, err
+}
+
+func (repository *gormTransactionRepository) FindProcessedWithin(window time.Duration, fromId int32, limit int) ([]entities.Transaction, error) {
+	var txs []entities.Transaction
+	err := repository.db.Where(map[string]interface{}{"isProcessed": true, "isSkipped": false, "isReversed": false}).
+		Where(clause.Gt{Column: "processedTime", Value: dbProvider.Ago(window)}).Where(clause.Gt{Column: "id", Value: fromId}).
+		Order("id").Limit(limit).Find(&txs).Error
+	return txs, err
+}
+
+func (repository *gormTransactionRepository) FindUnindexedCreatedBefore(age time.Duration) ([]entities.Transaction, error) {
+	var txs []entities.Transaction
+	err := repository.db.Where(map[string]interface{}{"index": nil}).
+		Where(clause.Lt{Column: "createTime", Value: dbProvider.Ago(age)}).Find(&txs).Error
+	return txs, err
+}
+
+func (repository *gormTransactionRepository) FindSkipped(fromId int32, limit int) ([]entities.Transaction, error) {
+	var txs []entities.Transaction
+	err := repository.db.Where(map[string]interface{}{"isSkipped": true, "isCoveredByStamp": false}).Where(clause.Gt{Column: "id", Value: fromId}).
+		Order("id").Limit(limit).Find(&txs).Error
+	return txs, err
+}
+
+func (repository *gormTransactionRepository) CountBacklogs(confirmation Confirmation) (*Backlogs, error) {
+	var backlogs Backlogs
+	err := repository.db.Model(&entities.Transaction{}).Where(map[string]interface{}{"isProcessed": false}).Not(map[string]interface{}{"type": "ZeroSpend"}).
+		Where(confirmation.Condition()).Count(&backlogs.BalanceProcessing).Error
+	if err != nil {
+		return nil, err
+	}
+	err = repository.db.Model(&entities.Transaction{}).Not(map[string]interface{}{"index": nil}).Where(map[string]interface{}{"isProcessed": false}).
+		Not(confirmation.Condition()).Count(&backlogs.Monitor).Error
+	if err != nil {
+		return nil, err
+	}
+	err = repository.db.Model(&entities.Transaction{}).Where(map[string]interface{}{"index": nil}).Count(&backlogs.PendingUnindexed).Error
+	if err != nil {
+		return nil, err
+	}
+	return &backlogs, nil
+}
+
+// appliedAfter is the query of the transactions after the point whose balance effects are applied, the unindexed ones are after any index
+func (repository *gormTransactionRepository) appliedAfter(point BalancePoint) *gorm.DB {
+	appliedTransactions := repository.db.Model(&entities.Transaction{}).Where(map[string]interface{}{"isProcessed": true, "isSkipped": false, "isReversed": false})
+	if point.Index != nil {
+		return appliedTransactions.Where(clause.Or(clause.Gt{Column: "index", Value: *point.Index}, clause.Eq{Column: "index", Value: nil}))
+	}
+	return appliedTransactions.Where(clause.Gt{Column: "attachmentTime", Value: *point.AttachmentTime})
+}
+
+func (repository *gormTransactionRepository) FindAppliedIdsAfter(point BalancePoint) ([]int32, error) {
+	var transactionIds []int32
+	err := repository.appliedAfter(point).Pluck("id", &transactionIds).Error
+	return transactionIds, err
+}
+
+func (repository *gormTransactionRepository) CountPendingUpTo(point BalancePoint) (int64, error) {
+	var pendingCount int64
+	pendingTransactions := repository.db.Model(&entities.Transaction{}).Where(map[string]interface{}{"isProcessed": false}).Not(map[string]interface{}{"type": "ZeroSpend"})
+	if point.Index != nil {
+		pendingTransactions = pendingTransactions.Where(clause.Lte{Column: "index", Value: *point.Index})
+	} else {
+		pendingTransactions = pendingTransactions.Where(clause.Lte{Column: "attachmentTime", Value: *point.AttachmentTime})
+	}
+	err := pendingTransactions.Count(&pendingCount).Error
+	return pendingCount, err
+}
+
+func (repository *gormTransactionRepository) MarkCoveredByStamp(index int64) error {
+	return repository.db.Model(&entities.Transaction{}).
+		Where(map[string]interface{}{"isProcessed": false}).Where(clause.Lte{Column: "index", Value: index}).
+		Updates(map[string]interface{}{"isProcessed": true, "isSkipped": true, "isCoveredByStamp": true}).Error
+}
+
+func (repository *gormTransactionRepository) GetArchivedRange() (*ArchivedRange, error) {
+	var archivedRange ArchivedRange
+	err := repository.db.Model(&entities.ArchiveBatch{}).
+		Select("MAX(?) AS ?, MAX(?) AS ?", clause.Column{Name: "maxIndex"}, clause.Column{Name: "maxIndex"},
+			clause.Column{Name: "toAttachmentTime"}, clause.Column{Name: "maxAttachmentTime"}).
+		Scan(&archivedRange).Error
+	if err != nil {
+		return nil, err
+	}
+	return &archivedRange, nil
+}
+
+func (repository *gormTransactionRepository) Create(txs []*entities.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&txs).Error
+}
+
+func (repository *gormTransactionRepository) Save(txs []*entities.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	err := repository.db.Omit("CreateTime", "UpdateTime", "ProcessedTime").Save(&txs).Error
+	if err != nil {
+		return err
+	}
+	var processedIds []int32
+	for _, tx := range txs {
+		if tx.IsProcessed && tx.ProcessedTime == nil {
+			processedIds = append(processedIds, tx.ID)
+		}
+	}
+	if len(processedIds) == 0 {
+		return nil
+	}
+	return repository.db.Model(&entities.Transaction{}).Where(map[string]interface{}{"id": processedIds, "processedTime": nil}).
+		Update("processedTime", dbProvider.Now()).Error
+}
+
+func (repository *gormTransactionRepository) DeleteWithBaseTransactions(transactionIds []int32) (*BaseTransactions, error) {
+	baseTransactions, err := repository.FindBaseTransactions(transactionIds)
+	if err != nil {
+		return nil, err
+	}
+	var tokenGenerationFeeBaseTransactionIds []int32
+	for _, baseTransaction := range baseTransactions.TokenGenerationFeeBaseTransactions {
+		tokenGenerationFeeBaseTransactionIds = append(tokenGenerationFeeBaseTransactionIds, baseTransaction.ID)
+	}
+	err = repository.db.Where(map[string]interface{}{"baseTransactionId": tokenGenerationFeeBaseTransactionIds}).Find(&baseTransactions.TokenGenerationServiceData).Error
+	if err != nil {
+		return nil, err
+	}
+	var serviceDataIds []int32
+	for _, serviceData := range baseTransactions.TokenGenerationServiceData {
+		serviceDataIds = append(serviceDataIds, serviceData.ID)
+	}
+	var tokenMintingServiceDataIds []int32
+	for _, serviceData := range baseTransactions.TokenMintingServiceData {
+		tokenMintingServiceDataIds = append(tokenMintingServiceDataIds, serviceData.ID)
+	}
+
+	deletes := []struct {
+		model     interface{}
+		column    string
+		values    []int32
+		isPresent bool
+	}{
+		{&entities.OriginatorCurrencyData{}, "serviceDataId", serviceDataIds, len(serviceDataIds) > 0},
+		{&entities.CurrencyTypeData{}, "serviceDataId", serviceDataIds, len(serviceDataIds) > 0},
+		{&entities.TokenGenerationServiceData{}, "id", serviceDataIds, len(serviceDataIds) > 0},
+		{&entities.TokenMintingServiceData{}, "id", tokenMintingServiceDataIds, len(tokenMintingServiceDataIds) > 0},
+		{&entities.TokenGenerationFeeBaseTransaction{}, "transactionId", transactionIds, true},
+		{&entities.TokenMintingFeeBaseTransaction{}, "transactionId", transactionIds, true},
+		{&entities.FullnodeFeeBaseTransaction{}, "transactionId", transactionIds, true},
+		{&entities.NetworkFeeBaseTransaction{}, "transactionId", transactionIds, true},
+		{&entities.ReceiverBaseTransaction{}, "transactionId", transactionIds, true},
+		{&entities.InputBaseTransaction{}, "transactionId", transactionIds, true},
+		{&entities.EventInputBaseTransaction{}, "transactionId", transactionIds, true},
+		{&entities.TransactionAddress{}, "transactionId", transactionIds, true},
+		{&entities.TransactionCurrency{}, "transactionId", transactionIds, true},
+		{&entities.Transaction{}, "id", transactionIds, true},
+	}
+	for _, d := range deletes {
+		if !d.isPresent {
+			continue
+		}
+		err = repository.db.Where(map[string]interface{}{d.column: d.values}).Delete(d.model).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return baseTransactions, nil
+}
+
+func (repository *gormTransactionRepository) FindBaseTransactions(transactionIds []int32) (*BaseTransactions, error) {
+	baseTransactions := &BaseTransactions{}
+	condition := map[string]interface{}{"transactionId": transactionIds}
+	finds := []interface{}{
+		&baseTransactions.InputBaseTransactions,
+		&baseTransactions.ReceiverBaseTransactions,
+		&baseTransactions.FullnodeFeeBaseTransactions,
+		&baseTransactions.NetworkFeeBaseTransactions,
+		&baseTransactions.EventInputBaseTransactions,
+		&baseTransactions.TokenGenerationFeeBaseTransactions,
+		&baseTransactions.TokenMintingFeeBaseTransactions,
+	}
+	for _, find := range finds {
+		err := repository.db.Where(condition).Find(find).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	var tokenMintingFeeBaseTransactionIds []int32
+	for _, baseTransaction := range baseTransactions.TokenMintingFeeBaseTransactions {
+		tokenMintingFeeBaseTransactionIds = append(tokenMintingFeeBaseTransactionIds, baseTransaction.ID)
+	}
+	err := repository.db.Where(map[string]interface{}{"baseTransactionId": tokenMintingFeeBaseTransactionIds}).Find(&baseTransactions.TokenMintingServiceData).Error
+	if err != nil {
+		return nil, err
+	}
+	return baseTransactions, nil
+}
+
+func (repository *gormTransactionRepository) CreateBaseTransactions(baseTransactions *BaseTransactions) error {
+	creates := []struct {
+		value     interface{}
+		isPresent bool
+	}{
+		{&baseTransactions.InputBaseTransactions, len(baseTransactions.InputBaseTransactions) > 0},
+		{&baseTransactions.ReceiverBaseTransactions, len(baseTransactions.ReceiverBaseTransactions) > 0},
+		{&baseTransactions.FullnodeFeeBaseTransactions, len(baseTransactions.FullnodeFeeBaseTransactions) > 0},
+		{&baseTransactions.NetworkFeeBaseTransactions, len(baseTransactions.NetworkFeeBaseTransactions) > 0},
+		{&baseTransactions.EventInputBaseTransactions, len(baseTransactions.EventInputBaseTransactions) > 0},
+		{&baseTransactions.TokenGenerationFeeBaseTransactions, len(baseTransactions.TokenGenerationFeeBaseTransactions) > 0},
+		{&baseTransactions.TokenMintingFeeBaseTransactions, len(baseTransactions.TokenMintingFeeBaseTransactions) > 0},
+	}
+	for _, create := range creates {
+		if !create.isPresent {
+			continue
+		}
+		err := repository.db.Omit("CreateTime", "UpdateTime").Create(create.value).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repository *gormTransactionRepository) CreateTokenGenerationServiceData(serviceData []*entities.TokenGenerationServiceData) error {
+	if len(serviceData) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&serviceData).Error
+}
+
+func (repository *gormTransactionRepository) CreateTokenMintingServiceData(serviceData []*entities.TokenMintingServiceData) error {
+	if len(serviceData) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&serviceData).Error
+}
+
+func (repository *gormTransactionRepository) CreateTransactionCurrencies(transactionCurrencies []*entities.TransactionCurrency) error {
+	if len(transactionCurrencies) == 0 {
+		return nil
+	}
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(&transactionCurrencies).Error
+}
+
+func (repository *gormTransactionRepository) CreateReversal(reversal *entities.TransactionReversal) error {
+	return repository.db.Omit("CreateTime", "UpdateTime").Create(reversal).Error
+}
+
+func (repository *gormTransactionRepository) FindReversals(fromId int32, limit int) ([]entities.TransactionReversal, error) {
+	var reversals []entities.TransactionReversal
+	err := repository.db.Where(clause.Gt{Column: "id", Value: fromId}).Order("id").Limit(limit).Find(&reversals).Error
+	return reversals, err
+}
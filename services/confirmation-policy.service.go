@@ -0,0 +1,148 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm/clause"
+)
+
+type ConfirmationPolicyName string
+
+const (
+	DspConsensusPolicy              ConfirmationPolicyName = "dspConsensus"
+	TrustChainConsensusPolicy       ConfirmationPolicyName = "trustChainConsensus"
+	DspAndTrustChainConsensusPolicy ConfirmationPolicyName = "dspAndTrustChainConsensus"
+	MinTrustChainTrustScorePolicy   ConfirmationPolicyName = "minTrustChainTrustScore"
+)
+
+// ConfirmationPolicy decides when the balance effects of a transaction can be applied
+type ConfirmationPolicy interface {
+	Name() ConfirmationPolicyName
+	// Condition is the sql condition matching the confirmed transactions
+	Condition() clause.Expression
+	IsConfirmed(tx *entities.Transaction) bool
+	// MinTrustScore is the threshold stored with the processed transactions, null for the consensus policies
+	MinTrustScore() decimal.NullDecimal
+}
+
+type dspConsensusPolicy struct {
+}
+
+type trustChainConsensusPolicy struct {
+}
+
+type dspAndTrustChainConsensusPolicy struct {
+}
+
+type minTrustChainTrustScorePolicy struct {
+	minTrustScore decimal.Decimal
+}
+
+// NewConfirmationPolicy creates the policy set by CONFIRMATION_POLICY, dsp consensus is used when it is not set
+// and the trust score policy uses MIN_TRUST_CHAIN_TRUST_SCORE
+func NewConfirmationPolicy() (ConfirmationPolicy, error) {
+	syncConfig := config.Get().Sync
+	policyName := ConfirmationPolicyName(syncConfig.ConfirmationPolicy)
+	if policyName != MinTrustChainTrustScorePolicy {
+		return newConsensusPolicy(policyName)
+	}
+	minTrustScore, err := decimal.NewFromString(syncConfig.MinTrustChainTrustScore)
+	if err != nil {
+		return nil, fmt.Errorf("MIN_TRUST_CHAIN_TRUST_SCORE is mandatory for the %s confirmation policy: %w", policyName, err)
+	}
+	return &minTrustChainTrustScorePolicy{minTrustScore: minTrustScore}, nil
+}
+
+// newProcessedConfirmationPolicy creates the policy a transaction was processed with, the trust score policy uses the
+// threshold stored with the transaction and not the current MIN_TRUST_CHAIN_TRUST_SCORE
+func newProcessedConfirmationPolicy(tx *entities.Transaction) (ConfirmationPolicy, error) {
+	policyName := ConfirmationPolicyName(*tx.ConfirmationPolicy)
+	if policyName != MinTrustChainTrustScorePolicy {
+		return newConsensusPolicy(policyName)
+	}
+	if !tx.ConfirmationMinTrustScore.Valid {
+		return nil, fmt.Errorf("the transaction has no %s threshold", policyName)
+	}
+	return &minTrustChainTrustScorePolicy{minTrustScore: tx.ConfirmationMinTrustScore.Decimal}, nil
+}
+
+// newConsensusPolicy creates the consensus policy of the name
+func newConsensusPolicy(policyName ConfirmationPolicyName) (ConfirmationPolicy, error) {
+	switch policyName {
+	case "", DspConsensusPolicy:
+		return &dspConsensusPolicy{}, nil
+	case TrustChainConsensusPolicy:
+		return &trustChainConsensusPolicy{}, nil
+	case DspAndTrustChainConsensusPolicy:
+		return &dspAndTrustChainConsensusPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown confirmation policy %s", policyName)
+	}
+}
+
+func (policy *dspConsensusPolicy) Name() ConfirmationPolicyName {
+	return DspConsensusPolicy
+}
+
+func (policy *dspConsensusPolicy) Condition() clause.Expression {
+	return clause.Neq{Column: "transactionConsensusUpdateTime", Value: nil}
+}
+
+func (policy *dspConsensusPolicy) IsConfirmed(tx *entities.Transaction) bool {
+	return tx.TransactionConsensusUpdateTime.Valid
+}
+
+func (policy *dspConsensusPolicy) MinTrustScore() decimal.NullDecimal {
+	return decimal.NullDecimal{}
+}
+
+func (policy *trustChainConsensusPolicy) Name() ConfirmationPolicyName {
+	return TrustChainConsensusPolicy
+}
+
+func (policy *trustChainConsensusPolicy) Condition() clause.Expression {
+	return clause.Eq{Column: "trustChainConsensus", Value: true}
+}
+
+func (policy *trustChainConsensusPolicy) IsConfirmed(tx *entities.Transaction) bool {
+	return tx.TrustChainConsensus
+}
+
+func (policy *trustChainConsensusPolicy) MinTrustScore() decimal.NullDecimal {
+	return decimal.NullDecimal{}
+}
+
+func (policy *dspAndTrustChainConsensusPolicy) Name() ConfirmationPolicyName {
+	return DspAndTrustChainConsensusPolicy
+}
+
+func (policy *dspAndTrustChainConsensusPolicy) Condition() clause.Expression {
+	return clause.And(clause.Neq{Column: "transactionConsensusUpdateTime", Value: nil}, clause.Eq{Column: "trustChainConsensus", Value: true})
+}
+
+func (policy *dspAndTrustChainConsensusPolicy) IsConfirmed(tx *entities.Transaction) bool {
+	return tx.TransactionConsensusUpdateTime.Valid && tx.TrustChainConsensus
+}
+
+func (policy *dspAndTrustChainConsensusPolicy) MinTrustScore() decimal.NullDecimal {
+	return decimal.NullDecimal{}
+}
+
+func (policy *minTrustChainTrustScorePolicy) Name() ConfirmationPolicyName {
+	return MinTrustChainTrustScorePolicy
+}
+
+func (policy *minTrustChainTrustScorePolicy) Condition() clause.Expression {
+	return clause.Gte{Column: "trustChainTrustScore", Value: policy.minTrustScore}
+}
+
+func (policy *minTrustChainTrustScorePolicy) IsConfirmed(tx *entities.Transaction) bool {
+	return tx.TrustChainTrustScore.GreaterThanOrEqual(policy.minTrustScore)
+}
+
+func (policy *minTrustChainTrustScorePolicy) MinTrustScore() decimal.NullDecimal {
+	return decimal.NewNullDecimal(policy.minTrustScore)
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/coti-io/coti-db-app/dto"
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/shopspring/decimal"
+)
+
+var currencySupplyOnce sync.Once
+
+type CurrencySupplyService interface {
+	GetCurrencySupplies() ([]dto.CurrencySupplyResponse, error)
+	GetCurrencySupply(currencyHash string) (*dto.CurrencySupplyResponse, error)
+}
+type currencySupplyService struct {
+	currencyRepository repository.CurrencyRepository
+}
+
+// currencySupplyDiff is the change to a currency supply caused by one balance update
+type currencySupplyDiff struct {
+	circulating decimal.Decimal
+	minted      decimal.Decimal
+	holders     int32
+}
+
+var currencySupplyServiceInstance *currencySupplyService
+
+func NewCurrencySupplyService(currencyRepository repository.CurrencyRepository) CurrencySupplyService {
+	currencySupplyOnce.Do(func() {
+		currencySupplyServiceInstance = &currencySupplyService{currencyRepository: currencyRepository}
+	})
+	return currencySupplyServiceInstance
+}
+
+func (service *currencySupplyService) GetCurrencySupplies() ([]dto.CurrencySupplyResponse, error) {
+	supplies, err := service.currencyRepository.FindSupplies()
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.CurrencySupplyResponse, 0, len(supplies))
+	for _, supply := range supplies {
+		response = append(response, toCurrencySupplyResponse(supply))
+	}
+	return response, nil
+}
+
+func (service *currencySupplyService) GetCurrencySupply(currencyHash string) (*dto.CurrencySupplyResponse, error) {
+	supply, err := service.currencyRepository.FindSupplyByHash(currencyHash)
+	if err != nil {
+		return nil, err
+	}
+	response := toCurrencySupplyResponse(*supply)
+	return &response, nil
+}
+
+func toCurrencySupplyResponse(res repository.CurrencySupplyRow) dto.CurrencySupplyResponse {
+	return dto.CurrencySupplyResponse{
+		CurrencyHash:      res.CurrencyHash,
+		MintedAmount:      res.MintedAmount,
+		BurnedAmount:      res.BurnedAmount,
+		CirculatingSupply: res.CirculatingSupply,
+		HolderCount:       res.HolderCount,
+	}
+}
+
+// InitCurrencySupplies makes sure a supply row exists for every given currency
+func InitCurrencySupplies(repositories repository.Repositories, currencyIds []int32) error {
+	_, err := lockCurrencySupplies(repositories, currencyIds)
+	return err
+}
+
+// lockCurrencySupplies gets the supply rows of the currencies for update, missing rows are seeded from the current address balances
+// so it must run before the balances of the iteration are applied
+func lockCurrencySupplies(repositories repository.Repositories, currencyIds []int32) (map[int32]*entities.CurrencySupply, error) {
+	currencyIdToSupplyMap := make(map[int32]*entities.CurrencySupply)
+	if len(currencyIds) == 0 {
+		return currencyIdToSupplyMap, nil
+	}
+	supplies, err := repositories.Currencies().FindSuppliesForUpdate(currencyIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, supply := range supplies {
+		currencyIdToSupplyMap[supply.CurrencyId] = supply
+	}
+
+	var missingCurrencyIds []int32
+	for _, currencyId := range currencyIds {
+		if currencyIdToSupplyMap[currencyId] == nil {
+			missingCurrencyIds = append(missingCurrencyIds, currencyId)
+		}
+	}
+	if len(missingCurrencyIds) == 0 {
+		return currencyIdToSupplyMap, nil
+	}
+
+	aggregates, err := repositories.Balances().SumByCurrency(missingCurrencyIds)
+	if err != nil {
+		return nil, err
+	}
+	currencyIdToAggregateMap := make(map[int32]repository.CurrencyBalanceTotal)
+	for _, aggregate := range aggregates {
+		currencyIdToAggregateMap[aggregate.CurrencyId] = aggregate
+	}
+	var suppliesToCreate []*entities.CurrencySupply
+	for _, currencyId := range missingCurrencyIds {
+		aggregate := currencyIdToAggregateMap[currencyId]
+		supply := entities.NewCurrencySupply(currencyId, aggregate.Total, aggregate.Holders)
+		suppliesToCreate = append(suppliesToCreate, supply)
+		currencyIdToSupplyMap[currencyId] = supply
+	}
+	if err := repositories.Currencies().CreateSupplies(suppliesToCreate); err != nil {
+		return nil, err
+	}
+	return currencyIdToSupplyMap, nil
+}
+
+// applyCurrencySupplyDiffs adds the diffs to the supplies, a decrease of the circulating supply that is not explained by minting is counted as burned
+// and the increase of a reversal gives the burned amount back
+func applyCurrencySupplyDiffs(repositories repository.Repositories, currencyIdToSupplyMap map[int32]*entities.CurrencySupply, currencyIdToSupplyDiffMap map[int32]*currencySupplyDiff, isReversal bool) error {
+	var suppliesToUpdate []*entities.CurrencySupply
+	for currencyId, supplyDiff := range currencyIdToSupplyDiffMap {
+		supply := currencyIdToSupplyMap[currencyId]
+		if supply == nil {
+			continue
+		}
+		nonMintingDiff := supplyDiff.circulating.Sub(supplyDiff.minted)
+		if !isReversal && nonMintingDiff.IsNegative() {
+			supply.BurnedAmount = supply.BurnedAmount.Add(nonMintingDiff.Neg())
+		}
+		if isReversal && nonMintingDiff.IsPositive() {
+			supply.BurnedAmount = decimal.Max(supply.BurnedAmount.Sub(nonMintingDiff), decimal.Zero)
+		}
+		supply.MintedAmount = supply.MintedAmount.Add(supplyDiff.minted)
+		supply.CirculatingSupply = supply.CirculatingSupply.Add(supplyDiff.circulating)
+		supply.HolderCount = supply.HolderCount + supplyDiff.holders
+		suppliesToUpdate = append(suppliesToUpdate, supply)
+	}
+	return repositories.Currencies().SaveSupplies(suppliesToUpdate)
+}
+
+func getCurrencySupplyDiff(currencyIdToSupplyDiffMap map[int32]*currencySupplyDiff, currencyId int32) *currencySupplyDiff {
+	supplyDiff := currencyIdToSupplyDiffMap[currencyId]
+	if supplyDiff == nil {
+		supplyDiff = &currencySupplyDiff{}
+		currencyIdToSupplyDiffMap[currencyId] = supplyDiff
+	}
+	return supplyDiff
+}
+
+// holderCountDiff returns the change in the holder count when a balance moves from oldAmount to newAmount
+func holderCountDiff(oldAmount decimal.Decimal, newAmount decimal.Decimal) int32 {
+	wasHolder := oldAmount.IsPositive()
+	isHolder := newAmount.IsPositive()
+	if !wasHolder && isHolder {
+		return 1
+	}
+	if wasHolder && !isHolder {
+		return -1
+	}
+	return 0
+}
+
+// RecalculateCurrencySupplies sets the circulating supply and holder count of the currencies from the address balances,
+// the minted and burned amounts are kept since they can't be derived from the balances
+func RecalculateCurrencySupplies(repositories repository.Repositories, currencyIds []int32) error {
+	currencyIdToSupplyMap, err := lockCurrencySupplies(repositories, currencyIds)
+	if err != nil {
+		return err
+	}
+	if len(currencyIdToSupplyMap) == 0 {
+		return nil
+	}
+	aggregates, err := repositories.Balances().SumByCurrency(currencyIds)
+	if err != nil {
+		return err
+	}
+	currencyIdToAggregateMap := make(map[int32]repository.CurrencyBalanceTotal)
+	for _, aggregate := range aggregates {
+		currencyIdToAggregateMap[aggregate.CurrencyId] = aggregate
+	}
+	var suppliesToUpdate []*entities.CurrencySupply
+	for currencyId, supply := range currencyIdToSupplyMap {
+		aggregate := currencyIdToAggregateMap[currencyId]
+		supply.CirculatingSupply = aggregate.Total
+		supply.HolderCount = aggregate.Holders
+		suppliesToUpdate = append(suppliesToUpdate, supply)
+	}
+	return repositories.Currencies().SaveSupplies(suppliesToUpdate)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/shopspring/decimal"
+)
+
+func TestApplyCurrencySupplyDiffsBurnedAmount(t *testing.T) {
+	tests := []struct {
+		name        string
+		burned      int64
+		circulating int64
+		minted      int64
+		isReversal  bool
+		expected    int64
+	}{
+		{"a decrease is burned", 0, -5, 0, false, 5},
+		{"a transfer burns nothing", 10, 0, 0, false, 10},
+		{"an increase gives nothing back", 10, 5, 0, false, 10},
+		{"a mint burns nothing", 0, 5, 5, false, 0},
+		{"a reversed burn gives the amount back", 10, 4, 0, true, 6},
+		{"the burned amount doesn't go below zero", 3, 5, 0, true, 0},
+		{"a reversed mint burns nothing", 0, -5, -5, true, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repositories := repository.NewMemoryRepositories()
+			supply := entities.NewCurrencySupply(1, decimal.NewFromInt(100), 1)
+			supply.BurnedAmount = decimal.NewFromInt(test.burned)
+			if err := repositories.Currencies().CreateSupplies([]*entities.CurrencySupply{supply}); err != nil {
+				t.Fatal(err)
+			}
+			supplyDiff := &currencySupplyDiff{circulating: decimal.NewFromInt(test.circulating), minted: decimal.NewFromInt(test.minted)}
+			err := applyCurrencySupplyDiffs(repositories, map[int32]*entities.CurrencySupply{1: supply}, map[int32]*currencySupplyDiff{1: supplyDiff}, test.isReversal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !supply.BurnedAmount.Equal(decimal.NewFromInt(test.expected)) {
+				t.Fatalf("the burned amount is %s, expected %d", supply.BurnedAmount, test.expected)
+			}
+		})
+	}
+}
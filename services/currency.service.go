@@ -2,36 +2,40 @@ package service
 
 import (
 	"encoding/hex"
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
 	"github.com/ebfe/keccak"
-	"os"
 	"strings"
 	"sync"
 )
 
-var currencyOnce sync.Once
+var nativeCurrencyHashOnce sync.Once
 
 type CurrencyService interface {
 	// The exported functions
 	NormalizeCurrencyHash(currencyHash *string) string
 	GetNativeCurrencyHash() string
 	GetCurrencyHashBySymbol(symbol string) (err error, currencyHash string)
+	GetNativeCurrency() (*entities.Currency, error)
 }
 type currencyService struct {
 	// exported Fields
 	nativeCurrencyHash string
+	currencyRepository repository.CurrencyRepository
 }
 
-var currencyServiceInstance *currencyService
+var nativeCurrencyHash string
 
-func NewCurrencyService() CurrencyService {
-	currencyOnce.Do(func() {
-		nativeSymbol := os.Getenv("NATIVE_SYMBOL")
-		_, nativeCurrencyHashGenerated := getCurrencyHashBySymbol(nativeSymbol)
-		currencyServiceInstance = &currencyService{
-			nativeCurrencyHash: nativeCurrencyHashGenerated,
-		}
+// NewCurrencyService isn't a singleton since the repository may be bound to a db transaction
+func NewCurrencyService(currencyRepository repository.CurrencyRepository) CurrencyService {
+	nativeCurrencyHashOnce.Do(func() {
+		_, nativeCurrencyHash = getCurrencyHashBySymbol(config.Get().NativeSymbol)
 	})
-	return currencyServiceInstance
+	return &currencyService{
+		nativeCurrencyHash: nativeCurrencyHash,
+		currencyRepository: currencyRepository,
+	}
 }
 
 func (service *currencyService) NormalizeCurrencyHash(currencyHash *string) string {
@@ -45,6 +49,11 @@ func (service *currencyService) GetNativeCurrencyHash() string {
 	return service.nativeCurrencyHash
 }
 
+// GetNativeCurrency gets the native currency and creates it if it doesn't exist
+func (service *currencyService) GetNativeCurrency() (*entities.Currency, error) {
+	return service.currencyRepository.FirstOrCreate(service.nativeCurrencyHash)
+}
+
 func (service *currencyService) GetCurrencyHashBySymbol(symbol string) (error error, currencyHash string) {
 	return getCurrencyHashBySymbol(symbol)
 }
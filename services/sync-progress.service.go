@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coti-io/coti-db-app/metrics"
+	repository "github.com/coti-io/coti-db-app/repositories"
+)
+
+// SyncRateWindow is how far back the rolling rates of the sync progress look
+const SyncRateWindow = 5 * time.Minute
+
+// SyncProgress is the sync state with the rolling rates, it is updated by the monitorSyncStatus job
+type SyncProgress struct {
+	LastMonitoredIndex  int64
+	MainNodeLastIndex   int64
+	BackupNodeLastIndex int64
+	// NodeLastIndex is the greater of the fullnode indexes
+	NodeLastIndex int64
+	IsSynced      bool
+	// SyncRate is the transactions per second the sync monitors and NodeRate the ones the fullnodes index
+	SyncRate float64
+	NodeRate float64
+	// Eta is how long the sync needs to catch up with the fullnodes, it is nil when it doesn't catch up at the current rates
+	Eta        *time.Duration
+	Backlogs   repository.Backlogs
+	UpdateTime time.Time
+}
+
+// GetRemaining is how many transactions the sync is behind the fullnodes
+func (progress SyncProgress) GetRemaining() int64 {
+	if progress.NodeLastIndex <= progress.LastMonitoredIndex {
+		return 0
+	}
+	return progress.NodeLastIndex - progress.LastMonitoredIndex
+}
+
+// GetSyncPercentage is 0 until a fullnode index is known
+func (progress SyncProgress) GetSyncPercentage() float64 {
+	return GetSyncPercentage(progress.LastMonitoredIndex, progress.NodeLastIndex)
+}
+
+// GetSyncPercentage is how much of the fullnode indexes the sync reached, it is 0 while either index is unknown
+func GetSyncPercentage(lastMonitoredIndex int64, lastIndex int64) float64 {
+	if lastIndex <= 0 || lastMonitoredIndex <= 0 {
+		return 0
+	}
+	if lastMonitoredIndex >= lastIndex {
+		return 100
+	}
+	return float64(lastMonitoredIndex) / float64(lastIndex) * 100
+}
+
+type syncSample struct {
+	time           time.Time
+	monitoredIndex int64
+	nodeIndex      int64
+}
+
+// syncProgressTracker keeps the samples of the rolling window
+type syncProgressTracker struct {
+	mutex     sync.Mutex
+	samples   []syncSample
+	progress  SyncProgress
+	isUpdated bool
+}
+
+// add adds a sample and returns the rates over the window, the window restarts when an index moves back
+func (tracker *syncProgressTracker) add(sample syncSample) (syncRate float64, nodeRate float64) {
+	if len(tracker.samples) > 0 {
+		last := tracker.samples[len(tracker.samples)-1]
+		if sample.monitoredIndex < last.monitoredIndex || sample.nodeIndex < last.nodeIndex {
+			tracker.samples = nil
+		}
+	}
+	tracker.samples = append(tracker.samples, sample)
+	// the oldest sample kept is the last one at the start of the window so the rates span the whole window
+	first := 0
+	for first < len(tracker.samples)-2 && sample.time.Sub(tracker.samples[first+1].time) >= SyncRateWindow {
+		first++
+	}
+	tracker.samples = tracker.samples[first:]
+
+	oldest := tracker.samples[0]
+	elapsed := sample.time.Sub(oldest.time).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
+	}
+	return float64(sample.monitoredIndex-oldest.monitoredIndex) / elapsed, float64(sample.nodeIndex-oldest.nodeIndex) / elapsed
+}
+
+// GetSyncProgress is the last progress of the monitorSyncStatus job, it is false until the job measured it once
+func (service *transactionService) GetSyncProgress() (SyncProgress, bool) {
+	service.syncProgress.mutex.Lock()
+	defer service.syncProgress.mutex.Unlock()
+	return service.syncProgress.progress, service.syncProgress.isUpdated
+}
+
+// updateSyncProgress measures the progress against the fullnode indexes the monitorSyncStatus job just read
+func (service *transactionService) updateSyncProgress(ctx context.Context) error {
+	lastMonitoredIndex, err := service.getLastMonitoredIndex(ctx)
+	if err != nil {
+		return err
+	}
+	backlogs, err := service.repositories.WithContext(ctx).Transactions().CountBacklogs(service.confirmationPolicy)
+	if err != nil {
+		return err
+	}
+	progress := SyncProgress{
+		LastMonitoredIndex:  lastMonitoredIndex,
+		MainNodeLastIndex:   service.syncHistory.LastIndexMainNode,
+		BackupNodeLastIndex: service.syncHistory.LastIndexBackupNode,
+		NodeLastIndex:       service.syncHistory.LastIndexMainNode,
+		IsSynced:            service.syncHistory.IsSynced,
+		Backlogs:            *backlogs,
+		UpdateTime:          time.Now(),
+	}
+	if progress.BackupNodeLastIndex > progress.NodeLastIndex {
+		progress.NodeLastIndex = progress.BackupNodeLastIndex
+	}
+
+	service.syncProgress.mutex.Lock()
+	defer service.syncProgress.mutex.Unlock()
+	progress.SyncRate, progress.NodeRate = service.syncProgress.add(syncSample{time: progress.UpdateTime, monitoredIndex: progress.LastMonitoredIndex, nodeIndex: progress.NodeLastIndex})
+	remaining := progress.GetRemaining()
+	if remaining == 0 {
+		eta := time.Duration(0)
+		progress.Eta = &eta
+	} else if catchUpRate := progress.SyncRate - progress.NodeRate; catchUpRate > 0 {
+		eta := time.Duration(float64(remaining) / catchUpRate * float64(time.Second))
+		progress.Eta = &eta
+	}
+	service.syncProgress.progress = progress
+	service.syncProgress.isUpdated = true
+
+	metrics.SyncRate.Set(progress.SyncRate)
+	metrics.SyncBacklog.WithLabelValues("balanceProcessing").Set(float64(backlogs.BalanceProcessing))
+	metrics.SyncBacklog.WithLabelValues("monitor").Set(float64(backlogs.Monitor))
+	metrics.SyncBacklog.WithLabelValues("pendingUnindexed").Set(float64(backlogs.PendingUnindexed))
+	return nil
+}
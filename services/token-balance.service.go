@@ -1,6 +1,9 @@
 package service
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 type tokenBalance struct {
 	CurrencyHash string
@@ -27,3 +30,7 @@ func newTokenBalanceFromString(tokenBalanceString string) *tokenBalance {
 	}
 	return instance
 }
+
+func addressBalanceKey(addressHash string, currencyId int32) string {
+	return addressHash + "_" + strconv.Itoa(int(currencyId))
+}
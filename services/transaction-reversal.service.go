@@ -0,0 +1,124 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/sirupsen/logrus"
+)
+
+var transactionReversalOnce sync.Once
+
+type TransactionReversalReason string
+
+const (
+	InvalidatedReversalReason   TransactionReversalReason = "invalidated"
+	ConsensusLostReversalReason TransactionReversalReason = "consensusLost"
+)
+
+type TransactionReversalService interface {
+	GetTransactionReversals(fromId int32, limit int) ([]entities.TransactionReversal, error)
+}
+type transactionReversalService struct {
+	transactionRepository repository.TransactionRepository
+}
+
+// transactionReversal is a processed transaction that has to be reversed
+type transactionReversal struct {
+	tx     *entities.Transaction
+	reason TransactionReversalReason
+}
+
+var transactionReversalServiceInstance *transactionReversalService
+
+func NewTransactionReversalService(transactionRepository repository.TransactionRepository) TransactionReversalService {
+	transactionReversalOnce.Do(func() {
+		transactionReversalServiceInstance = &transactionReversalService{transactionRepository: transactionRepository}
+	})
+	return transactionReversalServiceInstance
+}
+
+// GetTransactionReversals returns the reversal events with an id greater than fromId, so consumers can poll for new events
+func (service *transactionReversalService) GetTransactionReversals(fromId int32, limit int) ([]entities.TransactionReversal, error) {
+	return service.transactionRepository.FindReversals(fromId, limit)
+}
+
+// getReversalReason checks if the balance effects of a processed transaction are no longer valid,
+// consensus is checked against the policy and threshold the transaction was processed with
+func getReversalReason(tx *entities.Transaction) (TransactionReversalReason, bool) {
+	if !tx.IsProcessed || tx.IsSkipped || tx.IsReversed {
+		return "", false
+	}
+	if tx.IsValid.Valid && !tx.IsValid.Bool {
+		return InvalidatedReversalReason, true
+	}
+	if tx.ConfirmationPolicy == nil {
+		return "", false
+	}
+	if ConfirmationPolicyName(*tx.ConfirmationPolicy) == MinTrustChainTrustScorePolicy && !tx.ConfirmationMinTrustScore.Valid {
+		// processed before the threshold was stored, the current one may not be the one that confirmed it
+		return "", false
+	}
+	confirmationPolicy, err := newProcessedConfirmationPolicy(tx)
+	if err != nil {
+		logrus.WithError(err).WithField("hash", tx.Hash).Warn("the consensus of the transaction can't be checked")
+		return "", false
+	}
+	if !confirmationPolicy.IsConfirmed(tx) {
+		return ConsensusLostReversalReason, true
+	}
+	return "", false
+}
+
+// reverseTransactions removes the balance and address count effects of the transactions and logs each reversal
+func reverseTransactions(repositories repository.Repositories, currencyServiceInstance CurrencyService, reversals []transactionReversal) error {
+	if len(reversals) == 0 {
+		return nil
+	}
+	// balances are changed by the update balances job as well
+	_, err := repositories.AppStates().GetByNameForUpdate(entities.UpdateBalances)
+	if err != nil {
+		return err
+	}
+	transactionIds := make([]int32, 0, len(reversals))
+	txsToSave := make([]*entities.Transaction, 0, len(reversals))
+	for _, reversal := range reversals {
+		transactionIds = append(transactionIds, reversal.tx.ID)
+		txsToSave = append(txsToSave, reversal.tx)
+	}
+	diffs, err := collectBalanceDiffs(repositories, currencyServiceInstance, transactionIds)
+	if err != nil {
+		return err
+	}
+	diffs.negate()
+	err = updateBalances(repositories, diffs.currencyHashUniqueArray, diffs.addressBalanceDiffMap, diffs.currencyMintedAmountMap, true)
+	if err != nil {
+		return err
+	}
+	err = updateAddressCounts(repositories, diffs.addressTransactionCountMap)
+	if err != nil {
+		return err
+	}
+	for _, reversal := range reversals {
+		reversal.tx.IsReversed = true
+	}
+	err = repositories.Transactions().Save(txsToSave)
+	if err != nil {
+		return err
+	}
+	for _, reversal := range reversals {
+		balanceDiff, err := json.Marshal(diffs.transactionAddressBalanceDiffMap[reversal.tx.ID])
+		if err != nil {
+			return err
+		}
+		transactionReversal := entities.NewTransactionReversal(reversal.tx, string(reversal.reason), string(balanceDiff))
+		err = repositories.Transactions().CreateReversal(transactionReversal)
+		if err != nil {
+			return err
+		}
+		logrus.WithFields(logrus.Fields{"hash": reversal.tx.Hash, "reason": reversal.reason}).Info("transaction reversed")
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/dto"
+	"github.com/coti-io/coti-db-app/entities"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/shopspring/decimal"
+)
+
+func monitorIteration(t *testing.T, service *transactionService) {
+	t.Helper()
+	if err := service.monitorTransactionIteration(service.GetCurrentFullnodeUrl(), monitorTransactionsBatchSize); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReverseInvalidatedTransactions(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories repository.Repositories) {
+		fullnode := newFakeFullnode(t)
+		first := testTransfer{hash: "first", index: 0, sender: "alice", receiver: "bob", amount: 10, isConfirmed: true}
+		second := testTransfer{hash: "second", index: 1, sender: "bob", receiver: "carol", amount: 4, isConfirmed: true}
+		fullnode.set(first.response(), second.response())
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		updateBalancesIteration(t, service)
+		addresses := []string{"alice", "bob", "carol", "fullnode"}
+		assertBalances(t, getNativeBalances(t, repositories, service, addresses...), map[string]int64{"alice": -11, "bob": 5, "carol": 4, "fullnode": 2})
+
+		// both transactions are reversed by one iteration of the monitor
+		var invalidated []dto.TransactionResponse
+		for _, transfer := range []testTransfer{first, second} {
+			tx := transfer.response()
+			tx.IsValid = new(bool)
+			invalidated = append(invalidated, tx)
+		}
+		fullnode.set(invalidated...)
+		monitorIteration(t, service)
+
+		assertBalances(t, getNativeBalances(t, repositories, service, addresses...), map[string]int64{"alice": 0, "bob": 0, "carol": 0, "fullnode": 0})
+		txs := getTransactions(t, repositories, "first", "second")
+		if !txs["first"].IsReversed || !txs["second"].IsReversed {
+			t.Fatalf("the transactions were reversed as %+v", txs)
+		}
+		counts, err := repositories.Addresses().FindTransactionCounts(addresses)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, count := range counts {
+			if count.Count != 0 {
+				t.Fatalf("the transaction count of %s is %d after the reversals", count.AddressHash, count.Count)
+			}
+		}
+
+		reversals, err := repositories.Transactions().FindReversals(0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reversals) != 2 {
+			t.Fatalf("%d reversals were logged", len(reversals))
+		}
+		nativeHash := service.currencyService.GetNativeCurrencyHash()
+		expectedDiffs := map[string]map[string]int64{
+			"first":  {"alice": 11, "bob": -10, "fullnode": -1},
+			"second": {"bob": 5, "carol": -4, "fullnode": -1},
+		}
+		for _, reversal := range reversals {
+			if reversal.Reason != string(InvalidatedReversalReason) {
+				t.Fatalf("the reversal of %s has the reason %s", reversal.TransactionHash, reversal.Reason)
+			}
+			// each reversal logs the diffs of its own transaction
+			var balanceDiff map[string]decimal.Decimal
+			if err := json.Unmarshal([]byte(reversal.BalanceDiff), &balanceDiff); err != nil {
+				t.Fatal(err)
+			}
+			addressDiffs := make(map[string]decimal.Decimal)
+			for key, diff := range balanceDiff {
+				tb := newTokenBalanceFromString(key)
+				if tb.CurrencyHash != nativeHash {
+					t.Fatalf("the reversal of %s has a diff in %s", reversal.TransactionHash, tb.CurrencyHash)
+				}
+				addressDiffs[tb.AddressHash] = diff
+			}
+			assertBalances(t, addressDiffs, expectedDiffs[reversal.TransactionHash])
+		}
+	})
+}
+
+func TestReverseByTheRecordedConfirmationPolicy(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories repository.Repositories) {
+		fullnode := newFakeFullnode(t)
+		recorded := testTransfer{hash: "recorded", index: 0, sender: "alice", receiver: "bob", amount: 10, isConfirmed: true}
+		current := testTransfer{hash: "current", index: 1, sender: "carol", receiver: "dave", amount: 3, isConfirmed: true}
+		fullnode.set(recorded.response(), current.response())
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		updateBalancesIteration(t, service)
+
+		// the recorded transaction was processed when the policy was trust chain consensus
+		txs := getTransactions(t, repositories, "recorded")
+		recordedTx := txs["recorded"]
+		trustChainConsensusPolicy := string(TrustChainConsensusPolicy)
+		recordedTx.ConfirmationPolicy = &trustChainConsensusPolicy
+		if err := repositories.Transactions().Save([]*entities.Transaction{&recordedTx}); err != nil {
+			t.Fatal(err)
+		}
+
+		// both lose the trust chain consensus and keep the dsp consensus of the current policy
+		var lost []dto.TransactionResponse
+		for _, transfer := range []testTransfer{recorded, current} {
+			tx := transfer.response()
+			tx.TrustChainConsensus = false
+			lost = append(lost, tx)
+		}
+		fullnode.set(lost...)
+		monitorIteration(t, service)
+
+		txs = getTransactions(t, repositories, "recorded", "current")
+		if !txs["recorded"].IsReversed || txs["current"].IsReversed {
+			t.Fatalf("the transactions were reversed as %+v", txs)
+		}
+		reversals, err := repositories.Transactions().FindReversals(0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reversals) != 1 || reversals[0].TransactionHash != "recorded" || reversals[0].Reason != string(ConsensusLostReversalReason) {
+			t.Fatalf("the reversals are %+v", reversals)
+		}
+		assertBalances(t, getNativeBalances(t, repositories, service, "alice", "bob", "carol", "dave", "fullnode"),
+			map[string]int64{"alice": 0, "bob": 0, "carol": -4, "dave": 3, "fullnode": 1})
+	})
+}
+
+func TestKeepTheTransactionsConfirmedByALowerMinTrustScore(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories repository.Repositories) {
+		config.Get().Sync.ConfirmationPolicy = string(MinTrustChainTrustScorePolicy)
+		config.Get().Sync.MinTrustChainTrustScore = "50"
+		fullnode := newFakeFullnode(t)
+		recorded := testTransfer{hash: "recorded", index: 0, sender: "alice", receiver: "bob", amount: 10, isConfirmed: true}
+		unrecorded := testTransfer{hash: "unrecorded", index: 1, sender: "carol", receiver: "dave", amount: 3, isConfirmed: true}
+		fullnode.set(recorded.response(), unrecorded.response())
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		updateBalancesIteration(t, service)
+		txs := getTransactions(t, repositories, "recorded", "unrecorded")
+		if !txs["recorded"].ConfirmationMinTrustScore.Valid || !txs["recorded"].ConfirmationMinTrustScore.Decimal.Equal(decimal.NewFromInt(50)) {
+			t.Fatalf("the transaction was processed with the threshold %v", txs["recorded"].ConfirmationMinTrustScore)
+		}
+		// the unrecorded transaction was processed before the threshold was stored
+		unrecordedTx := txs["unrecorded"]
+		unrecordedTx.ConfirmationMinTrustScore = decimal.NullDecimal{}
+		if err := repositories.Transactions().Save([]*entities.Transaction{&unrecordedTx}); err != nil {
+			t.Fatal(err)
+		}
+
+		// the threshold is raised above the trust scores, which still change
+		config.Get().Sync.MinTrustChainTrustScore = "150"
+		var responses []dto.TransactionResponse
+		for _, transfer := range []testTransfer{recorded, unrecorded} {
+			tx := transfer.response()
+			tx.TrustChainTrustScore = decimal.NewFromInt(120)
+			responses = append(responses, tx)
+		}
+		fullnode.set(responses...)
+		monitorIteration(t, service)
+
+		txs = getTransactions(t, repositories, "recorded", "unrecorded")
+		if txs["recorded"].IsReversed || txs["unrecorded"].IsReversed {
+			t.Fatalf("the transactions were reversed as %+v", txs)
+		}
+		if !txs["recorded"].TrustChainTrustScore.Equal(decimal.NewFromInt(120)) {
+			t.Fatalf("the trust score was updated to %s", txs["recorded"].TrustChainTrustScore)
+		}
+		assertBalances(t, getNativeBalances(t, repositories, service, "alice", "bob", "carol", "dave"),
+			map[string]int64{"alice": -11, "bob": 10, "carol": -4, "dave": 3})
+	})
+}
+
+func TestMonitorPagesThroughTheProcessedWindow(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories repository.Repositories) {
+		fullnode := newFakeFullnode(t)
+		transfers := []testTransfer{
+			{hash: "first", index: 0, sender: "alice", receiver: "bob", amount: 1, isConfirmed: true},
+			{hash: "second", index: 1, sender: "alice", receiver: "bob", amount: 1, isConfirmed: true},
+			{hash: "third", index: 2, sender: "alice", receiver: "bob", amount: 1, isConfirmed: true},
+		}
+		var confirmed, invalidated []dto.TransactionResponse
+		for _, transfer := range transfers {
+			confirmed = append(confirmed, transfer.response())
+			tx := transfer.response()
+			tx.IsValid = new(bool)
+			invalidated = append(invalidated, tx)
+		}
+		fullnode.set(confirmed...)
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		updateBalancesIteration(t, service)
+		fullnode.set(invalidated...)
+
+		// each iteration checks the next batch after the monitored id and starts over at the end of the window
+		for i, expected := range []struct {
+			reversed    []string
+			monitoredId string
+		}{
+			{[]string{"first", "second"}, "2"},
+			{[]string{"first", "second", "third"}, "0"},
+		} {
+			if err := service.monitorTransactionIteration(service.GetCurrentFullnodeUrl(), 2); err != nil {
+				t.Fatal(err)
+			}
+			var reversed []string
+			for hash, tx := range getTransactions(t, repositories, "first", "second", "third") {
+				if tx.IsReversed {
+					reversed = append(reversed, hash)
+				}
+			}
+			sort.Strings(reversed)
+			if strings.Join(reversed, ",") != strings.Join(expected.reversed, ",") {
+				t.Fatalf("iteration %d reversed %v and not %v", i+1, reversed, expected.reversed)
+			}
+			appStates, err := repositories.AppStates().FindByNames([]entities.AppStatesNames{entities.MonitoredProcessedId})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(appStates) != 1 || appStates[0].Value != expected.monitoredId {
+				t.Fatalf("iteration %d monitored up to %+v and not %s", i+1, appStates, expected.monitoredId)
+			}
+		}
+		for _, count := range fullnode.requestedHashCounts {
+			if count > 2 {
+				t.Fatalf("the fullnode was asked for %d hashes at once", count)
+			}
+		}
+	})
+}
+
+func TestKeepTheRebasedTransactions(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories repository.Repositories) {
+		fullnode := newFakeFullnode(t)
+		rebased := testTransfer{hash: "rebased", index: 0, sender: "alice", receiver: "bob", amount: 10, isConfirmed: true}
+		later := testTransfer{hash: "later", index: 1, sender: "bob", receiver: "carol", amount: 4, isConfirmed: true}
+		fullnode.set(rebased.response(), later.response())
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		updateBalancesIteration(t, service)
+
+		// a cluster stamp taken at index 0 was rebased on
+		appState, err := repositories.AppStates().FirstOrCreate(entities.RebasedTransactionIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appState.Value = "0"
+		if err := repositories.AppStates().Save(appState); err != nil {
+			t.Fatal(err)
+		}
+
+		var invalidated []dto.TransactionResponse
+		for _, transfer := range []testTransfer{rebased, later} {
+			tx := transfer.response()
+			tx.IsValid = new(bool)
+			invalidated = append(invalidated, tx)
+		}
+		fullnode.set(invalidated...)
+		monitorIteration(t, service)
+
+		txs := getTransactions(t, repositories, "rebased", "later")
+		if txs["rebased"].IsReversed || !txs["later"].IsReversed {
+			t.Fatalf("the transactions were reversed as %+v", txs)
+		}
+		assertBalances(t, getNativeBalances(t, repositories, service, "alice", "bob", "carol", "fullnode"),
+			map[string]int64{"alice": -11, "bob": 10, "carol": 0, "fullnode": 1})
+	})
+}
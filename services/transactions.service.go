@@ -2,29 +2,49 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/shopspring/decimal"
 	"io/ioutil"
-	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	dbProvider "github.com/coti-io/coti-db-app/db-provider"
+	"github.com/coti-io/coti-db-app/config"
 	"github.com/coti-io/coti-db-app/dto"
 	"github.com/coti-io/coti-db-app/entities"
-
-	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
+	"github.com/coti-io/coti-db-app/health"
+	"github.com/coti-io/coti-db-app/jobs"
+	"github.com/coti-io/coti-db-app/leader"
+	"github.com/coti-io/coti-db-app/logger"
+	"github.com/coti-io/coti-db-app/metrics"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/coti-io/coti-db-app/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var transactionOnce sync.Once
 
+// updateBalancesBatchSize is how many transactions an updateBalances iteration processes until it is changed by the admin api
+const updateBalancesBatchSize = 3000
+
+// monitorTransactionsBatchSize is how many processed transactions a monitorTransactions iteration checks for reversal
+// and how many hashes it requests from the fullnode at once until it is changed by the admin api
+const monitorTransactionsBatchSize = 1000
+
+// ErrInvalidAdminRequest is wrapped by the errors of the admin actions that were refused
+var ErrInvalidAdminRequest = errors.New("invalid request")
+
+// ErrSyncNotRunning is returned by the admin actions that need the sync in a process that doesn't run it
+var ErrSyncNotRunning = errors.New("the sync doesn't run in this process")
+
+const addressBalanceBatchSize = 1000
+
 type BaseTransactionName string
 
 type SyncHistory struct {
@@ -33,33 +53,56 @@ type SyncHistory struct {
 	IsSynced            bool
 }
 
+// fullnodeClient records the fullnode requests in the metrics and traces them
+var fullnodeClient = tracing.WrapClient(metrics.NewFullnodeClient(), "fullnode")
+
 func isResError(res *http.Response) bool {
 	return res.StatusCode < 200 || res.StatusCode >= 300
 }
 
 type TransactionService interface {
-	RunSync()
-	GetLastIndex(fullnodeUrl string) <-chan dto.TransactionsLastIndexChanelResult
+	// RunSync runs the writer jobs while the elector has the instance lead, the sync status is monitored either way
+	RunSync(elector leader.Elector)
+	RunSyncStatusMonitor()
+	GetLastIndex(ctx context.Context, fullnodeUrl string) <-chan dto.TransactionsLastIndexChanelResult
 	GetLastIteration() int64
 	GetFullnodeUrl() string
 	GetBackupFullnodeUrl() string
 	GetSyncHistory() SyncHistory
+	// GetSyncProgress is the sync state with the rolling rates and the backlogs, it is false until it was measured once
+	GetSyncProgress() (SyncProgress, bool)
+	GetCurrentFullnodeUrl() string
+	// SwitchFullnode makes the jobs use the main or the backup fullnode, an empty node switches to the other one
+	SwitchFullnode(node string) (string, error)
+	// RunJobIteration runs one iteration of the monitorTransactions or the cleanUnindexedTransaction job now
+	RunJobIteration(name string) error
+	// SetLastMonitoredIndex moves the index the sync continues from, syncNewTransactions must be paused.
+	// Moving it forward skips transactions so it needs isForced
+	SetLastMonitoredIndex(ctx context.Context, index int64, isForced bool) (previousIndex int64, err error)
+	PingFullnodes(ctx context.Context) map[string]error
 }
 type transactionService struct {
-	fullnodeUrl        string
-	backupFullnodeUrl  string
-	isSyncRunning      bool
-	lastIterationIndex int64
-	syncHistory        SyncHistory
+	fullnodeUrl       string
+	backupFullnodeUrl string
+	isSyncRunning     bool
+	// isSyncStatusMonitorRunning is set by RunSync as well since the sync monitors the fullnodes
+	isSyncStatusMonitorRunning bool
+	lastIterationIndex         int64
+	syncHistory                SyncHistory
+	syncProgress               syncProgressTracker
+	// fullnodeMutex guards the fullnode the jobs use and its retry count, the admin api switches the fullnode as well
+	fullnodeMutex      sync.Mutex
 	retries            uint8
 	currentFullnodeUrl string
 	serviceUpTime      time.Time
+	confirmationPolicy ConfirmationPolicy
+	// how long processed transactions are monitored for reversal
+	reversalMonitorWindowInHours float64
+	repositories                 repository.Repositories
+	currencyService              CurrencyService
+	elector                      leader.Elector
 }
 
-type UpdateBalanceRes struct {
-	Id         int32
-	Identifier string
-}
 type TxBuilder struct {
 	Tx   dto.TransactionResponse
 	DbTx *entities.Transaction
@@ -97,23 +140,36 @@ type TransactionCurrencyBuilder struct {
 var instance *transactionService
 
 // NewTransactionService we made this one a singleton because it has a state
-func NewTransactionService() TransactionService {
+func NewTransactionService(repositories repository.Repositories) TransactionService {
 	transactionOnce.Do(func() {
-
-		instance = &transactionService{
-			fullnodeUrl:        os.Getenv("FULLNODE_URL"),
-			backupFullnodeUrl:  os.Getenv("FULLNODE_BACKUP_URL"),
-			isSyncRunning:      false,
-			lastIterationIndex: 0,
-			syncHistory:        SyncHistory{LastIndexMainNode: 0, LastIndexBackupNode: 0, IsSynced: false},
-			retries:            0,
-			currentFullnodeUrl: os.Getenv("FULLNODE_URL"),
-			serviceUpTime:      time.Now(),
-		}
+		instance = newTransactionService(repositories)
 	})
 	return instance
 }
 
+// newTransactionService creates a service with its own state, the process shares the one of NewTransactionService
+func newTransactionService(repositories repository.Repositories) *transactionService {
+	confirmationPolicy, err := NewConfirmationPolicy()
+	if err != nil {
+		panic(err)
+	}
+	appConfig := config.Get()
+	return &transactionService{
+		fullnodeUrl:                  appConfig.Fullnode.Url,
+		backupFullnodeUrl:            appConfig.Fullnode.BackupUrl,
+		isSyncRunning:                false,
+		lastIterationIndex:           0,
+		syncHistory:                  SyncHistory{LastIndexMainNode: 0, LastIndexBackupNode: 0, IsSynced: false},
+		retries:                      0,
+		currentFullnodeUrl:           appConfig.Fullnode.Url,
+		serviceUpTime:                time.Now(),
+		confirmationPolicy:           confirmationPolicy,
+		reversalMonitorWindowInHours: appConfig.Sync.ReversalMonitorWindow.Hours(),
+		repositories:                 repositories,
+		currencyService:              NewCurrencyService(repositories.Currencies()),
+	}
+}
+
 func (service *transactionService) GetFullnodeUrl() string {
 	return service.fullnodeUrl
 }
@@ -125,15 +181,146 @@ func (service *transactionService) GetSyncHistory() SyncHistory {
 	return service.syncHistory
 }
 
+func (service *transactionService) GetCurrentFullnodeUrl() string {
+	service.fullnodeMutex.Lock()
+	defer service.fullnodeMutex.Unlock()
+	return service.currentFullnodeUrl
+}
+
+func (service *transactionService) SwitchFullnode(node string) (string, error) {
+	service.fullnodeMutex.Lock()
+	defer service.fullnodeMutex.Unlock()
+	var fullnodeUrl string
+	switch node {
+	case "":
+		fullnodeUrl = service.getAlternateNodeUrl(service.currentFullnodeUrl)
+	case metrics.MainNode:
+		fullnodeUrl = service.fullnodeUrl
+	case metrics.BackupNode:
+		fullnodeUrl = service.backupFullnodeUrl
+	default:
+		return "", fmt.Errorf("%w: the node must be %s or %s", ErrInvalidAdminRequest, metrics.MainNode, metrics.BackupNode)
+	}
+	if fullnodeUrl == "" {
+		return "", fmt.Errorf("%w: the url of the fullnode to switch to is not set", ErrInvalidAdminRequest)
+	}
+	service.switchFullnode(fullnodeUrl)
+	logger.WithJob("admin").WithField(logger.FullnodeField, fullnodeUrl).Warn("switched the fullnode")
+	return fullnodeUrl, nil
+}
+
+// switchFullnode makes the jobs use fullnodeUrl with a fresh retry count, the caller holds the fullnode mutex
+func (service *transactionService) switchFullnode(fullnodeUrl string) {
+	service.currentFullnodeUrl = fullnodeUrl
+	metrics.SetCurrentFullnode(service.getNodeName(fullnodeUrl))
+	service.retries = 0
+}
+
+// retryFullnode counts a failed iteration on fullnodeUrl and returns whether to retry it, after maxRetries retries the jobs
+// are switched to the alternate fullnode. There is no retry when the fullnode was switched since the iteration started
+func (service *transactionService) retryFullnode(fullnodeUrl string, maxRetries uint8) bool {
+	service.fullnodeMutex.Lock()
+	defer service.fullnodeMutex.Unlock()
+	if service.currentFullnodeUrl != fullnodeUrl {
+		return false
+	}
+	if service.retries >= maxRetries {
+		service.switchFullnode(service.getAlternateNodeUrl(fullnodeUrl))
+		return false
+	}
+	service.retries++
+	return true
+}
+
+func (service *transactionService) resetRetries() {
+	service.fullnodeMutex.Lock()
+	defer service.fullnodeMutex.Unlock()
+	service.retries = 0
+}
+
+func (service *transactionService) RunJobIteration(name string) error {
+	if !service.isSyncRunning {
+		return ErrSyncNotRunning
+	}
+	job, ok := jobs.Get(name)
+	var iteration func() error
+	switch name {
+	case "monitorTransactions":
+		iteration = func() error {
+			return service.monitorTransactionIteration(service.GetCurrentFullnodeUrl(), job.GetBatchSize())
+		}
+	case "cleanUnindexedTransaction":
+		iteration = service.cleanUnindexedTransactionIteration
+	default:
+		return fmt.Errorf("%w: only monitorTransactions and cleanUnindexedTransaction can be run", ErrInvalidAdminRequest)
+	}
+	if !ok {
+		return ErrSyncNotRunning
+	}
+	dtStart := time.Now()
+	err := job.RunIteration(iteration)
+	job.Record(0, jobs.AdminTrigger, dtStart, err)
+	return err
+}
+
+func (service *transactionService) SetLastMonitoredIndex(ctx context.Context, index int64, isForced bool) (previousIndex int64, err error) {
+	if !service.isSyncRunning {
+		return 0, ErrSyncNotRunning
+	}
+	if index < -1 {
+		return 0, fmt.Errorf("%w: the index can't be less than -1", ErrInvalidAdminRequest)
+	}
+	if job, ok := jobs.Get("syncNewTransactions"); !ok || !job.IsPaused() {
+		return 0, fmt.Errorf("%w: pause syncNewTransactions first", ErrInvalidAdminRequest)
+	}
+	lastIndexResult := <-service.GetLastIndex(ctx, service.GetCurrentFullnodeUrl())
+	if lastIndexResult.Error != nil {
+		return 0, fmt.Errorf("failed to get the last index of the fullnode: %w", lastIndexResult.Error)
+	}
+	if index > lastIndexResult.Tran.LastIndex {
+		return 0, fmt.Errorf("%w: the index is past the last index %d of the fullnode", ErrInvalidAdminRequest, lastIndexResult.Tran.LastIndex)
+	}
+	err = service.repositories.WithContext(ctx).Transaction(func(repositories repository.Repositories) error {
+		if err := service.elector.Fence(repositories); err != nil {
+			return err
+		}
+		appState, err := repositories.AppStates().GetByNameForUpdate(entities.LastMonitoredTransactionIndex)
+		if err != nil {
+			return err
+		}
+		previousIndex = -1
+		if appState.Value != "" {
+			previousIndex, err = strconv.ParseInt(appState.Value, 10, 64)
+			if err != nil {
+				return err
+			}
+		}
+		// the transactions between the indexes would never be synced
+		if index > previousIndex && !isForced {
+			return fmt.Errorf("%w: moving the index forward from %d skips transactions, force it to do so", ErrInvalidAdminRequest, previousIndex)
+		}
+		appState.Value = strconv.FormatInt(index, 10)
+		return repositories.AppStates().Save(appState)
+	})
+	if err != nil {
+		return 0, err
+	}
+	metrics.LastMonitoredIndex.Set(float64(index))
+	logger.WithJob("admin").WithFields(logrus.Fields{"previousIndex": previousIndex, "index": index}).Warn("set the last monitored transaction index")
+	return previousIndex, nil
+}
+
 // RunSync TODO: handle all errors by channels
-func (service *transactionService) RunSync() {
+func (service *transactionService) RunSync(elector leader.Elector) {
 
 	if service.isSyncRunning {
 		return
 	}
 	service.isSyncRunning = true
+	service.elector = elector
+	metrics.SetCurrentFullnode(service.getNodeName(service.GetCurrentFullnodeUrl()))
 	// run sync tasks
-	go service.monitorSyncStatus()
+	service.RunSyncStatusMonitor()
 	go service.syncNewTransactions(2)
 	go service.monitorTransactions(2)
 	go service.cleanUnindexedTransaction()
@@ -141,94 +328,146 @@ func (service *transactionService) RunSync() {
 
 }
 
+// RunSyncStatusMonitor only follows the last index of the fullnodes, it lets a process that doesn't sync report the sync state
+func (service *transactionService) RunSyncStatusMonitor() {
+	if service.isSyncStatusMonitorRunning {
+		return
+	}
+	service.isSyncStatusMonitorRunning = true
+	go service.monitorSyncStatus()
+}
+
 func (service *transactionService) monitorSyncStatus() {
+	health.RegisterJob("monitorSyncStatus", 10*time.Second)
 	iteration := 0
 	for {
 		dtStart := time.Now()
-		fmt.Println("[monitorSyncStatus][iteration start] " + strconv.Itoa(iteration))
+		iterationLog := logger.WithIteration("monitorSyncStatus", iteration)
+		health.Beat("monitorSyncStatus", iteration)
+		iterationLog.Debug("iteration start")
 		iteration = iteration + 1
 		err := service.monitorSyncStatusIteration()
 		if err != nil {
-			fmt.Println(err)
+			iterationLog.WithError(err).Error("iteration failed")
 		}
-		fmt.Println("[monitorSyncStatus][iteration end] " + strconv.Itoa(iteration))
+		logger.WithDuration(iterationLog, dtStart).Debug("iteration end")
 		dtEnd := time.Now()
 		diff := dtEnd.Sub(dtStart)
 		diffInSeconds := diff.Seconds()
+		metrics.IterationDuration.WithLabelValues(metrics.MonitorSyncStatusJob).Observe(diffInSeconds)
 		timeDurationToSleep := time.Duration(float64(10) - diffInSeconds)
-		fmt.Println("[monitorSyncStatus][sleeping for] ", timeDurationToSleep)
+		iterationLog.WithField("sleepSeconds", int64(timeDurationToSleep)).Debug("sleeping")
 		time.Sleep(timeDurationToSleep * time.Second)
 		iteration += 1
 	}
 }
 
-func (service *transactionService) monitorSyncStatusIteration() error {
+func (service *transactionService) monitorSyncStatusIteration() (err error) {
+	ctx, span := tracing.StartIteration("monitorSyncStatus")
+	defer func() { tracing.End(span, err) }()
 	defer func() {
 		if r := recover(); r != nil {
-			log.Println("error in monitorSyncStatusIteration")
+			logger.LogPanic(logger.WithJob("monitorSyncStatus"), r)
+			tracing.RecordPanic(span, r)
 		}
 	}()
-	mainNodeCh, backupNodeCh := service.GetLastIndex(service.fullnodeUrl), service.GetLastIndex(service.backupFullnodeUrl)
+	// a process that doesn't sync or doesn't lead compares the fullnodes with the index the sync leader reached
+	syncedIndex := service.lastIterationIndex
+	if !service.isSyncRunning || !service.elector.IsLeader() {
+		syncedIndex, err = service.getLastMonitoredIndex(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	mainNodeCh, backupNodeCh := service.GetLastIndex(ctx, service.fullnodeUrl), service.GetLastIndex(ctx, service.backupFullnodeUrl)
 	mainNodeRes, backupNodeRes := <-mainNodeCh, <-backupNodeCh
 	isMainNodeError := mainNodeRes.Error != nil || mainNodeRes.Tran.Status == "error"
 	isBackupNodeError := backupNodeRes.Error != nil || backupNodeRes.Tran.Status == "error"
 	if isMainNodeError && isBackupNodeError {
-		log.Println("Main fullnode and backup fullnode could not get last index")
+		logger.WithJob("monitorSyncStatus").WithFields(logrus.Fields{"mainError": mainNodeRes.Error, "backupError": backupNodeRes.Error}).Warn("main fullnode and backup fullnode could not get the last index")
 		service.syncHistory.IsSynced = false
 
 	} else if isMainNodeError {
-		log.Println("Main fullnode could not get last index")
+		logger.WithJob("monitorSyncStatus").WithField(logrus.ErrorKey, mainNodeRes.Error).Warn("main fullnode could not get the last index")
 		service.syncHistory.IsSynced = false
 	} else {
-		if mainNodeRes.Tran.LastIndex >= backupNodeRes.Tran.LastIndex && service.lastIterationIndex >= mainNodeRes.Tran.LastIndex-100 {
+		if mainNodeRes.Tran.LastIndex >= backupNodeRes.Tran.LastIndex && syncedIndex >= mainNodeRes.Tran.LastIndex-100 {
 			service.syncHistory.IsSynced = true
 
 		} else if !isBackupNodeError {
 			if mainNodeRes.Tran.LastIndex < service.syncHistory.LastIndexBackupNode &&
-				float64(backupNodeRes.Tran.LastIndex-service.syncHistory.LastIndexBackupNode)*0.2 > float64(backupNodeRes.Tran.LastIndex-mainNodeRes.Tran.LastIndex) || service.lastIterationIndex < mainNodeRes.Tran.LastIndex-100 {
+				float64(backupNodeRes.Tran.LastIndex-service.syncHistory.LastIndexBackupNode)*0.2 > float64(backupNodeRes.Tran.LastIndex-mainNodeRes.Tran.LastIndex) || syncedIndex < mainNodeRes.Tran.LastIndex-100 {
 				service.syncHistory.IsSynced = false
 			}
 			service.syncHistory.LastIndexBackupNode = backupNodeRes.Tran.LastIndex
 		} else {
-			if service.lastIterationIndex >= mainNodeRes.Tran.LastIndex-100 {
+			if syncedIndex >= mainNodeRes.Tran.LastIndex-100 {
 				service.syncHistory.IsSynced = true
 			}
 		}
 		service.syncHistory.LastIndexMainNode = mainNodeRes.Tran.LastIndex
+		metrics.FullnodeLastIndex.WithLabelValues(metrics.MainNode).Set(float64(mainNodeRes.Tran.LastIndex))
 	}
-	return nil
+	if !isBackupNodeError {
+		metrics.FullnodeLastIndex.WithLabelValues(metrics.BackupNode).Set(float64(backupNodeRes.Tran.LastIndex))
+	}
+	return service.updateSyncProgress(ctx)
+}
+
+func (service *transactionService) getLastMonitoredIndex(ctx context.Context) (int64, error) {
+	appState, err := service.repositories.WithContext(ctx).AppStates().GetByName(entities.LastMonitoredTransactionIndex)
+	if err != nil {
+		return 0, err
+	}
+	if appState.Value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(appState.Value, 10, 64)
 }
 
 func (service *transactionService) getAlternateNodeUrl(fullnodeUrl string) string {
 	if fullnodeUrl == service.fullnodeUrl {
 		return service.backupFullnodeUrl
 	}
-	return service.backupFullnodeUrl
+	return service.fullnodeUrl
+}
+
+// getNodeName is the node label of a fullnode url in the metrics
+func (service *transactionService) getNodeName(fullnodeUrl string) string {
+	if fullnodeUrl == service.fullnodeUrl {
+		return metrics.MainNode
+	}
+	return metrics.BackupNode
 }
 
 func (service *transactionService) cleanUnindexedTransaction() {
 	// when slice was less than 1000 once replace to the other method that gets un-indexed ones as well
-	interval, err := strconv.ParseFloat(os.Getenv("CLEAN_UNINDEXED_TRANSACTIONS_INTERVAL_IN_SECONDS"), 64)
-	if err != nil {
-		panic(err.Error())
-	}
+	job := jobs.Register("cleanUnindexedTransaction", config.Get().Sync.CleanUnindexedTransactionsInterval, 0)
 	iteration := 0
 	for {
+		interval := job.GetInterval().Seconds()
 		dtStart := time.Now()
-		fmt.Println("[cleanUnindexedTransaction][iteration start] " + strconv.Itoa(iteration))
+		iterationLog := logger.WithIteration("cleanUnindexedTransaction", iteration)
+		health.Beat("cleanUnindexedTransaction", iteration)
+		if job.SleepIfPaused(iterationLog) || leader.SleepIfFollower(service.elector, iterationLog, time.Duration(interval*float64(time.Second))) {
+			continue
+		}
+		iterationLog.Debug("iteration start")
 		iteration = iteration + 1
-		err := service.cleanUnindexedTransactionIteration()
+		err := job.RunIteration(service.cleanUnindexedTransactionIteration)
+		job.Record(iteration, jobs.ScheduleTrigger, dtStart, err)
 		if err != nil {
-			fmt.Println(err)
+			iterationLog.WithError(err).Error("iteration failed")
 		}
 
-		fmt.Println("[cleanUnindexedTransaction][iteration end] " + strconv.Itoa(iteration))
+		logger.WithDuration(iterationLog, dtStart).Debug("iteration end")
 		dtEnd := time.Now()
 		diff := dtEnd.Sub(dtStart)
 		diffInSeconds := diff.Seconds()
+		metrics.IterationDuration.WithLabelValues(metrics.CleanUnindexedTransactionJob).Observe(diffInSeconds)
 		if diffInSeconds < interval && diffInSeconds > 0 {
 			timeDurationToSleep := time.Duration(interval - diffInSeconds)
-			fmt.Println("[cleanUnindexedTransaction][sleeping for] ", timeDurationToSleep)
+			iterationLog.WithField("sleepSeconds", int64(timeDurationToSleep)).Debug("sleeping")
 			time.Sleep(timeDurationToSleep * time.Second)
 
 		}
@@ -237,202 +476,236 @@ func (service *transactionService) cleanUnindexedTransaction() {
 
 func (service *transactionService) updateBalances() {
 	// when slice was less than 1000 once replace to the other method that gets un-indexed ones as well
-	interval, err := strconv.ParseFloat(os.Getenv("UPDATE_BALANCES_INTERVAL_IN_SECONDS"), 64)
-	if err != nil {
-		panic(err.Error())
-	}
+	job := jobs.Register("updateBalances", config.Get().Sync.UpdateBalancesInterval, updateBalancesBatchSize)
 	iteration := 0
 	for {
+		interval := job.GetInterval().Seconds()
 		dtStart := time.Now()
-		fmt.Println("[updateBalances][iteration start] " + strconv.Itoa(iteration))
+		iterationLog := logger.WithIteration("updateBalances", iteration)
+		health.Beat("updateBalances", iteration)
+		if job.SleepIfPaused(iterationLog) || leader.SleepIfFollower(service.elector, iterationLog, time.Duration(interval*float64(time.Second))) {
+			continue
+		}
+		iterationLog.Debug("iteration start")
 		iteration = iteration + 1
-		err := service.updateBalancesIteration()
+		err := service.updateBalancesIteration(job.GetBatchSize())
+		job.Record(iteration, jobs.ScheduleTrigger, dtStart, err)
 		if err != nil {
-			fmt.Println(err)
+			iterationLog.WithError(err).Error("iteration failed")
 		}
 
-		fmt.Println("[updateBalances][iteration end] " + strconv.Itoa(iteration))
+		logger.WithDuration(iterationLog, dtStart).Debug("iteration end")
 		dtEnd := time.Now()
 		diff := dtEnd.Sub(dtStart)
 		diffInSeconds := diff.Seconds()
+		metrics.IterationDuration.WithLabelValues(metrics.UpdateBalancesJob).Observe(diffInSeconds)
 		if diffInSeconds < interval && diffInSeconds > 0 {
 			timeDurationToSleep := time.Duration(interval - diffInSeconds)
-			fmt.Println("[updateBalances][sleeping for] ", timeDurationToSleep)
+			iterationLog.WithField("sleepSeconds", int64(timeDurationToSleep)).Debug("sleeping")
 			time.Sleep(timeDurationToSleep * time.Second)
 
 		}
 	}
 }
 
-func (service *transactionService) updateBalancesIteration() error {
+func (service *transactionService) updateBalancesIteration(batchSize int) (err error) {
+	ctx, span := tracing.StartIteration("updateBalances")
+	defer func() { tracing.End(span, err) }()
 	defer func() {
 		if r := recover(); r != nil {
-			log.Println("error in updateBalancesIteration")
+			logger.LogPanic(logger.WithJob("updateBalances"), r)
+			metrics.DbTransactionRollbacks.WithLabelValues(metrics.UpdateBalancesJob).Inc()
+			tracing.RecordPanic(span, r)
 		}
 	}()
-	err := dbProvider.DB.Transaction(func(dbTransaction *gorm.DB) (err error) {
-		var appState entities.AppState
-		err = dbTransaction.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", entities.UpdateBalances).First(&appState).Error
+	var processedCount, skippedCount, balanceUpdateCount int
+	err = service.repositories.WithContext(ctx).Transaction(func(repositories repository.Repositories) (err error) {
+		err = service.elector.Fence(repositories)
+		if err != nil {
+			return err
+		}
+		_, err = repositories.AppStates().GetByNameForUpdate(entities.UpdateBalances)
 		if err != nil {
 			return err
 		}
 
-		var txs []entities.Transaction
-		var ffbts []entities.FullnodeFeeBaseTransaction
-		var nfbts []entities.NetworkFeeBaseTransaction
-		var rbts []entities.ReceiverBaseTransaction
-		var ibts []entities.InputBaseTransaction
-		var tmbts []entities.TokenMintingFeeBaseTransaction
-		var tgbts []entities.TokenGenerationFeeBaseTransaction
-		var eibts []entities.EventInputBaseTransaction
-		var tmbtServiceData []entities.TokenMintingServiceData
-
-		// get all transaction with consensus and not processed
-		err = dbTransaction.Where("`isProcessed` = 0 AND transactionConsensusUpdateTime IS NOT NULL AND type <> 'ZeroSpend'").Limit(3000).Find(&txs).Error
+		// get all transaction confirmed by the policy or invalid and not processed
+		txs, err := repositories.Transactions().FindToProcess(service.confirmationPolicy, batchSize)
 		if err != nil {
 			return err
 		}
 		if len(txs) == 0 {
-			fmt.Println("[updateBalancesIteration][no transactions to update balance was found]")
+			logger.WithJob("updateBalances").Debug("no transactions to update balance were found")
 			return nil
 		}
 		txIdToAttachmentTime := make(map[int32]decimal.Decimal)
 		for _, tx := range txs {
 			txIdToAttachmentTime[tx.ID] = tx.AttachmentTime
 		}
+		confirmationPolicyName := string(service.confirmationPolicy.Name())
+		confirmationMinTrustScore := service.confirmationPolicy.MinTrustScore()
 		var transactionIds []int32
+		var skippedTransactionHashes []string
+		txsToSave := make([]*entities.Transaction, 0, len(txs))
 		for i, v := range txs {
+			txsToSave = append(txsToSave, &txs[i])
 			txs[i].IsProcessed = true
+			txs[i].ConfirmationPolicy = &confirmationPolicyName
+			txs[i].ConfirmationMinTrustScore = confirmationMinTrustScore
+			// invalid transactions are marked as processed without applying their balances
+			if v.IsValid.Valid && !v.IsValid.Bool {
+				txs[i].IsSkipped = true
+				skippedTransactionHashes = append(skippedTransactionHashes, v.Hash)
+				continue
+			}
 			transactionIds = append(transactionIds, v.ID)
 		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&ffbts).Error
-		if err != nil {
-			return err
-		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&nfbts).Error
-		if err != nil {
-			return err
-		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&rbts).Error
-		if err != nil {
-			return err
-		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&ibts).Error
-		if err != nil {
-			return err
-		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&eibts).Error
-		if err != nil {
-			return err
+		if len(skippedTransactionHashes) > 0 {
+			logger.WithJob("updateBalances").WithField("hashes", strings.Join(skippedTransactionHashes, ",")).Infof("skipped %d invalid transactions", len(skippedTransactionHashes))
 		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&tmbts).Error
+		diffs, err := collectBalanceDiffs(repositories, service.currencyService, transactionIds)
 		if err != nil {
 			return err
 		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&tgbts).Error
+		err = repositories.Transactions().Save(txsToSave)
 		if err != nil {
 			return err
 		}
-		err = dbTransaction.Save(&txs).Error
+
+		err = updateBalances(repositories, diffs.currencyHashUniqueArray, diffs.addressBalanceDiffMap, diffs.currencyMintedAmountMap, false)
 		if err != nil {
 			return err
 		}
+		processedCount = len(txs)
+		skippedCount = len(skippedTransactionHashes)
+		balanceUpdateCount = len(diffs.addressBalanceDiffMap)
 
-		var tmbtIds []int32
-		for _, v := range tmbts {
-			tmbtIds = append(tmbtIds, v.ID)
+		return nil
+	})
+	if err != nil {
+		metrics.DbTransactionRollbacks.WithLabelValues(metrics.UpdateBalancesJob).Inc()
+		return err
+	}
+	metrics.TransactionsProcessed.Add(float64(processedCount))
+	metrics.TransactionsSkipped.Add(float64(skippedCount))
+	metrics.BalanceUpdatesApplied.Add(float64(balanceUpdateCount))
+	return nil
+}
+
+// balanceDiffs are the balance effects of a set of transactions
+type balanceDiffs struct {
+	currencyHashUniqueArray    []string
+	addressBalanceDiffMap      map[string]decimal.Decimal
+	currencyMintedAmountMap    map[string]decimal.Decimal
+	addressTransactionCountMap map[string]int32
+	// transactionAddressBalanceDiffMap splits the balance diffs by transaction id
+	transactionAddressBalanceDiffMap map[int32]map[string]decimal.Decimal
+}
+
+// negate turns the diffs into the diffs that reverse them
+func (diffs *balanceDiffs) negate() {
+	for k, v := range diffs.addressBalanceDiffMap {
+		diffs.addressBalanceDiffMap[k] = v.Neg()
+	}
+	for k, v := range diffs.currencyMintedAmountMap {
+		diffs.currencyMintedAmountMap[k] = v.Neg()
+	}
+	for k, v := range diffs.addressTransactionCountMap {
+		diffs.addressTransactionCountMap[k] = -v
+	}
+	for _, addressBalanceDiffMap := range diffs.transactionAddressBalanceDiffMap {
+		for k, v := range addressBalanceDiffMap {
+			addressBalanceDiffMap[k] = v.Neg()
 		}
-		err = dbTransaction.Where(map[string]interface{}{"baseTransactionId": tmbtIds}).Find(&tmbtServiceData).Error
-		if err != nil {
-			return err
+	}
+}
+
+// CollectAddressBalanceDiffs returns the summed balance diffs of the transactions by currency hash and address hash
+func CollectAddressBalanceDiffs(repositories repository.Repositories, transactionIds []int32) (map[string]map[string]decimal.Decimal, error) {
+	diffs, err := collectBalanceDiffs(repositories, NewCurrencyService(repositories.Currencies()), transactionIds)
+	if err != nil {
+		return nil, err
+	}
+	currencyAddressDiffMap := make(map[string]map[string]decimal.Decimal)
+	for k, diff := range diffs.addressBalanceDiffMap {
+		tb := newTokenBalanceFromString(k)
+		if currencyAddressDiffMap[tb.CurrencyHash] == nil {
+			currencyAddressDiffMap[tb.CurrencyHash] = make(map[string]decimal.Decimal)
 		}
+		currencyAddressDiffMap[tb.CurrencyHash][tb.AddressHash] = diff
+	}
+	return currencyAddressDiffMap, nil
+}
 
-		serviceDataIdToTxId := make(map[int32]int32)
-		for _, tmbtTx := range tmbts {
-			for _, serviceDataTx := range tmbtServiceData {
-				if tmbtTx.ID == serviceDataTx.BaseTransactionId {
-					serviceDataIdToTxId[serviceDataTx.ID] = tmbtTx.TransactionId
-				}
+func collectBalanceDiffs(repositories repository.Repositories, currencyServiceInstance CurrencyService, transactionIds []int32) (*balanceDiffs, error) {
+	baseTransactions, err := repositories.Transactions().FindBaseTransactions(transactionIds)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceDataIdToTxId := make(map[int32]int32)
+	for _, tmbtTx := range baseTransactions.TokenMintingFeeBaseTransactions {
+		for _, serviceDataTx := range baseTransactions.TokenMintingServiceData {
+			if tmbtTx.ID == serviceDataTx.BaseTransactionId {
+				serviceDataIdToTxId[serviceDataTx.ID] = tmbtTx.TransactionId
 			}
 		}
+	}
 
-		uniqueHelperMap := make(map[string]bool)
-		currencyHashUniqueArray := make([]string, 1)
-
-		var currencyServiceInstance = NewCurrencyService()
-		var addressBalanceDiffMap = make(map[string]decimal.Decimal)
-		for _, baseTransaction := range tgbts {
-			// calculate hash of currency
-			currencyHash := currencyServiceInstance.NormalizeCurrencyHash(baseTransaction.CurrencyHash)
-			addItemToUniqueArray(uniqueHelperMap, &currencyHashUniqueArray, currencyHash)
-			btTokenBalance := newTokenBalance(currencyHash, baseTransaction.AddressHash)
-			key := btTokenBalance.toString()
-			addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(baseTransaction.Amount)
-
-		}
-
-		for _, baseTransaction := range ffbts {
-			currencyHash := currencyServiceInstance.NormalizeCurrencyHash(baseTransaction.CurrencyHash)
-			addItemToUniqueArray(uniqueHelperMap, &currencyHashUniqueArray, currencyHash)
-			btTokenBalance := newTokenBalance(currencyHash, baseTransaction.AddressHash)
-			key := btTokenBalance.toString()
-			addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(baseTransaction.Amount)
-		}
-		for _, baseTransaction := range nfbts {
-			currencyHash := currencyServiceInstance.NormalizeCurrencyHash(baseTransaction.CurrencyHash)
-			addItemToUniqueArray(uniqueHelperMap, &currencyHashUniqueArray, currencyHash)
-			btTokenBalance := newTokenBalance(currencyHash, baseTransaction.AddressHash)
-			key := btTokenBalance.toString()
-			addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(baseTransaction.Amount)
-		}
-		for _, baseTransaction := range rbts {
-			currencyHash := currencyServiceInstance.NormalizeCurrencyHash(baseTransaction.CurrencyHash)
-			addItemToUniqueArray(uniqueHelperMap, &currencyHashUniqueArray, currencyHash)
-			btTokenBalance := newTokenBalance(currencyHash, baseTransaction.AddressHash)
-			key := btTokenBalance.toString()
-			addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(baseTransaction.Amount)
-		}
-		for _, baseTransaction := range ibts {
-			currencyHash := currencyServiceInstance.NormalizeCurrencyHash(baseTransaction.CurrencyHash)
-			addItemToUniqueArray(uniqueHelperMap, &currencyHashUniqueArray, currencyHash)
-			btTokenBalance := newTokenBalance(currencyHash, baseTransaction.AddressHash)
-			key := btTokenBalance.toString()
-			addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(baseTransaction.Amount)
-		}
-		for _, baseTransaction := range eibts {
-			currencyHash := currencyServiceInstance.NormalizeCurrencyHash(baseTransaction.CurrencyHash)
-			addItemToUniqueArray(uniqueHelperMap, &currencyHashUniqueArray, currencyHash)
-			btTokenBalance := newTokenBalance(currencyHash, baseTransaction.AddressHash)
-			key := btTokenBalance.toString()
-			addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(baseTransaction.Amount)
-		}
-		for _, baseTransaction := range tmbts {
-			currencyHash := currencyServiceInstance.NormalizeCurrencyHash(baseTransaction.CurrencyHash)
-			addItemToUniqueArray(uniqueHelperMap, &currencyHashUniqueArray, currencyHash)
-			btTokenBalance := newTokenBalance(currencyHash, baseTransaction.AddressHash)
-			key := btTokenBalance.toString()
-			addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(baseTransaction.Amount)
-		}
-		for _, serviceData := range tmbtServiceData {
-			addItemToUniqueArray(uniqueHelperMap, &currencyHashUniqueArray, serviceData.MintingCurrencyHash)
-			btTokenBalance := newTokenBalance(serviceData.MintingCurrencyHash, serviceData.ReceiverAddress)
-			key := btTokenBalance.toString()
-			fmt.Println(serviceData.MintingAmount.String())
-			addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(serviceData.MintingAmount)
-		}
-
-		err = updateBalances(dbTransaction, currencyHashUniqueArray, addressBalanceDiffMap)
-		if err != nil {
-			return err
-		}
+	uniqueHelperMap := make(map[string]bool)
+	helperMapAddressTransactionCount := make(map[string]bool)
+	diffs := &balanceDiffs{
+		currencyHashUniqueArray:          make([]string, 1),
+		addressBalanceDiffMap:            make(map[string]decimal.Decimal),
+		currencyMintedAmountMap:          make(map[string]decimal.Decimal),
+		addressTransactionCountMap:       make(map[string]int32),
+		transactionAddressBalanceDiffMap: make(map[int32]map[string]decimal.Decimal),
+	}
 
-		return nil
-	})
-	return err
+	addBaseTransactionDiff := func(transactionId int32, addressHash string, currencyHash *string, amount decimal.Decimal) {
+		normalizedCurrencyHash := currencyServiceInstance.NormalizeCurrencyHash(currencyHash)
+		addItemToUniqueArray(uniqueHelperMap, &diffs.currencyHashUniqueArray, normalizedCurrencyHash)
+		btTokenBalance := newTokenBalance(normalizedCurrencyHash, addressHash)
+		key := btTokenBalance.toString()
+		diffs.addressBalanceDiffMap[key] = diffs.addressBalanceDiffMap[key].Add(amount)
+		transactionDiffMap := diffs.transactionAddressBalanceDiffMap[transactionId]
+		if transactionDiffMap == nil {
+			transactionDiffMap = make(map[string]decimal.Decimal)
+			diffs.transactionAddressBalanceDiffMap[transactionId] = transactionDiffMap
+		}
+		transactionDiffMap[key] = transactionDiffMap[key].Add(amount)
+		increaseCountIfUnique(helperMapAddressTransactionCount, diffs.addressTransactionCountMap, fmt.Sprintf("%d_%s", transactionId, addressHash), addressHash)
+	}
+	for _, baseTransaction := range baseTransactions.TokenGenerationFeeBaseTransactions {
+		addBaseTransactionDiff(baseTransaction.TransactionId, baseTransaction.AddressHash, baseTransaction.CurrencyHash, baseTransaction.Amount)
+	}
+	for _, baseTransaction := range baseTransactions.FullnodeFeeBaseTransactions {
+		addBaseTransactionDiff(baseTransaction.TransactionId, baseTransaction.AddressHash, baseTransaction.CurrencyHash, baseTransaction.Amount)
+	}
+	for _, baseTransaction := range baseTransactions.NetworkFeeBaseTransactions {
+		addBaseTransactionDiff(baseTransaction.TransactionId, baseTransaction.AddressHash, baseTransaction.CurrencyHash, baseTransaction.Amount)
+	}
+	for _, baseTransaction := range baseTransactions.ReceiverBaseTransactions {
+		addBaseTransactionDiff(baseTransaction.TransactionId, baseTransaction.AddressHash, baseTransaction.CurrencyHash, baseTransaction.Amount)
+	}
+	for _, baseTransaction := range baseTransactions.InputBaseTransactions {
+		addBaseTransactionDiff(baseTransaction.TransactionId, baseTransaction.AddressHash, baseTransaction.CurrencyHash, baseTransaction.Amount)
+	}
+	for _, baseTransaction := range baseTransactions.EventInputBaseTransactions {
+		addBaseTransactionDiff(baseTransaction.TransactionId, baseTransaction.AddressHash, baseTransaction.CurrencyHash, baseTransaction.Amount)
+	}
+	for _, baseTransaction := range baseTransactions.TokenMintingFeeBaseTransactions {
+		addBaseTransactionDiff(baseTransaction.TransactionId, baseTransaction.AddressHash, baseTransaction.CurrencyHash, baseTransaction.Amount)
+	}
+	for _, serviceData := range baseTransactions.TokenMintingServiceData {
+		mintingCurrencyHash := serviceData.MintingCurrencyHash
+		addBaseTransactionDiff(serviceDataIdToTxId[serviceData.ID], serviceData.ReceiverAddress, &mintingCurrencyHash, serviceData.MintingAmount)
+		diffs.currencyMintedAmountMap[mintingCurrencyHash] = diffs.currencyMintedAmountMap[mintingCurrencyHash].Add(serviceData.MintingAmount)
+	}
+	return diffs, nil
 }
 
-func appendTransactionCurrency(txId int32, attachmentTime decimal.Decimal, currencyHash *string, helperMapTransactionCurrencies map[string]bool, txCurrencyBuilders *[]*TransactionCurrencyBuilder, helperMapCurrencies map[string]bool) {
-	var currencyServiceInstance = NewCurrencyService()
+func appendTransactionCurrency(currencyServiceInstance CurrencyService, txId int32, attachmentTime decimal.Decimal, currencyHash *string, helperMapTransactionCurrencies map[string]bool, txCurrencyBuilders *[]*TransactionCurrencyBuilder, helperMapCurrencies map[string]bool) {
 	var finalCurrencyHash string
 	if currencyHash == nil {
 		finalCurrencyHash = currencyServiceInstance.NormalizeCurrencyHash(currencyHash)
@@ -447,97 +720,85 @@ func appendTransactionCurrency(txId int32, attachmentTime decimal.Decimal, curre
 	}
 }
 
-func updateBalances(dbTransaction *gorm.DB, currencyHashUniqueArray []string, addressBalanceDiffMap map[string]decimal.Decimal) (err error) {
+// updateBalances applies the diffs to the balances and the currency supplies, isReversal tells the diffs undo processed transactions
+func updateBalances(repositories repository.Repositories, currencyHashUniqueArray []string, addressBalanceDiffMap map[string]decimal.Decimal, currencyMintedAmountMap map[string]decimal.Decimal, isReversal bool) (err error) {
 	// get all currency that have currency hash
-	currenciesEntities := make([]entities.Currency, len(currencyHashUniqueArray))
-	err = dbTransaction.Where(map[string]interface{}{"hash": currencyHashUniqueArray}).Find(&currenciesEntities).Error
+	currenciesEntities, err := repositories.Currencies().FindByHashes(currencyHashUniqueArray)
 	if err != nil {
 		return err
 	}
 	var currencyHashToIdMap = make(map[string]int32)
+	var currencyIds []int32
 	if len(currenciesEntities) > 0 {
 		for _, c := range currenciesEntities {
 			currencyHashToIdMap[c.Hash] = c.ID
+			currencyIds = append(currencyIds, c.ID)
 		}
 	}
 
-	// get all address balances that exists
-	addressHashes := make([]interface{}, 0, len(addressBalanceDiffMap))
-	for key := range addressBalanceDiffMap {
-		addressHashes = append(addressHashes, key)
-	}
-
-	// handle existing balance to update
-	var updateBalanceResponseArray []UpdateBalanceRes
-
-	err = dbTransaction.Model(&entities.AddressBalance{}).
-		Select("address_balances.id, CONCAT(address_balances.addressHash, '_', currencies.hash) as identifier").
-		Joins("INNER JOIN currencies on currencies.id = address_balances.currencyId").
-		Where("CONCAT(address_balances.addressHash, '_', currencies.hash) IN (?"+strings.Repeat(",?", len(addressHashes)-1)+")", addressHashes...).
-		Find(&updateBalanceResponseArray).Error
+	// lock the supplies before the balances change, missing ones are seeded from the balances
+	currencyIdToSupplyMap, err := lockCurrencySupplies(repositories, currencyIds)
 	if err != nil {
 		return err
 	}
+	currencyIdToSupplyDiffMap := make(map[int32]*currencySupplyDiff)
+	for currencyHash, mintedAmount := range currencyMintedAmountMap {
+		supplyDiff := getCurrencySupplyDiff(currencyIdToSupplyDiffMap, currencyHashToIdMap[currencyHash])
+		supplyDiff.minted = supplyDiff.minted.Add(mintedAmount)
+	}
 
-	if len(updateBalanceResponseArray) > 0 {
-		// get all ids to update
-		var addressBalancesToUpdate []entities.AddressBalance
-		var balanceIdsToUpdate []int32
-		for _, v := range updateBalanceResponseArray {
-			balanceIdsToUpdate = append(balanceIdsToUpdate, v.Id)
+	// build the balance rows to apply, keyed by the unique (addressHash, currencyId) of address_balances
+	var addressBalancesToApply []entities.AddressBalance
+	for k, balanceDiff := range addressBalanceDiffMap {
+		tb := newTokenBalanceFromString(k)
+		currencyId := currencyHashToIdMap[tb.CurrencyHash]
+		addressBalancesToApply = append(addressBalancesToApply, *entities.NewAddressBalance(tb.AddressHash, balanceDiff, currencyId))
+	}
+
+	for i := 0; i < len(addressBalancesToApply); i += addressBalanceBatchSize {
+		end := i + addressBalanceBatchSize
+		if end > len(addressBalancesToApply) {
+			end = len(addressBalancesToApply)
 		}
-		err = dbTransaction.Where(map[string]interface{}{"id": balanceIdsToUpdate}).Find(&addressBalancesToUpdate).Error
+		batch := addressBalancesToApply[i:end]
+
+		// lock the existing balances of the batch by the unique key to know which holders cross zero
+		keys := make([]repository.AddressCurrency, 0, len(batch))
+		for _, ab := range batch {
+			keys = append(keys, repository.AddressCurrency{AddressHash: ab.AddressHash, CurrencyId: ab.CurrencyId})
+		}
+		existingAddressBalances, err := repositories.Balances().FindForUpdate(keys)
 		if err != nil {
 			return err
 		}
-		var mapIdToAddressBalance = make(map[int32]entities.AddressBalance)
-		for _, ab := range addressBalancesToUpdate {
-			mapIdToAddressBalance[ab.ID] = ab
-		}
-		var modifiedAddressBalancesToUpdate []entities.AddressBalance
-		for _, adr := range updateBalanceResponseArray {
-			// get balance diff by identifier
-			balanceDiff := addressBalanceDiffMap[adr.Identifier]
-			// get balanceToUpdate by id
-			balanceToUpdate := mapIdToAddressBalance[adr.Id]
-			// update balance
-			balanceToUpdate.Amount = balanceToUpdate.Amount.Add(balanceDiff)
-			modifiedAddressBalancesToUpdate = append(modifiedAddressBalancesToUpdate, balanceToUpdate)
-			// remove from diff map
-			delete(addressBalanceDiffMap, adr.Identifier)
-
+		existingAmountMap := make(map[string]decimal.Decimal)
+		for _, ab := range existingAddressBalances {
+			existingAmountMap[addressBalanceKey(ab.AddressHash, ab.CurrencyId)] = ab.Amount
 		}
-		if err := dbTransaction.Save(&modifiedAddressBalancesToUpdate).Error; err != nil {
-			return err
+		for _, ab := range batch {
+			oldAmount := existingAmountMap[addressBalanceKey(ab.AddressHash, ab.CurrencyId)]
+			supplyDiff := getCurrencySupplyDiff(currencyIdToSupplyDiffMap, ab.CurrencyId)
+			supplyDiff.circulating = supplyDiff.circulating.Add(ab.Amount)
+			supplyDiff.holders = supplyDiff.holders + holderCountDiff(oldAmount, oldAmount.Add(ab.Amount))
 		}
 
-	}
-	var addressBalancesToCreate []entities.AddressBalance
-	for k, balanceDiff := range addressBalanceDiffMap {
-		tb := newTokenBalanceFromString(k)
-		currencyId := currencyHashToIdMap[tb.CurrencyHash]
-		// create a new address balance
-		addressBalance := entities.NewAddressBalance(tb.AddressHash, balanceDiff, currencyId)
-		addressBalancesToCreate = append(addressBalancesToCreate, *addressBalance)
-	}
-
-	if len(addressBalancesToCreate) > 0 {
-		if err := dbTransaction.Omit("CreateTime", "UpdateTime").Create(&addressBalancesToCreate).Error; err != nil {
+		// insert new balances and add the diff to existing ones in one statement
+		err = repositories.Balances().AddAmounts(batch)
+		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return applyCurrencySupplyDiffs(repositories, currencyIdToSupplyMap, currencyIdToSupplyDiffMap, isReversal)
 }
 
-func updateAddressCounts(dbTransaction *gorm.DB, mapAddressTransactionCount map[string]int32) (err error) {
+func updateAddressCounts(repositories repository.Repositories, mapAddressTransactionCount map[string]int32) (err error) {
 	// build array of addressHash unique array
 	uniqueAddressHashArray := make([]string, 0, len(mapAddressTransactionCount))
 	for k := range mapAddressTransactionCount {
 		uniqueAddressHashArray = append(uniqueAddressHashArray, k)
 	}
 	// get all currency that have currency hash
-	addressCountEntities := make([]entities.AddressTransactionCount, len(mapAddressTransactionCount))
-	err = dbTransaction.Where(map[string]interface{}{"addressHash": uniqueAddressHashArray}).Find(&addressCountEntities).Error
+	addressCountEntities, err := repositories.Addresses().FindTransactionCounts(uniqueAddressHashArray)
 	if err != nil {
 		return err
 	}
@@ -558,7 +819,7 @@ func updateAddressCounts(dbTransaction *gorm.DB, mapAddressTransactionCount map[
 			delete(mapAddressTransactionCount, ac.AddressHash)
 
 		}
-		if err := dbTransaction.Save(&addressCountToUpdate).Error; err != nil {
+		if err := repositories.Addresses().SaveTransactionCounts(addressCountToUpdate); err != nil {
 			return err
 		}
 
@@ -570,67 +831,58 @@ func updateAddressCounts(dbTransaction *gorm.DB, mapAddressTransactionCount map[
 	}
 
 	if len(addressCountToCreate) > 0 {
-		if err := dbTransaction.Omit("CreateTime", "UpdateTime").Create(&addressCountToCreate).Error; err != nil {
+		if err := repositories.Addresses().CreateTransactionCounts(addressCountToCreate); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (service *transactionService) cleanUnindexedTransactionIteration() error {
+func (service *transactionService) cleanUnindexedTransactionIteration() (err error) {
+	ctx, span := tracing.StartIteration("cleanUnindexedTransaction")
+	defer func() { tracing.End(span, err) }()
 	defer func() {
 		if r := recover(); r != nil {
-			log.Println("[cleanUnindexedTransactionIteration][error]")
+			logger.LogPanic(logger.WithJob("cleanUnindexedTransaction"), r)
+			metrics.DbTransactionRollbacks.WithLabelValues(metrics.CleanUnindexedTransactionJob).Inc()
+			tracing.RecordPanic(span, r)
 		}
 	}()
-	deleteTxDelayInHours, err := strconv.ParseFloat(os.Getenv("DELETE_TX_DELAY_IN_HOURS"), 64)
-
-	if err != nil {
-		return err
-	}
-	deleteTxPendingMinHours := os.Getenv("DELETE_TX_PENDING_MIN_HOURS")
+	syncConfig := config.Get().Sync
+	deleteTxDelayInHours := syncConfig.DeleteTxDelay.Hours()
 
 	currTime := time.Now()
 	diffTimeInHours := currTime.Sub(service.serviceUpTime).Hours()
 	if diffTimeInHours < deleteTxDelayInHours {
-		fmt.Println("[cleanUnindexedTransactionIteration][skip delete, time to start is not upon us]")
+		logger.WithJob("cleanUnindexedTransaction").Debug("skip delete, time to start is not upon us")
 		return nil
 	}
-	err = dbProvider.DB.Transaction(func(dbTransaction *gorm.DB) error {
-		var appState entities.AppState
-		err = dbTransaction.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", entities.DeleteUnindexedTransactions).First(&appState).Error
+	var deletedCount int
+	err = service.repositories.WithContext(ctx).Transaction(func(repositories repository.Repositories) error {
+		if err := service.elector.Fence(repositories); err != nil {
+			return err
+		}
+		_, err := repositories.AppStates().GetByNameForUpdate(entities.DeleteUnindexedTransactions)
 		if err != nil {
 			return err
 		}
 		// get all indexed transaction or with status attached to dag from db
-		var txs []entities.Transaction
-		var txsAddresses []entities.TransactionAddress
-		var txsCurrencies []entities.TransactionCurrency
-		var ffbts []entities.FullnodeFeeBaseTransaction
-		var nfbts []entities.NetworkFeeBaseTransaction
-		var rbts []entities.ReceiverBaseTransaction
-		var ibts []entities.InputBaseTransaction
-		var eibts []entities.EventInputBaseTransaction
-		var tgbt []entities.TokenGenerationFeeBaseTransaction
-		var tmbt []entities.TokenMintingFeeBaseTransaction
-
-		err = dbTransaction.Where("`index` IS NULL AND createTime < DATE_SUB(NOW(), INTERVAL ? HOUR)", deleteTxPendingMinHours).Find(&txs).Error
+		txs, err := repositories.Transactions().FindUnindexedCreatedBefore(syncConfig.DeleteTxPendingMin)
 		if err != nil {
 			return err
 		}
 		if len(txs) == 0 {
-			fmt.Println("[cleanUnindexedTransactionIteration][no transactions to delete was found]")
+			logger.WithJob("cleanUnindexedTransaction").Debug("no transactions to delete were found")
 			return nil
 		}
-		var newTxAppState entities.AppState
-		err = dbTransaction.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", entities.LastMonitoredTransactionIndex).First(&newTxAppState).Error
+		_, err = repositories.AppStates().GetByNameForUpdate(entities.LastMonitoredTransactionIndex)
 		if err != nil {
 			return err
 		}
 
 		txs = make([]entities.Transaction, 0)
 		if len(txs) == 0 {
-			fmt.Println("[cleanUnindexedTransactionIteration][no transactions to delete was found]")
+			logger.WithJob("cleanUnindexedTransaction").Debug("no transactions to delete were found")
 			return nil
 		}
 
@@ -642,178 +894,106 @@ func (service *transactionService) cleanUnindexedTransactionIteration() error {
 		for _, v := range txs {
 			transactionIds = append(transactionIds, v.ID)
 		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&tmbt).Error
-		if err != nil {
-			return err
-		}
-		if len(tmbt) > 0 {
-			// get base transactions ids
-			var tmbtBaseTransactionIds []int32
-			var mapBtxIdToTxId = make(map[int32]int32)
-			for _, v := range tmbt {
-				mapBtxIdToTxId[v.ID] = v.TransactionId
-				increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", v.TransactionId, v.AddressHash), v.AddressHash)
-				tmbtBaseTransactionIds = append(tmbtBaseTransactionIds, v.ID)
-			}
-
-			// delete the service data
-			var deletedTmbtsd []entities.TokenMintingServiceData
-			err = dbTransaction.Where(map[string]interface{}{"baseTransactionId": tmbtBaseTransactionIds}).Delete(&deletedTmbtsd).Error
-			if err != nil {
-				return err
-			}
-			for _, v := range deletedTmbtsd {
-				increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", mapBtxIdToTxId[v.ID], v.ReceiverAddress), v.ReceiverAddress)
-			}
-			err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&tmbt).Error
-			if err != nil {
-				return err
-			}
-		}
-
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Find(&tgbt).Error
-		if err != nil {
-			return err
-		}
-		if len(tgbt) > 0 {
-			// get base transactions ids
-			var tgbtBaseTransactionIds []int32
-			for _, v := range tmbt {
-				increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", v.TransactionId, v.AddressHash), v.AddressHash)
-				tgbtBaseTransactionIds = append(tgbtBaseTransactionIds, v.ID)
-			}
-			// find the service data
-			var tokenGenerationServiceData []entities.TokenGenerationServiceData
-			err = dbTransaction.Where(map[string]interface{}{"baseTransactionId": tgbtBaseTransactionIds}).Find(&tokenGenerationServiceData).Error
-			if err != nil {
-				return err
-			}
-			// get base transactions ids
-			var serviceDataIds []int32
-			for _, v := range tmbt {
-
-				serviceDataIds = append(serviceDataIds, v.ID)
-			}
-			var ocd []entities.OriginatorCurrencyData
-			var ctd []entities.CurrencyTypeData
-			err = dbTransaction.Where(map[string]interface{}{"transactionId": serviceDataIds}).Delete(&ocd).Error
-			if err != nil {
-				return err
-			}
-			err = dbTransaction.Where(map[string]interface{}{"transactionId": serviceDataIds}).Delete(&ctd).Error
-			if err != nil {
-				return err
-			}
-			err = dbTransaction.Where(map[string]interface{}{"baseTransactionId": tgbtBaseTransactionIds}).Delete(&tokenGenerationServiceData).Error
-			if err != nil {
-				return err
-			}
-			err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&tgbt).Error
-			if err != nil {
-				return err
-			}
-		}
-
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&ffbts).Error
+		deleted, err := repositories.Transactions().DeleteWithBaseTransactions(transactionIds)
 		if err != nil {
 			return err
 		}
-		for _, v := range ffbts {
-			increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", v.TransactionId, v.AddressHash), v.AddressHash)
+		countAddress := func(transactionId int32, addressHash string) {
+			increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", transactionId, addressHash), addressHash)
 		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&nfbts).Error
-		if err != nil {
-			return err
+		mapBtxIdToTxId := make(map[int32]int32)
+		for _, v := range deleted.TokenMintingFeeBaseTransactions {
+			mapBtxIdToTxId[v.ID] = v.TransactionId
+			countAddress(v.TransactionId, v.AddressHash)
 		}
-		for _, v := range nfbts {
-			increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", v.TransactionId, v.AddressHash), v.AddressHash)
+		for _, v := range deleted.TokenMintingServiceData {
+			countAddress(mapBtxIdToTxId[v.BaseTransactionId], v.ReceiverAddress)
 		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&rbts).Error
-		if err != nil {
-			return err
+		for _, v := range deleted.TokenGenerationFeeBaseTransactions {
+			countAddress(v.TransactionId, v.AddressHash)
 		}
-		for _, v := range rbts {
-			increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", v.TransactionId, v.AddressHash), v.AddressHash)
+		for _, v := range deleted.FullnodeFeeBaseTransactions {
+			countAddress(v.TransactionId, v.AddressHash)
 		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&ibts).Error
-		if err != nil {
-			return err
+		for _, v := range deleted.NetworkFeeBaseTransactions {
+			countAddress(v.TransactionId, v.AddressHash)
 		}
-		for _, v := range ibts {
-			increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", v.TransactionId, v.AddressHash), v.AddressHash)
-		}
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&eibts).Error
-		if err != nil {
-			return err
+		for _, v := range deleted.ReceiverBaseTransactions {
+			countAddress(v.TransactionId, v.AddressHash)
 		}
-		for _, v := range eibts {
-			increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, fmt.Sprintf("%d_%s", v.TransactionId, v.AddressHash), v.AddressHash)
+		for _, v := range deleted.InputBaseTransactions {
+			countAddress(v.TransactionId, v.AddressHash)
 		}
-		err = dbTransaction.Where(map[string]interface{}{"id": transactionIds}).Delete(&txs).Error
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&txsAddresses).Error
-		err = dbTransaction.Where(map[string]interface{}{"transactionId": transactionIds}).Delete(&txsCurrencies).Error
-
-		if err != nil {
-			return err
+		for _, v := range deleted.EventInputBaseTransactions {
+			countAddress(v.TransactionId, v.AddressHash)
 		}
 		// reverse the amount to decrease
 		for k, v := range mapAddressTransactionCount {
 			mapAddressTransactionCount[k] = -v
 		}
-		err = updateAddressCounts(dbTransaction, mapAddressTransactionCount)
+		err = updateAddressCounts(repositories, mapAddressTransactionCount)
 		if err != nil {
 			return err
 		}
+		deletedCount = len(transactionIds)
 		return nil
 	})
 	if err != nil {
+		metrics.DbTransactionRollbacks.WithLabelValues(metrics.CleanUnindexedTransactionJob).Inc()
 		return err
 	}
+	metrics.UnindexedTransactionsDeleted.Add(float64(deletedCount))
 
 	return nil
 }
 
 func (service *transactionService) syncNewTransactions(maxRetries uint8) {
-	maxTransactionsInSync, err := strconv.ParseInt(os.Getenv("MAX_TRANSACTION_IN_SYNC_ITERATION"), 0, 64)
-	if err != nil {
-		panic(err.Error())
-	}
-	interval, err := strconv.ParseFloat(os.Getenv("SYNC_NEW_TRANSACTIONS_INTERVAL_IN_SECONDS"), 64)
-	if err != nil {
-		panic(err.Error())
-	}
+	syncConfig := config.Get().Sync
+	job := jobs.Register("syncNewTransactions", syncConfig.SyncNewTransactionsInterval, int(syncConfig.MaxTransactionsInSyncIteration))
 	var includeUnindexed = false
 
 	// when slice was less than 1000 once replace to the other method that gets un-indexed ones as well
 	iteration := 0
 	for {
 		iteration = iteration + 1
+		interval := job.GetInterval().Seconds()
+		maxTransactionsInSync := int64(job.GetBatchSize())
 		dtStart := time.Now()
-		fmt.Println("[syncNewTransactions][iteration start] " + strconv.Itoa(iteration))
+		iterationLog := logger.WithIteration("syncNewTransactions", iteration)
+		health.Beat("syncNewTransactions", iteration)
+		if job.SleepIfPaused(iterationLog) || leader.SleepIfFollower(service.elector, iterationLog, time.Duration(interval*float64(time.Second))) {
+			continue
+		}
+		iterationLog.Debug("iteration start")
+		var err error
 		for {
-			err := service.syncNewTransactionsIteration(maxTransactionsInSync, &includeUnindexed, service.currentFullnodeUrl)
+			fullnodeUrl := service.GetCurrentFullnodeUrl()
+			err = service.syncNewTransactionsIteration(maxTransactionsInSync, &includeUnindexed, fullnodeUrl)
+			if errors.Is(err, leader.ErrNotLeader) {
+				iterationLog.Warn("lost the leadership, the iteration is rolled back")
+				break
+			}
 			if err != nil {
-				fmt.Println(err)
-				if service.retries >= maxRetries {
-					service.currentFullnodeUrl = service.getAlternateNodeUrl(service.currentFullnodeUrl)
-					service.retries = 0
+				iterationLog.WithError(err).WithField(logger.FullnodeField, fullnodeUrl).Error("iteration failed")
+				if !service.retryFullnode(fullnodeUrl, maxRetries) {
 					break
 				}
-				service.retries = service.retries + 1
 			} else {
-				service.retries = 0
+				service.resetRetries()
 				break
 			}
 
 		}
+		job.Record(iteration, jobs.ScheduleTrigger, dtStart, err)
 
-		fmt.Println("[syncNewTransactions][iteration end] " + strconv.Itoa(iteration))
+		logger.WithDuration(iterationLog, dtStart).Debug("iteration end")
 		dtEnd := time.Now()
 		diff := dtEnd.Sub(dtStart)
 		diffInSeconds := diff.Seconds()
+		metrics.IterationDuration.WithLabelValues(metrics.SyncNewTransactionsJob).Observe(diffInSeconds)
 		if diffInSeconds < interval && diffInSeconds > 0 && includeUnindexed {
 			timeDurationToSleep := time.Duration(interval - diffInSeconds)
-			fmt.Println("[syncNewTransactions][sleeping for] ", timeDurationToSleep)
+			iterationLog.WithField("sleepSeconds", int64(timeDurationToSleep)).Debug("sleeping")
 			time.Sleep(timeDurationToSleep * time.Second)
 
 		}
@@ -822,18 +1002,25 @@ func (service *transactionService) syncNewTransactions(maxRetries uint8) {
 }
 
 func (service *transactionService) syncNewTransactionsIteration(maxTransactionsInSync int64, includeUnindexed *bool, fullnodeUrl string) (err error) {
+	ctx, span := tracing.StartIteration("syncNewTransactions", attribute.String(logger.FullnodeField, fullnodeUrl))
+	defer func() { tracing.End(span, err) }()
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Println(r)
+			logger.LogPanic(logger.WithJob("syncNewTransactions").WithField(logger.FullnodeField, fullnodeUrl), r)
+			metrics.DbTransactionRollbacks.WithLabelValues(metrics.SyncNewTransactionsJob).Inc()
+			tracing.RecordPanic(span, r)
 		}
 	}()
-	err = dbProvider.DB.Transaction(func(dbTransaction *gorm.DB) error {
-		var appState entities.AppState
-		err := dbTransaction.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", entities.LastMonitoredTransactionIndex).First(&appState).Error
+	var lastMonitoredIndex int64
+	var insertedCount int
+	err = service.repositories.WithContext(ctx).Transaction(func(repositories repository.Repositories) error {
+		if err := service.elector.Fence(repositories); err != nil {
+			return err
+		}
+		appState, err := repositories.AppStates().GetByNameForUpdate(entities.LastMonitoredTransactionIndex)
 		if err != nil {
 			return err
 		}
-		var lastMonitoredIndex int64
 		if appState.Value == "" {
 			lastMonitoredIndex = -1
 		} else {
@@ -844,7 +1031,7 @@ func (service *transactionService) syncNewTransactionsIteration(maxTransactionsI
 			lastMonitoredIndex = int64(lastMonitoredIndexInt)
 		}
 		// get the tip
-		lastIndexDtoChannel := service.GetLastIndex(fullnodeUrl)
+		lastIndexDtoChannel := service.GetLastIndex(ctx, fullnodeUrl)
 		lastIndexObj := <-lastIndexDtoChannel
 		if lastIndexObj.Error != nil {
 			return lastIndexObj.Error
@@ -864,22 +1051,30 @@ func (service *transactionService) syncNewTransactionsIteration(maxTransactionsI
 		if startingIndex > endingIndex {
 			includeIndexed = false
 		}
-		err, transactions := service.getTransactions(startingIndex, endingIndex, includeIndexed, *includeUnindexed, fullnodeUrl)
+		err, transactions := service.getTransactions(ctx, startingIndex, endingIndex, includeIndexed, *includeUnindexed, fullnodeUrl)
 		if err != nil {
 			return err
 		}
 		if len(transactions) > 0 {
 			// get all the transactions hash
-			var txHashArray []interface{}
+			var txHashArray []string
 			for _, tx := range transactions {
 				txHashArray = append(txHashArray, tx.Hash)
 			}
 			// find records with a tx hash like the one we got and filter them from the array
-			var dbTransactionsRes []entities.Transaction
-			err = dbTransaction.Where("hash IN (?"+strings.Repeat(",?", len(txHashArray)-1)+")", txHashArray...).Find(&dbTransactionsRes).Error
+			dbTransactionsRes, err := repositories.Transactions().FindByHashes(txHashArray)
+			if err != nil {
+				return err
+			}
+			// the archived transactions were applied already, a rewound index returns them again
+			archivedHashes, err := repositories.Transactions().FindArchivedHashes(txHashArray)
 			if err != nil {
 				return err
 			}
+			isArchived := make(map[string]bool, len(archivedHashes))
+			for _, hash := range archivedHashes {
+				isArchived[hash] = true
+			}
 			var newTransactions []dto.TransactionResponse
 
 			largestIndex := 0
@@ -900,17 +1095,35 @@ func (service *transactionService) syncNewTransactionsIteration(maxTransactionsI
 						if tx.TrustChainTrustScore != dbTx.TrustChainTrustScore {
 							dbTransactionsRes[i].TrustChainTrustScore = tx.TrustChainTrustScore
 						}
+						if isValid := entities.NewNullBool(tx.IsValid); isValid != dbTx.IsValid {
+							dbTransactionsRes[i].IsValid = isValid
+						}
 					}
 				}
-				if largestIndex < int(*tx.Index) {
+				// the unindexed transactions don't move the index
+				if tx.Index != nil && largestIndex < int(*tx.Index) {
 					largestIndex = int(*tx.Index)
 				}
-				if !exists {
+				if !exists && !isArchived[tx.Hash] {
 					newTransactions = append(newTransactions, tx)
 				}
 			}
 			if len(dbTransactionsRes) > 0 {
-				if err := dbTransaction.Save(&dbTransactionsRes).Error; err != nil {
+				dbTransactionsToSave := make([]*entities.Transaction, 0, len(dbTransactionsRes))
+				for i := range dbTransactionsRes {
+					dbTransactionsToSave = append(dbTransactionsToSave, &dbTransactionsRes[i])
+				}
+				if err := repositories.Transactions().Save(dbTransactionsToSave); err != nil {
+					return err
+				}
+				// reverse processed transactions that were invalidated or lost consensus
+				var reversals []transactionReversal
+				for i := range dbTransactionsRes {
+					if reason, isReversal := getReversalReason(&dbTransactionsRes[i]); isReversal {
+						reversals = append(reversals, transactionReversal{tx: &dbTransactionsRes[i], reason: reason})
+					}
+				}
+				if err := reverseTransactions(repositories, service.currencyService, reversals); err != nil {
 					return err
 				}
 			}
@@ -928,90 +1141,169 @@ func (service *transactionService) syncNewTransactionsIteration(maxTransactionsI
 
 				// save all of them
 				if len(entitiesToBeSaved) > 0 {
-					if err := dbTransaction.Omit("CreateTime", "UpdateTime").Create(&entitiesToBeSaved).Error; err != nil {
+					if err := repositories.Transactions().Create(entitiesToBeSaved); err != nil {
 						return err
 					}
 				}
 
-				if err := service.insertBaseTransactionsInputsOutputs(txHashToTxBuilderMap, dbTransaction); err != nil {
+				if err := service.insertBaseTransactionsInputsOutputs(ctx, txHashToTxBuilderMap, repositories); err != nil {
 					return err
 				}
+				insertedCount = len(entitiesToBeSaved)
 
 			}
 
 			if int64(largestIndex) > lastMonitoredIndex {
 				appState.Value = strconv.Itoa(largestIndex)
 
-				if err := dbTransaction.Omit("CreateTime", "UpdateTime").Save(&appState).Error; err != nil {
+				if err := repositories.AppStates().Save(appState); err != nil {
 					return err
 				}
+				lastMonitoredIndex = int64(largestIndex)
 			}
 		}
 
 		return nil
 	})
-	return err
+	if err != nil {
+		metrics.DbTransactionRollbacks.WithLabelValues(metrics.SyncNewTransactionsJob).Inc()
+		return err
+	}
+	metrics.TransactionsInserted.Observe(float64(insertedCount))
+	metrics.LastMonitoredIndex.Set(float64(lastMonitoredIndex))
+	metrics.FullnodeLastIndex.WithLabelValues(service.getNodeName(fullnodeUrl)).Set(float64(service.lastIterationIndex))
+	metrics.SyncLag.Set(float64(service.lastIterationIndex - lastMonitoredIndex))
+	return nil
 }
 
 func (service *transactionService) monitorTransactions(maxRetries uint8) {
 	iteration := 0
-	interval, err := strconv.ParseFloat(os.Getenv("MONITOR_TRANSACTION_INTERVAL_IN_SECONDS"), 64)
-	if err != nil {
-		panic(err.Error())
-	}
+	job := jobs.Register("monitorTransactions", config.Get().Sync.MonitorTransactionsInterval, monitorTransactionsBatchSize)
 	for {
 		iteration++
+		interval := job.GetInterval().Seconds()
 		dtStart := time.Now()
-		fmt.Println("[monitorTransactions][iteration start] " + strconv.Itoa(iteration))
+		iterationLog := logger.WithIteration("monitorTransactions", iteration)
+		health.Beat("monitorTransactions", iteration)
+		if job.SleepIfPaused(iterationLog) || leader.SleepIfFollower(service.elector, iterationLog, time.Duration(interval*float64(time.Second))) {
+			continue
+		}
+		iterationLog.Debug("iteration start")
 
+		var err error
 		for {
-			err := service.monitorTransactionIteration(service.currentFullnodeUrl)
+			fullnodeUrl := service.GetCurrentFullnodeUrl()
+			batchSize := job.GetBatchSize()
+			err = job.RunIteration(func() error { return service.monitorTransactionIteration(fullnodeUrl, batchSize) })
+			if errors.Is(err, leader.ErrNotLeader) {
+				iterationLog.Warn("lost the leadership, the iteration is rolled back")
+				break
+			}
 			if err != nil {
-				fmt.Println(err)
+				iterationLog.WithError(err).WithField(logger.FullnodeField, fullnodeUrl).Error("iteration failed")
 
 				// retry or try with replacement
-				if service.retries >= maxRetries {
-					service.currentFullnodeUrl = service.getAlternateNodeUrl(service.currentFullnodeUrl)
-					service.retries = 0
+				if !service.retryFullnode(fullnodeUrl, maxRetries) {
 					break
 				}
 			} else {
-				service.retries = 0
+				service.resetRetries()
 				break
 			}
 		}
-		fmt.Println("[monitorTransactions][iteration end] " + strconv.Itoa(iteration))
+		job.Record(iteration, jobs.ScheduleTrigger, dtStart, err)
+		logger.WithDuration(iterationLog, dtStart).Debug("iteration end")
 		dtEnd := time.Now()
 		diff := dtEnd.Sub(dtStart)
 		diffInSeconds := diff.Seconds()
+		metrics.IterationDuration.WithLabelValues(metrics.MonitorTransactionsJob).Observe(diffInSeconds)
 		if diffInSeconds < interval && diffInSeconds > 0 {
 			timeDurationToSleep := time.Duration(interval - diffInSeconds)
-			fmt.Println("[monitorTransactions][sleeping for] ", timeDurationToSleep)
+			iterationLog.WithField("sleepSeconds", int64(timeDurationToSleep)).Debug("sleeping")
 			time.Sleep(timeDurationToSleep * time.Second)
 
 		}
 	}
 }
 
-func (service *transactionService) monitorTransactionIteration(fullnodeUrl string) error {
+// findProcessedToMonitor finds the next batch of the transactions processed within the window after the monitored id
+// so their balances can be reversed if they are invalidated, the monitored id starts over at the end of the window
+func findProcessedToMonitor(repositories repository.Repositories, window time.Duration, batchSize int) ([]entities.Transaction, error) {
+	monitoredIdAppState, err := repositories.AppStates().FirstOrCreate(entities.MonitoredProcessedId)
+	if err != nil {
+		return nil, err
+	}
+	var monitoredId int64
+	if monitoredIdAppState.Value != "" {
+		monitoredId, err = strconv.ParseInt(monitoredIdAppState.Value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+	}
+	txs, err := repositories.Transactions().FindProcessedWithin(window, int32(monitoredId), batchSize)
+	if err != nil {
+		return nil, err
+	}
+	monitoredId = 0
+	if len(txs) == batchSize {
+		monitoredId = int64(txs[len(txs)-1].ID)
+	}
+	monitoredIdAppState.Value = strconv.FormatInt(monitoredId, 10)
+	if err := repositories.AppStates().Save(monitoredIdAppState); err != nil {
+		return nil, err
+	}
+	return excludeRebasedTransactions(repositories, txs)
+}
+
+// excludeRebasedTransactions drops the transactions up to the index of the last cluster stamp rebase,
+// their balance effects are part of the stamp balances and can't be reversed
+func excludeRebasedTransactions(repositories repository.Repositories, txs []entities.Transaction) ([]entities.Transaction, error) {
+	appStates, err := repositories.AppStates().FindByNames([]entities.AppStatesNames{entities.RebasedTransactionIndex})
+	if err != nil || len(appStates) == 0 || appStates[0].Value == "" {
+		return txs, err
+	}
+	rebasedIndex, err := strconv.ParseInt(appStates[0].Value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	var reversibleTxs []entities.Transaction
+	for _, tx := range txs {
+		if tx.Index == nil || int64(*tx.Index) > rebasedIndex {
+			reversibleTxs = append(reversibleTxs, tx)
+		}
+	}
+	return reversibleTxs, nil
+}
+
+func (service *transactionService) monitorTransactionIteration(fullnodeUrl string, batchSize int) (err error) {
+	ctx, span := tracing.StartIteration("monitorTransactions", attribute.String(logger.FullnodeField, fullnodeUrl))
+	defer func() { tracing.End(span, err) }()
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Println("[monitorTransactionIteration][error] ")
+			logger.LogPanic(logger.WithJob("monitorTransactions").WithField(logger.FullnodeField, fullnodeUrl), r)
+			metrics.DbTransactionRollbacks.WithLabelValues(metrics.MonitorTransactionsJob).Inc()
+			tracing.RecordPanic(span, r)
 		}
 	}()
 
-	err := dbProvider.DB.Transaction(func(dbTransaction *gorm.DB) error {
+	err = service.repositories.WithContext(ctx).Transaction(func(repositories repository.Repositories) error {
+		if err := service.elector.Fence(repositories); err != nil {
+			return err
+		}
 		// get all indexed transaction or with status attached to dag from db
-		var appState entities.AppState
-		err := dbTransaction.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", entities.MonitorTransaction).First(&appState).Error
+		_, err := repositories.AppStates().GetByNameForUpdate(entities.MonitorTransaction)
+		if err != nil {
+			return err
+		}
+		dbTransactions, err := repositories.Transactions().FindUnconfirmed(service.confirmationPolicy)
 		if err != nil {
 			return err
 		}
-		var dbTransactions []entities.Transaction
-		err = dbProvider.DB.Where("`index` IS NOT NULL AND transactionConsensusUpdateTime IS NULL").Find(&dbTransactions).Error
+		processedTransactions, err := findProcessedToMonitor(repositories, time.Duration(service.reversalMonitorWindowInHours*float64(time.Hour)), batchSize)
 		if err != nil {
 			return err
 		}
+		dbTransactions = append(dbTransactions, processedTransactions...)
 		m := map[string]entities.Transaction{}
 		var hashArray []string
 		for _, tx := range dbTransactions {
@@ -1020,9 +1312,17 @@ func (service *transactionService) monitorTransactionIteration(fullnodeUrl strin
 		}
 		if hashArray != nil {
 			// get the transactions from the node
-			transactions, err := service.getTransactionsByHash(hashArray, fullnodeUrl)
-			if err != nil {
-				return err
+			var transactions []dto.TransactionResponse
+			for start := 0; start < len(hashArray); start += batchSize {
+				end := start + batchSize
+				if end > len(hashArray) {
+					end = len(hashArray)
+				}
+				batch, err := service.getTransactionsByHash(ctx, hashArray[start:end], fullnodeUrl)
+				if err != nil {
+					return err
+				}
+				transactions = append(transactions, batch...)
 			}
 			// update the transactions
 			var transactionToSave []*entities.Transaction
@@ -1045,27 +1345,43 @@ func (service *transactionService) monitorTransactionIteration(fullnodeUrl strin
 					isChanged = true
 					txToSave.TrustChainTrustScore = tx.TrustChainTrustScore
 				}
+				if isValid := entities.NewNullBool(tx.IsValid); isValid != txToSave.IsValid {
+					isChanged = true
+					txToSave.IsValid = isValid
+				}
 				if isChanged {
 					transactionToSave = append(transactionToSave, &txToSave)
 				}
 			}
 			if len(transactionToSave) > 0 {
-				err := dbProvider.DB.Omit("CreateTime", "UpdateTime").Save(&transactionToSave).Error
+				err := repositories.Transactions().Save(transactionToSave)
 				if err != nil {
 					return err
 				}
+				var reversals []transactionReversal
+				for _, tx := range transactionToSave {
+					if reason, isReversal := getReversalReason(tx); isReversal {
+						reversals = append(reversals, transactionReversal{tx: tx, reason: reason})
+					}
+				}
+				if err := reverseTransactions(repositories, service.currencyService, reversals); err != nil {
+					return err
+				}
 			}
 		}
 		return nil
 	})
+	if err != nil {
+		metrics.DbTransactionRollbacks.WithLabelValues(metrics.MonitorTransactionsJob).Inc()
+	}
 	return err
 }
 
-func (service *transactionService) getTransactions(startingIndex int64, endingIndex int64, includeIndexed bool, includeUnindexed bool, fullnodeUrl string) (err error, transactionsResponse []dto.TransactionResponse) {
+func (service *transactionService) getTransactions(ctx context.Context, startingIndex int64, endingIndex int64, includeIndexed bool, includeUnindexed bool, fullnodeUrl string) (err error, transactionsResponse []dto.TransactionResponse) {
 	var data []dto.TransactionResponse
 
 	if includeIndexed {
-		log.Printf("[getTransactions][Getting transactions from index %d to index %d]\n", startingIndex, endingIndex)
+		logrus.WithFields(logrus.Fields{logger.FromIndexField: startingIndex, logger.ToIndexField: endingIndex, logger.FullnodeField: fullnodeUrl}).Debug("getting transactions")
 		values := map[string]string{"startingIndex": strconv.FormatInt(startingIndex, 10), "endingIndex": strconv.FormatInt(endingIndex, 10), "extended": "true", "includeRuntimeTrustScore": "true"}
 		jsonData, err := json.Marshal(values)
 
@@ -1073,8 +1389,7 @@ func (service *transactionService) getTransactions(startingIndex int64, endingIn
 			return err, nil
 		}
 
-		res, err := http.Post(fullnodeUrl+"/transaction_batch", "application/json",
-			bytes.NewBuffer(jsonData))
+		res, err := postFullnode(ctx, fullnodeUrl+"/transaction_batch", jsonData)
 
 		if err != nil {
 			return err, nil
@@ -1095,8 +1410,8 @@ func (service *transactionService) getTransactions(startingIndex int64, endingIn
 	}
 
 	if includeUnindexed {
-		log.Println("[getTransactions][Getting unindexed transactions]")
-		res, err := http.Get(fullnodeUrl + "/transaction/none-indexed/batch")
+		logrus.WithField(logger.FullnodeField, fullnodeUrl).Debug("getting unindexed transactions")
+		res, err := getFullnode(ctx, fullnodeUrl+"/transaction/none-indexed/batch")
 
 		if err != nil {
 			return err, nil
@@ -1121,7 +1436,7 @@ func (service *transactionService) getTransactions(startingIndex int64, endingIn
 	return nil, data
 }
 
-func (service *transactionService) getTransactionsByHash(hashArray []string, fullnodeUrl string) (txs []dto.TransactionResponse, err error) {
+func (service *transactionService) getTransactionsByHash(ctx context.Context, hashArray []string, fullnodeUrl string) (txs []dto.TransactionResponse, err error) {
 	values := map[string]interface{}{"transactionHashes": hashArray, "includeRuntimeTrustScore": "true"}
 	jsonData, err := json.Marshal(values)
 
@@ -1129,8 +1444,7 @@ func (service *transactionService) getTransactionsByHash(hashArray []string, ful
 		return nil, err
 	}
 
-	res, err := http.Post(fullnodeUrl+"/transaction/multiple", "application/json",
-		bytes.NewBuffer(jsonData))
+	res, err := postFullnode(ctx, fullnodeUrl+"/transaction/multiple", jsonData)
 
 	if err != nil {
 		return nil, err
@@ -1153,14 +1467,14 @@ func (service *transactionService) getTransactionsByHash(hashArray []string, ful
 	return data, nil
 }
 
-func (service *transactionService) GetLastIndex(fullnodeUrl string) <-chan dto.TransactionsLastIndexChanelResult {
+func (service *transactionService) GetLastIndex(ctx context.Context, fullnodeUrl string) <-chan dto.TransactionsLastIndexChanelResult {
 
 	r := make(chan dto.TransactionsLastIndexChanelResult)
 	go func() {
 		defer close(r)
 		channelResult := dto.TransactionsLastIndexChanelResult{}
 
-		res, err := http.Get(fullnodeUrl + "/transaction/lastIndex")
+		res, err := getFullnode(ctx, fullnodeUrl+"/transaction/lastIndex")
 
 		if err != nil {
 			channelResult.Error = err
@@ -1196,13 +1510,70 @@ func (service *transactionService) GetLastIndex(fullnodeUrl string) <-chan dto.T
 	return r
 }
 
+// PingFullnodes requests the last index of the main and the backup fullnode, the error of a reachable node is nil
+func (service *transactionService) PingFullnodes(ctx context.Context) map[string]error {
+	nodeToUrl := map[string]string{metrics.MainNode: service.fullnodeUrl, metrics.BackupNode: service.backupFullnodeUrl}
+	results := make(map[string]error, len(nodeToUrl))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for node, fullnodeUrl := range nodeToUrl {
+		if fullnodeUrl == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(node string, fullnodeUrl string) {
+			defer wg.Done()
+			err := PingFullnode(ctx, fullnodeUrl)
+			mutex.Lock()
+			defer mutex.Unlock()
+			results[node] = err
+		}(node, fullnodeUrl)
+	}
+	wg.Wait()
+	return results
+}
+
+// PingFullnode requests the last index of a fullnode, the error is nil when it answers
+func PingFullnode(ctx context.Context, fullnodeUrl string) error {
+	res, err := getFullnode(ctx, fullnodeUrl+"/transaction/lastIndex")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if isResError(res) {
+		return errors.New(res.Status)
+	}
+	return nil
+}
+
+// getFullnode and postFullnode send the fullnode requests with the context of the caller so they are traced as its children
+func getFullnode(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return fullnodeClient.Do(req)
+}
+
+func postFullnode(ctx context.Context, url string, jsonData []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return fullnodeClient.Do(req)
+}
+
 func (service *transactionService) GetLastIteration() int64 {
 	return service.lastIterationIndex
 }
 
-func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToTxBuilderMap map[string]TxBuilder, db *gorm.DB) error {
+func (service *transactionService) insertBaseTransactionsInputsOutputs(ctx context.Context, txHashToTxBuilderMap map[string]TxBuilder, repositories repository.Repositories) (err error) {
+	ctx, span := tracing.Start(ctx, "insertBaseTransactionsInputsOutputs", attribute.Int("transactions", len(txHashToTxBuilderMap)))
+	defer func() { tracing.End(span, err) }()
+	repositories = repositories.WithContext(ctx)
 
-	var currencyServiceInstance = NewCurrencyService()
+	var currencyServiceInstance = service.currencyService
 	var currencies []*entities.Currency
 	var ibtsToBeSaved []*entities.InputBaseTransaction
 	var rbtsToBeSaved []*entities.ReceiverBaseTransaction
@@ -1238,7 +1609,7 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 		txId := value.DbTx.ID
 		attachmentTime := value.DbTx.AttachmentTime
 		for _, baseTransaction := range value.Tx.BaseTransactionsRes {
-			appendTransactionCurrency(txId, txIdToAttachmentTime[txId], baseTransaction.CurrencyHash, helperMapTransactionCurrencies, &txCurrencyBuilders, helperMapCurrencies)
+			appendTransactionCurrency(currencyServiceInstance, txId, txIdToAttachmentTime[txId], baseTransaction.CurrencyHash, helperMapTransactionCurrencies, &txCurrencyBuilders, helperMapCurrencies)
 			uniqueTxIdAddressString := fmt.Sprintf("%d_%s", txId, baseTransaction.AddressHash)
 			if !helperMapTransactionAddresses[uniqueTxIdAddressString] {
 				txAddressBuilders = append(txAddressBuilders, &TransactionAddressBuilder{txId, baseTransaction.AddressHash, attachmentTime})
@@ -1267,67 +1638,45 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 				tgbtsToBeSaved = append(tgbtsToBeSaved, tgbt)
 				tgbtServiceDataBuilders = append(tgbtServiceDataBuilders, &TokenGenerationServiceDataBuilder{ServiceDataRes: &baseTransaction.TokenGenerationServiceData, DbBaseTx: tgbt})
 				_, newCurrencyHash := currencyServiceInstance.GetCurrencyHashBySymbol(baseTransaction.TokenGenerationServiceData.OriginatorCurrencyData.Symbol)
-				appendTransactionCurrency(txId, txIdToAttachmentTime[txId], &newCurrencyHash, helperMapTransactionCurrencies, &txCurrencyBuilders, helperMapCurrencies)
+				appendTransactionCurrency(currencyServiceInstance, txId, txIdToAttachmentTime[txId], &newCurrencyHash, helperMapTransactionCurrencies, &txCurrencyBuilders, helperMapCurrencies)
 			case "TMBT":
 				tmbt := entities.NewTokenMintingFeeBaseTransaction(&baseTransaction, txId)
 				tmbtsToBeSaved = append(tmbtsToBeSaved, tmbt)
 				tmbtServiceDataBuilders = append(tmbtServiceDataBuilders, &TokenMintingServiceDataBuilder{ServiceDataRes: &baseTransaction.TokenMintingServiceData, DbBaseTx: tmbt, DbTx: value.DbTx})
 				newCurrencyHash := baseTransaction.TokenMintingServiceData.MintingCurrencyHash
-				appendTransactionCurrency(txId, txIdToAttachmentTime[txId], &newCurrencyHash, helperMapTransactionCurrencies, &txCurrencyBuilders, helperMapCurrencies)
+				appendTransactionCurrency(currencyServiceInstance, txId, txIdToAttachmentTime[txId], &newCurrencyHash, helperMapTransactionCurrencies, &txCurrencyBuilders, helperMapCurrencies)
 			case "EIBT":
 				eibt := entities.NewEventInputBaseTransaction(&baseTransaction, txId)
 				eibtsToBeSaved = append(eibtsToBeSaved, eibt)
 			default:
-				fmt.Println("Unknown base transaction name: ", baseTransaction.Name)
+				logrus.WithField("name", baseTransaction.Name).Warn("unknown base transaction name")
 			}
 		}
 
 	}
 
-	if len(ibtsToBeSaved) > 0 {
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&ibtsToBeSaved).Error; err != nil {
-			log.Println(err)
-			return err
-		}
-	}
-
-	if len(rbtsToBeSaved) > 0 {
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&rbtsToBeSaved).Error; err != nil {
-			log.Println(err)
-			return err
-		}
-	}
-	if len(ffbtsToBeSaved) > 0 {
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&ffbtsToBeSaved).Error; err != nil {
-			log.Println(err)
-			return err
-		}
-	}
-	if len(nfbtsToBeSaved) > 0 {
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&nfbtsToBeSaved).Error; err != nil {
-			log.Println(err)
-			return err
-		}
-	}
-	if len(eibtsToBeSaved) > 0 {
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&eibtsToBeSaved).Error; err != nil {
-			log.Println(err)
-			return err
-		}
+	err = repositories.Transactions().CreateBaseTransactions(&repository.BaseTransactions{
+		InputBaseTransactions:              ibtsToBeSaved,
+		ReceiverBaseTransactions:           rbtsToBeSaved,
+		FullnodeFeeBaseTransactions:        ffbtsToBeSaved,
+		NetworkFeeBaseTransactions:         nfbtsToBeSaved,
+		EventInputBaseTransactions:         eibtsToBeSaved,
+		TokenGenerationFeeBaseTransactions: tgbtsToBeSaved,
+		TokenMintingFeeBaseTransactions:    tmbtsToBeSaved,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("failed to create the base transactions")
+		return err
 	}
 	if len(tgbtsToBeSaved) > 0 {
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&tgbtsToBeSaved).Error; err != nil {
-			log.Println(err)
-			return err
-		}
 		var tgbtServiceDataToBeSaved []*entities.TokenGenerationServiceData
 		for _, tgbtServiceDataBuilder := range tgbtServiceDataBuilders {
 			dbServiceData := entities.NewTokenGenerationServiceData(tgbtServiceDataBuilder.ServiceDataRes, tgbtServiceDataBuilder.DbBaseTx.ID)
 			tgbtServiceDataToBeSaved = append(tgbtServiceDataToBeSaved, dbServiceData)
 			tgCurrencyBuilders = append(tgCurrencyBuilders, &TokenGenerationCurrencyBuilder{ServiceDataRes: tgbtServiceDataBuilder.ServiceDataRes, DbServiceData: dbServiceData, TxId: tgbtServiceDataBuilder.DbBaseTx.TransactionId})
 		}
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&tgbtServiceDataToBeSaved).Error; err != nil {
-			log.Println(err)
+		if err := repositories.Transactions().CreateTokenGenerationServiceData(tgbtServiceDataToBeSaved); err != nil {
+			logrus.WithError(err).Error("failed to create the token generation service data")
 			return err
 		}
 		if len(tgCurrencyBuilders) > 0 {
@@ -1339,15 +1688,15 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 				originatorCurrencyDataToBeSaved = append(originatorCurrencyDataToBeSaved, entities.NewOriginatorCurrencyData(&currencyBuilder.ServiceDataRes.OriginatorCurrencyData, currencyBuilder.DbServiceData.ID))
 			}
 			if len(currencyTypeDataToBeSaved) > 0 {
-				if err := db.Omit("CreateTime", "UpdateTime").Create(&currencyTypeDataToBeSaved).Error; err != nil {
-					log.Println(err)
+				if err := repositories.Currencies().CreateCurrencyTypeData(currencyTypeDataToBeSaved); err != nil {
+					logrus.WithError(err).Error("failed to create the currency type data")
 					return err
 				}
 			}
 
 			if len(originatorCurrencyDataToBeSaved) > 0 {
-				if err := db.Omit("CreateTime", "UpdateTime").Create(&originatorCurrencyDataToBeSaved).Error; err != nil {
-					log.Println(err)
+				if err := repositories.Currencies().CreateOriginatorCurrencyData(originatorCurrencyDataToBeSaved); err != nil {
+					logrus.WithError(err).Error("failed to create the originator currency data")
 					return err
 				}
 				for _, oct := range originatorCurrencyDataToBeSaved {
@@ -1359,10 +1708,6 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 		}
 	}
 	if len(tmbtsToBeSaved) > 0 {
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&tmbtsToBeSaved).Error; err != nil {
-			log.Println(err)
-			return err
-		}
 		var tmbtServiceDataToBeSaved []*entities.TokenMintingServiceData
 		for _, tmbtServiceDataBuilder := range tmbtServiceDataBuilders {
 			dbServiceData := entities.NewTokenMintingServiceData(tmbtServiceDataBuilder.ServiceDataRes, tmbtServiceDataBuilder.DbBaseTx.ID)
@@ -1378,12 +1723,12 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 			}
 			increaseCountIfUnique(helperMapAddressTransactionCount, mapAddressTransactionCount, uniqueTxIdAddressString, dbServiceData.ReceiverAddress)
 		}
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&tmbtServiceDataToBeSaved).Error; err != nil {
-			log.Println(err)
+		if err := repositories.Transactions().CreateTokenMintingServiceData(tmbtServiceDataToBeSaved); err != nil {
+			logrus.WithError(err).Error("failed to create the token minting service data")
 			return err
 		}
 	}
-	err := updateAddressCounts(db, mapAddressTransactionCount)
+	err = updateAddressCounts(repositories, mapAddressTransactionCount)
 	if err != nil {
 		return err
 	}
@@ -1393,8 +1738,7 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 		for _, v := range addressesToBeSaved {
 			addressHashes = append(addressHashes, v.AddressHash)
 		}
-		var existingAddresses []*entities.Address
-		err := db.Where(map[string]interface{}{"addressHash": addressHashes}).Find(&existingAddresses).Error
+		existingAddresses, err := repositories.Addresses().FindByHashes(addressHashes)
 		if err != nil {
 			return err
 		}
@@ -1412,9 +1756,9 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 			}
 		}
 		if len(newAddresses) > 0 {
-			err = db.Omit("CreateTime", "UpdateTime").Create(&newAddresses).Error
+			err = repositories.Addresses().Create(newAddresses)
 			if err != nil {
-				log.Println(err)
+				logrus.WithError(err).Error("failed to create the addresses")
 				return err
 			}
 		}
@@ -1428,8 +1772,8 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 	}
 
 	if len(currencies) > 0 {
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&currencies).Error; err != nil {
-			log.Println(err)
+		if err := repositories.Currencies().Create(currencies); err != nil {
+			logrus.WithError(err).Error("failed to create the currencies")
 			return err
 		}
 	}
@@ -1439,21 +1783,20 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 			addressId := mapAddressHashToAddressId[txAddressBuilder.addressHash]
 			transactionAddressesToBeSaved = append(transactionAddressesToBeSaved, entities.NewTransactionAddress(addressId, txAddressBuilder.attachmentTime, txAddressBuilder.txId))
 		}
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&transactionAddressesToBeSaved).Error; err != nil {
-			log.Println(err)
+		if err := repositories.Addresses().CreateTransactionAddresses(transactionAddressesToBeSaved); err != nil {
+			logrus.WithError(err).Error("failed to create the transaction addresses")
 			return err
 		}
 	}
 
 	if len(txCurrencyBuilders) > 0 {
-		var currencies []entities.Currency
 		var currencyHashArray []string
 		currencyHashToIdMap := make(map[string]int32)
 		for hash := range helperMapCurrencies {
 			currencyHashArray = append(currencyHashArray, hash)
 		}
 
-		err = db.Where(map[string]interface{}{"hash": currencyHashArray}).Find(&currencies).Error
+		currencies, err := repositories.Currencies().FindByHashes(currencyHashArray)
 		if err != nil {
 			return err
 		}
@@ -1466,8 +1809,8 @@ func (service *transactionService) insertBaseTransactionsInputsOutputs(txHashToT
 			transactionCurrenciesToBeSaved = append(transactionCurrenciesToBeSaved, entities.NewTransactionCurrency(currencyId, txCurrencyBuilder.attachmentTime, txCurrencyBuilder.txId))
 		}
 
-		if err := db.Omit("CreateTime", "UpdateTime").Create(&transactionCurrenciesToBeSaved).Error; err != nil {
-			log.Println(err)
+		if err := repositories.Transactions().CreateTransactionCurrencies(transactionCurrenciesToBeSaved); err != nil {
+			logrus.WithError(err).Error("failed to create the transaction currencies")
 			return err
 		}
 	}
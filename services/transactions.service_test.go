@@ -0,0 +1,730 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coti-io/coti-db-app/config"
+	dbProvider "github.com/coti-io/coti-db-app/db-provider"
+	dbTest "github.com/coti-io/coti-db-app/db-provider/db-test"
+	"github.com/coti-io/coti-db-app/dto"
+	"github.com/coti-io/coti-db-app/entities"
+	"github.com/coti-io/coti-db-app/leader"
+	"github.com/coti-io/coti-db-app/metrics"
+	repository "github.com/coti-io/coti-db-app/repositories"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// testConfig is the config of the service tests, the native currency hash is computed once per process
+var testConfig = map[string]string{"NATIVE_SYMBOL": "COTI", "CONFIRMATION_POLICY": "dspConsensus"}
+
+// forEachRepositories runs test on the memory repositories and the gorm repositories of every dialect,
+// with the app states and the native currency the app creates on start
+func forEachRepositories(t *testing.T, test func(t *testing.T, repositories repository.Repositories)) {
+	t.Run("memory", func(t *testing.T) {
+		if err := config.Init("", testConfig); err != nil {
+			t.Fatal(err)
+		}
+		repositories := repository.NewMemoryRepositories()
+		initTestData(t, repositories)
+		test(t, repositories)
+	})
+	dbTest.ForEachDialect(t, testConfig, func(t *testing.T, db *gorm.DB) {
+		repositories := repository.NewGormRepositories(db)
+		initTestData(t, repositories)
+		test(t, repositories)
+	})
+}
+
+func initTestData(t *testing.T, repositories repository.Repositories) {
+	appStateNames := []entities.AppStatesNames{
+		entities.LastMonitoredTransactionIndex,
+		entities.UpdateBalances,
+		entities.DeleteUnindexedTransactions,
+		entities.MonitorTransaction,
+	}
+	for _, appStateName := range appStateNames {
+		if _, err := repositories.AppStates().FirstOrCreate(appStateName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := NewCurrencyService(repositories.Currencies()).GetNativeCurrency(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testElector always leads
+type testElector struct{}
+
+func (elector testElector) Run() {}
+
+func (elector testElector) IsLeader() bool { return true }
+
+func (elector testElector) Fence(repositories repository.Repositories) error { return nil }
+
+func (elector testElector) GetStatus() leader.Status { return leader.Status{IsLeader: true} }
+
+// fakeFullnode serves the fullnode endpoints the sync calls from the transactions it is given
+type fakeFullnode struct {
+	mutex        sync.Mutex
+	transactions []dto.TransactionResponse
+	// requestedHashCounts is the number of hashes of every /transaction/multiple request
+	requestedHashCounts []int
+	server              *httptest.Server
+}
+
+func newFakeFullnode(t *testing.T) *fakeFullnode {
+	fullnode := &fakeFullnode{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/transaction/lastIndex", func(w http.ResponseWriter, r *http.Request) {
+		lastIndex := int64(-1)
+		fullnode.find(func(tx *dto.TransactionResponse) bool {
+			if tx.Index != nil && int64(*tx.Index) > lastIndex {
+				lastIndex = int64(*tx.Index)
+			}
+			return false
+		})
+		writeJson(w, dto.TransactionsLastIndex{Status: "success", LastIndex: lastIndex})
+	})
+	mux.HandleFunc("/transaction_batch", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			StartingIndex int64 `json:"startingIndex,string"`
+			EndingIndex   int64 `json:"endingIndex,string"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJson(w, fullnode.find(func(tx *dto.TransactionResponse) bool {
+			return tx.Index != nil && int64(*tx.Index) >= request.StartingIndex && int64(*tx.Index) <= request.EndingIndex
+		}))
+	})
+	mux.HandleFunc("/transaction/none-indexed/batch", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, fullnode.find(func(tx *dto.TransactionResponse) bool {
+			return tx.Index == nil
+		}))
+	})
+	mux.HandleFunc("/transaction/multiple", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			TransactionHashes []string `json:"transactionHashes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fullnode.mutex.Lock()
+		fullnode.requestedHashCounts = append(fullnode.requestedHashCounts, len(request.TransactionHashes))
+		fullnode.mutex.Unlock()
+		writeJson(w, fullnode.find(func(tx *dto.TransactionResponse) bool {
+			for _, hash := range request.TransactionHashes {
+				if hash == tx.Hash {
+					return true
+				}
+			}
+			return false
+		}))
+	})
+	fullnode.server = httptest.NewServer(mux)
+	t.Cleanup(fullnode.server.Close)
+	return fullnode
+}
+
+func writeJson(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func (fullnode *fakeFullnode) find(isMatch func(tx *dto.TransactionResponse) bool) []dto.TransactionResponse {
+	fullnode.mutex.Lock()
+	defer fullnode.mutex.Unlock()
+	txs := make([]dto.TransactionResponse, 0)
+	for i := range fullnode.transactions {
+		if isMatch(&fullnode.transactions[i]) {
+			txs = append(txs, fullnode.transactions[i])
+		}
+	}
+	return txs
+}
+
+// set adds the transactions or replaces the ones with the same hash
+func (fullnode *fakeFullnode) set(txs ...dto.TransactionResponse) {
+	fullnode.mutex.Lock()
+	defer fullnode.mutex.Unlock()
+	for _, tx := range txs {
+		isReplaced := false
+		for i := range fullnode.transactions {
+			if fullnode.transactions[i].Hash == tx.Hash {
+				fullnode.transactions[i] = tx
+				isReplaced = true
+			}
+		}
+		if !isReplaced {
+			fullnode.transactions = append(fullnode.transactions, tx)
+		}
+	}
+}
+
+func newTestService(t *testing.T, repositories repository.Repositories, fullnode *fakeFullnode) *transactionService {
+	service := newTransactionService(repositories)
+	service.fullnodeUrl = fullnode.server.URL
+	service.currentFullnodeUrl = fullnode.server.URL
+	service.elector = testElector{}
+	return service
+}
+
+// testTransfer is a transaction from sender to receiver of amount native coins and a fee of 1 to the fullnode
+type testTransfer struct {
+	hash        string
+	index       int32
+	sender      string
+	receiver    string
+	amount      int64
+	isConfirmed bool
+}
+
+func (transfer testTransfer) response() dto.TransactionResponse {
+	txType := "Transfer"
+	tx := dto.TransactionResponse{
+		Hash:                 transfer.hash,
+		Amount:               decimal.NewFromInt(transfer.amount + 1),
+		AttachmentTime:       decimal.NewFromInt(1600000000),
+		CreateTime:           decimal.NewFromInt(1600000000),
+		TrustChainConsensus:  transfer.isConfirmed,
+		TrustChainTrustScore: decimal.NewFromInt(100),
+		Type:                 &txType,
+		BaseTransactionsRes: []dto.BaseTransactionsRes{
+			{Hash: transfer.hash + "-ffbt", Name: "FFBT", AddressHash: "fullnode", Amount: decimal.NewFromInt(1), OriginalAmount: decimal.NewNullDecimal(decimal.NewFromInt(1))},
+			{Hash: transfer.hash + "-rbt", Name: "RBT", AddressHash: transfer.receiver, Amount: decimal.NewFromInt(transfer.amount), OriginalAmount: decimal.NewNullDecimal(decimal.NewFromInt(transfer.amount))},
+			{Hash: transfer.hash + "-ibt", Name: "IBT", AddressHash: transfer.sender, Amount: decimal.NewFromInt(-transfer.amount - 1)},
+		},
+	}
+	if transfer.index >= 0 {
+		index := transfer.index
+		tx.Index = &index
+	}
+	if transfer.isConfirmed {
+		tx.TransactionConsensusUpdateTime = decimal.NewNullDecimal(decimal.NewFromInt(1600000001))
+	}
+	return tx
+}
+
+// getNativeBalances returns the native balances of the addresses, a missing balance is not in the map
+func getNativeBalances(t *testing.T, repositories repository.Repositories, service *transactionService, addressHashes ...string) map[string]decimal.Decimal {
+	t.Helper()
+	native, err := service.currencyService.GetNativeCurrency()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []repository.AddressCurrency
+	for _, addressHash := range addressHashes {
+		keys = append(keys, repository.AddressCurrency{AddressHash: addressHash, CurrencyId: native.ID})
+	}
+	balances := make(map[string]decimal.Decimal)
+	err = repositories.Transaction(func(repositories repository.Repositories) error {
+		addressBalances, err := repositories.Balances().FindForUpdate(keys)
+		for _, addressBalance := range addressBalances {
+			balances[addressBalance.AddressHash] = addressBalance.Amount
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balances
+}
+
+func assertBalances(t *testing.T, balances map[string]decimal.Decimal, expected map[string]int64) {
+	t.Helper()
+	if len(balances) != len(expected) {
+		t.Fatalf("the balances are %v, expected %v", balances, expected)
+	}
+	for addressHash, amount := range expected {
+		if balance, ok := balances[addressHash]; !ok || !balance.Equal(decimal.NewFromInt(amount)) {
+			t.Fatalf("the balances are %v, expected %v", balances, expected)
+		}
+	}
+}
+
+func getTransactions(t *testing.T, repositories repository.Repositories, hashes ...string) map[string]entities.Transaction {
+	t.Helper()
+	txs, err := repositories.Transactions().FindByHashes(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashToTx := make(map[string]entities.Transaction)
+	for _, tx := range txs {
+		hashToTx[tx.Hash] = tx
+	}
+	return hashToTx
+}
+
+func syncIteration(t *testing.T, service *transactionService) {
+	t.Helper()
+	includeUnindexed := false
+	if err := service.syncNewTransactionsIteration(100, &includeUnindexed, service.GetCurrentFullnodeUrl()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func updateBalancesIteration(t *testing.T, service *transactionService) {
+	t.Helper()
+	if err := service.updateBalancesIteration(updateBalancesBatchSize); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncNewTransactions(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories repository.Repositories) {
+		fullnode := newFakeFullnode(t)
+		fullnode.set(
+			testTransfer{hash: "first", index: 0, sender: "alice", receiver: "bob", amount: 10, isConfirmed: true}.response(),
+			testTransfer{hash: "second", index: 1, sender: "bob", receiver: "carol", amount: 4}.response(),
+			testTransfer{hash: "unindexed", index: -1, sender: "carol", receiver: "dave", amount: 1}.response(),
+		)
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		// a second iteration finds nothing new and inserts no duplicates
+		syncIteration(t, service)
+
+		txs := getTransactions(t, repositories, "first", "second", "unindexed")
+		if len(txs) != 3 {
+			t.Fatalf("the sync inserted %d of 3 transactions", len(txs))
+		}
+		if txs["unindexed"].Index != nil || txs["second"].Index == nil || *txs["second"].Index != 1 {
+			t.Fatalf("the indexes were synced as %v and %v", txs["unindexed"].Index, txs["second"].Index)
+		}
+		baseTransactions, err := repositories.Transactions().FindBaseTransactions([]int32{txs["first"].ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(baseTransactions.InputBaseTransactions) != 1 || len(baseTransactions.ReceiverBaseTransactions) != 1 || len(baseTransactions.FullnodeFeeBaseTransactions) != 1 {
+			t.Fatalf("the base transactions were synced as %+v", baseTransactions)
+		}
+		appState, err := repositories.AppStates().GetByName(entities.LastMonitoredTransactionIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if appState.Value != "1" {
+			t.Fatalf("the last monitored index is %q", appState.Value)
+		}
+		counts, err := repositories.Addresses().FindTransactionCounts([]string{"alice", "bob", "carol", "dave", "fullnode"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		addressToCount := make(map[string]int32)
+		for _, count := range counts {
+			addressToCount[count.AddressHash] = count.Count
+		}
+		expectedCounts := map[string]int32{"alice": 1, "bob": 2, "carol": 2, "dave": 1, "fullnode": 3}
+		for addressHash, count := range expectedCounts {
+			if addressToCount[addressHash] != count {
+				t.Fatalf("the address transaction counts are %v, expected %v", addressToCount, expectedCounts)
+			}
+		}
+	})
+}
+
+func TestSyncSkipsArchivedTransactions(t *testing.T) {
+	// the memory repositories have no archive
+	dbTest.ForEachDialect(t, testConfig, func(t *testing.T, db *gorm.DB) {
+		repositories := repository.NewGormRepositories(db)
+		initTestData(t, repositories)
+		fullnode := newFakeFullnode(t)
+		fullnode.set(
+			testTransfer{hash: "first", index: 0, sender: "alice", receiver: "bob", amount: 10, isConfirmed: true}.response(),
+			testTransfer{hash: "second", index: 1, sender: "bob", receiver: "carol", amount: 4, isConfirmed: true}.response(),
+		)
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		updateBalancesIteration(t, service)
+
+		// the first transaction is archived and the index is rewound before it
+		first := getTransactions(t, repositories, "first")["first"]
+		batch := &entities.ArchiveBatch{Target: entities.TableArchiveTarget, TransactionCount: 1, MaxIndex: *first.Index}
+		if err := db.Create(batch).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(entities.NewArchivedTransaction(&first, batch.ID)).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repositories.Transactions().DeleteWithBaseTransactions([]int32{first.ID}); err != nil {
+			t.Fatal(err)
+		}
+		appState, err := repositories.AppStates().GetByName(entities.LastMonitoredTransactionIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appState.Value = "-1"
+		if err := repositories.AppStates().Save(appState); err != nil {
+			t.Fatal(err)
+		}
+		syncIteration(t, service)
+		updateBalancesIteration(t, service)
+
+		if txs := getTransactions(t, repositories, "first"); len(txs) != 0 {
+			t.Fatalf("the archived transaction was synced again as %+v", txs["first"])
+		}
+		assertBalances(t, getNativeBalances(t, repositories, service, "alice", "bob", "carol", "fullnode"),
+			map[string]int64{"alice": -11, "bob": 5, "carol": 4, "fullnode": 2})
+	})
+}
+
+func TestRetryFullnode(t *testing.T) {
+	service := &transactionService{fullnodeUrl: "main", backupFullnodeUrl: "backup", currentFullnodeUrl: "main"}
+	for i := 0; i < 2; i++ {
+		if !service.retryFullnode("main", 2) {
+			t.Fatalf("retry %d was refused", i+1)
+		}
+	}
+	if service.retryFullnode("main", 2) || service.GetCurrentFullnodeUrl() != "backup" {
+		t.Fatalf("the third failure kept the fullnode %s", service.GetCurrentFullnodeUrl())
+	}
+	// an iteration that started on the main fullnode doesn't count against the backup
+	if service.retryFullnode("main", 2) || service.retries != 0 || service.GetCurrentFullnodeUrl() != "backup" {
+		t.Fatalf("a failure on the previous fullnode was counted, the fullnode is %s after %d retries", service.GetCurrentFullnodeUrl(), service.retries)
+	}
+}
+
+func TestSwitchFullnodeBothWays(t *testing.T) {
+	service := &transactionService{fullnodeUrl: "main", backupFullnodeUrl: "backup", currentFullnodeUrl: "main"}
+	for _, expected := range []string{"backup", "main", "backup"} {
+		if url, err := service.SwitchFullnode(""); err != nil || url != expected || service.GetCurrentFullnodeUrl() != expected {
+			t.Fatalf("the switch went to %q (%v), expected %s", url, err, expected)
+		}
+	}
+	// the failing backup fullnode switches back to the main one
+	for i := 0; i < 2; i++ {
+		service.retryFullnode("backup", 1)
+	}
+	if service.GetCurrentFullnodeUrl() != "main" {
+		t.Fatalf("the retries on the backup fullnode switched to %s", service.GetCurrentFullnodeUrl())
+	}
+}
+
+func TestSwitchFullnodeWhileTheJobsRetry(t *testing.T) {
+	service := &transactionService{fullnodeUrl: "main", backupFullnodeUrl: "backup", currentFullnodeUrl: "main"}
+	var wait sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wait.Add(2)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 100; j++ {
+				if !service.retryFullnode(service.GetCurrentFullnodeUrl(), 2) {
+					service.resetRetries()
+				}
+			}
+		}()
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := service.SwitchFullnode(metrics.MainNode); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wait.Wait()
+	if url := service.GetCurrentFullnodeUrl(); url != "main" && url != "backup" {
+		t.Fatalf("the fullnode is %q", url)
+	}
+}
+
+func TestUpdateBalances(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories repository.Repositories) {
+		fullnode := newFakeFullnode(t)
+		second := testTransfer{hash: "second", index: 1, sender: "bob", receiver: "carol", amount: 4}
+		invalid := testTransfer{hash: "invalid", index: 2, sender: "carol", receiver: "dave", amount: 100}.response()
+		invalid.IsValid = new(bool)
+		fullnode.set(
+			testTransfer{hash: "first", index: 0, sender: "alice", receiver: "bob", amount: 10, isConfirmed: true}.response(),
+			second.response(),
+			invalid,
+		)
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		updateBalancesIteration(t, service)
+
+		addresses := []string{"alice", "bob", "carol", "dave", "fullnode"}
+		assertBalances(t, getNativeBalances(t, repositories, service, addresses...), map[string]int64{"alice": -11, "bob": 10, "fullnode": 1})
+		txs := getTransactions(t, repositories, "first", "second", "invalid")
+		if !txs["first"].IsProcessed || txs["second"].IsProcessed || !txs["invalid"].IsProcessed || !txs["invalid"].IsSkipped {
+			t.Fatalf("the transactions were processed as %+v", txs)
+		}
+		skipped, err := repositories.Transactions().FindSkipped(0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(skipped) != 1 || skipped[0].Hash != "invalid" {
+			t.Fatalf("the skipped transactions are %+v", skipped)
+		}
+
+		// the second transfer is applied once the fullnode confirms it and monitorTransactions syncs the confirmation
+		second.isConfirmed = true
+		fullnode.set(second.response())
+		if err := service.monitorTransactionIteration(service.GetCurrentFullnodeUrl(), monitorTransactionsBatchSize); err != nil {
+			t.Fatal(err)
+		}
+		updateBalancesIteration(t, service)
+		updateBalancesIteration(t, service)
+		assertBalances(t, getNativeBalances(t, repositories, service, addresses...), map[string]int64{"alice": -11, "bob": 5, "carol": 4, "fullnode": 2})
+
+		supply, err := repositories.Currencies().FindSupplyByHash(service.currencyService.GetNativeCurrencyHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !supply.CirculatingSupply.IsZero() || supply.HolderCount != 3 {
+			t.Fatalf("the native supply is %+v", supply)
+		}
+	})
+}
+
+func TestBalanceDiffs(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repositories repository.Repositories) {
+		fullnode := newFakeFullnode(t)
+		fullnode.set(
+			testTransfer{hash: "first", index: 0, sender: "alice", receiver: "bob", amount: 10, isConfirmed: true}.response(),
+			testTransfer{hash: "second", index: 1, sender: "bob", receiver: "alice", amount: 3, isConfirmed: true}.response(),
+		)
+		service := newTestService(t, repositories, fullnode)
+		syncIteration(t, service)
+		txs := getTransactions(t, repositories, "first", "second")
+		diffs, err := CollectAddressBalanceDiffs(repositories, []int32{txs["first"].ID, txs["second"].ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertBalances(t, diffs[service.currencyService.GetNativeCurrencyHash()], map[string]int64{"alice": -8, "bob": 6, "fullnode": 2})
+	})
+}
+
+// legacyUpdateBalanceRes is a balance found by its address and currency hash identifier
+type legacyUpdateBalanceRes struct {
+	Id         int32
+	Identifier string
+}
+
+// legacyUpdateBalances is the balance update that was replaced by the upsert, it finds the existing balances by
+// CONCAT(addressHash, '_', currencyHash) IN the keys of the diffs, adds the diffs to them and creates the missing ones.
+// sqlite has no CONCAT so the same identifier is built with || there
+func legacyUpdateBalances(db *gorm.DB, addressBalanceDiffMap map[string]decimal.Decimal) error {
+	diffMap := make(map[string]decimal.Decimal, len(addressBalanceDiffMap))
+	identifiers := make([]interface{}, 0, len(addressBalanceDiffMap))
+	currencyHashes := make(map[string]bool)
+	for key, diff := range addressBalanceDiffMap {
+		diffMap[key] = diff
+		identifiers = append(identifiers, key)
+		currencyHashes[newTokenBalanceFromString(key).CurrencyHash] = true
+	}
+	var hashes []string
+	for currencyHash := range currencyHashes {
+		hashes = append(hashes, currencyHash)
+	}
+	var currencies []entities.Currency
+	if err := db.Where(map[string]interface{}{"hash": hashes}).Find(&currencies).Error; err != nil {
+		return err
+	}
+	currencyHashToIdMap := make(map[string]int32)
+	for _, currency := range currencies {
+		currencyHashToIdMap[currency.Hash] = currency.ID
+	}
+
+	identifier := "address_balances.addressHash || '_' || currencies.hash"
+	if dbProvider.GetDialect() == dbProvider.MysqlDialect {
+		identifier = "CONCAT(address_balances.addressHash, '_', currencies.hash)"
+	}
+	var updateBalanceResponseArray []legacyUpdateBalanceRes
+	err := db.Model(&entities.AddressBalance{}).
+		Select("address_balances.id, "+identifier+" as identifier").
+		Joins("INNER JOIN currencies on currencies.id = address_balances.currencyId").
+		Where(identifier+" IN (?"+strings.Repeat(",?", len(identifiers)-1)+")", identifiers...).
+		Find(&updateBalanceResponseArray).Error
+	if err != nil {
+		return err
+	}
+	if len(updateBalanceResponseArray) > 0 {
+		var balanceIdsToUpdate []int32
+		for _, v := range updateBalanceResponseArray {
+			balanceIdsToUpdate = append(balanceIdsToUpdate, v.Id)
+		}
+		var addressBalancesToUpdate []entities.AddressBalance
+		if err := db.Where(map[string]interface{}{"id": balanceIdsToUpdate}).Find(&addressBalancesToUpdate).Error; err != nil {
+			return err
+		}
+		idToAddressBalance := make(map[int32]entities.AddressBalance)
+		for _, ab := range addressBalancesToUpdate {
+			idToAddressBalance[ab.ID] = ab
+		}
+		var modifiedAddressBalances []entities.AddressBalance
+		for _, v := range updateBalanceResponseArray {
+			balanceToUpdate := idToAddressBalance[v.Id]
+			balanceToUpdate.Amount = balanceToUpdate.Amount.Add(diffMap[v.Identifier])
+			modifiedAddressBalances = append(modifiedAddressBalances, balanceToUpdate)
+			delete(diffMap, v.Identifier)
+		}
+		if err := db.Save(&modifiedAddressBalances).Error; err != nil {
+			return err
+		}
+	}
+	var addressBalancesToCreate []entities.AddressBalance
+	for key, diff := range diffMap {
+		tb := newTokenBalanceFromString(key)
+		addressBalancesToCreate = append(addressBalancesToCreate, *entities.NewAddressBalance(tb.AddressHash, diff, currencyHashToIdMap[tb.CurrencyHash]))
+	}
+	if len(addressBalancesToCreate) > 0 {
+		return db.Omit("CreateTime", "UpdateTime").Create(&addressBalancesToCreate).Error
+	}
+	return nil
+}
+
+// upsertBalances applies the diffs with updateBalances
+func upsertBalances(repositories repository.Repositories, addressBalanceDiffMap map[string]decimal.Decimal) error {
+	currencyHashes := make(map[string]bool)
+	var currencyHashUniqueArray []string
+	for key := range addressBalanceDiffMap {
+		currencyHash := newTokenBalanceFromString(key).CurrencyHash
+		if !currencyHashes[currencyHash] {
+			currencyHashes[currencyHash] = true
+			currencyHashUniqueArray = append(currencyHashUniqueArray, currencyHash)
+		}
+	}
+	return repositories.Transaction(func(repositories repository.Repositories) error {
+		return updateBalances(repositories, currencyHashUniqueArray, addressBalanceDiffMap, map[string]decimal.Decimal{}, false)
+	})
+}
+
+// openBalanceTestDb opens an empty db of the dialect with the native currency and a token
+func openBalanceTestDb(t testing.TB, dialect dbProvider.Dialect) (*gorm.DB, repository.Repositories, []string) {
+	db := dbTest.Open(t, dialect, testConfig)
+	repositories := repository.NewGormRepositories(db)
+	nativeCurrency, err := NewCurrencyService(repositories.Currencies()).GetNativeCurrency()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repositories.Currencies().Create([]*entities.Currency{entities.NewCurrency("token")}); err != nil {
+		t.Fatal(err)
+	}
+	return db, repositories, []string{nativeCurrency.Hash, "token"}
+}
+
+// getAllBalances maps the addressHash_currencyId of every balance to its amount
+func getAllBalances(t testing.TB, db *gorm.DB) map[string]string {
+	var addressBalances []entities.AddressBalance
+	if err := db.Find(&addressBalances).Error; err != nil {
+		t.Fatal(err)
+	}
+	balances := make(map[string]string, len(addressBalances))
+	for _, ab := range addressBalances {
+		balances[addressBalanceKey(ab.AddressHash, ab.CurrencyId)] = ab.Amount.String()
+	}
+	return balances
+}
+
+func TestUpdateBalancesMatchesLegacyUpdate(t *testing.T) {
+	// each batch is a list of address, currency index and diff
+	type diff struct {
+		addressHash   string
+		currencyIndex int
+		amount        string
+	}
+	manyAddresses := make([]diff, 0, 2500)
+	for i := 0; i < 2500; i++ {
+		manyAddresses = append(manyAddresses, diff{fmt.Sprintf("address%d", i), i % 2, fmt.Sprintf("%d.5", i)})
+	}
+	tests := []struct {
+		name    string
+		batches [][]diff
+	}{
+		{"new balances", [][]diff{{{"alice", 0, "10"}, {"bob", 0, "-3.25"}, {"alice", 1, "7"}}}},
+		{"existing balances", [][]diff{{{"alice", 0, "10"}, {"bob", 1, "1"}}, {{"alice", 0, "-4.5"}, {"bob", 1, "2"}, {"carol", 0, "1"}}}},
+		{"balances crossing zero", [][]diff{{{"alice", 0, "1"}, {"bob", 0, "-1"}}, {{"alice", 0, "-1"}, {"bob", 0, "1"}}, {{"alice", 0, "-0.0000000001"}}}},
+		{"more than a batch", [][]diff{manyAddresses, manyAddresses}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			legacyDb, _, currencyHashes := openBalanceTestDb(t, dbProvider.SqliteDialect)
+			upsertDb, upsertRepositories, _ := openBalanceTestDb(t, dbProvider.SqliteDialect)
+			for _, batch := range test.batches {
+				addressBalanceDiffMap := make(map[string]decimal.Decimal)
+				for _, d := range batch {
+					key := newTokenBalance(currencyHashes[d.currencyIndex], d.addressHash).toString()
+					addressBalanceDiffMap[key] = addressBalanceDiffMap[key].Add(decimal.RequireFromString(d.amount))
+				}
+				err := legacyDb.Transaction(func(dbTransaction *gorm.DB) error {
+					return legacyUpdateBalances(dbTransaction, addressBalanceDiffMap)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := upsertBalances(upsertRepositories, addressBalanceDiffMap); err != nil {
+					t.Fatal(err)
+				}
+			}
+			legacyBalances, upsertedBalances := getAllBalances(t, legacyDb), getAllBalances(t, upsertDb)
+			if len(legacyBalances) != len(upsertedBalances) {
+				t.Fatalf("the legacy update has %d balances and the upsert %d", len(legacyBalances), len(upsertedBalances))
+			}
+			for key, amount := range legacyBalances {
+				if !decimal.RequireFromString(upsertedBalances[key]).Equal(decimal.RequireFromString(amount)) {
+					t.Fatalf("the balance %s is %s by the legacy update and %q by the upsert", key, amount, upsertedBalances[key])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkUpdateBalances applies diffs to 1000 of 20000 seeded balances and 100 new ones in each iteration on mysql,
+// the ON DUPLICATE KEY path of the upsert. It is skipped when TEST_MYSQL_HOST is not set
+func BenchmarkUpdateBalances(b *testing.B) {
+	const seededBalances, updatedBalances, newBalances = 20000, 1000, 100
+	newDiffs := func(currencyHash string, iteration int) map[string]decimal.Decimal {
+		addressBalanceDiffMap := make(map[string]decimal.Decimal, updatedBalances+newBalances)
+		for i := 0; i < updatedBalances; i++ {
+			addressHash := fmt.Sprintf("address%d", (iteration*updatedBalances+i)%seededBalances)
+			addressBalanceDiffMap[newTokenBalance(currencyHash, addressHash).toString()] = decimal.NewFromInt(int64(i%7 - 3))
+		}
+		for i := 0; i < newBalances; i++ {
+			addressHash := fmt.Sprintf("new%d-%d", iteration, i)
+			addressBalanceDiffMap[newTokenBalance(currencyHash, addressHash).toString()] = decimal.NewFromInt(1)
+		}
+		return addressBalanceDiffMap
+	}
+	seed := func(b *testing.B, repositories repository.Repositories, currencyHash string) {
+		seedDiffs := make(map[string]decimal.Decimal, seededBalances)
+		for i := 0; i < seededBalances; i++ {
+			seedDiffs[newTokenBalance(currencyHash, fmt.Sprintf("address%d", i)).toString()] = decimal.NewFromInt(100)
+		}
+		if err := upsertBalances(repositories, seedDiffs); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("concatIn", func(b *testing.B) {
+		db, repositories, currencyHashes := openBalanceTestDb(b, dbProvider.MysqlDialect)
+		seed(b, repositories, currencyHashes[0])
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			addressBalanceDiffMap := newDiffs(currencyHashes[0], i)
+			err := db.Transaction(func(dbTransaction *gorm.DB) error {
+				return legacyUpdateBalances(dbTransaction, addressBalanceDiffMap)
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("upsert", func(b *testing.B) {
+		_, repositories, currencyHashes := openBalanceTestDb(b, dbProvider.MysqlDialect)
+		seed(b, repositories, currencyHashes[0])
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := upsertBalances(repositories, newDiffs(currencyHashes[0], i)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/coti-io/coti-db-app/config"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newExporter creates the exporter of TRACING_EXPORTER, nil when tracing is disabled
+func newExporter(tracingConfig config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch tracingConfig.Exporter {
+	case "", NoneExporter:
+		return nil, nil
+	case StdoutExporter:
+		return stdouttrace.New()
+	case OtlpExporter:
+		return newOtlpExporter(tracingConfig)
+	default:
+		return nil, fmt.Errorf("TRACING_EXPORTER must be %s, %s or %s, got %s", NoneExporter, StdoutExporter, OtlpExporter, tracingConfig.Exporter)
+	}
+}
+
+// newOtlpExporter posts the spans to <TRACING_OTLP_ENDPOINT>/v1/traces with the otlp/http encoding
+func newOtlpExporter(tracingConfig config.TracingConfig) (sdktrace.SpanExporter, error) {
+	endpoint, err := url.Parse(tracingConfig.OtlpEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("TRACING_OTLP_ENDPOINT %w", err)
+	}
+	headers, err := tracingConfig.ParseOtlpHeaders()
+	if err != nil {
+		return nil, fmt.Errorf("TRACING_OTLP_HEADERS %w", err)
+	}
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(endpoint.Host),
+		otlptracehttp.WithURLPath(strings.TrimSuffix(endpoint.Path, "/") + "/v1/traces"),
+		otlptracehttp.WithHeaders(headers),
+	}
+	if endpoint.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(context.Background(), options...)
+}
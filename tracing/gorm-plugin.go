@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	gormSpanKey = "tracing:span"
+	// maxStatementLength bounds the statement attribute, the batch inserts have thousands of placeholders
+	maxStatementLength = 4096
+)
+
+// gormPlugin starts a span for each gorm operation, the span is a child of the span in the context of the db handle (db.WithContext)
+type gormPlugin struct{}
+
+// NewGormPlugin creates the tracing plugin registered with db.Use
+func NewGormPlugin() gorm.Plugin {
+	return &gormPlugin{}
+}
+
+func (plugin *gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (plugin *gormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("tracing:before_create", plugin.before("create")),
+		callback.Create().After("gorm:create").Register("tracing:after_create", plugin.after),
+		callback.Query().Before("gorm:query").Register("tracing:before_query", plugin.before("query")),
+		callback.Query().After("gorm:query").Register("tracing:after_query", plugin.after),
+		callback.Update().Before("gorm:update").Register("tracing:before_update", plugin.before("update")),
+		callback.Update().After("gorm:update").Register("tracing:after_update", plugin.after),
+		callback.Delete().Before("gorm:delete").Register("tracing:before_delete", plugin.before("delete")),
+		callback.Delete().After("gorm:delete").Register("tracing:after_delete", plugin.after),
+		callback.Row().Before("gorm:row").Register("tracing:before_row", plugin.before("row")),
+		callback.Row().After("gorm:row").Register("tracing:after_row", plugin.after),
+		callback.Raw().Before("gorm:raw").Register("tracing:before_raw", plugin.before("raw")),
+		callback.Raw().After("gorm:raw").Register("tracing:after_raw", plugin.after),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (plugin *gormPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		spanName := "gorm." + operation
+		if db.Statement.Table != "" {
+			spanName += " " + db.Statement.Table
+		}
+		_, span := otel.Tracer(tracerName).Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemKey.String(db.Dialector.Name())),
+		)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func (plugin *gormPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	if span.IsRecording() {
+		statement := db.Statement.SQL.String()
+		if len(statement) > maxStatementLength {
+			statement = statement[:maxStatementLength]
+		}
+		span.SetAttributes(
+			semconv.DBStatementKey.String(statement),
+			semconv.DBSQLTableKey.String(db.Statement.Table),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+	}
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	End(span, err)
+}
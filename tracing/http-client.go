@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// clientTransport starts a client span for each request and propagates the trace to the server
+type clientTransport struct {
+	service string
+	next    http.RoundTripper
+}
+
+// WrapClient traces the requests of the client, the spans are named after the called service and the request path
+func WrapClient(client *http.Client, service string) *http.Client {
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = &clientTransport{service: service, next: next}
+	return &wrapped
+}
+
+func (transport *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), transport.service+" "+req.Method+" "+req.URL.Path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(req)...),
+	)
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	res, err := transport.next.RoundTrip(req)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	// the span ends with the response headers, reading the body is part of the caller span
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(res.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(res.StatusCode, trace.SpanKindClient))
+	span.End()
+	return res, nil
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coti-io/coti-db-app/config"
+	"github.com/coti-io/coti-db-app/logger"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the exporters of TRACING_EXPORTER
+const (
+	NoneExporter   = "none"
+	StdoutExporter = "stdout"
+	OtlpExporter   = "otlp"
+)
+
+const (
+	tracerName  = "github.com/coti-io/coti-db-app"
+	serviceName = "coti-db-app"
+)
+
+// Init sets the global tracer provider from TRACING_EXPORTER: none, stdout or otlp.
+// The otlp exporter posts the spans to TRACING_OTLP_ENDPOINT with the TRACING_OTLP_HEADERS.
+// TRACING_SAMPLE_RATIO is the ratio of the sampled traces. The returned shutdown flushes the pending spans
+func Init(tracingConfig config.TracingConfig) (shutdown func(ctx context.Context) error, err error) {
+	exporter, err := newExporter(tracingConfig)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the service name above
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracingConfig.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logrus.WithError(err).Warn("tracing error")
+	}))
+	return provider.Shutdown, nil
+}
+
+// Start starts a span of the app tracer, the span is a no-op when tracing is not enabled
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// StartIteration starts the root span of an iteration of a background job
+func StartIteration(job string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Start(context.Background(), job+".iteration", append(attributes, attribute.String(logger.JobField, job))...)
+}
+
+// End ends the span, the span status is an error when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// RecordPanic marks the span as failed by a recovered panic
+func RecordPanic(span trace.Span, r interface{}) {
+	span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", r))
+}